@@ -9,6 +9,9 @@ var (
 	VersionDate string
 	// Git commit shortID
 	VersionCommit string
+	// Base64 Ed25519 public key which release checksums are signed with.
+	// self-update only checks signatures when it is set.
+	ReleaseKey string
 )
 
 func FullVersion() string {
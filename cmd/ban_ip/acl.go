@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+// listBanEntries fetches the entries of the acl written to alongside the
+// dictionary in dual-write mode, keeping those for single addresses keyed by
+// address.
+func listBanEntries(service *fastly.Service, acl *fastly.ACL) (map[string]*fastly.ACLEntry, error) {
+	entries, _, err := client.ACLEntry.List(service.ID, acl.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing entries in acl %s: %s", acl.Name, err)
+	}
+	byAddress := make(map[string]*fastly.ACLEntry)
+	for _, entry := range entries {
+		ip := net.ParseIP(entry.IP)
+		if ip == nil {
+			continue
+		}
+		hostBits := 128
+		if ip.To4() != nil {
+			hostBits = 32
+		}
+		if entry.Subnet == 0 || int(entry.Subnet) == hostBits {
+			byAddress[ip.String()] = entry
+		}
+	}
+	return byAddress, nil
+}
+
+// aclEntryFor returns the entry for address among entries, as returned by
+// listBanEntries.
+func aclEntryFor(entries map[string]*fastly.ACLEntry, address string) (*fastly.ACLEntry, bool) {
+	entry, ok := entries[net.ParseIP(address).String()]
+	return entry, ok
+}
+
+// printACLBanPlan prints the changes banning (or, if remove is set,
+// unbanning) addresses would make to an acl.
+func printACLBanPlan(service *fastly.Service, acl *fastly.ACL, entries map[string]*fastly.ACLEntry, addresses []string, remove bool) {
+	var plan util.BatchPlan
+	for _, address := range addresses {
+		_, ok := aclEntryFor(entries, address)
+		if remove && ok {
+			plan.Delete = append(plan.Delete, address)
+		} else if !remove && !ok {
+			plan.Add = append(plan.Add, address)
+		}
+	}
+	plan.Print(fmt.Sprintf("acl %s on service %s", acl.Name, service.Name))
+}
+
+// rollbackError describes a failure to write to the acl after the dictionary
+// was written, such as "adding 192.0.2.1 to", and whether undoing the
+// dictionary write succeeded.
+func rollbackError(action string, acl *fastly.ACL, err, rollbackErr error) error {
+	if rollbackErr != nil {
+		return fmt.Errorf("Error %s acl %s: %s\nRolling back the dictionary also failed, leaving them inconsistent: %s", action, acl.Name, err, rollbackErr)
+	}
+	return fmt.Errorf("Error %s acl %s: %s\nThe dictionary change was rolled back.", action, acl.Name, err)
+}
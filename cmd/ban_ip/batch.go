@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+// batchLimit is the most changes the API accepts in one batch update.
+const batchLimit = 1000
+
+// waitForRateLimit sleeps until the write rate limit resets, if the client
+// has seen it exhausted.
+func waitForRateLimit() {
+	rate := client.RateLimit()
+	if rate == nil || rate.Remaining > 0 || !time.Now().Before(rate.Reset) {
+		return
+	}
+	fmt.Printf("Write rate limit reached. Waiting until %s\n", rate.Reset.Format(time.RFC3339))
+	time.Sleep(time.Until(rate.Reset))
+}
+
+// applyBatch makes a batch update, first waiting out the rate limit, and
+// retrying once if the API reports the limit exceeded.
+func applyBatch(update func() (*http.Response, error)) error {
+	waitForRateLimit()
+	_, err := update()
+	if _, ok := err.(*fastly.RateLimitError); ok {
+		waitForRateLimit()
+		_, err = update()
+	}
+	return err
+}
+
+// banTarget is the dictionary, and in dual-write mode the acl, holding the
+// bans of a service.
+type banTarget struct {
+	service    *fastly.Service
+	dictionary *fastly.Dictionary
+	// items holds the values of the dictionary's items by key, or is nil
+	// if the dictionary is write-only.
+	items map[string]string
+
+	acl     *fastly.ACL
+	entries map[string]*fastly.ACLEntry
+}
+
+// dictionaryChange reports whether banning (or, if remove is set, unbanning)
+// address changes the dictionary. An address already banned with the same
+// comment keeps its original metadata. The items of write-only dictionaries
+// are unknown, so are always written.
+func (t *banTarget) dictionaryChange(address, value string, remove bool) bool {
+	if t.items == nil {
+		return true
+	}
+	current, ok := t.items[address]
+	if remove {
+		return ok
+	}
+	return !ok || parseBanValue(current).Comment != parseBanValue(value).Comment
+}
+
+func (t *banTarget) aclChange(address string, remove bool) bool {
+	if t.acl == nil {
+		return false
+	}
+	_, ok := aclEntryFor(t.entries, address)
+	return ok == remove
+}
+
+// pending returns the addresses whose ban changes the dictionary or acl.
+func (t *banTarget) pending(addresses []string, value string, remove bool) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		if t.dictionaryChange(address, value, remove) || t.aclChange(address, remove) {
+			out = append(out, address)
+		}
+	}
+	return out
+}
+
+// printPlan prints the changes banning addresses would make.
+func (t *banTarget) printPlan(addresses []string, value string, remove bool) {
+	if t.items == nil {
+		fmt.Printf("%s Skipping\n", util.WriteOnlyError(t.service.Name, t.dictionary))
+	} else {
+		var plan util.BatchPlan
+		for _, address := range addresses {
+			if !t.dictionaryChange(address, value, remove) {
+				continue
+			}
+			if remove {
+				plan.Delete = append(plan.Delete, address)
+			} else if _, ok := t.items[address]; ok {
+				plan.Update = append(plan.Update, address)
+			} else {
+				plan.Add = append(plan.Add, address)
+			}
+		}
+		plan.Print(fmt.Sprintf("dictionary %s on service %s", t.dictionary.Name, t.service.Name))
+	}
+	if t.acl != nil {
+		printACLBanPlan(t.service, t.acl, t.entries, addresses, remove)
+	}
+}
+
+// dictionaryOps returns the batch operations banning addresses, along with
+// those undoing them.
+func (t *banTarget) dictionaryOps(addresses []string, value string, remove bool) (ops, undo []fastly.DictionaryItemUpdate) {
+	for _, address := range addresses {
+		if !t.dictionaryChange(address, value, remove) {
+			continue
+		}
+		current, existed := t.items[address]
+		if remove {
+			ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationDelete, Key: address})
+			undo = append(undo, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpsert, Key: address, Value: current})
+			continue
+		}
+		ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpsert, Key: address, Value: value})
+		if existed {
+			undo = append(undo, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpsert, Key: address, Value: current})
+		} else {
+			undo = append(undo, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationDelete, Key: address})
+		}
+	}
+	return ops, undo
+}
+
+func (t *banTarget) aclOps(addresses []string, comment string, remove bool) []fastly.ACLEntryUpdate {
+	var ops []fastly.ACLEntryUpdate
+	for _, address := range addresses {
+		if !t.aclChange(address, remove) {
+			continue
+		}
+		if remove {
+			entry, _ := aclEntryFor(t.entries, address)
+			ops = append(ops, fastly.ACLEntryUpdate{Operation: fastly.BatchOperationDelete, ID: entry.ID})
+		} else {
+			ops = append(ops, fastly.ACLEntryUpdate{Operation: fastly.BatchOperationCreate, IP: address, Comment: comment})
+		}
+	}
+	return ops
+}
+
+// apply bans (or, if remove is set, unbans) addresses, batchLimit at a time.
+// In dual-write mode, a batch which can't be written to the acl is undone
+// in the dictionary, leaving the earlier batches applied to both.
+func (t *banTarget) apply(addresses []string, value, comment string, remove bool) error {
+	pending := t.pending(addresses, value, remove)
+	if len(pending) == 0 {
+		fmt.Printf("No changes needed on service %s\n", t.service.Name)
+		return nil
+	}
+	s := t.service
+	for i := 0; i < len(pending); i += batchLimit {
+		end := i + batchLimit
+		if end > len(pending) {
+			end = len(pending)
+		}
+		chunk := pending[i:end]
+
+		ops, undo := t.dictionaryOps(chunk, value, remove)
+		if len(ops) > 0 {
+			err := applyBatch(func() (*http.Response, error) {
+				return client.DictionaryItem.BatchUpdate(s.ID, t.dictionary.ID, ops)
+			})
+			if err != nil {
+				return fmt.Errorf("Error updating dictionary %s on service %s after %d/%d addresses: %s", t.dictionary.Name, s.Name, i, len(pending), err)
+			}
+		}
+		if aclOps := t.aclOps(chunk, comment, remove); len(aclOps) > 0 {
+			err := applyBatch(func() (*http.Response, error) {
+				return client.ACLEntry.BatchUpdate(s.ID, t.acl.ID, aclOps)
+			})
+			if err != nil {
+				var rollbackErr error
+				if len(undo) > 0 {
+					rollbackErr = applyBatch(func() (*http.Response, error) {
+						return client.DictionaryItem.BatchUpdate(s.ID, t.dictionary.ID, undo)
+					})
+				}
+				action := fmt.Sprintf("updating %d addresses on service %s, after %d/%d were applied, in", len(chunk), s.Name, i, len(pending))
+				return rollbackError(action, t.acl, err, rollbackErr)
+			}
+		}
+		fmt.Printf("%s: %d/%d addresses applied\n", s.Name, end, len(pending))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// discoveryCache records which services hold the ban dictionary, and in
+// dual-write mode the acl, so that repeat runs needn't list every service and
+// fetch each of their dictionaries.
+type discoveryCache struct {
+	// Key identifies the API key the services were discovered with, without
+	// revealing it.
+	Key        string          `json:"key"`
+	Dictionary string          `json:"dictionary"`
+	ACL        string          `json:"acl,omitempty"`
+	Fetched    time.Time       `json:"fetched"`
+	Services   []cachedService `json:"services"`
+}
+
+type cachedService struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	DictionaryID string `json:"dictionary_id"`
+	WriteOnly    bool   `json:"write_only,omitempty"`
+	ACLID        string `json:"acl_id,omitempty"`
+}
+
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newDiscoveryCache returns the cache of the services discovered in
+// targets, under the global flags of c.
+func newDiscoveryCache(c *cli.Context, targets []*banTarget) *discoveryCache {
+	cache := &discoveryCache{
+		Key:        keyFingerprint(c.GlobalString("fastly-key")),
+		Dictionary: c.GlobalString("dictionary"),
+		Fetched:    time.Now().UTC(),
+	}
+	if c.GlobalBool("dual-write") {
+		cache.ACL = c.GlobalString("acl")
+	}
+	for _, t := range targets {
+		s := cachedService{
+			ID:           t.service.ID,
+			Name:         t.service.Name,
+			DictionaryID: t.dictionary.ID,
+			WriteOnly:    t.dictionary.WriteOnly,
+		}
+		if t.acl != nil {
+			s.ACLID = t.acl.ID
+		}
+		cache.Services = append(cache.Services, s)
+	}
+	return cache
+}
+
+// readDiscoveryCache returns the cache stored in file, or nil if there is
+// none, or it was discovered with other flags or is older than ttl.
+func readDiscoveryCache(c *cli.Context, file string, ttl time.Duration) (*discoveryCache, error) {
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var cache discoveryCache
+	if err := json.Unmarshal(body, &cache); err != nil {
+		return nil, fmt.Errorf("Error parsing cache file %s: %s", file, err)
+	}
+	want := newDiscoveryCache(c, nil)
+	if cache.Key != want.Key || cache.Dictionary != want.Dictionary || cache.ACL != want.ACL {
+		return nil, nil
+	}
+	if time.Since(cache.Fetched) > ttl {
+		return nil, nil
+	}
+	return &cache, nil
+}
+
+func writeDiscoveryCache(file string, cache *discoveryCache) error {
+	body, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0644)
+}
+
+// targets returns the cached ban targets of the services named or with the
+// IDs in names, or of all cached services if names is empty. It returns nil
+// if any of them isn't cached.
+func (cache *discoveryCache) targets(names []string) []*banTarget {
+	var out []*banTarget
+	for _, s := range cache.Services {
+		if len(names) > 0 && !util.StringInSlice(s.Name, names) && !util.StringInSlice(s.ID, names) {
+			continue
+		}
+		t := &banTarget{
+			service:    &fastly.Service{ID: s.ID, Name: s.Name},
+			dictionary: &fastly.Dictionary{ServiceID: s.ID, ID: s.DictionaryID, Name: cache.Dictionary, WriteOnly: s.WriteOnly},
+		}
+		if s.ACLID != "" {
+			t.acl = &fastly.ACL{ServiceID: s.ID, ID: s.ACLID, Name: cache.ACL}
+		}
+		out = append(out, t)
+	}
+	if len(out) < len(names) {
+		return nil
+	}
+	return out
+}
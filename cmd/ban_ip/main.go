@@ -4,26 +4,42 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
+	"time"
 
+	versionInfo "github.com/alienth/fastlyctl/_version"
+	fsync "github.com/alienth/fastlyctl/sync"
 	"github.com/alienth/fastlyctl/util"
 	"github.com/alienth/go-fastly"
 	"github.com/urfave/cli"
 )
 
-var services []*fastly.Service
+var targets []*banTarget
 var client *fastly.Client
 
 func main() {
 	app := cli.NewApp()
 	app.Name = "ban_ip"
+	app.Version = versionInfo.FullVersion()
 
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:   "fastly-key, K",
-			Usage:  "Fastly API Key. Can be read from 'fastly_key' file in CWD.",
+			Usage:  "Fastly API Key, or '-' to read it from stdin. Can be read from 'fastly_key' file in CWD.",
 			EnvVar: "FASTLY_KEY",
 			Value:  util.GetFastlyKey(),
 		},
+		cli.StringFlag{
+			Name:   "fastly-key-file",
+			Usage:  "Read the Fastly API key from `PATH`, such as a mounted secret.",
+			EnvVar: "FASTLY_KEY_FILE",
+		},
+		cli.StringFlag{
+			Name:   "credential-helper",
+			Usage:  "Fetch the Fastly API key by running `HELPER` when no key is set. A bare name runs 'fastlyctl-credential-HELPER get'. Can be read from 'credential_helper' file in CWD.",
+			EnvVar: "FASTLYCTL_CREDENTIAL_HELPER",
+			Value:  util.GetCredentialHelper(),
+		},
 		cli.BoolFlag{
 			Name:  "debug, d",
 			Usage: "Print more detailed info for debugging.",
@@ -37,38 +53,49 @@ func main() {
 			Usage: "The dictionary which we add the IP to.",
 			Value: "banned_ips",
 		},
+		cli.StringFlag{
+			Name:  "acl, A",
+			Usage: "The acl which we add the IP to in --dual-write mode.",
+			Value: "banned_ips",
+		},
+		cli.BoolFlag{
+			Name:  "dual-write",
+			Usage: "Apply each change to both the dictionary and the acl, undoing the dictionary change if the acl can't be changed. Services lacking either are skipped.",
+		},
+		cli.StringFlag{
+			Name:  "config",
+			Usage: "Check ban values against the ValueType of the dictionary in the fastlyctl config `FILE`, if it exists.",
+			Value: "config.toml",
+		},
 		cli.StringSliceFlag{
 			Name:  "service, s",
 			Usage: "The service name which we're going to ban on. Can be specified multiple times. (default: all services which have the specified dictionary)",
 		},
+		cli.StringFlag{
+			Name:  "cache-file",
+			Usage: "Cache the services holding the dictionary, and acl, in `FILE`, so that repeat runs needn't rediscover them. Set to '' to disable.",
+			Value: "ban_ip-cache.json",
+		},
+		cli.DurationFlag{
+			Name:  "cache-ttl",
+			Usage: "Rediscover the services once the cache is older than this.",
+			Value: time.Hour,
+		},
+		cli.BoolFlag{
+			Name:  "refresh-cache",
+			Usage: "Rediscover the services, ignoring the cache, such as after adding the dictionary to a service.",
+		},
 	}
 
 	app.Before = func(c *cli.Context) error {
 		if err := util.CheckFastlyKey(c); err != nil {
 			return err
 		}
-		client = fastly.NewClient(nil, c.GlobalString("fastly-key"))
-
-		serviceNames := c.GlobalStringSlice("service")
-
-		services = make([]*fastly.Service, len(serviceNames))
+		client = util.NewClient(c)
 
-		if len(serviceNames) == 0 {
-			results, _, err := client.Service.List()
-			if err != nil {
-				return fmt.Errorf("Error fetching service list.")
-			}
-			services = results
-		} else {
-			for i, name := range serviceNames {
-				service, err := util.GetServiceByName(client, name)
-				if err != nil {
-					return fmt.Errorf("Error fetching service %s: %s.", name, err)
-				}
-				services[i] = service
-			}
-		}
-		return nil
+		var err error
+		targets, err = discoverTargets(c)
+		return err
 	}
 
 	app.Commands = []cli.Command{
@@ -83,8 +110,9 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "comment, c",
-					Usage: "Optional comment. Placed in the currently unused dictionary value.",
+					Usage: "Optional comment. Recorded, along with when the ban was added, in the currently unused dictionary value.",
 				},
+				dryRunFlag,
 			},
 			ArgsUsage: "<ADDRESS>...",
 			Action:    banAdd,
@@ -93,15 +121,62 @@ func main() {
 		cli.Command{
 			Name:      "rm",
 			ArgsUsage: "<ADDRESS>...",
-			Usage:     "Remove one or more `ADDRESS`es from the ban list",
-			Action:    banRemove,
-			Before:    validateAddresses,
+			Usage:     "Remove one or more `ADDRESS`es from the ban list, or those matching --comment or --older-than",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "comment, c",
+					Usage: "Remove every ban added with `COMMENT`, on each service.",
+				},
+				cli.StringFlag{
+					Name:  "older-than",
+					Usage: "Remove every ban added more than `AGE` ago, such as 30d or 12h. Bans added before their age was recorded are kept.",
+				},
+				dryRunFlag,
+			},
+			Action: banRemove,
+			Before: validateRemove,
 		},
 	}
 
 	app.Run(os.Args)
 }
 
+var dryRunFlag = cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "Print the addresses which would be added, updated, or removed on each service, without changing anything.",
+}
+
+// removeFilter returns the filter given to rm, or nil if addresses were
+// given instead.
+func removeFilter(c *cli.Context) (*banFilter, error) {
+	if !c.IsSet("comment") && !c.IsSet("older-than") {
+		return nil, nil
+	}
+	f := &banFilter{comment: c.String("comment"), now: time.Now()}
+	if c.IsSet("older-than") {
+		age, err := parseAge(c.String("older-than"))
+		if err != nil {
+			return nil, err
+		}
+		f.olderThan = age
+	}
+	return f, nil
+}
+
+func validateRemove(c *cli.Context) error {
+	f, err := removeFilter(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if f == nil {
+		return validateAddresses(c)
+	}
+	if c.NArg() > 0 {
+		return cli.NewExitError("Specify addresses, or --comment or --older-than, but not both.", -1)
+	}
+	return nil
+}
+
 func validateAddresses(c *cli.Context) error {
 	if c.NArg() == 0 {
 		return cli.NewExitError("Specify at least one address.", -1)
@@ -116,83 +191,192 @@ func validateAddresses(c *cli.Context) error {
 	return nil
 }
 
-func banAdd(c *cli.Context) error {
-	value := "1"
-	if c.String("comment") != "" {
-		value = c.String("comment")
+// discoverTarget fetches the dictionary, and in dual-write mode the acl, which
+// hold the bans of service. It returns nil if the service should be skipped.
+func discoverTarget(c *cli.Context, service *fastly.Service) (*banTarget, error) {
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding active version for service %s: %s", service.Name, err)
+	}
+	dictionary, _, err := client.Dictionary.Get(service.ID, activeVersion, c.GlobalString("dictionary"))
+	if err != nil {
+		fmt.Printf("Unable to fetch dictionary %s on service %s. Skipping\n", c.GlobalString("dictionary"), service.Name)
+		return nil, nil
 	}
+	t := &banTarget{service: service, dictionary: dictionary}
+	if c.GlobalBool("dual-write") {
+		if dictionary.WriteOnly {
+			fmt.Printf("%s Its changes can't be rolled back in dual-write mode. Skipping\n", util.WriteOnlyError(service.Name, dictionary))
+			return nil, nil
+		}
+		if t.acl, _, err = client.ACL.Get(service.ID, activeVersion, c.GlobalString("acl")); err != nil {
+			fmt.Printf("Unable to fetch acl %s on service %s. Skipping\n", c.GlobalString("acl"), service.Name)
+			return nil, nil
+		}
+	}
+	return t, nil
+}
 
-	for _, service := range services {
-		activeVersion, err := util.GetActiveVersion(service)
+// discoverTargets returns the ban targets of the services given with
+// --service, or of all services holding the dictionary. Discovering all
+// services is slow on large accounts, so the result is cached in
+// --cache-file.
+func discoverTargets(c *cli.Context) ([]*banTarget, error) {
+	serviceNames := c.GlobalStringSlice("service")
+	file := c.GlobalString("cache-file")
+	if file != "" && !c.GlobalBool("refresh-cache") {
+		cache, err := readDiscoveryCache(c, file, c.GlobalDuration("cache-ttl"))
 		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("Error finding active version for service %s: %s\n", service.Name, err), -1)
+			return nil, err
 		}
-		dictionary, _, err := client.Dictionary.Get(service.ID, activeVersion, c.GlobalString("dictionary"))
-		if err != nil {
-			fmt.Printf("Unable to fetch dictionary %s on service %s. Skipping\n", c.GlobalString("dictionary"), service.Name)
-			continue
+		if cache != nil {
+			if cached := cache.targets(serviceNames); cached != nil {
+				fmt.Printf("Using services cached in %s at %s. Pass --refresh-cache to rediscover them.\n", file, cache.Fetched.Format(time.RFC3339))
+				return cached, nil
+			}
 		}
+	}
 
-		for _, address := range c.Args() {
-			item := new(fastly.DictionaryItem)
-			item.Key = address
-			item.Value = value
-			_, _, err := client.DictionaryItem.Create(service.ID, dictionary.ID, item)
+	var services []*fastly.Service
+	if len(serviceNames) == 0 {
+		results, _, err := client.Service.List(nil)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching service list.")
+		}
+		services = results
+	} else {
+		for _, name := range serviceNames {
+			service, err := util.GetServiceByNameOrID(client, name)
 			if err != nil {
-				return cli.NewExitError(fmt.Sprintf("Error adding item: %s\n", err), -1)
+				return nil, fmt.Errorf("Error fetching service %s: %s.", name, err)
 			}
-			fmt.Printf("Added address %s to dictionary %s on service %s\n", address, c.GlobalString("dictionary"), service.Name)
+			services = append(services, service)
 		}
 	}
-
-	return nil
+	var discovered []*banTarget
+	for _, service := range services {
+		t, err := discoverTarget(c, service)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			discovered = append(discovered, t)
+		}
+	}
+	if file != "" && len(serviceNames) == 0 {
+		if err := writeDiscoveryCache(file, newDiscoveryCache(c, discovered)); err != nil {
+			fmt.Printf("Unable to write cache file %s: %s\n", file, err)
+		}
+	}
+	return discovered, nil
 }
 
-func banRemove(c *cli.Context) error {
-	for _, service := range services {
-		activeVersion, err := util.GetActiveVersion(service)
+// load fetches the current items of the dictionary, and the entries of the
+// acl, which the ban plan is computed against.
+func (t *banTarget) load() error {
+	s := t.service
+	if !t.dictionary.WriteOnly {
+		items, _, err := client.DictionaryItem.List(s.ID, t.dictionary.ID, nil)
 		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("Error finding active version for service %s: %s\n", service.Name, err), -1)
+			return fmt.Errorf("Error listing items in dictionary %s on service %s: %s. If the dictionary was recreated, rerun with --refresh-cache.", t.dictionary.Name, s.Name, err)
+		}
+		t.items = make(map[string]string)
+		for _, item := range items {
+			t.items[item.Key] = item.Value
 		}
-		dictionary, _, err := client.Dictionary.Get(service.ID, activeVersion, c.GlobalString("dictionary"))
+	}
+	if t.acl != nil {
+		entries, err := listBanEntries(s, t.acl)
 		if err != nil {
-			fmt.Printf("Unable to fetch dictionary %s on service %s. Skipping\n", c.GlobalString("dictionary"), service.Name)
-			continue
+			return fmt.Errorf("%s. If the acl was recreated, rerun with --refresh-cache.", err)
 		}
+		t.entries = entries
+	}
+	return nil
+}
 
-		for _, address := range c.Args() {
-			resp, err := client.DictionaryItem.Delete(service.ID, dictionary.ID, address)
-			if err != nil {
-				if resp.StatusCode == 404 {
-					fmt.Printf("IP %s not found in dictionary %s on service %s. Skipping\n", address, c.GlobalString("dictionary"), service.Name)
-					continue
-				}
-				return cli.NewExitError(fmt.Sprintf("Error removing item: %s\n", err), -1)
+// applyBans bans (or, if remove is set, unbans) the addresses given on the
+// command line on each service. If filter is set, the bans it matches are
+// removed instead.
+func applyBans(c *cli.Context, value string, remove bool, filter *banFilter) error {
+	if !remove {
+		if err := checkBanValue(c, value); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+	for _, t := range targets {
+		if err := t.load(); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		addresses := []string(c.Args())
+		if filter != nil {
+			if t.items == nil {
+				fmt.Printf("%s Its bans can't be matched. Skipping\n", util.WriteOnlyError(t.service.Name, t.dictionary))
+				continue
 			}
-			fmt.Printf("Removed address %s from dictionary %s on service %s\n", address, c.GlobalString("dictionary"), service.Name)
+			addresses = t.matching(filter)
+		}
+		if c.Bool("dry-run") {
+			t.printPlan(addresses, value, remove)
+			continue
+		}
+		if err := t.apply(addresses, value, c.String("comment"), remove); err != nil {
+			return cli.NewExitError(err.Error(), -1)
 		}
 	}
+	return nil
+}
 
+// checkBanValue checks value against the ValueType the config file gives the
+// dictionary of each service, before any ban is written.
+func checkBanValue(c *cli.Context, value string) error {
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Error reading config file: %s", err)
+	}
+	for _, t := range targets {
+		d := fsync.LookupDictionary(configs, t.service.Name, t.dictionary.Name)
+		if d == nil {
+			continue
+		}
+		var items []*fastly.DictionaryItem
+		for _, address := range c.Args() {
+			items = append(items, &fastly.DictionaryItem{Key: address, Value: value})
+		}
+		if err := d.CheckValues(items); err != nil {
+			return fmt.Errorf("Service %s: %s", t.service.Name, err)
+		}
+	}
 	return nil
 }
+
+func banAdd(c *cli.Context) error {
+	return applyBans(c, newBanValue(c.String("comment")).String(), false, nil)
+}
+
+func banRemove(c *cli.Context) error {
+	filter, err := removeFilter(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	return applyBans(c, "", true, filter)
+}
+
 func banList(c *cli.Context) error {
-	for _, service := range services {
-		activeVersion, err := util.GetActiveVersion(service)
-		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("Error finding active version for service %s: %s\n", service.Name, err), -1)
-		}
-		dictionary, _, err := client.Dictionary.Get(service.ID, activeVersion, c.GlobalString("dictionary"))
-		if err != nil {
-			fmt.Printf("Unable to fetch dictionary %s on service %s. Skipping\n", c.GlobalString("dictionary"), service.Name)
+	for _, t := range targets {
+		if t.dictionary.WriteOnly {
+			fmt.Printf("%s Skipping\n\n", util.WriteOnlyError(t.service.Name, t.dictionary))
 			continue
 		}
-		items, _, err := client.DictionaryItem.List(service.ID, dictionary.ID)
+		items, _, err := client.DictionaryItem.List(t.service.ID, t.dictionary.ID, nil)
 		if err != nil {
 			return cli.NewExitError(fmt.Sprintf("Error listing items: %s\n", err), -1)
 		}
-		fmt.Printf("Banned IP addresses for service %s:\n\n", service.Name)
+		fmt.Printf("Banned IP addresses for service %s:\n\n", t.service.Name)
 		for _, i := range items {
-			fmt.Println(i.Key, i.Value)
+			fmt.Println(strings.TrimSpace(i.Key + " " + parseBanValue(i.Value).describe()))
 		}
 		fmt.Println("")
 	}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// banValue is the metadata of a ban, stored as JSON in the otherwise unused
+// dictionary value.
+type banValue struct {
+	Comment string    `json:"comment,omitempty"`
+	Added   time.Time `json:"added"`
+}
+
+func newBanValue(comment string) banValue {
+	return banValue{Comment: comment, Added: time.Now().UTC().Truncate(time.Second)}
+}
+
+func (v banValue) String() string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// parseBanValue parses the metadata of a ban. Bans added before the metadata
+// was recorded hold just their comment, or "1" if they had none, and have no
+// known age.
+func parseBanValue(value string) banValue {
+	var v banValue
+	if strings.HasPrefix(value, "{") && json.Unmarshal([]byte(value), &v) == nil {
+		return v
+	}
+	if value == "1" {
+		return banValue{}
+	}
+	return banValue{Comment: value}
+}
+
+// describe formats the metadata of a ban for listing.
+func (v banValue) describe() string {
+	var fields []string
+	if v.Comment != "" {
+		fields = append(fields, v.Comment)
+	}
+	if !v.Added.IsZero() {
+		fields = append(fields, "(added "+v.Added.Format(time.RFC3339)+")")
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseAge parses a duration such as 30d or 12h, allowing days in addition
+// to the units of time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseUint(strings.TrimSuffix(s, "d"), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid age %s.", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid age %s.", s)
+	}
+	return d, nil
+}
+
+// banFilter selects bans for removal by their metadata.
+type banFilter struct {
+	comment   string
+	olderThan time.Duration
+	now       time.Time
+}
+
+// matches reports whether the ban with value is selected. Bans of unknown
+// age are never older than the filter's age.
+func (f *banFilter) matches(value string) bool {
+	v := parseBanValue(value)
+	if f.comment != "" && v.Comment != f.comment {
+		return false
+	}
+	if f.olderThan > 0 && (v.Added.IsZero() || f.now.Sub(v.Added) < f.olderThan) {
+		return false
+	}
+	return true
+}
+
+// matching returns the banned addresses of t selected by f, which must have
+// been loaded from a readable dictionary.
+func (t *banTarget) matching(f *banFilter) []string {
+	var out []string
+	for address, value := range t.items {
+		if f.matches(value) {
+			out = append(out, address)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -2,21 +2,23 @@ package main
 
 import (
 	"fmt"
-	"strconv"
+	"net"
+	"os"
 	"strings"
 
+	fsync "github.com/alienth/fastlyctl/sync"
 	"github.com/alienth/fastlyctl/util"
 	"github.com/alienth/go-fastly"
 	"github.com/urfave/cli"
 )
 
 func aclList(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	var err error
 	serviceParam := c.Args().Get(0)
 	var service *fastly.Service
-	if service, _, err = client.Service.Search(serviceParam); err != nil {
+	if service, err = util.GetServiceByNameOrID(client, serviceParam); err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
 	activeVersion, err := util.GetActiveVersion(service)
@@ -35,10 +37,64 @@ func aclList(c *cli.Context) error {
 	return nil
 }
 
+func aclCreate(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	aclParam := c.Args().Get(1)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		acl := new(fastly.ACL)
+		acl.Name = aclParam
+		if _, _, err := client.ACL.Create(service.ID, version, acl); err != nil {
+			return fmt.Errorf("Error creating ACL %s: %s", aclParam, err)
+		}
+		fmt.Printf("Created ACL %s in version %d of service %s\n", aclParam, version, service.Name)
+		syncer.RecordChange(service, "acl", util.ChangeAdded, aclParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
+
+func aclDelete(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	aclParam := c.Args().Get(1)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, err := client.ACL.Delete(service.ID, version, aclParam); err != nil {
+			return fmt.Errorf("Error deleting ACL %s: %s", aclParam, err)
+		}
+		fmt.Printf("Deleted ACL %s from version %d of service %s\n", aclParam, version, service.Name)
+		syncer.RecordChange(service, "acl", util.ChangeRemoved, aclParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
+
 func getACL(client *fastly.Client, serviceName, aclName string) (*fastly.ACL, error) {
 	var err error
 	var service *fastly.Service
-	if service, _, err = client.Service.Search(serviceName); err != nil {
+	if service, err = util.GetServiceByNameOrID(client, serviceName); err != nil {
 		return nil, err
 	}
 	activeVersion, err := util.GetActiveVersion(service)
@@ -55,30 +111,17 @@ func getACL(client *fastly.Client, serviceName, aclName string) (*fastly.ACL, er
 	return acl, err
 }
 
-func ipMaskSplit(ipParam string) (string, uint8, error) {
-	var subnet uint8
-	ipSplit := strings.Split(ipParam, "/")
-	if len(ipSplit) == 2 {
-		s, err := strconv.Atoi(ipSplit[1])
-		if err != nil {
-			return "", 0, fmt.Errorf("Invalid subnet mask specified: %s", err)
-		}
-		subnet = uint8(s)
-	}
-	return ipSplit[0], subnet, nil
-}
-
 func aclAddEntry(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	serviceParam := c.Args().Get(0)
 	aclParam := c.Args().Get(1)
-	ip, subnet, err := ipMaskSplit(c.Args().Get(2))
+	ip, subnet, err := fsync.SplitIPMask(c.Args().Get(2))
 	if err != nil {
 		return cli.NewExitError(fmt.Sprintf("Invalid subnet mask specified: %s", err), -1)
 	}
 
-	negate := fastly.Compatibool(c.Bool("negate"))
+	negate := fastly.NewCompatibool(c.Bool("negate"))
 	comment := c.String("comment")
 
 	acl, err := getACL(client, serviceParam, aclParam)
@@ -100,11 +143,11 @@ func aclAddEntry(c *cli.Context) error {
 }
 
 func aclRemoveEntry(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	serviceParam := c.Args().Get(0)
 	aclParam := c.Args().Get(1)
-	ip, subnet, err := ipMaskSplit(c.Args().Get(2))
+	ip, subnet, err := fsync.SplitIPMask(c.Args().Get(2))
 	if err != nil {
 		return cli.NewExitError(fmt.Sprintf("Invalid subnet mask specified: %s", err), -1)
 	}
@@ -114,7 +157,7 @@ func aclRemoveEntry(c *cli.Context) error {
 		return cli.NewExitError(err.Error(), -1)
 	}
 
-	entries, _, err := client.ACLEntry.List(acl.ServiceID, acl.ID)
+	entries, _, err := client.ACLEntry.List(acl.ServiceID, acl.ID, nil)
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
@@ -138,26 +181,217 @@ func aclRemoveEntry(c *cli.Context) error {
 	return nil
 }
 
+// netsOverlap reports whether two ranges share any address. As ranges are
+// aligned to their masks, they overlap only if one contains the other.
+func netsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// aclEntryNotes describes, for each entry, any others which it duplicates or
+// overlaps. Overlaps between a range and a negated range within it are
+// intended, and aren't flagged.
+func aclEntryNotes(entries []*fastly.ACLEntry, nets []*net.IPNet) [][]string {
+	notes := make([][]string, len(entries))
+	for i := range entries {
+		for j := range entries {
+			if i == j || nets[i] == nil || nets[j] == nil || !netsOverlap(nets[i], nets[j]) {
+				continue
+			}
+			ones, _ := nets[i].Mask.Size()
+			otherOnes, _ := nets[j].Mask.Size()
+			switch {
+			case ones == otherOnes:
+				notes[i] = append(notes[i], "duplicates "+nets[j].String())
+			case entries[i].Negated.Bool() != entries[j].Negated.Bool():
+			case ones > otherOnes:
+				notes[i] = append(notes[i], "within "+nets[j].String())
+			default:
+				notes[i] = append(notes[i], "contains "+nets[j].String())
+			}
+		}
+	}
+	return notes
+}
+
+// aclMatch returns the entry which decides whether ip is matched, being the
+// most specific entry containing it, or nil if no entry does. The address is
+// matched unless that entry is negated.
+func aclMatch(entries []*fastly.ACLEntry, nets []*net.IPNet, ip net.IP) *fastly.ACLEntry {
+	var match *fastly.ACLEntry
+	best := -1
+	for i, n := range nets {
+		if n == nil || !n.Contains(ip) {
+			continue
+		}
+		if ones, _ := n.Mask.Size(); ones > best {
+			match, best = entries[i], ones
+		}
+	}
+	return match
+}
+
 func aclListEntries(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	serviceParam := c.Args().Get(0)
 	aclParam := c.Args().Get(1)
 
+	var contains net.IP
+	if c.String("contains") != "" {
+		if contains = net.ParseIP(c.String("contains")); contains == nil {
+			return cli.NewExitError(fmt.Sprintf("%s is not a valid IP address.", c.String("contains")), -1)
+		}
+	}
+
 	acl, err := getACL(client, serviceParam, aclParam)
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
 
-	entries, _, err := client.ACLEntry.List(acl.ServiceID, acl.ID)
+	entries, _, err := client.ACLEntry.List(acl.ServiceID, acl.ID, nil)
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
+	nets := make([]*net.IPNet, len(entries))
+	for i, entry := range entries {
+		if nets[i], err = fsync.EntryNet(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping entry %s: %s\n", entry.ID, err)
+		}
+	}
 
+	if contains != nil {
+		match := aclMatch(entries, nets, contains)
+		switch {
+		case match == nil:
+			return cli.NewExitError(fmt.Sprintf("%s is not matched by any entry in acl %s.", contains, aclParam), 1)
+		case match.Negated.Bool():
+			return cli.NewExitError(fmt.Sprintf("%s is excluded from acl %s by negated entry !%s.", contains, aclParam, formatEntryNet(match)), 1)
+		}
+		fmt.Printf("%s is matched by acl %s through entry %s.\n", contains, aclParam, formatEntryNet(match))
+		return nil
+	}
+
+	notes := aclEntryNotes(entries, nets)
 	fmt.Printf("Entries in acl %s for service %s:\n\n", aclParam, serviceParam)
-	for _, entry := range entries {
-		fmt.Println(entry.IP, entry.Subnet, entry.Negated, entry.Comment)
+	var flagged int
+	for i, entry := range entries {
+		cidr := formatEntryNet(entry)
+		if entry.Negated.Bool() {
+			cidr = "!" + cidr
+		}
+		fields := []string{fmt.Sprintf("%-44s", cidr)}
+		if entry.Comment != "" {
+			fields = append(fields, entry.Comment)
+		}
+		if len(notes[i]) > 0 {
+			fields = append(fields, fmt.Sprintf("[%s]", strings.Join(notes[i], ", ")))
+			flagged++
+		}
+		fmt.Println(strings.TrimSpace(strings.Join(fields, " ")))
+	}
+	if flagged > 0 {
+		fmt.Printf("\n%d entries duplicate or overlap others.\n", flagged)
+	}
+
+	return nil
+}
+
+// formatEntryNet renders the range of an entry in CIDR notation, falling back
+// to its address and subnet as given if they don't form a valid range.
+func formatEntryNet(entry *fastly.ACLEntry) string {
+	n, err := fsync.EntryNet(entry)
+	if err != nil {
+		return fmt.Sprintf("%s/%d", entry.IP, entry.Subnet)
 	}
+	return n.String()
+}
+
+func aclCopy(c *cli.Context) error {
+	client := util.NewClient(c)
 
+	srcServiceParam := c.Args().Get(0)
+	srcACLParam := c.Args().Get(1)
+	dstServiceParam := c.Args().Get(2)
+	dstACLParam := c.Args().Get(3)
+	if dstACLParam == "" {
+		dstACLParam = srcACLParam
+	}
+
+	src, err := getACL(client, srcServiceParam, srcACLParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching acl %s for service %s: %s", srcACLParam, srcServiceParam, err), -1)
+	}
+	dst, err := getACL(client, dstServiceParam, dstACLParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching acl %s for service %s: %s", dstACLParam, dstServiceParam, err), -1)
+	}
+	if src.ServiceID == dst.ServiceID && src.ID == dst.ID {
+		return cli.NewExitError("Source and destination acls are the same.", -1)
+	}
+
+	entries, _, err := client.ACLEntry.List(src.ServiceID, src.ID, nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error listing entries in acl %s: %s", src.Name, err), -1)
+	}
+	// Unless replacing the destination's contents, keep the entries for
+	// ranges which the source doesn't cover.
+	if !c.Bool("replace") {
+		existing, _, err := client.ACLEntry.List(dst.ServiceID, dst.ID, nil)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error listing entries in acl %s: %s", dst.Name, err), -1)
+		}
+		covered := make(map[string]bool)
+		for _, e := range entries {
+			covered[fsync.ACLEntryKey(e)] = true
+		}
+		for _, e := range existing {
+			if !covered[fsync.ACLEntryKey(e)] {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	changes, err := syncACLEntries(client, dst.ServiceID, dst.ID, entries, nil, "")
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error copying entries to acl %s: %s", dst.Name, err), -1)
+	}
+	fmt.Printf("Copied acl %s/%s to %s/%s: %d entries created, updated, or removed\n", srcServiceParam, src.Name, dstServiceParam, dst.Name, changes)
+	return nil
+}
+
+func aclImportEntries(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	aclParam := c.Args().Get(1)
+	fileParam := c.Args().Get(2)
+
+	entries, err := fsync.ReadACLEntries(fileParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading entries: %s", err), -1)
+	}
+	acl, err := getACL(client, serviceParam, aclParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	existing, _, err := client.ACLEntry.List(acl.ServiceID, acl.ID, nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error listing entries in acl %s: %s", acl.Name, err), -1)
+	}
+
+	ops, plan := fsync.ACLEntryOps(existing, entries, c.Bool("replace"))
+	target := fmt.Sprintf("acl %s on service %s", acl.Name, serviceParam)
+	if c.Bool("dry-run") {
+		plan.Print(target)
+		return nil
+	}
+	if err := deletionGuard(c).CheckContents(target, "entries", plan, len(existing)); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	changes, err := fsync.BatchUpdateACL(client.ACLEntry, acl.ServiceID, acl.ID, ops)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error importing entries into acl %s after %d changes: %s", acl.Name, changes, err), -1)
+	}
+	fmt.Printf("Imported %d entries into %s/%s: %d entries changed\n", len(entries), serviceParam, acl.Name, changes)
 	return nil
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// pendingActivation is a draft version staged by fastlyctl, such as with
+// push --noop, which has yet to be activated.
+type pendingActivation struct {
+	service             *fastly.Service
+	version             *fastly.Version
+	activeVersion       uint
+	additions, removals int
+}
+
+// skippedActivation is a fastlyctl draft which won't be activated, and why.
+type skippedActivation struct {
+	service *fastly.Service
+	version uint
+	reason  string
+}
+
+// findPendingActivations returns the latest validated fastlyctl draft of each
+// service which is newer than its active version and differs from it, along
+// with the drafts passed over. Drafts awaiting approval by another operator
+// are left to approve. Only locked drafts are activated, as push --noop locks
+// those it leaves, while a draft left unlocked may hold a push which failed
+// part way.
+func findPendingActivations(client *fastly.Client) ([]pendingActivation, []skippedActivation, error) {
+	services, _, err := client.Service.List(nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error listing services: %s", err)
+	}
+
+	var pending []pendingActivation
+	var skipped []skippedActivation
+	for _, s := range services {
+		details, _, err := client.Service.Details(s.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error fetching service %s: %s", s.Name, err)
+		}
+		service := details.Service()
+		activeVersion, err := util.GetActiveVersion(service)
+		if err != nil {
+			continue
+		}
+		var draft *fastly.Version
+		for _, v := range service.Versions {
+			if v.Number <= activeVersion || v.Active || !fsync.IsToolDraft(v.Comment) || approvalRequester.MatchString(v.Comment) {
+				continue
+			}
+			if draft == nil || v.Number > draft.Number {
+				draft = v
+			}
+		}
+		if draft == nil {
+			continue
+		}
+		if !draft.Locked {
+			skipped = append(skipped, skippedActivation{service, draft.Number, "unlocked, so may be left from a failed push"})
+			continue
+		}
+		diff, err := util.GetUnifiedDiff(client, service, activeVersion, draft.Number)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error fetching diff for service %s: %s", service.Name, err)
+		}
+		additions, removals := util.CountChanges(&diff)
+		if additions == 0 && removals == 0 {
+			skipped = append(skipped, skippedActivation{service, draft.Number, "no changes"})
+			continue
+		}
+		if err := util.ValidateVersion(client, service, draft.Number); err != nil {
+			skipped = append(skipped, skippedActivation{service, draft.Number, err.Error()})
+			continue
+		}
+		pending = append(pending, pendingActivation{service, draft, activeVersion, additions, removals})
+	}
+	return pending, skipped, nil
+}
+
+func activatePending(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	pending, skipped, err := findPendingActivations(client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("\nSkipped versions:\n\n")
+		fmt.Printf("%-30s %7s  %s\n", "Service", "Version", "Reason")
+		for _, s := range skipped {
+			fmt.Printf("%-30s %7d  %s\n", s.service.Name, s.version, s.reason)
+		}
+	}
+	if len(pending) == 0 {
+		fmt.Println("No pending versions to activate.")
+		return nil
+	}
+
+	fmt.Printf("\nPending versions:\n\n")
+	fmt.Printf("%-30s %7s %7s %9s %9s\n", "Service", "Active", "Pending", "Additions", "Removals")
+	for _, p := range pending {
+		fmt.Printf("%-30s %7d %7d %9d %9d\n", p.service.Name, p.activeVersion, p.version.Number, p.additions, p.removals)
+	}
+	for _, p := range pending {
+		fmt.Printf("Diff URL for %s: %s\n", p.service.Name, util.GetDiffUrl(p.service, p.activeVersion, p.version.Number).String())
+	}
+	fmt.Println()
+
+	if !c.GlobalBool("assume-yes") {
+		proceed, err := util.Prompt(fmt.Sprintf("Activate %d versions?", len(pending)))
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		if !proceed {
+			return nil
+		}
+	}
+
+	for i, p := range pending {
+		if _, _, err := client.Version.Activate(p.service.ID, p.version.Number); err != nil {
+			var remaining []string
+			for _, r := range pending[i+1:] {
+				remaining = append(remaining, r.service.Name)
+			}
+			msg := fmt.Sprintf("Error activating version %d for service %s: %s", p.version.Number, p.service.Name, err)
+			if len(remaining) > 0 {
+				msg += fmt.Sprintf("\nNot activated: %s", strings.Join(remaining, ", "))
+			}
+			return cli.NewExitError(msg, -1)
+		}
+		fmt.Printf("Activated version %d for %s. Old version: %d\n", p.version.Number, p.service.Name, p.activeVersion)
+		if c.Bool("wait") {
+			if err := util.WaitForDeployment(client, p.service, p.version.Number, c.Duration("wait-timeout")); err != nil {
+				return cli.NewExitError(err.Error(), -1)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// detectConfigFormat guesses the format of a config document from its file
+// extension or, for stdin, its content. Documents which aren't JSON are tried
+// as TOML before YAML, as a YAML document is rarely valid TOML.
+func detectConfigFormat(file string, body []byte) []string {
+	switch filepath.Ext(file) {
+	case ".toml":
+		return []string{"toml"}
+	case ".json":
+		return []string{"json"}
+	case ".yaml", ".yml":
+		return []string{"yaml"}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return []string{"json"}
+	}
+	return []string{"toml", "yaml"}
+}
+
+func applyConfig(c *cli.Context) error {
+	file := c.String("file")
+	var body []byte
+	var err error
+	if file == "-" {
+		body, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		body, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config: %s", err), util.ExitError)
+	}
+
+	formats := detectConfigFormat(file, body)
+	if format := c.String("format"); format != "" {
+		formats = []string{format}
+	}
+	var configs map[string]fsync.SiteConfig
+	for _, format := range formats {
+		if configs, err = fsync.ParseConfig(body, format); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config: %s", err), util.ExitError)
+	}
+
+	// Defaults have been merged into the service's config by now.
+	delete(configs, "_default_")
+	if len(configs) != 1 {
+		return cli.NewExitError(fmt.Sprintf("Error: apply takes a config for exactly one service, but %d were given.", len(configs)), util.ExitError)
+	}
+	return pushConfigs(c, configs, true)
+}
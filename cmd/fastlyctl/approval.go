@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// approvalMarker is appended to the comment of versions awaiting approval,
+// followed by the name of the operator who requested it.
+const approvalMarker = "pending-approval requested-by="
+
+var approvalRequester = regexp.MustCompile(regexp.QuoteMeta(approvalMarker) + `(\S+)`)
+
+var approvalsFileFlag = cli.StringFlag{
+	Name:  "approvals-file",
+	Usage: "Record pending and completed approvals in `FILE`.",
+	Value: "fastlyctl-approvals.json",
+}
+
+var operatorFlag = cli.StringFlag{
+	Name:   "operator",
+	Usage:  "Name recorded as the operator requesting or approving a version. Defaults to the current user.",
+	EnvVar: "FASTLYCTL_OPERATOR",
+}
+
+// Approval records a version which has been staged for activation by a second
+// operator.
+type Approval struct {
+	Service     string
+	ServiceID   string
+	Version     uint
+	RequestedBy string
+	RequestedAt time.Time
+	ApprovedBy  string     `json:",omitempty"`
+	ApprovedAt  *time.Time `json:",omitempty"`
+}
+
+// currentOperator returns the name recorded against approval requests and
+// approvals.
+func currentOperator(c *cli.Context) string {
+	if operator := c.String("operator"); operator != "" {
+		return operator
+	}
+	return systemUser()
+}
+
+func readApprovals(file string) ([]Approval, error) {
+	var approvals []Approval
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return approvals, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &approvals); err != nil {
+		return nil, fmt.Errorf("Error parsing approvals file %s: %s", file, err)
+	}
+	return approvals, nil
+}
+
+func writeApprovals(file string, approvals []Approval) error {
+	body, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0644)
+}
+
+// requestApproval marks a validated version as awaiting approval rather than
+// activating it. The version is locked so that the configuration which is
+// approved is the configuration which was staged.
+func requestApproval(c *cli.Context, client *fastly.Client, s *fastly.Service, v *fastly.Version) error {
+	operator := currentOperator(c)
+	if operator == "" {
+		return fmt.Errorf("Unable to determine operator name. Specify one with --operator.")
+	}
+
+	update := *v
+	update.Comment = fmt.Sprintf("%s %s%s", v.Comment, approvalMarker, operator)
+	update.Created = ""
+	update.Updated = ""
+	if _, _, err := client.Version.Update(s.ID, v.Number, &update); err != nil {
+		return err
+	}
+	if _, _, err := client.Version.Lock(s.ID, v.Number); err != nil {
+		return err
+	}
+
+	activeVersion, err := util.GetActiveVersion(s)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Diff URL: %s\n", util.GetDiffUrl(s, activeVersion, v.Number).String())
+
+	file := c.String("approvals-file")
+	approvals, err := readApprovals(file)
+	if err != nil {
+		return err
+	}
+	approvals = append(approvals, Approval{
+		Service:     s.Name,
+		ServiceID:   s.ID,
+		Version:     v.Number,
+		RequestedBy: operator,
+		RequestedAt: time.Now().UTC(),
+	})
+	if err = writeApprovals(file, approvals); err != nil {
+		return fmt.Errorf("Error writing approvals file %s: %s", file, err)
+	}
+
+	fmt.Printf("Version %d for %s is staged and awaiting approval. Another operator must run:\n\n", v.Number, s.Name)
+	fmt.Printf("    fastlyctl approve %s %d\n\n", s.Name, v.Number)
+	return nil
+}
+
+func approveVersion(c *cli.Context) error {
+	client := util.NewClient(c)
+	serviceParam := c.Args().Get(0)
+	number, err := strconv.Atoi(c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError("Invalid version number.\n", -1)
+	}
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	version, _, err := client.Version.Get(service.ID, uint(number))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching version %d: %s", number, err), -1)
+	}
+
+	match := approvalRequester.FindStringSubmatch(version.Comment)
+	if match == nil {
+		return cli.NewExitError(fmt.Sprintf("Version %d on service %s is not awaiting approval.", number, service.Name), -1)
+	}
+	if version.Active {
+		return cli.NewExitError(fmt.Sprintf("Version %d on service %s is already active.", number, service.Name), -1)
+	}
+	operator := currentOperator(c)
+	if operator == "" {
+		return cli.NewExitError("Unable to determine operator name. Specify one with --operator.", -1)
+	}
+	if operator == match[1] {
+		return cli.NewExitError(fmt.Sprintf("Version %d was staged by %s, and must be approved by a different operator.", number, operator), -1)
+	}
+
+	fmt.Printf("Version %d on service %s was staged by %s.\n", number, service.Name, match[1])
+	if err = util.ValidateVersion(client, service, version.Number); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activated, err := util.ActivateVersion(c, client, service, version, nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error activating version %d for service %s: %s", number, service.Name, err), -1)
+	}
+	if !activated {
+		return nil
+	}
+
+	file := c.String("approvals-file")
+	approvals, err := readApprovals(file)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	now := time.Now().UTC()
+	for i, a := range approvals {
+		if a.ServiceID == service.ID && a.Version == uint(number) && a.ApprovedBy == "" {
+			approvals[i].ApprovedBy = operator
+			approvals[i].ApprovedAt = &now
+		}
+	}
+	if err = writeApprovals(file, approvals); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error writing approvals file %s: %s", file, err), -1)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+)
+
+// versionComment is the comment given to new versions: the marker followed by
+// the expansion of the --version-comment template, if one was given.
+var versionComment = fsync.VersionMarker
+
+// versionCommentFields are available to --version-comment templates.
+type versionCommentFields struct {
+	User      string
+	GitSHA    string
+	Timestamp string
+}
+
+// setVersionComment expands tmpl into the comment given to new versions. The
+// git SHA is that of the repository holding configFile.
+func setVersionComment(tmpl, configFile string) error {
+	versionComment = fsync.VersionMarker
+	if tmpl == "" {
+		return nil
+	}
+	t, err := template.New("comment").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("Error parsing version comment template: %s", err)
+	}
+	fields := versionCommentFields{
+		User:      systemUser(),
+		GitSHA:    gitSHA(filepath.Dir(configFile)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, fields); err != nil {
+		return fmt.Errorf("Error expanding version comment template: %s", err)
+	}
+	if comment := strings.TrimSpace(buf.String()); comment != "" {
+		versionComment = fsync.VersionMarker + " " + comment
+	}
+	return nil
+}
+
+// systemUser returns the name of the user running fastlyctl.
+func systemUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// gitSHA returns the abbreviated commit of the git repository containing dir,
+// or an empty string if there isn't one.
+func gitSHA(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+// checkSnapshotContents checks the recorded items of every dictionary in a
+// snapshot against Fastly's limits, so that a restore doesn't fail part way.
+func checkSnapshotContents(snapshot *fsync.Snapshot, maxItems int) error {
+	var errs []string
+	for _, d := range snapshot.Dictionaries {
+		items, ok := snapshot.DictionaryItems[d.Name]
+		if !ok {
+			continue
+		}
+		if err := fsync.CheckDictionaryItems(d.Name, items, len(items), maxItems); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// syncDictionaryItems makes the items in a dictionary match the given items,
+// returning the number of items created, updated, or deleted. If guard is
+// set, it must allow the deletions, which are described by target.
+func syncDictionaryItems(client *fastly.Client, serviceID, dictionaryID string, items []*fastly.DictionaryItem, guard *util.DeletionGuard, target string) (int, error) {
+	existingItems, _, err := client.DictionaryItem.List(serviceID, dictionaryID, nil)
+	if err != nil {
+		return 0, err
+	}
+	ops, plan := fsync.DictionaryItemOps(existingItems, items, true)
+	if guard != nil {
+		if err := guard.CheckContents(target, "items", plan, len(existingItems)); err != nil {
+			return 0, err
+		}
+	}
+	return fsync.BatchUpdateDictionary(client.DictionaryItem, serviceID, dictionaryID, ops)
+}
+
+// syncACLEntries makes the entries in an ACL match the given entries,
+// returning the number of entries created, updated, or deleted. If guard is
+// set, it must allow the deletions, which are described by target.
+func syncACLEntries(client *fastly.Client, serviceID, aclID string, entries []*fastly.ACLEntry, guard *util.DeletionGuard, target string) (int, error) {
+	existingEntries, _, err := client.ACLEntry.List(serviceID, aclID, nil)
+	if err != nil {
+		return 0, err
+	}
+	ops, plan := fsync.ACLEntryOps(existingEntries, entries, true)
+	if guard != nil {
+		if err := guard.CheckContents(target, "entries", plan, len(existingEntries)); err != nil {
+			return 0, err
+		}
+	}
+	return fsync.BatchUpdateACL(client.ACLEntry, serviceID, aclID, ops)
+}
+
+// restoreContents populates the dictionaries and ACLs in a version of a
+// service with the contents recorded in a snapshot, subject to guard.
+func restoreContents(client *fastly.Client, s *fastly.Service, version uint, snapshot *fsync.Snapshot, guard util.DeletionGuard) error {
+	for _, d := range snapshot.Dictionaries {
+		items, ok := snapshot.DictionaryItems[d.Name]
+		if !ok {
+			fmt.Printf("No contents recorded for dictionary %s. Skipping\n", d.Name)
+			continue
+		}
+		dictionary, _, err := client.Dictionary.Get(s.ID, version, d.Name)
+		if err != nil {
+			return fmt.Errorf("Error fetching dictionary %s: %s", d.Name, err)
+		}
+		changes, err := syncDictionaryItems(client, s.ID, dictionary.ID, items, &guard, fmt.Sprintf("dictionary %s on service %s", d.Name, s.Name))
+		if err != nil {
+			return fmt.Errorf("Error restoring items of dictionary %s: %s", d.Name, err)
+		}
+		fmt.Printf("Restored dictionary %s on service %s: %d items changed\n", d.Name, s.Name, changes)
+	}
+	for _, a := range snapshot.ACLs {
+		entries, ok := snapshot.ACLEntries[a.Name]
+		if !ok {
+			fmt.Printf("No contents recorded for ACL %s. Skipping\n", a.Name)
+			continue
+		}
+		acl, _, err := client.ACL.Get(s.ID, version, a.Name)
+		if err != nil {
+			return fmt.Errorf("Error fetching ACL %s: %s", a.Name, err)
+		}
+		changes, err := syncACLEntries(client, s.ID, acl.ID, entries, &guard, fmt.Sprintf("acl %s on service %s", a.Name, s.Name))
+		if err != nil {
+			return fmt.Errorf("Error restoring entries of ACL %s: %s", a.Name, err)
+		}
+		fmt.Printf("Restored ACL %s on service %s: %d entries changed\n", a.Name, s.Name, changes)
+	}
+	return nil
+}
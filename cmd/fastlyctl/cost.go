@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// unattributedTeam collects the usage of services which belong to no team,
+// including those missing from the config file.
+const unattributedTeam = "(unattributed)"
+
+// teamUsage is the traffic of the services of one team.
+type teamUsage struct {
+	services            []string
+	bandwidth, requests float64
+}
+
+// serviceTeams returns the teams of a service: its groups starting with
+// prefix, with the prefix removed.
+func serviceTeams(config fsync.SiteConfig, prefix string) []string {
+	var teams []string
+	for _, group := range config.Groups {
+		if strings.HasPrefix(group, prefix) && group != prefix {
+			teams = append(teams, strings.TrimPrefix(group, prefix))
+		}
+	}
+	return teams
+}
+
+func reportCost(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	month, err := usageMonth(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), -1)
+	}
+	delete(configs, "_default_")
+	prefix := c.String("prefix")
+
+	usage, _, err := client.Usage.ByMonth(month.Year(), month.Month())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching usage for %s: %s", month.Format("2006-01"), err), -1)
+	}
+
+	teams := make(map[string]*teamUsage)
+	for id, s := range usage.Services {
+		name := s.Name
+		if name == "" {
+			name = id
+		}
+		var bandwidth, requests float64
+		for _, stats := range s.Regions {
+			bandwidth += float64(stats.Bandwidth)
+			requests += float64(stats.Requests)
+		}
+		names := serviceTeams(configs[name], prefix)
+		if len(names) == 0 {
+			names = []string{unattributedTeam}
+		}
+		// Services shared by several teams are split evenly between
+		// them, so that the teams add up to the account's total.
+		share := float64(len(names))
+		for _, team := range names {
+			t, ok := teams[team]
+			if !ok {
+				t = new(teamUsage)
+				teams[team] = t
+			}
+			t.services = append(t.services, name)
+			t.bandwidth += bandwidth / share
+			t.requests += requests / share
+		}
+	}
+
+	var names []string
+	for team := range teams {
+		names = append(names, team)
+	}
+	sort.Strings(names)
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"month", "team", "services", "bandwidth_bytes", "requests"})
+	for _, team := range names {
+		t := teams[team]
+		sort.Strings(t.services)
+		w.Write([]string{month.Format("2006-01"), team, strings.Join(t.services, " "), strconv.FormatFloat(t.bandwidth, 'f', 0, 64), strconv.FormatFloat(t.requests, 'f', 0, 64)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	return nil
+}
@@ -2,19 +2,23 @@ package main
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
+	"github.com/alienth/fastlyctl/log"
+	fsync "github.com/alienth/fastlyctl/sync"
 	"github.com/alienth/fastlyctl/util"
 	"github.com/alienth/go-fastly"
 	"github.com/urfave/cli"
 )
 
 func dictionaryList(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	var err error
 	serviceParam := c.Args().Get(0)
 	var service *fastly.Service
-	if service, _, err = client.Service.Search(serviceParam); err != nil {
+	if service, err = util.GetServiceByNameOrID(client, serviceParam); err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
 	activeVersion, err := util.GetActiveVersion(service)
@@ -28,13 +32,17 @@ func dictionaryList(c *cli.Context) error {
 	}
 	fmt.Printf("Dictionaries for %s:\n\n", service.Name)
 	for _, d := range dictionaries {
-		fmt.Println(d.Name)
+		if d.WriteOnly {
+			fmt.Println(d.Name, "(write-only)")
+		} else {
+			fmt.Println(d.Name)
+		}
 	}
 	return nil
 }
 
 func dictionaryAddItem(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	serviceParam := c.Args().Get(0)
 	dictParam := c.Args().Get(1)
@@ -49,6 +57,12 @@ func dictionaryAddItem(c *cli.Context) error {
 	item := new(fastly.DictionaryItem)
 	item.Key = keyParam
 	item.Value = valueParam
+	if err = fsync.CheckDictionaryItems(dictionary.Name, []*fastly.DictionaryItem{item}, 0, fsync.DefaultMaxDictionaryItems); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if err = checkConfiguredValues(c, client, serviceParam, dictionary, []*fastly.DictionaryItem{item}); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
 
 	if _, _, err = client.DictionaryItem.Create(dictionary.ServiceID, dictionary.ID, item); err != nil {
 		return cli.NewExitError(err.Error(), -1)
@@ -58,7 +72,7 @@ func dictionaryAddItem(c *cli.Context) error {
 }
 
 func dictionaryRemoveItem(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	serviceParam := c.Args().Get(0)
 	dictParam := c.Args().Get(1)
@@ -77,7 +91,7 @@ func dictionaryRemoveItem(c *cli.Context) error {
 }
 
 func dictionaryListItems(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
 	serviceParam := c.Args().Get(0)
 	dictParam := c.Args().Get(1)
@@ -86,8 +100,11 @@ func dictionaryListItems(c *cli.Context) error {
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
+	if dictionary.WriteOnly {
+		return cli.NewExitError(util.WriteOnlyError(serviceParam, dictionary).Error(), -1)
+	}
 
-	items, _, err := client.DictionaryItem.List(dictionary.ServiceID, dictionary.ID)
+	items, _, err := client.DictionaryItem.List(dictionary.ServiceID, dictionary.ID, nil)
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
@@ -99,3 +116,314 @@ func dictionaryListItems(c *cli.Context) error {
 
 	return nil
 }
+
+func dictionaryInfo(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	dictParam := c.Args().Get(1)
+
+	dictionary, err := util.GetDictionaryByName(client, serviceParam, dictParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	info, _, err := client.Dictionary.Info(dictionary.ServiceID, dictionary.Version, dictionary.ID)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	fmt.Printf("Info for dictionary %s on service %s:\n\n", dictParam, serviceParam)
+	fmt.Printf("%-14s %d\n", "Item count:", info.ItemCount)
+	fmt.Printf("%-14s %s\n", "Last updated:", info.LastUpdated)
+	fmt.Printf("%-14s %s\n", "Digest:", info.Digest)
+	fmt.Printf("%-14s %t\n", "Write-only:", dictionary.WriteOnly)
+
+	return nil
+}
+
+// dictionaryItemValues lists the items of a dictionary by key.
+func dictionaryItemValues(client *fastly.Client, dictionary *fastly.Dictionary) (map[string]string, error) {
+	items, _, err := client.DictionaryItem.List(dictionary.ServiceID, dictionary.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		values[item.Key] = item.Value
+	}
+	return values, nil
+}
+
+func dictionaryWatch(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	dictParam := c.Args().Get(1)
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		return cli.NewExitError("--interval must be positive.", -1)
+	}
+
+	dictionary, err := util.GetDictionaryByName(client, serviceParam, dictParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	info, _, err := client.Dictionary.Info(dictionary.ServiceID, dictionary.Version, dictionary.ID)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching info for dictionary %s: %s", dictionary.Name, err), -1)
+	}
+	// The items of write-only dictionaries can't be listed, so only
+	// changes to their digest and count are shown.
+	var values map[string]string
+	if !dictionary.WriteOnly {
+		if values, err = dictionaryItemValues(client, dictionary); err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error listing items in dictionary %s: %s", dictionary.Name, err), -1)
+		}
+	}
+	fmt.Printf("Watching dictionary %s on service %s, holding %d items. Press Ctrl-C to stop.\n", dictionary.Name, serviceParam, info.ItemCount)
+
+	for {
+		time.Sleep(interval)
+		now := time.Now().Format("15:04:05")
+		latest, _, err := client.Dictionary.Info(dictionary.ServiceID, dictionary.Version, dictionary.ID)
+		if err != nil {
+			fmt.Printf("%s error fetching info: %s\n", now, err)
+			continue
+		}
+		// Without a digest, the items must be listed to tell whether
+		// they changed.
+		if latest.Digest != "" && latest.Digest == info.Digest && latest.ItemCount == info.ItemCount {
+			continue
+		}
+		if dictionary.WriteOnly {
+			fmt.Printf("%s items changed: %d items, from %d\n", now, latest.ItemCount, info.ItemCount)
+			info = latest
+			continue
+		}
+		current, err := dictionaryItemValues(client, dictionary)
+		if err != nil {
+			fmt.Printf("%s error listing items: %s\n", now, err)
+			continue
+		}
+		info = latest
+
+		var keys []string
+		for key := range values {
+			keys = append(keys, key)
+		}
+		for key := range current {
+			if _, ok := values[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			was, existed := values[key]
+			value, exists := current[key]
+			switch {
+			case !existed:
+				fmt.Printf("%s + %s: %s\n", now, key, value)
+			case !exists:
+				fmt.Printf("%s - %s: %s\n", now, key, was)
+			case was != value:
+				fmt.Printf("%s ~ %s: %s -> %s\n", now, key, was, value)
+			}
+		}
+		values = current
+	}
+}
+
+// dictionaryCopyPageSize is the number of items fetched from the source
+// dictionary at a time.
+const dictionaryCopyPageSize = 100
+
+func dictionaryCopy(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	srcServiceParam := c.Args().Get(0)
+	srcDictParam := c.Args().Get(1)
+	dstServiceParam := c.Args().Get(2)
+	dstDictParam := c.Args().Get(3)
+	if dstDictParam == "" {
+		dstDictParam = srcDictParam
+	}
+
+	src, err := util.GetDictionaryByName(client, srcServiceParam, srcDictParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching dictionary %s for service %s: %s", srcDictParam, srcServiceParam, err), -1)
+	}
+	if src.WriteOnly {
+		return cli.NewExitError(util.WriteOnlyError(srcServiceParam, src).Error(), -1)
+	}
+	dst, err := util.GetDictionaryByName(client, dstServiceParam, dstDictParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching dictionary %s for service %s: %s", dstDictParam, dstServiceParam, err), -1)
+	}
+	if src.ServiceID == dst.ServiceID && src.ID == dst.ID {
+		return cli.NewExitError("Source and destination dictionaries are the same.", -1)
+	}
+	if c.Bool("replace") && dst.WriteOnly {
+		return cli.NewExitError(fmt.Sprintf("Cannot --replace the contents of write-only dictionary %s, as its existing items can't be listed.", dst.Name), -1)
+	}
+
+	// Stream the source a page at a time, upserting each page into the
+	// destination, so that large dictionaries needn't be held in memory.
+	copied := make(map[string]bool)
+	var ops []fastly.DictionaryItemUpdate
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+		if _, err := client.DictionaryItem.BatchUpdate(dst.ServiceID, dst.ID, ops); err != nil {
+			return err
+		}
+		ops = ops[:0]
+		return nil
+	}
+	for page := uint(1); ; page++ {
+		items, _, err := client.DictionaryItem.List(src.ServiceID, src.ID, &fastly.ListOptions{Page: page, PerPage: dictionaryCopyPageSize, Sort: "item_key"})
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error listing items in dictionary %s: %s", src.Name, err), -1)
+		}
+		for _, item := range items {
+			copied[item.Key] = true
+			ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpsert, Key: item.Key, Value: item.Value})
+			if len(ops) == fsync.BatchLimit {
+				if err = flush(); err != nil {
+					return cli.NewExitError(fmt.Sprintf("Error copying items to dictionary %s: %s", dst.Name, err), -1)
+				}
+			}
+		}
+		if len(items) < dictionaryCopyPageSize {
+			break
+		}
+	}
+	if err = flush(); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error copying items to dictionary %s: %s", dst.Name, err), -1)
+	}
+	fmt.Printf("Copied %d items from %s/%s to %s/%s\n", len(copied), srcServiceParam, src.Name, dstServiceParam, dst.Name)
+
+	if !c.Bool("replace") {
+		return nil
+	}
+	existing, _, err := client.DictionaryItem.List(dst.ServiceID, dst.ID, nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error listing items in dictionary %s: %s", dst.Name, err), -1)
+	}
+	var removed int
+	for _, item := range existing {
+		if copied[item.Key] {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Removing item %s, which is not in the source dictionary.\n", item.Key))
+		ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationDelete, Key: item.Key})
+		removed++
+		if len(ops) == fsync.BatchLimit {
+			if err = flush(); err != nil {
+				return cli.NewExitError(fmt.Sprintf("Error removing items from dictionary %s: %s", dst.Name, err), -1)
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error removing items from dictionary %s: %s", dst.Name, err), -1)
+	}
+	fmt.Printf("Removed %d items from %s/%s which were not in the source\n", removed, dstServiceParam, dst.Name)
+	return nil
+}
+
+// checkConfiguredValues checks items against the ValueType which the config
+// file gives dictionary, if any.
+func checkConfiguredValues(c *cli.Context, client *fastly.Client, serviceParam string, dictionary *fastly.Dictionary, items []*fastly.DictionaryItem) error {
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return err
+	}
+	configured, err := fsync.ConfiguredDictionary(c.GlobalString("config"), service.Name, dictionary.Name)
+	if err != nil || configured == nil {
+		return err
+	}
+	return configured.CheckValues(items)
+}
+
+func dictionaryImport(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	dictParam := c.Args().Get(1)
+	fileParam := c.Args().Get(2)
+
+	items, err := fsync.ReadDictionaryItems(fileParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading items: %s", err), -1)
+	}
+	dictionary, err := util.GetDictionaryByName(client, serviceParam, dictParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if dictionary.WriteOnly && c.Bool("replace") {
+		return cli.NewExitError(fmt.Sprintf("Cannot --replace the contents of write-only dictionary %s, as its existing items can't be listed.", dictionary.Name), -1)
+	}
+	if dictionary.WriteOnly && c.Bool("dry-run") {
+		return cli.NewExitError(fmt.Sprintf("Cannot --dry-run against write-only dictionary %s, as its existing items can't be listed.", dictionary.Name), -1)
+	}
+
+	// Count the items the dictionary will hold once the import is done.
+	// The items of a write-only dictionary can't be listed, so any of
+	// them may be overwritten by the import.
+	keys := make(map[string]bool)
+	for _, item := range items {
+		keys[item.Key] = true
+	}
+	total := len(keys)
+	var existing []*fastly.DictionaryItem
+	if dictionary.WriteOnly {
+		info, _, err := client.Dictionary.Info(dictionary.ServiceID, dictionary.Version, dictionary.ID)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error fetching info for dictionary %s: %s", dictionary.Name, err), -1)
+		}
+		total += int(info.ItemCount)
+	} else {
+		existing, _, err = client.DictionaryItem.List(dictionary.ServiceID, dictionary.ID, nil)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error listing items in dictionary %s: %s", dictionary.Name, err), -1)
+		}
+		if !c.Bool("replace") {
+			for _, item := range existing {
+				if !keys[item.Key] {
+					total++
+				}
+			}
+		}
+	}
+	if err = fsync.CheckDictionaryItems(dictionary.Name, items, total, c.Int("max-dictionary-items")); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if err = checkConfiguredValues(c, client, serviceParam, dictionary, items); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	var ops []fastly.DictionaryItemUpdate
+	if dictionary.WriteOnly {
+		for _, item := range items {
+			ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpsert, Key: item.Key, Value: item.Value})
+		}
+	} else {
+		var plan util.BatchPlan
+		ops, plan = fsync.DictionaryItemOps(existing, items, c.Bool("replace"))
+		target := fmt.Sprintf("dictionary %s on service %s", dictionary.Name, serviceParam)
+		if c.Bool("dry-run") {
+			plan.Print(target)
+			return nil
+		}
+		if err := deletionGuard(c).CheckContents(target, "items", plan, len(existing)); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+	changes, err := fsync.BatchUpdateDictionary(client.DictionaryItem, dictionary.ServiceID, dictionary.ID, ops)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error importing items into dictionary %s after %d changes: %s", dictionary.Name, changes, err), -1)
+	}
+	fmt.Printf("Imported %d items into %s/%s: %d items changed\n", len(items), serviceParam, dictionary.Name, changes)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+func exportService(c *cli.Context) error {
+	client := util.NewClient(c)
+	serviceParam := c.Args().Get(0)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	version := uint(c.Int("version"))
+	if version == 0 {
+		if version, err = util.GetActiveVersion(service); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+	format := c.String("format")
+	if format != "snapshot" && format != "terraform" && !util.StringInSlice(format, fsync.ConfigFormats) {
+		return cli.NewExitError(fmt.Sprintf("Invalid --format %q. Must be snapshot, terraform, or a config format: %s.", format, strings.Join(fsync.ConfigFormats, ", ")), -1)
+	}
+
+	snapshot, err := fsync.FetchSnapshot(client, service, version)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error exporting version %d of service %s: %s", version, service.Name, err), -1)
+	}
+	if format == "terraform" {
+		if err := exportTerraform(snapshot, c.String("out")); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+	if format != "snapshot" {
+		if err := exportConfig(snapshot, format, c.String("out")); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	out := c.String("out")
+	if out == "" {
+		out = snapshotDir(".", service.Name)
+	}
+	if err = writeSnapshot(out, snapshot); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error writing snapshot to %s: %s", out, err), -1)
+	}
+
+	fmt.Printf("Exported version %d of service %s to %s\n", version, service.Name, out)
+	return nil
+}
+
+// exportConfig writes the config of a snapshot as a config file in format,
+// to out or, if it is -, stdout. The config is first pushed to a copy of the
+// snapshot, to make sure that pushing it to the service changes nothing.
+func exportConfig(snapshot *fsync.Snapshot, format, out string) error {
+	name := snapshot.Service.Name
+	changes, err := fsync.RoundTrip(snapshot, format)
+	if err != nil {
+		return fmt.Errorf("Error checking exported config: %s", err)
+	}
+	if len(changes) > 0 {
+		changes.PrintFields(name)
+		return fmt.Errorf("The %s config of %s does not record the version faithfully, as pushing it would make the changes above.", format, name)
+	}
+	body, err := fsync.EncodeConfig(name, snapshot.SiteConfig(), format)
+	if err != nil {
+		return fmt.Errorf("Error encoding config: %s", err)
+	}
+	if out == "-" {
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+	if out == "" {
+		out = name + "." + format
+	}
+	if err = ioutil.WriteFile(out, body, 0644); err != nil {
+		return fmt.Errorf("Error writing config to %s: %s", out, err)
+	}
+	fmt.Printf("Exported version %d of service %s to %s\n", snapshot.Version, name, out)
+	return nil
+}
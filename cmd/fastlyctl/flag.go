@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// Feature flags are items of a dictionary whose values are flagOn or flagOff.
+const (
+	flagOn  = "true"
+	flagOff = "false"
+)
+
+// flagName matches the keys which may be used as feature flags, so that flags
+// can be read in VCL with table.lookup without any quoting surprises.
+var flagName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+var flagFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "dictionary",
+		Usage:  "Keep feature flags in the dictionary `NAME`.",
+		Value:  "feature_flags",
+		EnvVar: "FASTLYCTL_FLAG_DICTIONARY",
+	},
+	cli.StringFlag{
+		Name:  "audit-file",
+		Usage: "Record every change to a feature flag, and who made it, in `FILE`.",
+		Value: "fastlyctl-flags.json",
+	},
+	operatorFlag,
+}
+
+// FlagChange records a feature flag being switched on or off.
+type FlagChange struct {
+	Service   string
+	ServiceID string
+	Flag      string
+	// Old is empty when the change created the flag.
+	Old      string `json:",omitempty"`
+	New      string
+	Operator string
+	Time     time.Time
+}
+
+func readFlagChanges(file string) ([]FlagChange, error) {
+	var changes []FlagChange
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return changes, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &changes); err != nil {
+		return nil, fmt.Errorf("Error parsing audit file %s: %s", file, err)
+	}
+	return changes, nil
+}
+
+func writeFlagChanges(file string, changes []FlagChange) error {
+	body, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0644)
+}
+
+// flagDictionary looks up the feature flag dictionary of the service given by
+// the first argument, which must be readable so that flags can be checked.
+func flagDictionary(c *cli.Context, client *fastly.Client) (*fastly.Service, *fastly.Dictionary, error) {
+	service, err := util.GetServiceByNameOrID(client, c.Args().Get(0))
+	if err != nil {
+		return nil, nil, err
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := c.String("dictionary")
+	dictionary, _, err := client.Dictionary.Get(service.ID, activeVersion, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error fetching feature flag dictionary %s of service %s: %s", name, service.Name, err)
+	}
+	if dictionary.WriteOnly {
+		return nil, nil, util.WriteOnlyError(service.Name, dictionary)
+	}
+	return service, dictionary, nil
+}
+
+// getFlag returns the value of a feature flag, which is empty if the flag
+// doesn't exist.
+func getFlag(client *fastly.Client, dictionary *fastly.Dictionary, flag string) (string, error) {
+	item, resp, err := client.DictionaryItem.Get(dictionary.ServiceID, dictionary.ID, flag)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return item.Value, nil
+}
+
+func flagEnable(c *cli.Context) error {
+	return setFlag(c, flagOn)
+}
+
+func flagDisable(c *cli.Context) error {
+	return setFlag(c, flagOff)
+}
+
+// setFlag switches the feature flag given by the second argument to value.
+// Only existing flags may be switched unless --create is set, as a flag with
+// a mistyped name would silently do nothing.
+func setFlag(c *cli.Context, value string) error {
+	flag := c.Args().Get(1)
+	if !flagName.MatchString(flag) {
+		return cli.NewExitError(fmt.Sprintf("Invalid feature flag name %q. Names are lower case letters, digits, '_', '.' and '-'.", flag), -1)
+	}
+	operator := currentOperator(c)
+	if operator == "" {
+		return cli.NewExitError("Unable to determine operator name. Specify one with --operator.", -1)
+	}
+	file := c.String("audit-file")
+	changes, err := readFlagChanges(file)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	client := util.NewClient(c)
+	service, dictionary, err := flagDictionary(c, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	old, err := getFlag(client, dictionary, flag)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching feature flag %s: %s", flag, err), -1)
+	}
+	switch old {
+	case value:
+		fmt.Printf("Feature flag %s of %s is already %s.\n", flag, service.Name, flagState(value))
+		return nil
+	case "":
+		if !c.Bool("create") {
+			return cli.NewExitError(fmt.Sprintf("Feature flag %s does not exist in dictionary %s of %s. Check its spelling, or give --create to add it.", flag, dictionary.Name, service.Name), -1)
+		}
+	case flagOn, flagOff:
+	default:
+		return cli.NewExitError(fmt.Sprintf("Item %s of dictionary %s has the value %q, so is not a feature flag. Feature flags are %s or %s.", flag, dictionary.Name, old, flagOn, flagOff), -1)
+	}
+
+	item := &fastly.DictionaryItem{Key: flag, Value: value}
+	if old == "" {
+		_, _, err = client.DictionaryItem.Create(dictionary.ServiceID, dictionary.ID, item)
+	} else {
+		_, _, err = client.DictionaryItem.Update(dictionary.ServiceID, dictionary.ID, flag, item)
+	}
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error setting feature flag %s: %s", flag, err), -1)
+	}
+	fmt.Printf("Feature flag %s of %s is now %s.\n", flag, service.Name, flagState(value))
+
+	changes = append(changes, FlagChange{
+		Service:   service.Name,
+		ServiceID: service.ID,
+		Flag:      flag,
+		Old:       old,
+		New:       value,
+		Operator:  operator,
+		Time:      time.Now().UTC(),
+	})
+	if err = writeFlagChanges(file, changes); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Feature flag %s was set, but writing audit file %s failed: %s", flag, file, err), -1)
+	}
+	return nil
+}
+
+// flagState describes the value of a feature flag.
+func flagState(value string) string {
+	switch value {
+	case flagOn:
+		return "enabled"
+	case flagOff:
+		return "disabled"
+	}
+	return fmt.Sprintf("not a feature flag (%q)", value)
+}
+
+// flagStatus shows whether the given feature flag, or else every flag, is
+// enabled, along with the last recorded change to it.
+func flagStatus(c *cli.Context) error {
+	changes, err := readFlagChanges(c.String("audit-file"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	client := util.NewClient(c)
+	service, dictionary, err := flagDictionary(c, client)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	var items []*fastly.DictionaryItem
+	if flag := c.Args().Get(1); flag != "" {
+		value, err := getFlag(client, dictionary, flag)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error fetching feature flag %s: %s", flag, err), -1)
+		}
+		if value == "" {
+			return cli.NewExitError(fmt.Sprintf("Feature flag %s does not exist in dictionary %s of %s.", flag, dictionary.Name, service.Name), -1)
+		}
+		items = append(items, &fastly.DictionaryItem{Key: flag, Value: value})
+	} else if items, _, err = client.DictionaryItem.List(dictionary.ServiceID, dictionary.ID, nil); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	last := make(map[string]FlagChange)
+	for _, change := range changes {
+		if change.ServiceID == service.ID {
+			last[change.Flag] = change
+		}
+	}
+	fmt.Printf("Feature flags of %s:\n\n", service.Name)
+	for _, item := range items {
+		line := fmt.Sprintf("%-30s %s", item.Key, flagState(item.Value))
+		if change, ok := last[item.Key]; ok && change.New == item.Value {
+			line += fmt.Sprintf(" by %s at %s", change.Operator, change.Time.Format(time.RFC3339))
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+func gzipList(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	gzips, _, err := client.Gzip.List(service.ID, activeVersion)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to list gzip policies for service %s\n", service.Name), -1)
+	}
+	fmt.Printf("Gzip policies for %s:\n\n", service.Name)
+	for _, g := range gzips {
+		fmt.Println(g.Name)
+		fmt.Printf("  %-17s %s\n", "Extensions:", g.Extensions)
+		fmt.Printf("  %-17s %s\n", "Content types:", g.ContentTypes)
+		if g.CacheCondition != "" {
+			fmt.Printf("  %-17s %s\n", "Cache condition:", g.CacheCondition)
+		}
+	}
+	return nil
+}
+
+func gzipAdd(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	gzip := &fastly.Gzip{
+		Name:           nameParam,
+		Extensions:     c.String("extensions"),
+		ContentTypes:   c.String("content-types"),
+		CacheCondition: c.String("cache-condition"),
+	}
+	if gzip.Extensions == "" && gzip.ContentTypes == "" {
+		return cli.NewExitError("Please specify --extensions or --content-types.", -1)
+	}
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, _, err := client.Gzip.Create(service.ID, version, gzip); err != nil {
+			return fmt.Errorf("Error creating gzip policy %s: %s", nameParam, err)
+		}
+		fmt.Printf("Created gzip policy %s in version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "gzip", util.ChangeAdded, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
+
+func gzipRemove(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, err := client.Gzip.Delete(service.ID, version, nameParam); err != nil {
+			return fmt.Errorf("Error deleting gzip policy %s: %s", nameParam, err)
+		}
+		fmt.Printf("Deleted gzip policy %s from version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "gzip", util.ChangeRemoved, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
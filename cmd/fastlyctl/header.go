@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+func headerList(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	headers, _, err := client.Header.List(service.ID, activeVersion)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to list headers for service %s\n", service.Name), -1)
+	}
+	fmt.Printf("Headers for %s:\n\n", service.Name)
+	for _, h := range headers {
+		typ, _ := h.Type.MarshalText()
+		action, _ := h.Action.MarshalText()
+		fmt.Printf("%-30s %-8s %-12s %s\n", h.Name, typ, action, h.Destination)
+	}
+	return nil
+}
+
+func headerAdd(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	header := &fastly.Header{
+		Name:              nameParam,
+		Source:            c.String("src"),
+		Destination:       c.String("dst"),
+		Regex:             c.String("regex"),
+		Substitution:      c.String("substitution"),
+		Priority:          c.Uint("priority"),
+		IgnoreIfSet:       fastly.NewCompatibool(c.Bool("ignore-if-set")),
+		RequestCondition:  c.String("request-condition"),
+		CacheCondition:    c.String("cache-condition"),
+		ResponseCondition: c.String("response-condition"),
+	}
+	if c.String("type") == "" {
+		return cli.NewExitError("Please specify --type.", -1)
+	}
+	if err := header.Type.UnmarshalText([]byte(c.String("type"))); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if err := header.Action.UnmarshalText([]byte(c.String("action"))); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if header.Destination == "" {
+		return cli.NewExitError("Please specify --dst.", -1)
+	}
+	if header.Action != fastly.HeaderActionDelete && header.Source == "" {
+		return cli.NewExitError(fmt.Sprintf("Please specify --src for the %s action.", c.String("action")), -1)
+	}
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, _, err := client.Header.Create(service.ID, version, header); err != nil {
+			return fmt.Errorf("Error creating header %s: %s", nameParam, err)
+		}
+		fmt.Printf("Created header %s in version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "header", util.ChangeAdded, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
+
+func headerRemove(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, err := client.Header.Delete(service.ID, version, nameParam); err != nil {
+			return fmt.Errorf("Error deleting header %s: %s", nameParam, err)
+		}
+		fmt.Printf("Deleted header %s from version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "header", util.ChangeRemoved, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
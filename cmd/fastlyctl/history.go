@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// userNames looks up the users behind events, remembering each so that it is
+// only fetched once.
+type userNames struct {
+	client *fastly.Client
+	names  map[string]string
+}
+
+// name describes the user with the given ID by name and login. Users who
+// can't be looked up, such as those since removed, are given by ID.
+func (u *userNames) name(id string) string {
+	if id == "" {
+		return "-"
+	}
+	if name, ok := u.names[id]; ok {
+		return name
+	}
+	name := id
+	if user, _, err := u.client.User.Get(id); err == nil {
+		switch {
+		case user.Name != "" && user.Login != "":
+			name = fmt.Sprintf("%s <%s>", user.Name, user.Login)
+		case user.Login != "":
+			name = user.Login
+		case user.Name != "":
+			name = user.Name
+		}
+	}
+	u.names[id] = name
+	return name
+}
+
+// versionHistory prints a timeline of the activations and deactivations of a
+// service's versions, with the user behind each.
+func versionHistory(c *cli.Context) error {
+	client := util.NewClient(c)
+	service, err := util.GetServiceByNameOrID(client, c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	var since time.Time
+	if d := c.Duration("since"); d > 0 {
+		since = time.Now().Add(-d)
+	}
+
+	var events []*fastly.Event
+	for _, eventType := range []string{fastly.EventVersionActivate, fastly.EventVersionDeactivate} {
+		found, _, err := client.Event.List(&fastly.EventListInput{ServiceID: service.ID, Type: eventType, Since: since, PageSize: 100})
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error fetching the events of service %s: %s", service.Name, err), -1)
+		}
+		events = append(events, found...)
+	}
+	if len(events) == 0 {
+		fmt.Printf("No activations of %s have been recorded.\n", service.Name)
+		return nil
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+
+	// The latest activation of the active version is the one in effect.
+	current := -1
+	for i, e := range events {
+		if e.Type == fastly.EventVersionActivate && e.Version() == service.Version {
+			current = i
+		}
+	}
+	comments := make(map[uint]string)
+	for _, v := range service.Versions {
+		comments[v.Number] = v.Comment
+	}
+	users := &userNames{client: client, names: make(map[string]string)}
+
+	fmt.Printf("Activation history for %s:\n\n", service.Name)
+	fmt.Printf("%-2s%-21s %-12s %7s  %-30s %s\n", "", "Time", "Event", "Version", "User", "Comment")
+	for i, e := range events {
+		marker := ""
+		if i == current {
+			marker = "*"
+		}
+		action := "activated"
+		if e.Type == fastly.EventVersionDeactivate {
+			action = "deactivated"
+		}
+		user := users.name(e.UserID)
+		if e.Admin {
+			user += " (Fastly)"
+		}
+		fmt.Printf("%-2s%-21s %-12s %7d  %-30s %s\n", marker, e.CreatedAt.Format(time.RFC3339), action, e.Version(), user, comments[e.Version()])
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// serviceStanza is the TOML written by init. Field names match those of
+// SiteConfig and the go-fastly types it contains.
+var serviceStanza = template.Must(template.New("stanza").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`
+# Configuration for the {{.Name}} service, generated by 'fastlyctl init'.
+# Push it with 'fastlyctl push {{.Name}}'. Any resource type omitted here is
+# removed from the service on push.
+[{{quote .Name}}]
+
+  # Default TTL, in seconds, for responses which don't specify their own.
+  [{{quote .Name}}.Settings]
+  DefaultTTL = {{.DefaultTTL}}
+
+  # Hostnames which route to this service. DNS for each must point at Fastly.
+{{- range .Domains}}
+  [[{{quote $.Name}}.Domains]]
+  Name = {{quote .}}
+{{- end}}
+
+  # The origin Fastly fetches content from.
+  [[{{quote .Name}}.Backends]]
+  Name = "origin"
+  Address = {{quote .OriginHost}}
+  Port = {{.OriginPort}}
+{{- if .OriginTLS}}
+  UseSSL = true
+  SSLCheckCert = true
+  SSLCertHostname = {{quote .OriginHost}}
+  SSLSNIHostname = {{quote .OriginHost}}
+{{- end}}
+{{- if eq .Logging "syslog"}}
+
+  # Request logs are streamed to syslog.
+  [[{{quote .Name}}.Syslogs]]
+  Name = "syslog"
+  Address = {{quote .SyslogHost}}
+  Port = {{.SyslogPort}}
+{{- else if eq .Logging "s3"}}
+
+  # Request logs are written to S3. Credentials are read from the
+  # FASTLY_S3_ACCESS_KEY and FASTLY_S3_SECRET_KEY environment variables.
+  [[{{quote .Name}}.S3s]]
+  Name = "s3"
+  BucketName = {{quote .S3Bucket}}
+  Path = {{quote (printf "/%s/" .Name)}}
+{{- end}}
+
+  # Custom VCL. The main VCL must include the #FASTLY macros found in the
+  # skeleton for Fastly's generated configuration to take effect.
+  [[{{quote .Name}}.VCLs]]
+  Name = "main"
+  File = {{quote .VCLFile}}
+  Main = true
+`))
+
+const skeletonVCL = `sub vcl_recv {
+#FASTLY recv
+  return(lookup);
+}
+
+sub vcl_hash {
+#FASTLY hash
+  set req.hash += req.url;
+  set req.hash += req.http.host;
+  return(hash);
+}
+
+sub vcl_hit {
+#FASTLY hit
+  return(deliver);
+}
+
+sub vcl_miss {
+#FASTLY miss
+  return(fetch);
+}
+
+sub vcl_pass {
+#FASTLY pass
+  return(pass);
+}
+
+sub vcl_fetch {
+#FASTLY fetch
+  return(deliver);
+}
+
+sub vcl_error {
+#FASTLY error
+  return(deliver);
+}
+
+sub vcl_deliver {
+#FASTLY deliver
+  return(deliver);
+}
+
+sub vcl_log {
+#FASTLY log
+}
+`
+
+type serviceAnswers struct {
+	Name       string
+	Domains    []string
+	OriginHost string
+	OriginPort uint
+	OriginTLS  bool
+	DefaultTTL uint
+	Logging    string
+	SyslogHost string
+	SyslogPort uint
+	S3Bucket   string
+	VCLFile    string
+}
+
+func askUint(question, def string) (uint, error) {
+	for {
+		answer, err := util.Ask(question, def)
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.ParseUint(answer, 10, 32)
+		if err == nil {
+			return uint(n), nil
+		}
+		fmt.Printf("Invalid number: %s\n", answer)
+	}
+}
+
+func askServiceAnswers(name string) (serviceAnswers, error) {
+	var a serviceAnswers
+	var err error
+
+	if a.Name, err = util.Ask("Service name", name); err != nil {
+		return a, err
+	}
+	if a.Name == "" {
+		return a, fmt.Errorf("A service name is required.")
+	}
+
+	domains, err := util.Ask("Domains, comma separated", "")
+	if err != nil {
+		return a, err
+	}
+	for _, d := range strings.Split(domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			a.Domains = append(a.Domains, d)
+		}
+	}
+	if len(a.Domains) == 0 {
+		return a, fmt.Errorf("At least one domain is required.")
+	}
+
+	if a.OriginHost, err = util.Ask("Origin hostname", ""); err != nil {
+		return a, err
+	}
+	if a.OriginHost == "" {
+		return a, fmt.Errorf("An origin hostname is required.")
+	}
+	if a.OriginTLS, err = util.PromptDefault("Connect to origin over TLS?", true); err != nil {
+		return a, err
+	}
+	defaultPort := "80"
+	if a.OriginTLS {
+		defaultPort = "443"
+	}
+	if a.OriginPort, err = askUint("Origin port", defaultPort); err != nil {
+		return a, err
+	}
+	if a.DefaultTTL, err = askUint("Default TTL in seconds", "3600"); err != nil {
+		return a, err
+	}
+
+	for {
+		if a.Logging, err = util.Ask("Logging destination (syslog, s3, none)", "none"); err != nil {
+			return a, err
+		}
+		if util.StringInSlice(a.Logging, []string{"syslog", "s3", "none"}) {
+			break
+		}
+		fmt.Printf("Invalid logging destination: %s\n", a.Logging)
+	}
+	switch a.Logging {
+	case "syslog":
+		if a.SyslogHost, err = util.Ask("Syslog address", ""); err != nil {
+			return a, err
+		}
+		if a.SyslogPort, err = askUint("Syslog port", "514"); err != nil {
+			return a, err
+		}
+	case "s3":
+		if a.S3Bucket, err = util.Ask("S3 bucket", ""); err != nil {
+			return a, err
+		}
+	}
+
+	if a.VCLFile, err = util.Ask("Skeleton VCL file", a.Name+".vcl"); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+func initService(c *cli.Context) error {
+	configFile := c.GlobalString("config")
+	if !strings.HasSuffix(configFile, ".toml") {
+		return cli.NewExitError(fmt.Sprintf("init can only write TOML config files, not %s.", configFile), -1)
+	}
+
+	answers, err := askServiceAnswers(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	if _, err = os.Stat(configFile); err == nil {
+		configs, err := fsync.ReadConfig(configFile)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), -1)
+		}
+		if _, ok := configs[answers.Name]; ok {
+			return cli.NewExitError(fmt.Sprintf("Service %s is already defined in %s.", answers.Name, configFile), -1)
+		}
+	}
+
+	if _, err = os.Stat(answers.VCLFile); err == nil {
+		fmt.Printf("%s already exists. Leaving it in place.\n", answers.VCLFile)
+	} else if err = ioutil.WriteFile(answers.VCLFile, []byte(skeletonVCL), 0644); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error writing VCL file: %s", err), -1)
+	} else {
+		fmt.Printf("Wrote skeleton VCL to %s\n", answers.VCLFile)
+	}
+
+	f, err := os.OpenFile(configFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error opening config file: %s", err), -1)
+	}
+	defer f.Close()
+	if err = serviceStanza.Execute(f, answers); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error writing config file: %s", err), -1)
+	}
+	fmt.Printf("Added service %s to %s\n", answers.Name, configFile)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// checkLint prints the lint warnings for the named service, and returns an
+// error listing the rules it breaks which policy makes errors.
+func checkLint(configs map[string]fsync.SiteConfig, name string, policy fsync.LintPolicy) error {
+	var errors []string
+	for _, f := range fsync.Lint(configs, []string{name}, policy) {
+		if f.Severity == fsync.LintError {
+			errors = append(errors, fmt.Sprintf("%s (%s)", f.Message, f.Rule))
+		} else {
+			fmt.Println(f)
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("The config breaks lint rules set as errors: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+func configLint(c *cli.Context) error {
+	file := c.GlobalString("config")
+	if c.Bool("list-rules") {
+		policy, err := fsync.ReadLintPolicy(file)
+		if err != nil && !os.IsNotExist(err) {
+			return cli.NewExitError(fmt.Sprintf("Error reading lint policy: %s", err), util.ExitError)
+		}
+		for _, rule := range fsync.LintRules {
+			fmt.Printf("%-28s %-8s %s\n", rule.Name, policy.Severity(rule.Name), rule.Description)
+		}
+		return nil
+	}
+	configs, err := fsync.ReadConfig(file)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("error: %s", strings.TrimSpace(err.Error())), util.ExitError)
+	}
+	policy, err := fsync.ReadLintPolicy(file)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading lint policy: %s", err), util.ExitError)
+	}
+	names := c.Args()
+	if len(names) == 0 {
+		for name := range configs {
+			if name != "_default_" {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		if _, ok := configs[name]; !ok {
+			return cli.NewExitError(fmt.Sprintf("Service %s is not defined in the config file.", name), util.ExitError)
+		}
+	}
+
+	var errors, warnings int
+	for _, f := range fsync.Lint(configs, names, policy) {
+		fmt.Println(f)
+		if f.Severity == fsync.LintError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	if errors == 0 && warnings == 0 {
+		fmt.Printf("%s passes every lint rule.\n", file)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%d errors, %d warnings\n", errors, warnings)
+	if errors > 0 {
+		return cli.NewExitError("", util.ExitError)
+	}
+	return nil
+}
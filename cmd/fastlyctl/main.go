@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
+	versionInfo "github.com/alienth/fastlyctl/_version"
 	"github.com/alienth/fastlyctl/log"
+	fsync "github.com/alienth/fastlyctl/sync"
 	"github.com/alienth/fastlyctl/util"
 	"github.com/urfave/cli"
 )
@@ -13,6 +16,7 @@ import (
 func main() {
 	app := cli.NewApp()
 	app.Name = "fastlyctl"
+	app.Version = versionInfo.FullVersion()
 
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
@@ -22,21 +26,145 @@ func main() {
 		},
 		cli.StringFlag{
 			Name:   "fastly-key, K",
-			Usage:  "Fastly API Key. Can be read from 'fastly_key' file in CWD.",
+			Usage:  "Fastly API Key, or '-' to read it from stdin. Can be read from 'fastly_key' file in CWD.",
 			EnvVar: "FASTLY_KEY",
 			Value:  util.GetFastlyKey(),
 		},
+		cli.StringFlag{
+			Name:   "fastly-key-file",
+			Usage:  "Read the Fastly API key from `PATH`, such as a mounted secret.",
+			EnvVar: "FASTLY_KEY_FILE",
+		},
+		cli.StringFlag{
+			Name:   "credential-helper",
+			Usage:  "Fetch the Fastly API key by running `HELPER` when no key is set. A bare name runs 'fastlyctl-credential-HELPER get'. Can be read from 'credential_helper' file in CWD.",
+			EnvVar: "FASTLYCTL_CREDENTIAL_HELPER",
+			Value:  util.GetCredentialHelper(),
+		},
 		cli.BoolFlag{
 			Name:  "debug, d",
 			Usage: "Print more detailed info for debugging.",
 		},
 		cli.BoolFlag{
 			Name:  "assume-yes, y",
-			Usage: "Assume 'yes' to all prompts. USE ONLY IF YOU ARE CERTAIN YOUR COMMANDS WON'T BREAK ANYTHING!",
+			Usage: "Assume 'yes' to all prompts, printing only a count of each diff's changes. USE ONLY IF YOU ARE CERTAIN YOUR COMMANDS WON'T BREAK ANYTHING!",
+		},
+		cli.BoolFlag{
+			Name:  "auto-approve",
+			Usage: "Answer 'yes' to all prompts, like --assume-yes, but print each diff in full before activating it.",
+		},
+		cli.BoolFlag{
+			Name:  "skip-diff",
+			Usage: "Don't offer or print the diff of versions before activating them. The summary of changes is still printed.",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Print nothing but errors. Requires --assume-yes, as prompts would be hidden.",
+		},
+		cli.StringFlag{
+			Name:   "version-comment",
+			Usage:  "Template for the comment of new versions, after the fastlyctl marker. May use {{.User}}, {{.GitSHA}} and {{.Timestamp}}.",
+			EnvVar: "FASTLYCTL_VERSION_COMMENT",
+		},
+		cli.BoolFlag{
+			Name:  "service-id",
+			Usage: "Address services only by ID. By default, services may be given by ID or name.",
+		},
+		cli.BoolFlag{
+			Name:   "read-only",
+			Usage:  "Refuse to make any change in Fastly, so that services may be explored safely with production credentials.",
+			EnvVar: "FASTLYCTL_READ_ONLY",
+		},
+		cli.DurationFlag{
+			Name:   "prompt-timeout",
+			Usage:  "Abort if a prompt is left unanswered for this long, such as 5m. Zero waits forever.",
+			EnvVar: "FASTLYCTL_PROMPT_TIMEOUT",
+		},
+		cli.StringFlag{
+			Name:  "metrics-listen",
+			Usage: "Expose Prometheus metrics at /metrics on `ADDRESS`, such as ':9090'.",
+		},
+		cli.StringFlag{
+			Name:   "api-endpoint",
+			Usage:  "Send API requests to `URL` rather than https://api.fastly.com/, such as a proxy or recording gateway in front of it.",
+			EnvVar: "FASTLY_API_ENDPOINT",
+		},
+		cli.StringFlag{
+			Name:   "user-agent-suffix",
+			Usage:  "Append `SUFFIX` to the user agent of API requests, so that they can be attributed in Fastly's logs.",
+			EnvVar: "FASTLYCTL_USER_AGENT_SUFFIX",
+		},
+		cli.BoolFlag{
+			Name:  "timings",
+			Usage: "Print a summary of the API requests made to stderr once the command ends: how many, their total and 95th percentile latency, retries, and those not sent because the rate limit was exhausted.",
+		},
+		cli.StringFlag{
+			Name:  "record",
+			Usage: "Record every Fastly API request and response, with secrets redacted, to `FILE`, for use with replay.",
 		},
 	}
 
 	app.Before = func(c *cli.Context) error {
+		// Prompts are skipped wherever --assume-yes is checked. Diffs are
+		// printed in full only for --auto-approve, unless --skip-diff is
+		// also given.
+		if c.Bool("auto-approve") {
+			c.Set("assume-yes", "true")
+		}
+		if c.Bool("quiet") {
+			if !c.Bool("assume-yes") {
+				return cli.NewExitError("Error: --quiet requires --assume-yes.", util.ExitError)
+			}
+			devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			if err != nil {
+				return cli.NewExitError(err.Error(), util.ExitError)
+			}
+			os.Stdout = devNull
+		}
+		util.ServiceIDsOnly = c.Bool("service-id")
+		util.ReadOnly = c.Bool("read-only")
+		util.PromptTimeout = c.Duration("prompt-timeout")
+		if endpoint := c.String("api-endpoint"); endpoint != "" {
+			u, err := util.ParseAPIEndpoint(endpoint)
+			if err != nil {
+				return cli.NewExitError(err.Error(), util.ExitError)
+			}
+			util.APIEndpoint = u
+		}
+		util.UserAgentSuffix = c.String("user-agent-suffix")
+		if addr := c.String("metrics-listen"); addr != "" {
+			if err := util.ServeMetrics(addr); err != nil {
+				return cli.NewExitError(err.Error(), util.ExitError)
+			}
+		}
+		if file := c.String("record"); file != "" {
+			util.StartRecording(file)
+			// Commands exit as soon as they fail, so the session must be
+			// written on the way out.
+			exit := cli.OsExiter
+			cli.OsExiter = func(code int) {
+				stopRecording()
+				exit(code)
+			}
+		}
+		if c.Bool("timings") {
+			util.Timings = true
+			exit := cli.OsExiter
+			cli.OsExiter = func(code int) {
+				util.PrintTimings(os.Stderr)
+				exit(code)
+			}
+		}
+		if err := setVersionComment(c.String("version-comment"), c.String("config")); err != nil {
+			return cli.NewExitError(err.Error(), util.ExitError)
+		}
+		// init, config, replay and terraform only work with local files,
+		// and self-update only with GitHub, and so are usable before a key
+		// has been set up.
+		switch c.Args().First() {
+		case "init", "config", "replay", "terraform", "self-update":
+			return nil
+		}
 		if err := util.CheckFastlyKey(c); err != nil {
 			return err
 		}
@@ -45,37 +173,483 @@ func main() {
 
 	app.Commands = []cli.Command{
 		cli.Command{
-			Name:      "push",
-			Aliases:   []string{"p"},
-			Usage:     "Push locally defined service configuration options to Fastly.",
+			Name:    "push",
+			Aliases: []string{"p"},
+			Usage:   "Push locally defined service configuration options to Fastly.",
+			Description: "Exits 0 if no changes were needed, 2 if changes were activated, 3 if changes were left pending\n" +
+				"   activation (including with --noop, --offline, --require-approval and --stage), and 1 on error.",
 			ArgsUsage: "<SERVICE_NAME>...",
 			Flags: []cli.Flag{
 				cli.BoolFlag{
 					Name:  "all, a",
 					Usage: "Push all services listed in config file",
 				},
+				cli.StringSliceFlag{
+					Name:  "group, g",
+					Usage: "Push the services tagged with `GROUP` in their Groups. May be given more than once.",
+				},
 				cli.BoolFlag{
 					Name:  "noop, n",
 					Usage: "Push new config versions, but do not activate.",
 				},
+				cli.StringFlag{
+					Name:  "offline",
+					Usage: "Plan changes against service snapshots in `DIR`, one subdirectory per service as written by export, without contacting Fastly.",
+				},
+				cli.BoolFlag{
+					Name:  "require-approval",
+					Usage: "Stage and validate new config versions, and lock them pending activation by a second operator with approve.",
+				},
+				cli.BoolFlag{
+					Name:  "stage",
+					Usage: "Activate new config versions in the staging environment only, to be tested before activating them with version activate.",
+				},
+				cli.BoolFlag{
+					Name:  "resume",
+					Usage: "Retry only the services which failed to sync or validate in previous pushes, reusing their draft versions.",
+				},
+				cli.StringFlag{
+					Name:  "resume-file",
+					Usage: "Record services which fail during a push in `FILE`, for use with --resume.",
+					Value: "fastlyctl-resume.json",
+				},
+				cli.BoolFlag{
+					Name:  "no-progress",
+					Usage: "Don't display progress while syncing. Progress is only shown when stderr is a terminal and $CI is unset.",
+				},
+				cli.BoolFlag{
+					Name:  "strict",
+					Usage: "Treat validation warnings as errors, leaving the new version unactivated.",
+				},
+				serviceTimeoutFlag,
+				rateLimitWarningFlag,
+				diffModeFlag,
+				diffHTMLOutFlag,
+				policyBundleFlag,
+				approvalsFileFlag,
+				operatorFlag,
+				waitFlag,
+				waitTimeoutFlag,
+				allowDestructiveFlag,
+				maxDeletionsFlag,
+				maxDeletionPercentFlag,
+				maxDictionaryItemsFlag,
 			},
 			Before: func(c *cli.Context) error {
-				if !util.IsInteractive() && !c.GlobalBool("assume-yes") {
-					return cli.NewExitError(util.ErrNonInteractive.Error(), -1)
+				if c.String("offline") == "" && !util.IsInteractive() && !c.GlobalBool("assume-yes") {
+					return cli.NewExitError(util.ErrNonInteractive.Error(), util.ExitError)
+				}
+				named := c.Args().Present() || len(c.StringSlice("group")) > 0
+				if c.Bool("resume") {
+					if c.Bool("all") || named {
+						return cli.NewExitError("Error: --resume pushes the previously failed services, and cannot be combined with service names, groups or -a", util.ExitError)
+					}
+				} else if c.Bool("all") == named {
+					return cli.NewExitError("Error: either specify service names or groups to be pushed, or push all with -a", util.ExitError)
 				}
-				if (!c.Bool("all") && !c.Args().Present()) || (c.Bool("all") && c.Args().Present()) {
-					return cli.NewExitError("Error: either specify service names to be pushed, or push all with -a", -1)
+				if c.Bool("stage") && (c.Bool("noop") || c.Bool("require-approval") || c.String("offline") != "") {
+					return cli.NewExitError("Error: --stage cannot be combined with --noop, --require-approval or --offline", util.ExitError)
+				}
+				if _, err := util.ParseDiffMode(c.String("diff-mode")); err != nil {
+					return cli.NewExitError(fmt.Sprintf("Error: %s", err), util.ExitError)
 				}
 				if c.GlobalBool("debug") {
 					log.EnableDebug()
 				}
-				if c.Bool("noop") {
+				if c.String("offline") != "" {
+					fmt.Printf("!!! Running in offline mode. Changes will be planned against snapshots in %s.\n\n", c.String("offline"))
+				} else if c.Bool("require-approval") {
+					fmt.Printf("!!! Running in approval mode. Changes will be staged for activation by another operator.\n\n")
+				} else if c.Bool("noop") {
 					fmt.Printf("!!! Running in no-op mode. Changes will be prepared, but not activated.\n\n")
+				} else if c.Bool("stage") {
+					fmt.Printf("!!! Running in staging mode. Changes will be activated in the staging environment only.\n\n")
 				}
 				return nil
 			},
 			Action: syncConfig,
 		},
+		cli.Command{
+			Name:        "apply",
+			Usage:       "Push a config document for a single service, such as one generated by another tool and piped to stdin.",
+			Description: "The document is in the same form as the config file, with a single service. Exits as push does.",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "file, f",
+					Usage: "Read the config from `FILE`, or from stdin if -.",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "Parse the config as toml, json, or yaml. Inferred from the file extension or content if unset.",
+				},
+				cli.BoolFlag{
+					Name:  "noop, n",
+					Usage: "Push a new config version, but do not activate.",
+				},
+				cli.BoolFlag{
+					Name:  "require-approval",
+					Usage: "Stage and validate the new config version, and lock it pending activation by a second operator with approve.",
+				},
+				cli.StringFlag{
+					Name:  "resume-file",
+					Usage: "Record the service in `FILE` if it fails, for use with push --resume.",
+					Value: "fastlyctl-resume.json",
+				},
+				cli.BoolFlag{
+					Name:  "no-progress",
+					Usage: "Don't display progress while syncing.",
+				},
+				serviceTimeoutFlag,
+				rateLimitWarningFlag,
+				diffModeFlag,
+				diffHTMLOutFlag,
+				policyBundleFlag,
+				approvalsFileFlag,
+				operatorFlag,
+				waitFlag,
+				waitTimeoutFlag,
+				allowDestructiveFlag,
+				maxDeletionsFlag,
+				maxDeletionPercentFlag,
+			},
+			Before: func(c *cli.Context) error {
+				switch c.String("format") {
+				case "", "toml", "json", "yaml":
+				default:
+					return cli.NewExitError(fmt.Sprintf("Error: unknown format %q. Must be toml, json, or yaml.", c.String("format")), util.ExitError)
+				}
+				if c.String("file") == "" {
+					return cli.NewExitError("Error: please specify the config to apply with -f.", util.ExitError)
+				}
+				// Prompts can't be answered when the config itself is read
+				// from stdin.
+				if c.String("file") == "-" && !c.GlobalBool("assume-yes") {
+					return cli.NewExitError("Error: apply -f - reads the config from stdin, and so requires --assume-yes.", util.ExitError)
+				}
+				if !util.IsInteractive() && !c.GlobalBool("assume-yes") {
+					return cli.NewExitError(util.ErrNonInteractive.Error(), util.ExitError)
+				}
+				if _, err := util.ParseDiffMode(c.String("diff-mode")); err != nil {
+					return cli.NewExitError(fmt.Sprintf("Error: %s", err), util.ExitError)
+				}
+				if c.GlobalBool("debug") {
+					log.EnableDebug()
+				}
+				return nil
+			},
+			Action: applyConfig,
+		},
+		cli.Command{
+			Name:      "status",
+			Usage:     "Show the active version of services, who pushed it, and whether the local config matches the config it was pushed from. Exits 3 if any doesn't match.",
+			ArgsUsage: "<SERVICE_NAME>...",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all, a",
+					Usage: "Show all services listed in config file",
+				},
+				cli.BoolFlag{
+					Name:  "plan",
+					Usage: "Also plan the local config against the active version, catching changes made outside of fastlyctl.",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() && !c.Bool("all") {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Action: status,
+		},
+		cli.Command{
+			Name:      "preview-vcl",
+			Usage:     "Diff the VCL generated for the active version of a service against an approximation of that for its local config.",
+			ArgsUsage: "<SERVICE_NAME>",
+			Description: "Nothing is created in Fastly. The main VCL of the local config has its includes inlined, and its #FASTLY\n" +
+				"   macros expanded with the boilerplate of the active version, so changes to anything other than VCL\n" +
+				"   aren't reflected. Exits 0 if the VCL matches, and 3 if it differs.",
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Action: previewVCL,
+		},
+		cli.Command{
+			Name:  "config",
+			Usage: "Check the config file.",
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "lint",
+					Usage:     "Check services in the config file against the lint rules, whose severity is set in its lint table. Exits non-zero if any rule set as an error is broken.",
+					ArgsUsage: "[<SERVICE_NAME>...]",
+					Action:    configLint,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "list-rules",
+							Usage: "List the lint rules, rather than checking the config against them.",
+						},
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:      "init",
+			Usage:     "Interactively create a config stanza and skeleton VCL for a new service.",
+			ArgsUsage: "[<SERVICE_NAME>]",
+			Before: func(c *cli.Context) error {
+				if !util.IsInteractive() {
+					return cli.NewExitError("init must be run from an interactive shell.", -1)
+				}
+				return nil
+			},
+			Action: initService,
+		},
+		cli.Command{
+			Name:      "approve",
+			Usage:     "Activate a version staged by push --require-approval. The version must have been staged by a different operator.",
+			ArgsUsage: "<SERVICE_NAME> <VERSION>",
+			Flags: []cli.Flag{
+				approvalsFileFlag,
+				operatorFlag,
+				waitFlag,
+				waitTimeoutFlag,
+			},
+			Before: func(c *cli.Context) error {
+				if err := checkInteractive(c); err != nil {
+					return err
+				}
+				if len(c.Args()) != 2 {
+					return cli.NewExitError("Please specify a service and version.", -1)
+				}
+				return nil
+			},
+			Action: approveVersion,
+		},
+		cli.Command{
+			Name:  "activate",
+			Usage: "Activate the draft versions left pending by push, such as with --noop, after confirming them all at once.",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all-pending",
+					Usage: "Activate the latest validated fastlyctl draft of every service, if push --noop locked it.",
+				},
+				waitFlag,
+				waitTimeoutFlag,
+			},
+			Before: func(c *cli.Context) error {
+				if err := checkInteractive(c); err != nil {
+					return err
+				}
+				if !c.Bool("all-pending") {
+					return cli.NewExitError("Please specify --all-pending.", -1)
+				}
+				return nil
+			},
+			Action: activatePending,
+		},
+		cli.Command{
+			Name:      "export",
+			Usage:     "Export the full configuration of a service version, including dictionary and ACL contents, to a directory of JSON files.",
+			ArgsUsage: "<SERVICE_NAME>",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "version",
+					Usage: "Export `VERSION` rather than the active version.",
+				},
+				cli.StringFlag{
+					Name:  "out, o",
+					Usage: "Write the snapshot to `DIR`. Defaults to a directory named after the service. With --format terraform, this is the HCL file to write, or - for stdout, and defaults to the service name with a .tf extension. With a config format, this is the config file to write, or - for stdout, and defaults to the service name with the format as its extension.",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "Export as a `FORMAT`: snapshot, terraform to write a fastly_service_vcl resource and print the commands importing it into Terraform state, or toml, json or yaml to write a config file which pushes back without changes.",
+					Value: "snapshot",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Action: exportService,
+		},
+		cli.Command{
+			Name:      "terraform",
+			Usage:     "Convert services in the config file into Terraform fastly_service_vcl resources.",
+			ArgsUsage: "<SERVICE_NAME>...",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all, a",
+					Usage: "Convert all services listed in config file",
+				},
+				cli.StringFlag{
+					Name:  "out, o",
+					Usage: "Write the HCL to `FILE`, or - for stdout.",
+					Value: "-",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() && !c.Bool("all") {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Action: convertTerraform,
+		},
+		cli.Command{
+			Name:  "usage",
+			Usage: "Report the bandwidth and requests of each service by region for a month, along with its invoice.",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "month",
+					Usage: "Report on `YYYY-MM`. Defaults to the current month, which is incomplete.",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "Print the report as a `FORMAT`: table, or csv for the per-service usage alone.",
+					Value: "table",
+				},
+			},
+			Action: usageReport,
+		},
+		cli.Command{
+			Name:  "report",
+			Usage: "Report on every service in the account.",
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:   "inventory",
+					Usage:  "List the domains, origins, shields, TLS settings, logging endpoints, and active version age of each service.",
+					Action: reportInventory,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "out, o",
+							Usage: "Write the report to `FILE`, or - for stdout.",
+							Value: "-",
+						},
+						cli.StringFlag{
+							Name:  "format",
+							Usage: "Write the report as a `FORMAT`: csv or json. Defaults to the extension of --out, or csv.",
+						},
+					},
+				},
+				cli.Command{
+					Name:   "security",
+					Usage:  "List the security weaknesses of each service, by severity: TLS not forced, origins allowing TLS older than 1.2, no HSTS header, wildcard ACL entries, and no WAF.",
+					Action: reportSecurity,
+				},
+				cli.Command{
+					Name:   "cost",
+					Usage:  "Total the bandwidth and requests of each team for a month as CSV, by the groups of their services in the config file.",
+					Action: reportCost,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "month",
+							Usage: "Report on `YYYY-MM`. Defaults to the current month, which is incomplete.",
+						},
+						cli.StringFlag{
+							Name:  "prefix",
+							Usage: "Only count groups starting with `PREFIX`, such as team-, as teams, named without the prefix. Services of several teams are split evenly between them.",
+						},
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "stats",
+			Usage: "Show historical traffic metrics of a service.",
+			Before: func(c *cli.Context) error {
+				// less than 2 here since the subcommand is the first Arg
+				if len(c.Args()) < 2 {
+					cli.ShowAppHelp(c)
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "origins",
+					Usage:     "Break down responses, errors, and latency by origin, from Origin Inspector",
+					Action:    statsOrigins,
+					ArgsUsage: "<SERVICE_NAME>",
+					Flags:     statsFlags,
+				},
+				cli.Command{
+					Name:      "domains",
+					Usage:     "Break down requests, bandwidth, hit ratio, and errors by domain, from Domain Inspector",
+					Action:    statsDomains,
+					ArgsUsage: "<SERVICE_NAME>",
+					Flags:     statsFlags,
+				},
+			},
+		},
+		cli.Command{
+			Name:  "purge",
+			Usage: "Purge cached objects.",
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "verify",
+					Usage:     "Purge a URL, then poll it until the POP answering shows a fresh object has been cached, confirming the purge took effect.",
+					ArgsUsage: "<URL>",
+					Action:    purgeVerify,
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "timeout",
+							Usage: "Give up if the purge isn't confirmed within `DURATION`.",
+							Value: time.Minute,
+						},
+						cli.DurationFlag{
+							Name:  "interval",
+							Usage: "Poll the URL every `DURATION`.",
+							Value: 2 * time.Second,
+						},
+					},
+					Before: func(c *cli.Context) error {
+						if !c.Args().Present() {
+							return cli.NewExitError("Please specify URL.", -1)
+						}
+						return nil
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:      "restore",
+			Usage:     "Rebuild a service's configuration from a snapshot written by export.",
+			ArgsUsage: "<SERVICE_NAME>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from, f",
+					Usage: "Read the snapshot from `DIR`.",
+				},
+				cli.BoolFlag{
+					Name:  "contents",
+					Usage: "Also restore the items and entries of dictionaries and ACLs.",
+				},
+				maxDictionaryItemsFlag,
+				diffModeFlag,
+				waitFlag,
+				waitTimeoutFlag,
+				allowDestructiveFlag,
+				maxDeletionsFlag,
+				maxDeletionPercentFlag,
+			},
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				if c.String("from") == "" {
+					return cli.NewExitError("Please specify snapshot directory with --from.", -1)
+				}
+				if c.GlobalBool("debug") {
+					log.EnableDebug()
+				}
+				return checkInteractive(c)
+			},
+			Action: restoreService,
+		},
 		cli.Command{
 			Name:    "version",
 			Aliases: []string{"v"},
@@ -93,6 +667,24 @@ func main() {
 					Usage:     "List versions associated with a given service",
 					Action:    versionList,
 					ArgsUsage: "<SERVICE_NAME>",
+					Flags: []cli.Flag{
+						cli.StringSliceFlag{
+							Name:  "group, g",
+							Usage: "List versions of the services in the config file tagged with `GROUP`, rather than a single service. May be given more than once.",
+						},
+					},
+				},
+				cli.Command{
+					Name:      "history",
+					Usage:     "Show when each version of a service was activated or deactivated, and by whom",
+					ArgsUsage: "<SERVICE_NAME>",
+					Action:    versionHistory,
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "since",
+							Usage: "Only show the events of the last `DURATION`, such as 720h. By default the whole history is shown.",
+						},
+					},
 				},
 				cli.Command{
 					Name:      "validate",
@@ -110,6 +702,7 @@ func main() {
 					Name:      "activate",
 					Usage:     "Activate a specified VERSION",
 					ArgsUsage: "<SERVICE_NAME> <VERSION>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
 					Action:    versionActivate,
 					Before: func(c *cli.Context) error {
 						if !util.IsInteractive() && !c.GlobalBool("assume-yes") {
@@ -121,6 +714,30 @@ func main() {
 						return versionValidate(c)
 					},
 				},
+				cli.Command{
+					Name:      "stage",
+					Usage:     "Activate a specified VERSION in the staging environment only",
+					ArgsUsage: "<SERVICE_NAME> <VERSION>",
+					Action:    func(c *cli.Context) error { return versionStage(c, false) },
+					Before: func(c *cli.Context) error {
+						if _, err := strconv.Atoi(c.Args().Get(1)); err != nil {
+							return cli.NewExitError("Please specify version to stage.", -1)
+						}
+						return versionValidate(c)
+					},
+				},
+				cli.Command{
+					Name:      "unstage",
+					Usage:     "Deactivate a specified VERSION in the staging environment",
+					ArgsUsage: "<SERVICE_NAME> <VERSION>",
+					Action:    func(c *cli.Context) error { return versionStage(c, true) },
+					Before: func(c *cli.Context) error {
+						if _, err := strconv.Atoi(c.Args().Get(1)); err != nil {
+							return cli.NewExitError("Please specify version to unstage.", -1)
+						}
+						return nil
+					},
+				},
 			},
 		},
 		cli.Command{
@@ -132,6 +749,38 @@ func main() {
 					Usage:  "List services associated with account",
 					Action: serviceList,
 				},
+				cli.Command{
+					Name:      "clone",
+					Usage:     "Copy the active configuration of one service into a new version of another",
+					ArgsUsage: "<SRC_SERVICE_NAME> <DST_SERVICE_NAME>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "dst-key",
+							Usage:  "Fastly API Key for the account holding the destination service, if it differs.",
+							EnvVar: "FASTLY_DST_KEY",
+						},
+						cli.BoolFlag{
+							Name:  "contents",
+							Usage: "Also copy the items and entries of dictionaries and ACLs.",
+						},
+						maxDictionaryItemsFlag,
+						allowDestructiveFlag,
+						maxDeletionsFlag,
+						maxDeletionPercentFlag,
+						waitFlag,
+						waitTimeoutFlag,
+					},
+					Before: func(c *cli.Context) error {
+						if c.NArg() != 2 {
+							return cli.NewExitError("Please specify source and destination services.", -1)
+						}
+						if c.GlobalBool("debug") {
+							log.EnableDebug()
+						}
+						return checkInteractive(c)
+					},
+					Action: serviceClone,
+				},
 			},
 		},
 		cli.Command{
@@ -170,7 +819,203 @@ func main() {
 					Action:    dictionaryListItems,
 					ArgsUsage: "<SERVICE_NAME> <DICTIONARY_NAME>",
 				},
+				cli.Command{
+					Name:      "info",
+					Usage:     "Show item count, last update time, and digest of a dictionary",
+					Action:    dictionaryInfo,
+					ArgsUsage: "<SERVICE_NAME> <DICTIONARY_NAME>",
+				},
+				cli.Command{
+					Name:      "watch",
+					Usage:     "Poll a dictionary, printing items as they are added, changed, or removed",
+					Action:    dictionaryWatch,
+					ArgsUsage: "<SERVICE_NAME> <DICTIONARY_NAME>",
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "interval",
+							Usage: "Poll every `DURATION`.",
+							Value: 30 * time.Second,
+						},
+					},
+				},
+				cli.Command{
+					Name:      "item-import",
+					Usage:     "Import items into a dictionary from a CSV or JSON file, overwriting items with the same key",
+					Action:    dictionaryImport,
+					ArgsUsage: "<SERVICE_NAME> <DICTIONARY_NAME> <FILE>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "replace",
+							Usage: "Also remove items from the dictionary which are not in the file.",
+						},
+						maxDictionaryItemsFlag,
+						dryRunFlag,
+						allowDestructiveFlag,
+						maxDeletionPercentFlag,
+					},
+					Before: func(c *cli.Context) error {
+						if len(c.Args()) < 3 {
+							return cli.NewExitError("Please specify service, dictionary, and file.", -1)
+						}
+						return nil
+					},
+				},
+				cli.Command{
+					Name:      "copy",
+					Usage:     "Copy the items of a dictionary into a dictionary of another service, overwriting items with the same key",
+					Action:    dictionaryCopy,
+					ArgsUsage: "<SRC_SERVICE> <DICTIONARY_NAME> <DST_SERVICE> [<DST_DICTIONARY_NAME>]",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "replace",
+							Usage: "Also remove items from the destination which are not in the source.",
+						},
+					},
+					Before: func(c *cli.Context) error {
+						if len(c.Args()) < 3 {
+							return cli.NewExitError("Please specify source service, dictionary, and destination service.", -1)
+						}
+						return nil
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "flag",
+			Usage: "Manage feature flags kept in a dictionary, with each change recorded in an audit file.",
+			Before: func(c *cli.Context) error {
+				// less than 2 here since the subcommand is the first Arg
+				if len(c.Args()) < 2 {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "enable",
+					Usage:     "Enable a feature flag",
+					Action:    flagEnable,
+					ArgsUsage: "<SERVICE_NAME> <FLAG>",
+					Flags: append([]cli.Flag{
+						cli.BoolFlag{
+							Name:  "create",
+							Usage: "Add the flag if it doesn't already exist.",
+						},
+					}, flagFlags...),
+				},
+				cli.Command{
+					Name:      "disable",
+					Usage:     "Disable a feature flag",
+					Action:    flagDisable,
+					ArgsUsage: "<SERVICE_NAME> <FLAG>",
+					Flags: append([]cli.Flag{
+						cli.BoolFlag{
+							Name:  "create",
+							Usage: "Add the flag if it doesn't already exist.",
+						},
+					}, flagFlags...),
+				},
+				cli.Command{
+					Name:      "status",
+					Usage:     "Show whether a feature flag, or every flag, is enabled, and who last changed it",
+					Action:    flagStatus,
+					ArgsUsage: "<SERVICE_NAME> [<FLAG>]",
+					Flags:     flagFlags,
+				},
+			},
+		},
+		cli.Command{
+			Name:      "replicate",
+			Usage:     "Continually copy the contents of dictionaries and ACLs from one service to another, such as one in a DR account.",
+			ArgsUsage: "<SERVICE_NAME> [<DST_SERVICE_NAME>]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from-profile",
+					Usage: "Read from the account whose key is in the file 'fastly_key.`PROFILE`'. Defaults to the global key.",
+				},
+				cli.StringFlag{
+					Name:  "to-profile",
+					Usage: "Write to the account whose key is in the file 'fastly_key.`PROFILE`'. Defaults to the global key.",
+				},
+				cli.StringSliceFlag{
+					Name:  "dictionary",
+					Usage: "Replicate the dictionary `NAME`. May be repeated.",
+				},
+				cli.StringSliceFlag{
+					Name:  "acl",
+					Usage: "Replicate the acl `NAME`. May be repeated.",
+				},
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "Time between replication passes.",
+					Value: 60 * time.Second,
+				},
+				cli.StringFlag{
+					Name:  "conflicts",
+					Usage: "Resolve items changed at the destination since the last pass in favour of the `source` or `destination`.",
+					Value: "source",
+				},
+				cli.StringFlag{
+					Name:  "state-file",
+					Usage: "Checkpoint replicated state in `FILE`.",
+					Value: "fastlyctl-replicate.json",
+				},
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "Make a single replication pass and exit.",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() {
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
 			},
+			Action: replicate,
+		},
+		cli.Command{
+			Name:      "reconcile",
+			Usage:     "Repeatedly plan the services in the config file against Fastly, reporting and optionally correcting drift.",
+			ArgsUsage: "[<SERVICE_NAME>...]",
+			Description: "Services default to all of those in the config file. With --once, exits 0 if nothing drifted, 2 if\n" +
+				"   drift was corrected, 3 if drift was left in place, and 1 on error.",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "Time between reconciliation passes.",
+					Value: 10 * time.Minute,
+				},
+				cli.BoolFlag{
+					Name:  "auto-push",
+					Usage: "Push and activate the config of services which have drifted, without prompting. Requires --assume-yes.",
+				},
+				cli.StringFlag{
+					Name:  "webhook",
+					Usage: "POST a JSON event to `URL` for each service found to have drifted.",
+				},
+				diffHTMLOutFlag,
+				cli.StringFlag{
+					Name:  "diff-html-url",
+					Usage: "Link webhook events to the HTML diffs saved with --diff-html-out as served under `URL`, rather than by their paths.",
+				},
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "Make a single reconciliation pass and exit.",
+				},
+				allowDestructiveFlag,
+				maxDeletionsFlag,
+				maxDeletionPercentFlag,
+			},
+			Before: func(c *cli.Context) error {
+				if c.Bool("auto-push") && !c.GlobalBool("assume-yes") {
+					return cli.NewExitError("Error: --auto-push activates without prompting, and requires --assume-yes.", util.ExitError)
+				}
+				if c.GlobalBool("debug") {
+					log.EnableDebug()
+				}
+				return nil
+			},
+			Action: reconcile,
 		},
 		cli.Command{
 			Name:  "acl",
@@ -190,6 +1035,22 @@ func main() {
 					Action:    aclList,
 					ArgsUsage: "<SERVICE_NAME>",
 				},
+				cli.Command{
+					Name:      "create",
+					Usage:     "Create an acl in a new version of the service",
+					Action:    aclCreate,
+					ArgsUsage: "<SERVICE_NAME> <ACL_NAME>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
+					Before:    checkInteractive,
+				},
+				cli.Command{
+					Name:      "delete",
+					Usage:     "Delete an acl in a new version of the service",
+					Action:    aclDelete,
+					ArgsUsage: "<SERVICE_NAME> <ACL_NAME>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
+					Before:    checkInteractive,
+				},
 				cli.Command{
 					Name:      "entry-add",
 					Usage:     "Add an entry to a acl",
@@ -203,18 +1064,519 @@ func main() {
 					ArgsUsage: "<SERVICE_NAME> <ACL_NAME> <IP>[/<MASK>]",
 				},
 				cli.Command{
-					Name:      "entry-ls",
-					Usage:     "List entries in an acl",
-					Action:    aclListEntries,
-					ArgsUsage: "<SERVICE_NAME> <ACL_NAME>",
+					Name:        "entry-ls",
+					Usage:       "List entries in an acl in CIDR notation, flagging duplicate and overlapping ranges",
+					Description: "With --contains, exits 0 if the address is matched by the acl and 1 if it isn't.",
+					Action:      aclListEntries,
+					ArgsUsage:   "<SERVICE_NAME> <ACL_NAME>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "contains",
+							Usage: "Report whether `IP` is matched by the acl, through its most specific entry, rather than listing entries.",
+						},
+					},
+				},
+				cli.Command{
+					Name:      "entry-import",
+					Usage:     "Import entries into an acl from a file, overwriting the comment and negation of entries for the same range",
+					Action:    aclImportEntries,
+					ArgsUsage: "<SERVICE_NAME> <ACL_NAME> <FILE>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "replace",
+							Usage: "Also remove entries from the acl which are not in the file.",
+						},
+						dryRunFlag,
+						allowDestructiveFlag,
+						maxDeletionPercentFlag,
+					},
+					Before: func(c *cli.Context) error {
+						if len(c.Args()) < 3 {
+							return cli.NewExitError("Please specify service, acl, and file.", -1)
+						}
+						return nil
+					},
+				},
+				cli.Command{
+					Name:      "copy",
+					Usage:     "Copy the entries of an acl into an acl of another service, overwriting the comment and negation of entries for the same range",
+					Action:    aclCopy,
+					ArgsUsage: "<SRC_SERVICE> <ACL_NAME> <DST_SERVICE> [<DST_ACL_NAME>]",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "replace",
+							Usage: "Also remove entries from the destination which are not in the source.",
+						},
+					},
+					Before: func(c *cli.Context) error {
+						if len(c.Args()) < 3 {
+							return cli.NewExitError("Please specify source service, acl, and destination service.", -1)
+						}
+						return nil
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "reqsetting",
+			Usage: "Manage request settings.",
+			Before: func(c *cli.Context) error {
+				// less than 2 here since the subcommand is the first Arg
+				if len(c.Args()) < 2 {
+					cli.ShowAppHelp(c)
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "list",
+					Usage:     "List request settings in the active version of a service",
+					Action:    requestSettingList,
+					ArgsUsage: "<SERVICE_NAME>",
+				},
+				cli.Command{
+					Name:      "show",
+					Usage:     "Show a request setting in the active version of a service",
+					Action:    requestSettingShow,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+				},
+				cli.Command{
+					Name:      "add",
+					Usage:     "Add a request setting in a new version of the service",
+					Action:    requestSettingAdd,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "action",
+							Usage: "Either `lookup` or pass.",
+						},
+						cli.StringFlag{
+							Name:  "request-condition",
+							Usage: "Apply only to requests matching the condition `NAME`.",
+						},
+						cli.StringFlag{
+							Name:  "default-host",
+							Usage: "Set the Host header to `HOST` if the client didn't send one.",
+						},
+						cli.StringFlag{
+							Name:  "hash-keys",
+							Usage: "Comma separated `LIST` of VCL variables to build the cache hash from.",
+						},
+						cli.StringFlag{
+							Name:  "xff",
+							Usage: "How to treat X-Forwarded-For: clear, leave, `append`, append_all, or overwrite.",
+						},
+						cli.IntFlag{
+							Name:  "max-stale-age",
+							Usage: "Serve stale content for up to `SECONDS`.",
+						},
+						cli.BoolFlag{
+							Name:  "force-miss",
+							Usage: "Force a cache miss.",
+						},
+						cli.BoolFlag{
+							Name:  "force-ssl",
+							Usage: "Redirect plain HTTP requests to HTTPS.",
+						},
+						cli.BoolFlag{
+							Name:  "bypass-busy-wait",
+							Usage: "Disable collapsed forwarding.",
+						},
+						cli.BoolFlag{
+							Name:  "geo-headers",
+							Usage: "Add geo headers to the request.",
+						},
+						cli.BoolFlag{
+							Name:  "timer-support",
+							Usage: "Add timing headers to the request.",
+						},
+						waitFlag, waitTimeoutFlag,
+					},
+					Before: checkInteractive,
+				},
+				cli.Command{
+					Name:      "rm",
+					Usage:     "Remove a request setting in a new version of the service",
+					Action:    requestSettingRemove,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
+					Before:    checkInteractive,
 				},
 			},
 		},
+		cli.Command{
+			Name:  "respobject",
+			Usage: "Manage response objects.",
+			Before: func(c *cli.Context) error {
+				// less than 2 here since the subcommand is the first Arg
+				if len(c.Args()) < 2 {
+					cli.ShowAppHelp(c)
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "list",
+					Usage:     "List response objects in the active version of a service",
+					Action:    responseObjectList,
+					ArgsUsage: "<SERVICE_NAME>",
+				},
+				cli.Command{
+					Name:      "show",
+					Usage:     "Show a response object in the active version of a service",
+					Action:    responseObjectShow,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+				},
+				cli.Command{
+					Name:      "add",
+					Usage:     "Add a response object in a new version of the service",
+					Action:    responseObjectAdd,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "status",
+							Usage: "Respond with the HTTP status `CODE`.",
+							Value: "200",
+						},
+						cli.StringFlag{
+							Name:  "response",
+							Usage: "Respond with the status `TEXT`.",
+							Value: "OK",
+						},
+						cli.StringFlag{
+							Name:  "content",
+							Usage: "Respond with the body `CONTENT`.",
+						},
+						cli.StringFlag{
+							Name:  "content-file",
+							Usage: "Read the response body from `FILE`.",
+						},
+						cli.StringFlag{
+							Name:  "content-type",
+							Usage: "Content type of the body. Inferred from the extension of --content-file if unset.",
+						},
+						cli.StringFlag{
+							Name:  "request-condition",
+							Usage: "Respond only to requests matching the condition `NAME`.",
+						},
+						cli.StringFlag{
+							Name:  "cache-condition",
+							Usage: "Respond only to responses matching the cache condition `NAME`.",
+						},
+						waitFlag, waitTimeoutFlag,
+					},
+					Before: checkInteractive,
+				},
+				cli.Command{
+					Name:      "rm",
+					Usage:     "Remove a response object in a new version of the service",
+					Action:    responseObjectRemove,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
+					Before:    checkInteractive,
+				},
+			},
+		},
+		cli.Command{
+			Name:  "header",
+			Usage: "Manage header rules.",
+			Before: func(c *cli.Context) error {
+				// less than 2 here since the subcommand is the first Arg
+				if len(c.Args()) < 2 {
+					cli.ShowAppHelp(c)
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "list",
+					Usage:     "List headers in the active version of a service",
+					Action:    headerList,
+					ArgsUsage: "<SERVICE_NAME>",
+				},
+				cli.Command{
+					Name:      "add",
+					Usage:     "Add a header in a new version of the service",
+					Action:    headerAdd,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "type",
+							Usage: "Apply to the request, fetch, cache, or response `TYPE`.",
+						},
+						cli.StringFlag{
+							Name:  "action",
+							Usage: "One of set, append, delete, regex, or regex_repeat.",
+							Value: "set",
+						},
+						cli.StringFlag{
+							Name:  "src",
+							Usage: "Variable or value to take the header from, such as `client.ip`.",
+						},
+						cli.StringFlag{
+							Name:  "dst",
+							Usage: "Header to modify, such as `http.X-Client-IP`.",
+						},
+						cli.StringFlag{
+							Name:  "regex",
+							Usage: "`PATTERN` to match for the regex actions.",
+						},
+						cli.StringFlag{
+							Name:  "substitution",
+							Usage: "`VALUE` to substitute for the regex actions.",
+						},
+						cli.UintFlag{
+							Name:  "priority",
+							Usage: "Order the header is applied in, lowest first.",
+							Value: 100,
+						},
+						cli.BoolFlag{
+							Name:  "ignore-if-set",
+							Usage: "Don't modify the header if it is already set.",
+						},
+						cli.StringFlag{
+							Name:  "request-condition",
+							Usage: "Apply only to requests matching the condition `NAME`.",
+						},
+						cli.StringFlag{
+							Name:  "cache-condition",
+							Usage: "Apply only to responses matching the cache condition `NAME`.",
+						},
+						cli.StringFlag{
+							Name:  "response-condition",
+							Usage: "Apply only to responses matching the condition `NAME`.",
+						},
+						waitFlag, waitTimeoutFlag,
+					},
+					Before: checkInteractive,
+				},
+				cli.Command{
+					Name:      "rm",
+					Usage:     "Remove a header in a new version of the service",
+					Action:    headerRemove,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
+					Before:    checkInteractive,
+				},
+			},
+		},
+		cli.Command{
+			Name:  "gzip",
+			Usage: "Manage gzip policies.",
+			Before: func(c *cli.Context) error {
+				// less than 2 here since the subcommand is the first Arg
+				if len(c.Args()) < 2 {
+					cli.ShowAppHelp(c)
+					return cli.NewExitError("Please specify service.", -1)
+				}
+				return nil
+			},
+			Subcommands: cli.Commands{
+				cli.Command{
+					Name:      "list",
+					Usage:     "List gzip policies in the active version of a service",
+					Action:    gzipList,
+					ArgsUsage: "<SERVICE_NAME>",
+				},
+				cli.Command{
+					Name:      "add",
+					Usage:     "Add a gzip policy in a new version of the service",
+					Action:    gzipAdd,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "extensions",
+							Usage: "Space separated `LIST` of file extensions to compress, such as \"css js html\".",
+						},
+						cli.StringFlag{
+							Name:  "content-types",
+							Usage: "Space separated `LIST` of content types to compress.",
+						},
+						cli.StringFlag{
+							Name:  "cache-condition",
+							Usage: "Compress only responses matching the cache condition `NAME`.",
+						},
+						waitFlag, waitTimeoutFlag,
+					},
+					Before: checkInteractive,
+				},
+				cli.Command{
+					Name:      "rm",
+					Usage:     "Remove a gzip policy in a new version of the service",
+					Action:    gzipRemove,
+					ArgsUsage: "<SERVICE_NAME> <NAME>",
+					Flags:     []cli.Flag{waitFlag, waitTimeoutFlag},
+					Before:    checkInteractive,
+				},
+			},
+		},
+		cli.Command{
+			Name:  "self-update",
+			Usage: "Update fastlyctl to the latest release on GitHub",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "check",
+					Usage: "Only report whether a newer release is available.",
+				},
+				cli.StringFlag{
+					Name:  "version",
+					Usage: "Install release `VERSION` rather than the latest, even if it is older.",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Reinstall the latest release even if it isn't newer.",
+				},
+			},
+			Action: selfUpdate,
+		},
+		cli.Command{
+			Name:      "replay",
+			Usage:     "Replay the GET requests of a session recorded with --record against a mock API serving its responses, reporting where Fastly answered the same request differently.",
+			ArgsUsage: "<SESSION_FILE>",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "live",
+					Usage: "Replay the requests against the Fastly API instead, reporting the responses which differ from those recorded.",
+				},
+				cli.StringFlag{
+					Name:  "listen",
+					Usage: "Serve the recorded responses on `ADDRESS` until interrupted, rather than replaying them.",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if !c.Args().Present() {
+					return cli.NewExitError("Please specify a session file.", util.ExitError)
+				}
+				return nil
+			},
+			Action: replaySession,
+		},
+		cli.Command{
+			Name:   "rate-limit",
+			Usage:  "Show the API write quota remaining for the Fastly key, as last reported by Fastly.",
+			Action: rateLimit,
+		},
 	}
 
 	err := app.Run(os.Args)
+	stopRecording()
+	if util.Timings {
+		util.PrintTimings(os.Stderr)
+	}
 	if err != nil {
-		fmt.Printf("Error starting app: %s", err)
+		fmt.Fprintf(os.Stderr, "Error starting app: %s\n", err)
 	}
 
 }
+
+// statsFlags select the window and location of the metrics shown by stats.
+var statsFlags = []cli.Flag{
+	cli.DurationFlag{
+		Name:  "since",
+		Usage: "Show metrics for the `DURATION` up to now.",
+		Value: time.Hour,
+	},
+	cli.StringFlag{
+		Name:  "region",
+		Usage: "Only include traffic served in `REGION`, such as usa or europe.",
+	},
+	cli.StringFlag{
+		Name:  "datacenter",
+		Usage: "Only include traffic served by the POP `CODE`.",
+	},
+}
+
+// waitFlag and waitTimeoutFlag are shared by commands which activate versions.
+var waitFlag = cli.BoolFlag{
+	Name:  "wait",
+	Usage: "After activating, wait until Fastly reports the new version as deployed.",
+}
+
+var waitTimeoutFlag = cli.DurationFlag{
+	Name:  "wait-timeout",
+	Usage: "Give up waiting for deployment after `DURATION`.",
+	Value: 10 * time.Minute,
+}
+
+// serviceTimeoutFlag and rateLimitWarningFlag are shared by push and apply.
+var serviceTimeoutFlag = cli.DurationFlag{
+	Name:  "service-timeout",
+	Usage: "Give up syncing and validating a service after this long, such as 5m, recording it as failed and continuing with the rest. Prompts aren't counted. Zero waits forever.",
+}
+
+var rateLimitWarningFlag = cli.IntFlag{
+	Name:  "rate-limit-warning",
+	Usage: "Warn once fewer than `N` API writes remain before the hourly rate limit resets. Zero disables the warning.",
+	Value: 100,
+}
+
+// diffModeFlag is shared by commands which review changes before activating
+// them.
+var diffModeFlag = cli.StringFlag{
+	Name:  "diff-mode",
+	Usage: "Review changes as `MODE`: vcl for Fastly's diff of the generated VCL, semantic for the old and new values of each changed resource, or both.",
+	Value: string(util.DiffModeVCL),
+}
+
+// diffHTMLOutFlag is shared by the commands which push configs.
+var diffHTMLOutFlag = cli.StringFlag{
+	Name:  "diff-html-out",
+	Usage: "Save Fastly's HTML diff of each new version to `DIR`, for reviewers who don't use a terminal.",
+}
+
+// dryRunFlag is shared by bulk dictionary and ACL commands.
+var dryRunFlag = cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "Print the items or entries which would be added, updated, and deleted, without changing anything.",
+}
+
+// maxDictionaryItemsFlag is shared by commands which write dictionary items,
+// for accounts whose item limit has been raised.
+var maxDictionaryItemsFlag = cli.IntFlag{
+	Name:  "max-dictionary-items",
+	Usage: "Refuse to write more than `N` items to a dictionary.",
+	Value: fsync.DefaultMaxDictionaryItems,
+}
+
+// allowDestructiveFlag, maxDeletionsFlag, and maxDeletionPercentFlag configure
+// the guard against large deletions, shared by commands which may remove
+// resources wholesale.
+var allowDestructiveFlag = cli.BoolFlag{
+	Name:  "allow-destructive",
+	Usage: "Proceed with deletions beyond --max-deletions or --max-deletion-percent without a typed confirmation.",
+}
+
+var maxDeletionsFlag = cli.IntFlag{
+	Name:   "max-deletions",
+	Usage:  "Require a typed confirmation to remove more than `N` resources from a service.",
+	Value:  10,
+	EnvVar: "FASTLYCTL_MAX_DELETIONS",
+}
+
+var maxDeletionPercentFlag = cli.Float64Flag{
+	Name:   "max-deletion-percent",
+	Usage:  "Require a typed confirmation to delete more than `PERCENT` of the items or entries of a dictionary or ACL.",
+	Value:  50,
+	EnvVar: "FASTLYCTL_MAX_DELETION_PERCENT",
+}
+
+// deletionGuard returns the guard configured by the deletion flags.
+func deletionGuard(c *cli.Context) util.DeletionGuard {
+	return util.DeletionGuard{
+		MaxDeletions: c.Int("max-deletions"),
+		MaxPercent:   c.Float64("max-deletion-percent"),
+		Allow:        c.Bool("allow-destructive"),
+	}
+}
+
+// checkInteractive ensures that we can prompt the user, unless prompts are
+// being skipped with --assume-yes.
+func checkInteractive(c *cli.Context) error {
+	if !util.IsInteractive() && !c.GlobalBool("assume-yes") {
+		return cli.NewExitError(util.ErrNonInteractive.Error(), -1)
+	}
+	return nil
+}
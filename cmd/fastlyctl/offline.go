@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+	"github.com/urfave/cli"
+)
+
+// syncOffline runs the sync engine against service snapshots rather than the
+// Fastly API. Each snapshot is loaded into an in-process fake of the API, so
+// the full set of changes a push would make can be computed without
+// credentials or network access.
+func syncOffline(c *cli.Context) error {
+	root := c.String("offline")
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), util.ExitError)
+	}
+
+	var names []string
+	if c.Bool("all") {
+		for name := range configs {
+			if name != "_default_" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	} else {
+		for _, name := range c.Args() {
+			if _, ok := configs[name]; !ok {
+				return cli.NewExitError(fmt.Sprintf("Service %s is not defined in configuration.", name), util.ExitError)
+			}
+			names = append(names, name)
+		}
+	}
+
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	syncer := newSyncer(client, configs)
+
+	pending := false
+	for _, name := range names {
+		snapshot, err := readSnapshot(snapshotDir(root, name))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error reading snapshot for service %s: %s", name, err), util.ExitError)
+		}
+		if snapshot.Service.Name != name {
+			return cli.NewExitError(fmt.Sprintf("Snapshot in %s is for service %s, not %s", snapshotDir(root, name), snapshot.Service.Name, name), util.ExitError)
+		}
+		s, err := fsync.LoadSnapshot(srv, snapshot)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error loading snapshot for service %s: %s", name, err), util.ExitError)
+		}
+
+		fmt.Printf("Planning %s against snapshot of version %d\n", name, snapshot.Version)
+		version, err := syncer.Apply(s)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error syncing service config for %s: %s", name, err), util.ExitError)
+		}
+		if version != nil {
+			if err = printOfflinePlan(srv, client, s, version.Number, !c.GlobalBool("skip-diff")); err != nil {
+				return cli.NewExitError(fmt.Sprintf("Error generating plan for %s: %s", name, err), util.ExitError)
+			}
+			pending = true
+		}
+	}
+	return pushResult(false, pending)
+}
+
+// printOfflinePlan prints the resources which differ between the snapshot
+// version of a service and the version prepared from local config, followed
+// by the diff of the two if showDiff is set.
+func printOfflinePlan(srv *fastlytest.Server, client *fastly.Client, s *fastly.Service, version uint, showDiff bool) error {
+	fmt.Printf("Planned changes for %s:\n", s.Name)
+	settingsFrom, err := srv.Settings(s.ID, 1)
+	if err != nil {
+		return err
+	}
+	settingsTo, err := srv.Settings(s.ID, version)
+	if err != nil {
+		return err
+	}
+	settingsFrom.Version, settingsTo.Version = 0, 0
+	if settingsFrom != settingsTo {
+		fmt.Println("  ~ settings")
+	}
+
+	for _, kind := range fastlytest.Kinds() {
+		from := resourcesByName(srv.Resources(s.ID, 1, kind))
+		to := resourcesByName(srv.Resources(s.ID, version, kind))
+		var names []string
+		for name := range from {
+			names = append(names, name)
+		}
+		for name := range to {
+			if _, ok := from[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f, inFrom := from[name]
+			t, inTo := to[name]
+			if !inFrom {
+				fmt.Printf("  + %s %s%s\n", kind, name, effective(t))
+			} else if !inTo {
+				fmt.Printf("  - %s %s\n", kind, name)
+			} else if !reflect.DeepEqual(f, t) {
+				fmt.Printf("  ~ %s %s%s\n", kind, name, effective(t))
+			}
+		}
+	}
+
+	if !showDiff {
+		return nil
+	}
+	diff, err := util.GetUnifiedDiff(client, s, 1, version)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nDiff for %s:\n\n", s.Name)
+	fmt.Println(diff)
+	return nil
+}
+
+// effective describes the effective values of a resource for the plan, as
+// ChangeLog.Print does.
+func effective(resource interface{}) string {
+	if values := fsync.EffectiveValues(resource); values != "" {
+		return " (" + values + ")"
+	}
+	return ""
+}
+
+// resourcesByName indexes resources by name, zeroing out the fields which
+// differ between versions.
+func resourcesByName(resources []interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, r := range resources {
+		v := reflect.ValueOf(r).Elem()
+		v.FieldByName("Version").SetUint(0)
+		out[v.FieldByName("Name").String()] = v.Interface()
+	}
+	return out
+}
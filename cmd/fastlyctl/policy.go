@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// policyQuery is the Rego rule evaluated against plans. Each value it
+// produces is a violation, given either as a message or as an object with a
+// msg field.
+const policyQuery = "data.fastlyctl.deny"
+
+var policyBundleFlag = cli.StringFlag{
+	Name:  "policy-bundle",
+	Usage: "Evaluate the Rego policies in `DIR` against the plan of each service with 'opa eval', refusing to activate versions which " + policyQuery + " denies.",
+}
+
+// policyPlan is the document policies are evaluated against, as their input.
+type policyPlan struct {
+	Service   string        `json:"service"`
+	ServiceID string        `json:"service_id"`
+	Version   uint          `json:"version"`
+	Active    uint          `json:"active_version"`
+	Changes   []util.Change `json:"changes"`
+}
+
+// opaResult is the output of 'opa eval --format json'.
+type opaResult struct {
+	Result []struct {
+		Expressions []struct {
+			Value interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// checkPolicy evaluates the policies in bundle against the changes made in
+// version of s, returning an error listing the violations if any deny rule
+// matches.
+func checkPolicy(bundle string, s *fastly.Service, version *fastly.Version, changes []util.Change) error {
+	input, err := json.Marshal(policyPlan{Service: s.Name, ServiceID: s.ID, Version: version.Number, Active: s.Version, Changes: changes})
+	if err != nil {
+		return err
+	}
+	violations, err := evaluatePolicy(bundle, input)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("Version %d on service %s is denied by policy:\n    %s", version.Number, s.Name, strings.Join(violations, "\n    "))
+	}
+	return nil
+}
+
+// evaluatePolicy runs opa eval with input, returning the messages of the
+// violations it finds. A deny rule which is undefined finds none.
+func evaluatePolicy(bundle string, input []byte) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("opa", "eval", "--format", "json", "--bundle", bundle, "--stdin-input", policyQuery)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error evaluating policy bundle %s: %s %s", bundle, err, strings.TrimSpace(stderr.String()))
+	}
+	var result opaResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("Error reading the output of opa eval: %s", err)
+	}
+
+	var violations []string
+	for _, r := range result.Result {
+		for _, e := range r.Expressions {
+			values, ok := e.Value.([]interface{})
+			if !ok {
+				values = []interface{}{e.Value}
+			}
+			for _, v := range values {
+				violations = append(violations, violationMessage(v))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// violationMessage describes a value produced by a deny rule.
+func violationMessage(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if msg, ok := v["msg"].(string); ok {
+			return msg
+		}
+	}
+	text, _ := json.Marshal(v)
+	return string(text)
+}
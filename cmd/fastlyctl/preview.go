@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/urfave/cli"
+)
+
+var (
+	vclIncludePattern = regexp.MustCompile(`^\s*include\s+"([^"]+)"\s*;`)
+	vclMacroPattern   = regexp.MustCompile(`(?i)^\s*#FASTLY\s+(\w+)`)
+	// generatedBlockPattern matches the start of the boilerplate which a
+	// #FASTLY macro expands to in generated VCL.
+	generatedBlockPattern = regexp.MustCompile(`^\s*#--FASTLY (\w+) BEGIN`)
+)
+
+// vclLines splits VCL into lines, each ending in a newline.
+func vclLines(vcl string) []string {
+	if vcl == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(vcl, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+// generatedBlocks returns the boilerplate of generated VCL, including its
+// BEGIN and END markers, keyed by the upper-cased name of the macro it was
+// expanded from.
+func generatedBlocks(generated string) map[string][]string {
+	blocks := make(map[string][]string)
+	lines := vclLines(generated)
+	for i := 0; i < len(lines); i++ {
+		m := generatedBlockPattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		end := "#--FASTLY " + m[1] + " END"
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == end {
+				blocks[m[1]] = lines[i : j+1]
+				i = j
+				break
+			}
+		}
+	}
+	return blocks
+}
+
+// renderVCL approximates the VCL Fastly would generate for a config, by
+// inlining the includes of its main VCL and expanding its #FASTLY macros with
+// the boilerplate of those in generated. Macros absent from generated are
+// left as they are.
+func renderVCL(config fsync.SiteConfig, generated string) ([]string, error) {
+	vcls := make(map[string]string)
+	var main string
+	var found bool
+	for _, vcl := range config.VCLs {
+		content := vcl.Content
+		if vcl.File != "" {
+			b, err := fsync.ReadFile(vcl.File)
+			if err != nil {
+				return nil, err
+			}
+			content = string(b)
+		}
+		vcls[vcl.Name] = content
+		if vcl.Main {
+			main, found = content, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("No main VCL is configured, so there is no VCL to preview.")
+	}
+
+	blocks := generatedBlocks(generated)
+	var out []string
+	var expand func(content string, seen []string) error
+	expand = func(content string, seen []string) error {
+		for _, line := range vclLines(content) {
+			if m := vclIncludePattern.FindStringSubmatch(line); m != nil {
+				if included, ok := vcls[m[1]]; ok {
+					if util.StringInSlice(m[1], seen) {
+						return fmt.Errorf("VCL %s includes itself.", m[1])
+					}
+					if err := expand(included, append(seen, m[1])); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if m := vclMacroPattern.FindStringSubmatch(line); m != nil {
+				if block, ok := blocks[strings.ToUpper(m[1])]; ok {
+					out = append(out, block...)
+					continue
+				}
+			}
+			out = append(out, line)
+		}
+		return nil
+	}
+	if err := expand(main, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func previewVCL(c *cli.Context) error {
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), util.ExitError)
+	}
+	name := c.Args().First()
+	config, ok := configs[name]
+	if !ok {
+		return cli.NewExitError(fmt.Sprintf("Service %s is not defined in the config file.", name), util.ExitError)
+	}
+
+	key, err := util.ResolveKey(config.APIKey)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error resolving API key for service %s: %s", name, err), util.ExitError)
+	}
+	if key == "" {
+		key = c.GlobalString("fastly-key")
+	}
+	client := util.ClientFactory(key)
+	service, err := util.GetServiceByNameOrID(client, name)
+	if err != nil {
+		return cli.NewExitError(err.Error(), util.ExitError)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), util.ExitError)
+	}
+	generated, _, err := client.VCL.Generated(service.ID, activeVersion)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching generated VCL for version %d: %s", activeVersion, err), util.ExitError)
+	}
+
+	local, err := renderVCL(config, generated.Content)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error rendering VCL for service %s: %s", service.Name, err), util.ExitError)
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        vclLines(generated.Content),
+		B:        local,
+		FromFile: fmt.Sprintf("%s version %d (generated)", service.Name, activeVersion),
+		ToFile:   fmt.Sprintf("%s local config (preview)", service.Name),
+		Context:  3,
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), util.ExitError)
+	}
+	if diff == "" {
+		fmt.Printf("The VCL of %s matches version %d.\n", service.Name, activeVersion)
+		return nil
+	}
+	fmt.Print(diff)
+	return cli.NewExitError("", util.ExitChangesPending)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// cacheState is what a response says of how it was served.
+type cacheState struct {
+	// xCache is the X-Cache of the POP which answered, such as HIT or
+	// MISS. Shields add their own state ahead of it.
+	xCache string
+	// age is how long the object had been cached for, in seconds.
+	age int
+}
+
+// fetchCacheState requests u with Fastly-Debug set, without following
+// redirects.
+func fetchCacheState(u string) (cacheState, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return cacheState{}, err
+	}
+	req.Header.Set("Fastly-Debug", "1")
+	resp, err := client.Do(req)
+	if err != nil {
+		return cacheState{}, err
+	}
+	resp.Body.Close()
+
+	var state cacheState
+	hops := strings.Split(resp.Header.Get("X-Cache"), ",")
+	state.xCache = strings.TrimSpace(hops[len(hops)-1])
+	state.age, _ = strconv.Atoi(resp.Header.Get("Age"))
+	return state, nil
+}
+
+func purgeVerify(c *cli.Context) error {
+	client := util.NewClient(c)
+	target := c.Args().First()
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return cli.NewExitError(fmt.Sprintf("Invalid URL %q. Must be an http or https URL.", target), -1)
+	}
+	timeout := c.Duration("timeout")
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		return cli.NewExitError("--interval must be positive.", -1)
+	}
+
+	purge, _, err := client.Purge.URL(target)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error purging %s: %s", target, err), -1)
+	}
+	purged := time.Now()
+	fmt.Printf("Purged %s (purge ID %s). Waiting for a fresh object to be cached.\n", target, purge.ID)
+
+	// An object is fresh if it was cached after the purge, which a HIT
+	// shows by its Age. Normally the first request after the purge is a
+	// MISS refetching the object, and those after it HITs on the new copy.
+	var missed bool
+	for {
+		state, err := fetchCacheState(target)
+		elapsed := time.Since(purged)
+		if err != nil {
+			fmt.Printf("  %5.1fs error: %s\n", elapsed.Seconds(), err)
+		} else {
+			fmt.Printf("  %5.1fs %s, age %d\n", elapsed.Seconds(), state.xCache, state.age)
+			switch {
+			case strings.HasPrefix(state.xCache, "PASS"):
+				return cli.NewExitError(fmt.Sprintf("%s is not cached, so its purge cannot be verified.", target), -1)
+			case strings.HasPrefix(state.xCache, "MISS"):
+				missed = true
+			case strings.HasPrefix(state.xCache, "HIT") && float64(state.age) <= elapsed.Seconds()+1:
+				if !missed {
+					fmt.Println("The object was refetched by another request.")
+				}
+				fmt.Printf("Purge of %s confirmed after %s.\n", target, elapsed.Round(time.Millisecond))
+				return nil
+			}
+		}
+		if elapsed+interval > timeout {
+			break
+		}
+		time.Sleep(interval)
+	}
+	return cli.NewExitError(fmt.Sprintf("Purge of %s not confirmed within %s.", target, timeout), -1)
+}
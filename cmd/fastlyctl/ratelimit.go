@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alienth/fastlyctl/log"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// recordRateLimit records the write quota client has seen for rate-limit. A
+// failure to do so doesn't affect the command which made the writes.
+func recordRateLimit(key string, client *fastly.Client) {
+	if err := util.RecordRateLimit(key, client); err != nil {
+		log.Debug(fmt.Sprintf("Error recording rate limit: %s\n", err))
+	}
+}
+
+func rateLimit(c *cli.Context) error {
+	status, err := util.LastRateLimit(c.GlobalString("fastly-key"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading the recorded rate limit: %s", err), -1)
+	}
+	if status == nil {
+		fmt.Println("No rate limit has been recorded for this key. Fastly reports it in response to writes, such as those made by push.")
+		return nil
+	}
+	now := time.Now()
+	fmt.Printf("%-17s %s (%s ago)\n", "Reported at:", status.Seen.Format(time.RFC3339), now.Sub(status.Seen).Round(time.Second))
+	if !now.Before(status.Reset) {
+		fmt.Printf("%-17s %s\n", "Reset at:", status.Reset.Format(time.RFC3339))
+		fmt.Println("The quota has reset since, and Fastly will report the new one on the next write.")
+		return nil
+	}
+	fmt.Printf("%-17s %d\n", "Remaining writes:", status.Remaining)
+	fmt.Printf("%-17s %s (in %s)\n", "Resets at:", status.Reset.Format(time.RFC3339), status.Reset.Sub(now).Round(time.Second))
+	return nil
+}
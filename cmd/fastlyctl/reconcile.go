@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// webhookTimeout bounds how long delivering a drift event may take.
+const webhookTimeout = 10 * time.Second
+
+// driftEvent describes a service found to differ from its config, and what
+// was done about it. It is posted as JSON to the --webhook URL.
+type driftEvent struct {
+	Time              time.Time      `json:"time"`
+	Service           string         `json:"service"`
+	ServiceID         string         `json:"service_id"`
+	ActiveVersion     uint           `json:"active_version"`
+	Changes           util.ChangeLog `json:"changes"`
+	Remediated        bool           `json:"remediated"`
+	RemediatedVersion uint           `json:"remediated_version,omitempty"`
+	// DiffHTML links to the HTML diff of the remediation, when
+	// --diff-html-out is set.
+	DiffHTML string `json:"diff_html,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func postWebhook(url string, event *driftEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// diffHTMLLink returns the URL of a saved HTML diff under base, the URL at
+// which the --diff-html-out directory is served, or its path if base is
+// empty.
+func diffHTMLLink(base, file string) string {
+	if base == "" {
+		return file
+	}
+	return strings.TrimSuffix(base, "/") + "/" + url.PathEscape(filepath.Base(file))
+}
+
+// planDrift returns the changes a push would make to the active version of a
+// service, along with that version. Nothing is modified in Fastly.
+func planDrift(client *fastly.Client, configs map[string]fsync.SiteConfig, s *fastly.Service) (util.ChangeLog, uint, error) {
+	activeVersion, err := util.GetActiveVersion(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	changes, err := newSyncer(client, configs).Plan(s)
+	return changes, activeVersion, err
+}
+
+// remediateDrift pushes the config of a service, activating the result
+// without prompting unless guard objects to its deletions.
+func remediateDrift(client *fastly.Client, configs map[string]fsync.SiteConfig, s *fastly.Service, guard util.DeletionGuard) (uint, error) {
+	syncer := newSyncer(client, configs)
+	syncer.CheckContents = guard.CheckContents
+	version, err := syncer.Apply(s)
+	if err != nil {
+		return 0, fmt.Errorf("Error syncing service config: %s", err)
+	}
+	if version == nil {
+		return 0, nil
+	}
+	if err := guard.CheckChanges(s.Name, versionedChanges(syncer.Changes(s))); err != nil {
+		return 0, err
+	}
+	if err := util.ValidateVersion(client, s, version.Number); err != nil {
+		return 0, err
+	}
+	if _, _, err := client.Version.Activate(s.ID, version.Number); err != nil {
+		return 0, fmt.Errorf("Error activating version %d: %s", version.Number, err)
+	}
+	fmt.Printf("Activated version %d for %s\n", version.Number, s.Name)
+	util.CountChangesApplied(s.Name, len(syncer.Changes(s)))
+	return version.Number, nil
+}
+
+func reconcile(c *cli.Context) error {
+	configFile := c.GlobalString("config")
+	interval := c.Duration("interval")
+	if interval <= 0 && !c.Bool("once") {
+		return cli.NewExitError("--interval must be positive.", util.ExitError)
+	}
+
+	// pass checks each configured service for drift once, returning whether
+	// any was remediated or left in place.
+	pass := func() (bool, bool, error) {
+		configs, err := fsync.ReadConfig(configFile)
+		if err != nil {
+			return false, false, fmt.Errorf("Error reading config file: %s", err)
+		}
+		var names []string
+		for name := range configs {
+			if name == "_default_" {
+				continue
+			}
+			if c.Args().Present() && !util.StringInSlice(name, c.Args()) {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var remediated, drifted, failed bool
+		for _, name := range names {
+			now := time.Now().UTC()
+			key, err := util.ResolveKey(configs[name].APIKey)
+			if err != nil {
+				return false, false, fmt.Errorf("Error resolving API key for service %s: %s", name, err)
+			}
+			if key == "" {
+				key = c.GlobalString("fastly-key")
+			}
+			client := util.ClientFactory(key)
+
+			s, err := util.GetServiceByNameOrID(client, name)
+			if err != nil {
+				fmt.Printf("%s Error fetching service %s: %s\n", now.Format(time.RFC3339), name, err)
+				util.CountError("reconcile")
+				failed = true
+				continue
+			}
+			started := time.Now()
+			changes, activeVersion, err := planDrift(client, configs, s)
+			util.ObserveSync(s.Name, time.Since(started))
+			if err != nil {
+				fmt.Printf("%s Error planning %s: %s\n", now.Format(time.RFC3339), s.Name, err)
+				util.CountError("reconcile")
+				failed = true
+				continue
+			}
+			if len(changes) == 0 {
+				fmt.Printf("%s %s matches its config\n", now.Format(time.RFC3339), s.Name)
+				continue
+			}
+
+			fmt.Printf("%s Drift detected in %s: %s\n", now.Format(time.RFC3339), s.Name, changes.Summary())
+			event := &driftEvent{Time: now, Service: s.Name, ServiceID: s.ID, ActiveVersion: activeVersion, Changes: changes}
+			if c.Bool("auto-push") {
+				version, err := remediateDrift(client, configs, s, deletionGuard(c))
+				if err != nil {
+					fmt.Printf("%s Error remediating %s: %s\n", time.Now().UTC().Format(time.RFC3339), s.Name, err)
+					util.CountError("reconcile")
+					event.Error = err.Error()
+					failed = true
+				} else {
+					event.Remediated = true
+					event.RemediatedVersion = version
+					if dir := c.String("diff-html-out"); dir != "" && version != 0 {
+						if file := saveHTMLDiff(client, s, version, dir); file != "" {
+							event.DiffHTML = diffHTMLLink(c.String("diff-html-url"), file)
+						}
+					}
+				}
+			}
+			if event.Remediated {
+				remediated = true
+			} else {
+				drifted = true
+			}
+			if url := c.String("webhook"); url != "" {
+				if err := postWebhook(url, event); err != nil {
+					fmt.Printf("Error sending drift event for %s: %s\n", s.Name, err)
+					util.CountError("reconcile")
+				}
+			}
+		}
+		if failed {
+			return remediated, drifted, fmt.Errorf("Reconciliation failed for one or more services.")
+		}
+		return remediated, drifted, nil
+	}
+
+	if c.Bool("once") {
+		remediated, drifted, err := pass()
+		if err != nil {
+			return cli.NewExitError(err.Error(), util.ExitError)
+		}
+		return pushResult(remediated, drifted)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	fmt.Printf("Reconciling %s every %s\n", configFile, interval)
+	for {
+		// Failures are reported and retried on the next pass, rather
+		// than stopping reconciliation.
+		if _, _, err := pass(); err != nil {
+			fmt.Println(err)
+		}
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
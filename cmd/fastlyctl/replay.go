@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// stopRecording writes the session being recorded with --record, if any.
+func stopRecording() {
+	if err := util.StopRecording(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing recorded session: %s\n", err)
+	}
+}
+
+// requestURI returns the path and query of a recorded request.
+func requestURI(e util.Exchange) string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return e.URL
+	}
+	return u.RequestURI()
+}
+
+// replayHandler is a mock API which answers each request with the responses
+// recorded for it, in the order they were recorded. Once they run out, the
+// last is repeated.
+type replayHandler struct {
+	mu        sync.Mutex
+	responses map[string][]util.Exchange
+	served    map[string]int
+}
+
+func newReplayHandler(session *util.Session) *replayHandler {
+	h := &replayHandler{responses: make(map[string][]util.Exchange), served: make(map[string]int)}
+	for _, e := range session.Exchanges {
+		if e.Error == "" {
+			key := e.Method + " " + requestURI(e)
+			h.responses[key] = append(h.responses[key], e)
+		}
+	}
+	return h
+}
+
+func (h *replayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.RequestURI()
+	h.mu.Lock()
+	responses := h.responses[key]
+	i := h.served[key]
+	if i < len(responses)-1 {
+		h.served[key]++
+	}
+	h.mu.Unlock()
+	if len(responses) == 0 {
+		http.Error(w, `{"msg":"Not recorded"}`, http.StatusNotFound)
+		return
+	}
+	e := responses[i]
+	for name, values := range e.ResponseHeader {
+		w.Header()[name] = values
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(e.Status)
+	w.Write([]byte(e.ResponseBody))
+}
+
+// sameBody reports whether two response bodies are equal, comparing JSON by
+// value so that the order of keys doesn't matter.
+func sameBody(a, b string) bool {
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return a == b
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func replaySession(c *cli.Context) error {
+	file := c.Args().First()
+	session, err := util.ReadSession(file)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading session %s: %s", file, err), util.ExitError)
+	}
+	handler := newReplayHandler(session)
+	if addr := c.String("listen"); addr != "" {
+		fmt.Printf("Serving the %d responses recorded in %s on %s\n", len(session.Exchanges), file, addr)
+		if err := http.ListenAndServe(addr, handler); err != nil {
+			return cli.NewExitError(err.Error(), util.ExitError)
+		}
+		return nil
+	}
+
+	// newRequest builds the replayed request for a recorded one.
+	var newRequest func(uri string) (*http.Request, error)
+	if c.Bool("live") {
+		if err := util.CheckFastlyKey(c); err != nil {
+			return err
+		}
+		client := util.NewClient(c)
+		newRequest = func(uri string) (*http.Request, error) { return client.NewRequest("GET", uri, nil) }
+	} else {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		newRequest = func(uri string) (*http.Request, error) { return http.NewRequest("GET", srv.URL+uri, nil) }
+	}
+
+	fmt.Printf("Replaying %s, recorded %s by: %v\n\n", file, session.Recorded.Format("2006-01-02 15:04:05"), session.Args)
+	// earlier holds the first recorded response to each request, to spot
+	// Fastly answering the same request differently within the session.
+	earlier := make(map[string]int)
+	var replayed, differing int
+	for i, e := range session.Exchanges {
+		if e.Method != "GET" || e.Error != "" {
+			continue
+		}
+		uri := requestURI(e)
+		replayed++
+		req, err := newRequest(uri)
+		if err != nil {
+			return cli.NewExitError(err.Error(), util.ExitError)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error replaying %s: %s", uri, err), util.ExitError)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error replaying %s: %s", uri, err), util.ExitError)
+		}
+
+		var notes []string
+		if resp.StatusCode != e.Status {
+			notes = append(notes, fmt.Sprintf("status was %d when recorded", e.Status))
+		} else if !sameBody(string(body), e.ResponseBody) {
+			notes = append(notes, "response differs from the recording")
+		}
+		if first, ok := earlier[uri]; ok {
+			was := session.Exchanges[first]
+			if was.Status != e.Status || !sameBody(was.ResponseBody, e.ResponseBody) {
+				notes = append(notes, fmt.Sprintf("recorded differently from request %d", first+1))
+			}
+		} else {
+			earlier[uri] = i
+		}
+		line := fmt.Sprintf("%4d GET %s: %d", i+1, uri, resp.StatusCode)
+		if len(notes) > 0 {
+			differing++
+			line += " (" + strings.Join(notes, "; ") + ")"
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\nReplayed %d GET requests, %d with differing responses.\n", replayed, differing)
+	return nil
+}
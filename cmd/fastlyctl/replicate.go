@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/alienth/fastlyctl/log"
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// replicaCheckpoint records, for each replicated dictionary or ACL, the hash
+// of every source value as of the last successful pass. Comparing the
+// destination against it distinguishes changes made at the source from
+// changes made directly to the destination.
+type replicaCheckpoint map[string]map[string]string
+
+func readReplicaCheckpoint(file string) (replicaCheckpoint, error) {
+	checkpoint := make(replicaCheckpoint)
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &checkpoint); err != nil {
+		return nil, fmt.Errorf("Error parsing state file %s: %s", file, err)
+	}
+	return checkpoint, nil
+}
+
+func writeReplicaCheckpoint(file string, checkpoint replicaCheckpoint) error {
+	body, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0644)
+}
+
+// replicaPlan lists the changes needed to bring a destination in line with
+// its source.
+type replicaPlan struct {
+	Set       []string
+	Remove    []string
+	Conflicts []string
+}
+
+// planReplica compares source and destination values, keyed by item key or
+// ACL range. A key which differs from the checkpoint on the destination has
+// been changed there directly, and is a conflict. Conflicts are overwritten
+// from the source if preferSource is set, and otherwise left alone.
+func planReplica(src, dst, last map[string]string, preferSource bool) replicaPlan {
+	keys := make(map[string]bool)
+	for k := range src {
+		keys[k] = true
+	}
+	for k := range dst {
+		keys[k] = true
+	}
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var plan replicaPlan
+	for _, k := range sorted {
+		s, inSrc := src[k]
+		d, inDst := dst[k]
+		if inSrc == inDst && s == d {
+			continue
+		}
+		l, inLast := last[k]
+		if inDst != inLast || (inDst && fsync.ContentHash(d) != l) {
+			plan.Conflicts = append(plan.Conflicts, k)
+			if !preferSource {
+				continue
+			}
+		}
+		if inSrc {
+			plan.Set = append(plan.Set, k)
+		} else {
+			plan.Remove = append(plan.Remove, k)
+		}
+	}
+	return plan
+}
+
+func hashValues(values map[string]string) map[string]string {
+	hashes := make(map[string]string, len(values))
+	for k, v := range values {
+		hashes[k] = fsync.ContentHash(v)
+	}
+	return hashes
+}
+
+func replicateDictionary(src, dst *fastly.Client, srcService, dstService *fastly.Service, name string, last map[string]string, preferSource bool) (replicaPlan, map[string]string, error) {
+	var plan replicaPlan
+	srcDict, err := util.GetDictionaryByName(src, srcService.Name, name)
+	if err != nil {
+		return plan, nil, err
+	}
+	if srcDict.WriteOnly {
+		return plan, nil, util.WriteOnlyError(srcService.Name, srcDict)
+	}
+	dstDict, err := util.GetDictionaryByName(dst, dstService.Name, name)
+	if err != nil {
+		return plan, nil, err
+	}
+
+	srcValues := make(map[string]string)
+	items, _, err := src.DictionaryItem.List(srcDict.ServiceID, srcDict.ID, nil)
+	if err != nil {
+		return plan, nil, err
+	}
+	for _, item := range items {
+		srcValues[item.Key] = item.Value
+	}
+	dstValues := make(map[string]string)
+	items, _, err = dst.DictionaryItem.List(dstDict.ServiceID, dstDict.ID, nil)
+	if err != nil {
+		return plan, nil, err
+	}
+	for _, item := range items {
+		dstValues[item.Key] = item.Value
+	}
+
+	plan = planReplica(srcValues, dstValues, last, preferSource)
+	var ops []fastly.DictionaryItemUpdate
+	for _, k := range plan.Set {
+		ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpsert, Key: k, Value: srcValues[k]})
+	}
+	for _, k := range plan.Remove {
+		ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationDelete, Key: k})
+	}
+	if _, err := fsync.BatchUpdateDictionary(dst.DictionaryItem, dstDict.ServiceID, dstDict.ID, ops); err != nil {
+		return plan, nil, err
+	}
+	return plan, hashValues(srcValues), nil
+}
+
+func aclEntryValue(e *fastly.ACLEntry) string {
+	return fmt.Sprintf("%t %s", e.Negated.Bool(), e.Comment)
+}
+
+func replicateACL(src, dst *fastly.Client, srcService, dstService *fastly.Service, name string, last map[string]string, preferSource bool) (replicaPlan, map[string]string, error) {
+	var plan replicaPlan
+	srcACL, err := getACL(src, srcService.Name, name)
+	if err != nil {
+		return plan, nil, err
+	}
+	dstACL, err := getACL(dst, dstService.Name, name)
+	if err != nil {
+		return plan, nil, err
+	}
+
+	srcEntries := make(map[string]*fastly.ACLEntry)
+	srcValues := make(map[string]string)
+	entries, _, err := src.ACLEntry.List(srcACL.ServiceID, srcACL.ID, nil)
+	if err != nil {
+		return plan, nil, err
+	}
+	for _, e := range entries {
+		srcEntries[fsync.ACLEntryKey(e)] = e
+		srcValues[fsync.ACLEntryKey(e)] = aclEntryValue(e)
+	}
+	dstEntries := make(map[string]*fastly.ACLEntry)
+	dstValues := make(map[string]string)
+	entries, _, err = dst.ACLEntry.List(dstACL.ServiceID, dstACL.ID, nil)
+	if err != nil {
+		return plan, nil, err
+	}
+	for _, e := range entries {
+		dstEntries[fsync.ACLEntryKey(e)] = e
+		dstValues[fsync.ACLEntryKey(e)] = aclEntryValue(e)
+	}
+
+	plan = planReplica(srcValues, dstValues, last, preferSource)
+	var ops []fastly.ACLEntryUpdate
+	for _, k := range plan.Set {
+		e := srcEntries[k]
+		op := fastly.ACLEntryUpdate{Operation: fastly.BatchOperationCreate, IP: e.IP, Comment: e.Comment, Negated: e.Negated}
+		if e.Subnet != 0 {
+			op.Subnet = fmt.Sprint(e.Subnet)
+		}
+		if old, ok := dstEntries[k]; ok {
+			op.Operation = fastly.BatchOperationUpdate
+			op.ID = old.ID
+		}
+		ops = append(ops, op)
+	}
+	for _, k := range plan.Remove {
+		ops = append(ops, fastly.ACLEntryUpdate{Operation: fastly.BatchOperationDelete, ID: dstEntries[k].ID})
+	}
+	if _, err := fsync.BatchUpdateACL(dst.ACLEntry, dstACL.ServiceID, dstACL.ID, ops); err != nil {
+		return plan, nil, err
+	}
+	return plan, hashValues(srcValues), nil
+}
+
+// profileClient returns a client using the key for the named profile, or the
+// global key if no profile is given.
+func profileClient(c *cli.Context, profile string) (*fastly.Client, error) {
+	if profile == "" {
+		return util.NewClient(c), nil
+	}
+	key, err := util.ResolveKey("${profile:" + profile + "}")
+	if err != nil {
+		return nil, err
+	}
+	return util.ClientFactory(key), nil
+}
+
+func replicate(c *cli.Context) error {
+	srcServiceParam := c.Args().Get(0)
+	dstServiceParam := c.Args().Get(1)
+	if dstServiceParam == "" {
+		dstServiceParam = srcServiceParam
+	}
+	var preferSource bool
+	switch c.String("conflicts") {
+	case "source":
+		preferSource = true
+	case "destination":
+	default:
+		return cli.NewExitError(fmt.Sprintf("Invalid --conflicts value %q. Must be source or destination.", c.String("conflicts")), -1)
+	}
+	if len(c.StringSlice("dictionary")) == 0 && len(c.StringSlice("acl")) == 0 {
+		return cli.NewExitError("Please specify at least one --dictionary or --acl to replicate.", -1)
+	}
+
+	src, err := profileClient(c, c.String("from-profile"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error resolving source profile: %s", err), -1)
+	}
+	dst, err := profileClient(c, c.String("to-profile"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error resolving destination profile: %s", err), -1)
+	}
+
+	stateFile := c.String("state-file")
+	checkpoint, err := readReplicaCheckpoint(stateFile)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	pass := func() error {
+		srcService, err := util.GetServiceByNameOrID(src, srcServiceParam)
+		if err != nil {
+			return fmt.Errorf("Error fetching source service %s: %s", srcServiceParam, err)
+		}
+		dstService, err := util.GetServiceByNameOrID(dst, dstServiceParam)
+		if err != nil {
+			return fmt.Errorf("Error fetching destination service %s: %s", dstServiceParam, err)
+		}
+
+		type target struct {
+			kind, name string
+			sync       func(src, dst *fastly.Client, srcService, dstService *fastly.Service, name string, last map[string]string, preferSource bool) (replicaPlan, map[string]string, error)
+		}
+		var targets []target
+		for _, name := range c.StringSlice("dictionary") {
+			targets = append(targets, target{"dictionary", name, replicateDictionary})
+		}
+		for _, name := range c.StringSlice("acl") {
+			targets = append(targets, target{"acl", name, replicateACL})
+		}
+
+		var failed bool
+		for _, t := range targets {
+			id := fmt.Sprintf("%s/%s/%s", dstService.ID, t.kind, t.name)
+			plan, hashes, err := t.sync(src, dst, srcService, dstService, t.name, checkpoint[id], preferSource)
+			if err != nil {
+				fmt.Printf("%s Error replicating %s %s: %s\n", time.Now().Format(time.RFC3339), t.kind, t.name, err)
+				util.CountError("replicate")
+				failed = true
+				continue
+			}
+			for _, k := range plan.Conflicts {
+				if preferSource {
+					log.Debug(fmt.Sprintf("%s %s: %s was changed at the destination. Overwriting from source.\n", t.kind, t.name, k))
+				} else {
+					log.Debug(fmt.Sprintf("%s %s: %s was changed at the destination. Leaving it in place.\n", t.kind, t.name, k))
+				}
+			}
+			if len(plan.Set)+len(plan.Remove)+len(plan.Conflicts) > 0 {
+				fmt.Printf("%s Replicated %s %s to %s: %d set, %d removed, %d conflicts\n", time.Now().Format(time.RFC3339), t.kind, t.name, dstService.Name, len(plan.Set), len(plan.Remove), len(plan.Conflicts))
+			}
+			util.CountChangesApplied(dstService.Name, len(plan.Set)+len(plan.Remove))
+			checkpoint[id] = hashes
+			if err = writeReplicaCheckpoint(stateFile, checkpoint); err != nil {
+				return fmt.Errorf("Error writing state file %s: %s", stateFile, err)
+			}
+		}
+		if failed {
+			return fmt.Errorf("Replication of %s failed.", srcServiceParam)
+		}
+		return nil
+	}
+
+	if c.Bool("once") {
+		if err := pass(); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		return cli.NewExitError("--interval must be positive.", -1)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	fmt.Printf("Replicating %s to %s every %s\n", srcServiceParam, dstServiceParam, interval)
+	for {
+		// Failures are reported and retried on the next pass, rather
+		// than stopping replication.
+		if err := pass(); err != nil {
+			fmt.Println(err)
+		}
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
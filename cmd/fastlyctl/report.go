@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// inventoryRow describes the active version of one service.
+type inventoryRow struct {
+	ServiceID     string `json:"service_id"`
+	Service       string `json:"service"`
+	ActiveVersion uint   `json:"active_version"`
+	Updated       string `json:"active_version_updated"`
+	// AgeDays is the number of days since the active version was last
+	// updated, or -1 if that isn't known.
+	AgeDays  int      `json:"active_version_age_days"`
+	Domains  []string `json:"domains"`
+	Origins  []string `json:"origins"`
+	Shields  []string `json:"shields"`
+	TLS      []string `json:"tls"`
+	ForceTLS bool     `json:"force_tls"`
+	Logging  []string `json:"logging"`
+}
+
+// inventoryColumns are the CSV columns, matching the JSON names of
+// inventoryRow.
+var inventoryColumns = []string{"service_id", "service", "active_version", "active_version_updated", "active_version_age_days", "domains", "origins", "shields", "tls", "force_tls", "logging"}
+
+func (r inventoryRow) record() []string {
+	return []string{
+		r.ServiceID,
+		r.Service,
+		strconv.FormatUint(uint64(r.ActiveVersion), 10),
+		r.Updated,
+		strconv.Itoa(r.AgeDays),
+		strings.Join(r.Domains, " "),
+		strings.Join(r.Origins, " "),
+		strings.Join(r.Shields, " "),
+		strings.Join(r.TLS, " "),
+		strconv.FormatBool(r.ForceTLS),
+		strings.Join(r.Logging, " "),
+	}
+}
+
+// backendTLS describes the TLS settings of a backend, such as
+// "b1=tls:1.2-1.3" or "b2=plain". Backends which don't check the
+// certificate of their origin are marked with ":nocheck".
+func backendTLS(b *fastly.Backend) string {
+	if !b.UseSSL {
+		return b.Name + "=plain"
+	}
+	s := b.Name + "=tls"
+	if b.MinTLSVersion != "" || b.MaxTLSVersion != "" {
+		s += ":" + b.MinTLSVersion + "-" + b.MaxTLSVersion
+	}
+	if !b.SSLCheckCert {
+		s += ":nocheck"
+	}
+	return s
+}
+
+// serviceInventory describes the active version of s. Services without an
+// active version are described by their name alone.
+func serviceInventory(client *fastly.Client, s *fastly.Service, now time.Time) (inventoryRow, error) {
+	row := inventoryRow{ServiceID: s.ID, Service: s.Name, AgeDays: -1}
+	version, err := util.GetActiveVersion(s)
+	if err != nil {
+		return row, nil
+	}
+	row.ActiveVersion = version
+	for _, v := range s.Versions {
+		if v.Number != version {
+			continue
+		}
+		row.Updated = v.Updated
+		if updated, err := time.Parse(time.RFC3339, v.Updated); err == nil {
+			row.AgeDays = int(now.Sub(updated).Hours() / 24)
+		}
+	}
+
+	domains, _, err := client.Domain.List(s.ID, version)
+	if err != nil {
+		return row, fmt.Errorf("Error listing domains: %s", err)
+	}
+	for _, d := range domains {
+		row.Domains = append(row.Domains, d.Name)
+	}
+
+	shields := make(map[string]bool)
+	backends, _, err := client.Backend.List(s.ID, version)
+	if err != nil {
+		return row, fmt.Errorf("Error listing backends: %s", err)
+	}
+	for _, b := range backends {
+		address := b.Address
+		if b.Port != 0 {
+			address = fmt.Sprintf("%s:%d", address, b.Port)
+		}
+		row.Origins = append(row.Origins, b.Name+"="+address)
+		row.TLS = append(row.TLS, backendTLS(b))
+		if b.Shield != "" {
+			shields[b.Shield] = true
+		}
+	}
+	directors, _, err := client.Director.List(s.ID, version)
+	if err != nil {
+		return row, fmt.Errorf("Error listing directors: %s", err)
+	}
+	for _, d := range directors {
+		if d.Shield != "" {
+			shields[d.Shield] = true
+		}
+	}
+	for shield := range shields {
+		row.Shields = append(row.Shields, shield)
+	}
+	sort.Strings(row.Shields)
+
+	settings, _, err := client.RequestSetting.List(s.ID, version)
+	if err != nil {
+		return row, fmt.Errorf("Error listing request settings: %s", err)
+	}
+	for _, r := range settings {
+		if r.ForceSSL.Bool() {
+			row.ForceTLS = true
+		}
+	}
+
+	s3s, _, err := client.S3.List(s.ID, version)
+	if err != nil {
+		return row, fmt.Errorf("Error listing S3 endpoints: %s", err)
+	}
+	for _, l := range s3s {
+		row.Logging = append(row.Logging, "s3="+l.Name)
+	}
+	syslogs, _, err := client.Syslog.List(s.ID, version)
+	if err != nil {
+		return row, fmt.Errorf("Error listing syslog endpoints: %s", err)
+	}
+	for _, l := range syslogs {
+		row.Logging = append(row.Logging, "syslog="+l.Name)
+	}
+	return row, nil
+}
+
+func reportInventory(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	out := c.String("out")
+	format := c.String("format")
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(out), ".")
+		if out == "-" || format == "" {
+			format = "csv"
+		}
+	}
+	if format != "csv" && format != "json" {
+		return cli.NewExitError(fmt.Sprintf("Invalid --format %q. Must be csv or json.", format), -1)
+	}
+
+	services, _, err := client.Service.List(nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error listing services: %s", err), -1)
+	}
+	now := time.Now().UTC()
+	rows := make([]inventoryRow, 0, len(services))
+	for _, s := range services {
+		row, err := serviceInventory(client, s, now)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error reading service %s: %s", s.Name, err), -1)
+		}
+		rows = append(rows, row)
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error creating %s: %s", out, err), -1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if format == "json" {
+		body, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		if _, err = w.Write(append(body, '\n')); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	} else {
+		cw := csv.NewWriter(w)
+		cw.Write(inventoryColumns)
+		for _, r := range rows {
+			cw.Write(r.record())
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+	if out != "-" {
+		fmt.Printf("Wrote the inventory of %d services to %s\n", len(rows), out)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+func requestSettingList(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	settings, _, err := client.RequestSetting.List(service.ID, activeVersion)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to list request settings for service %s\n", service.Name), -1)
+	}
+	fmt.Printf("Request settings for %s:\n\n", service.Name)
+	for _, r := range settings {
+		fmt.Println(r.Name)
+	}
+	return nil
+}
+
+func requestSettingShow(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	r, _, err := client.RequestSetting.Get(service.ID, activeVersion, nameParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching request setting %s: %s", nameParam, err), -1)
+	}
+
+	fmt.Printf("Request setting %s on service %s:\n\n", r.Name, service.Name)
+	fmt.Printf("%-19s %s\n", "Action:", r.Action)
+	fmt.Printf("%-19s %s\n", "Request condition:", r.RequestCondition)
+	fmt.Printf("%-19s %s\n", "Default host:", r.DefaultHost)
+	fmt.Printf("%-19s %s\n", "Hash keys:", r.HashKeys)
+	fmt.Printf("%-19s %s\n", "XFF:", r.XFF)
+	fmt.Printf("%-19s %d\n", "Max stale age:", r.MaxStaleAge)
+	fmt.Printf("%-19s %t\n", "Force miss:", r.ForceMiss.Bool())
+	fmt.Printf("%-19s %t\n", "Force SSL:", r.ForceSSL.Bool())
+	fmt.Printf("%-19s %t\n", "Bypass busy wait:", r.BypassBusyWait.Bool())
+	fmt.Printf("%-19s %t\n", "Geo headers:", r.GeoHeaders.Bool())
+	fmt.Printf("%-19s %t\n", "Timer support:", r.TimerSupport.Bool())
+	return nil
+}
+
+func requestSettingAdd(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	switch c.String("action") {
+	case "", "lookup", "pass":
+	default:
+		return cli.NewExitError(fmt.Sprintf("Invalid --action %q. Must be lookup or pass.", c.String("action")), -1)
+	}
+	switch c.String("xff") {
+	case "", "clear", "leave", "append", "append_all", "overwrite":
+	default:
+		return cli.NewExitError(fmt.Sprintf("Invalid --xff %q. Must be one of clear, leave, append, append_all, or overwrite.", c.String("xff")), -1)
+	}
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		r := &fastly.RequestSetting{
+			Name:             nameParam,
+			Action:           c.String("action"),
+			RequestCondition: c.String("request-condition"),
+			DefaultHost:      c.String("default-host"),
+			HashKeys:         c.String("hash-keys"),
+			XFF:              c.String("xff"),
+			MaxStaleAge:      c.Int("max-stale-age"),
+			ForceMiss:        fastly.NewCompatibool(c.Bool("force-miss")),
+			ForceSSL:         fastly.NewCompatibool(c.Bool("force-ssl")),
+			BypassBusyWait:   fastly.NewCompatibool(c.Bool("bypass-busy-wait")),
+			GeoHeaders:       fastly.NewCompatibool(c.Bool("geo-headers")),
+			TimerSupport:     fastly.NewCompatibool(c.Bool("timer-support")),
+		}
+		if _, _, err := client.RequestSetting.Create(service.ID, version, r); err != nil {
+			return fmt.Errorf("Error creating request setting %s: %s", nameParam, err)
+		}
+		fmt.Printf("Created request setting %s in version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "request setting", util.ChangeAdded, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
+
+func requestSettingRemove(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, err := client.RequestSetting.Delete(service.ID, version, nameParam); err != nil {
+			return fmt.Errorf("Error deleting request setting %s: %s", nameParam, err)
+		}
+		fmt.Printf("Deleted request setting %s from version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "request setting", util.ChangeRemoved, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
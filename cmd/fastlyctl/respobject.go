@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"path/filepath"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+func responseObjectList(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	objects, _, err := client.ResponseObject.List(service.ID, activeVersion)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to list response objects for service %s\n", service.Name), -1)
+	}
+	fmt.Printf("Response objects for %s:\n\n", service.Name)
+	for _, r := range objects {
+		fmt.Println(r.Name)
+	}
+	return nil
+}
+
+func responseObjectShow(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	activeVersion, err := util.GetActiveVersion(service)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	r, _, err := client.ResponseObject.Get(service.ID, activeVersion, nameParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching response object %s: %s", nameParam, err), -1)
+	}
+
+	fmt.Printf("Response object %s on service %s:\n\n", r.Name, service.Name)
+	fmt.Printf("%-19s %s %s\n", "Status:", r.Status, r.Response)
+	fmt.Printf("%-19s %s\n", "Content type:", r.ContentType)
+	fmt.Printf("%-19s %s\n", "Request condition:", r.RequestCondition)
+	fmt.Printf("%-19s %s\n", "Cache condition:", r.CacheCondition)
+	fmt.Printf("Content:\n%s\n", r.Content)
+	return nil
+}
+
+func responseObjectAdd(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	content := c.String("content")
+	contentType := c.String("content-type")
+	if file := c.String("content-file"); file != "" {
+		if content != "" {
+			return cli.NewExitError("Cannot specify both --content and --content-file.", -1)
+		}
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error reading %s: %s", file, err), -1)
+		}
+		content = string(body)
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(file))
+		}
+	}
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		r := &fastly.ResponseObject{
+			Name:             nameParam,
+			Status:           c.String("status"),
+			Response:         c.String("response"),
+			Content:          content,
+			ContentType:      contentType,
+			RequestCondition: c.String("request-condition"),
+			CacheCondition:   c.String("cache-condition"),
+		}
+		if _, _, err := client.ResponseObject.Create(service.ID, version, r); err != nil {
+			return fmt.Errorf("Error creating response object %s: %s", nameParam, err)
+		}
+		fmt.Printf("Created response object %s in version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "response object", util.ChangeAdded, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
+
+func responseObjectRemove(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	serviceParam := c.Args().Get(0)
+	nameParam := c.Args().Get(1)
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	err = editDraftVersion(c, client, service, func(syncer *fsync.Syncer, version uint) error {
+		if _, err := client.ResponseObject.Delete(service.ID, version, nameParam); err != nil {
+			return fmt.Errorf("Error deleting response object %s: %s", nameParam, err)
+		}
+		fmt.Printf("Deleted response object %s from version %d of service %s\n", nameParam, version, service.Name)
+		syncer.RecordChange(service, "response object", util.ChangeRemoved, nameParam)
+		return nil
+	})
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	return nil
+}
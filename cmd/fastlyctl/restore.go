@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+func restoreService(c *cli.Context) error {
+	client := util.NewClient(c)
+	serviceParam := c.Args().Get(0)
+
+	snapshot, err := readSnapshot(c.String("from"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading snapshot from %s: %s", c.String("from"), err), -1)
+	}
+
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching service %s: %s. The service must be created in Fastly before it can be restored.", serviceParam, err), -1)
+	}
+
+	fmt.Printf("Restoring %s from snapshot of %s version %d\n", service.Name, snapshot.Service.Name, snapshot.Version)
+	if err = restoreSnapshot(c, client, service, snapshot, c.Bool("contents")); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error restoring service %s: %s", service.Name, err), -1)
+	}
+	return nil
+}
+
+// restoreSnapshot syncs the configuration recorded in a snapshot into a draft
+// version of the service, optionally restoring dictionary and ACL contents,
+// and then offers the draft for activation.
+func restoreSnapshot(c *cli.Context, client *fastly.Client, s *fastly.Service, snapshot *fsync.Snapshot, contents bool) error {
+	activeVersion, err := util.GetActiveVersion(s)
+	if err != nil {
+		return err
+	}
+	if contents {
+		if err = checkSnapshotContents(snapshot, c.Int("max-dictionary-items")); err != nil {
+			return err
+		}
+	}
+
+	guard := deletionGuard(c)
+	syncer := newSyncer(client, map[string]fsync.SiteConfig{s.Name: snapshot.SiteConfig()})
+	version, err := syncer.Apply(s)
+	if err != nil {
+		return err
+	}
+	if err = guard.CheckChanges(s.Name, syncer.Changes(s)); err != nil {
+		return err
+	}
+
+	if contents {
+		contentsVersion := activeVersion
+		if version != nil {
+			contentsVersion = version.Number
+		}
+		if err = restoreContents(client, s, contentsVersion, snapshot, guard); err != nil {
+			return err
+		}
+	}
+	if version == nil {
+		return nil
+	}
+
+	if err = util.ValidateVersion(client, s, version.Number); err != nil {
+		return err
+	}
+	if _, err = util.ActivateVersion(c, client, s, version, syncer.Changes(s)); err != nil {
+		return fmt.Errorf("Error activating pending version %d: %s", version.Number, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/alienth/go-fastly"
+)
+
+// pushFailure records a service which failed to sync or validate during a
+// push, along with the draft version left behind, if any.
+type pushFailure struct {
+	ServiceID string
+	Version   uint `json:",omitempty"`
+	Error     string
+}
+
+// readResumeState returns the failures stashed by previous pushes, keyed by
+// service name.
+func readResumeState(file string) (map[string]pushFailure, error) {
+	failures := make(map[string]pushFailure)
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return failures, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &failures); err != nil {
+		return nil, fmt.Errorf("Error parsing resume file %s: %s", file, err)
+	}
+	return failures, nil
+}
+
+// writeResumeState stashes failures for a later push --resume, removing the
+// file once there are none left.
+func writeResumeState(file string, failures map[string]pushFailure) error {
+	if len(failures) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	body, err := json.MarshalIndent(failures, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0644)
+}
+
+// resumeVersion looks up the draft version stashed for a failed service, so
+// that the push can continue where it left off. Versions which have since
+// been locked or activated are not reused.
+func resumeVersion(client *fastly.Client, failure pushFailure) (*fastly.Version, bool) {
+	if failure.Version == 0 {
+		return nil, false
+	}
+	version, _, err := client.Version.Get(failure.ServiceID, failure.Version)
+	if err != nil || version.Locked || version.Active {
+		return nil, false
+	}
+	return version, true
+}
+
+// failureSummary describes the services which failed during a push.
+func failureSummary(failures map[string]pushFailure, file string) string {
+	var names []string
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	summary := fmt.Sprintf("Failed services (%d):\n", len(names))
+	for _, name := range names {
+		f := failures[name]
+		if f.Version != 0 {
+			summary += fmt.Sprintf("  %s (version %d): %s\n", name, f.Version, f.Error)
+		} else {
+			summary += fmt.Sprintf("  %s: %s\n", name, f.Error)
+		}
+	}
+	return summary + fmt.Sprintf("Retry them with 'push --resume', which reads %s.", file)
+}
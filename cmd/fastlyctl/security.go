@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// Severities of security findings, most severe first.
+var securitySeverities = []string{"high", "medium", "low"}
+
+// securityFinding is one weakness in the active version of a service.
+type securityFinding struct {
+	service, severity, finding string
+}
+
+// wafMarkers are the resources Fastly adds to a service when a WAF is
+// deployed to it, as the API has no other way of telling. These are the
+// Edge_Security dictionary of the Next-Gen WAF's edge deployment and the
+// WAF_Response response object of the legacy WAF.
+var wafMarkers = struct {
+	dictionary, responseObject string
+}{"Edge_Security", "WAF_Response"}
+
+// wildcardPrefix is the shortest prefix, by address family, of an ACL entry
+// which isn't reported as a wildcard.
+var wildcardPrefix = map[int]int{32: 9, 128: 17}
+
+// minOriginTLS is the oldest TLS version origins should be reached with.
+const minOriginTLS = 1.2
+
+// securityFindings checks the active version of s. Services without an
+// active version have nothing to check.
+func securityFindings(client *fastly.Client, s *fastly.Service) ([]securityFinding, error) {
+	version, err := util.GetActiveVersion(s)
+	if err != nil {
+		return nil, nil
+	}
+	var out []securityFinding
+	add := func(severity, format string, args ...interface{}) {
+		out = append(out, securityFinding{s.Name, severity, fmt.Sprintf(format, args...)})
+	}
+
+	settings, _, err := client.RequestSetting.List(s.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing request settings: %s", err)
+	}
+	var forced bool
+	for _, r := range settings {
+		if r.ForceSSL.Bool() {
+			forced = true
+		} else {
+			add("medium", "request setting %s doesn't force TLS", r.Name)
+		}
+	}
+	if !forced {
+		add("high", "no request setting forces TLS, so clients may connect over plain HTTP")
+	}
+
+	backends, _, err := client.Backend.List(s.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing backends: %s", err)
+	}
+	for _, b := range backends {
+		switch {
+		case !b.UseSSL:
+			add("high", "backend %s is reached without TLS", b.Name)
+		case b.MinTLSVersion == "":
+			add("medium", "backend %s sets no minimum TLS version, allowing TLS older than %.1f", b.Name, minOriginTLS)
+		default:
+			if v, err := strconv.ParseFloat(b.MinTLSVersion, 64); err == nil && v < minOriginTLS {
+				add("medium", "backend %s allows TLS %s", b.Name, b.MinTLSVersion)
+			}
+		}
+		if b.UseSSL && !b.SSLCheckCert {
+			add("medium", "backend %s doesn't check the certificate of its origin", b.Name)
+		}
+	}
+
+	headers, _, err := client.Header.List(s.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing headers: %s", err)
+	}
+	var hsts bool
+	for _, h := range headers {
+		if h.Type == fastly.HeaderTypeResponse && h.Action == fastly.HeaderActionSet && strings.EqualFold(h.Destination, "http.Strict-Transport-Security") {
+			hsts = true
+		}
+	}
+	if !hsts {
+		add("medium", "no header sets Strict-Transport-Security on responses")
+	}
+
+	acls, _, err := client.ACL.List(s.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing ACLs: %s", err)
+	}
+	for _, acl := range acls {
+		entries, _, err := client.ACLEntry.List(s.ID, acl.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing entries of ACL %s: %s", acl.Name, err)
+		}
+		for _, e := range entries {
+			if e.Negated.Bool() {
+				continue
+			}
+			n, err := fsync.EntryNet(e)
+			if err != nil {
+				continue
+			}
+			ones, bits := n.Mask.Size()
+			if ones < wildcardPrefix[bits] {
+				add("high", "ACL %s has the wildcard entry %s", acl.Name, n)
+			}
+		}
+	}
+
+	var waf bool
+	dictionaries, _, err := client.Dictionary.List(s.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing dictionaries: %s", err)
+	}
+	for _, d := range dictionaries {
+		if d.Name == wafMarkers.dictionary {
+			waf = true
+		}
+	}
+	responseObjects, _, err := client.ResponseObject.List(s.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing response objects: %s", err)
+	}
+	for _, r := range responseObjects {
+		if r.Name == wafMarkers.responseObject {
+			waf = true
+		}
+	}
+	if !waf {
+		add("low", "no WAF is deployed")
+	}
+	return out, nil
+}
+
+func reportSecurity(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	services, _, err := client.Service.List(nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error listing services: %s", err), -1)
+	}
+	var findings []securityFinding
+	for _, s := range services {
+		f, err := securityFindings(client, s)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error reading service %s: %s", s.Name, err), -1)
+		}
+		findings = append(findings, f...)
+	}
+	rank := func(severity string) int {
+		for i, s := range securitySeverities {
+			if s == severity {
+				return i
+			}
+		}
+		return len(securitySeverities)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].severity != findings[j].severity {
+			return rank(findings[i].severity) < rank(findings[j].severity)
+		}
+		return findings[i].service < findings[j].service
+	})
+
+	if len(findings) == 0 {
+		fmt.Printf("No findings in %d services.\n", len(services))
+		return nil
+	}
+	fmt.Printf("%-30s %-8s %s\n", "Service", "Severity", "Finding")
+	counts := make(map[string]int)
+	for _, f := range findings {
+		fmt.Printf("%-30s %-8s %s\n", f.service, f.severity, f.finding)
+		counts[f.severity]++
+	}
+	var summary []string
+	for _, severity := range securitySeverities {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[severity], severity))
+	}
+	fmt.Printf("\n%d findings in %d services: %s.\n", len(findings), len(services), strings.Join(summary, ", "))
+	return nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	versionInfo "github.com/alienth/fastlyctl/_version"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// releasesURL is the GitHub API endpoint listing fastlyctl releases.
+var releasesURL = "https://api.github.com/repos/alienth/fastlyctl/releases"
+
+const (
+	// releaseChecksums is the release asset listing the SHA-256 of each
+	// archive, as written by goreleaser.
+	releaseChecksums = "checksums.txt"
+	// releaseSignature is the Ed25519 signature of releaseChecksums.
+	releaseSignature = "checksums.txt.sig"
+	// releaseAssetLimit bounds the size of a downloaded release asset.
+	releaseAssetLimit = 100 << 20
+)
+
+var releaseClient = &http.Client{Timeout: 5 * time.Minute}
+
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func (r *release) version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+func (r *release) asset(name string) (releaseAsset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// archiveName returns the name goreleaser gives the archive of a version for
+// the running platform.
+func archiveName(version string) string {
+	ext := "tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("fastlyctl_%s_%s_%s.%s", version, runtime.GOOS, runtime.GOARCH, ext)
+}
+
+func fetchRelease(url string, v interface{}) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, err := releaseClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching %s returned %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, releaseAssetLimit))
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", url, err)
+		}
+	}
+	return body, nil
+}
+
+// compareVersions compares dotted version numbers such as 1.10.2, returning
+// -1, 0 or 1. Non-numeric parts compare as zero.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// verifyArchive checks archive against its SHA-256 in checksums, and, if a
+// release key was built in, checks that checksums was signed by it.
+func verifyArchive(name string, archive, checksums, signature []byte) error {
+	if versionInfo.ReleaseKey != "" {
+		key, err := base64.StdEncoding.DecodeString(versionInfo.ReleaseKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("The release key built into this binary is invalid.")
+		}
+		if signature == nil {
+			return fmt.Errorf("The release has no %s, so its checksums can't be verified.", releaseSignature)
+		}
+		if !ed25519.Verify(ed25519.PublicKey(key), checksums, signature) {
+			return fmt.Errorf("The signature of %s does not match the release key.", releaseChecksums)
+		}
+	}
+	sum := sha256.Sum256(archive)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			if fields[0] != hex.EncodeToString(sum[:]) {
+				return fmt.Errorf("The checksum of %s does not match %s.", name, releaseChecksums)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no checksum for %s.", releaseChecksums, name)
+}
+
+// extractBinary returns the fastlyctl binary within a release archive.
+func extractBinary(name string, archive []byte) ([]byte, error) {
+	binary := "fastlyctl"
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	if strings.HasSuffix(name, ".zip") {
+		r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range r.File {
+			if filepath.Base(f.Name) != binary {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return ioutil.ReadAll(io.LimitReader(rc, releaseAssetLimit))
+		}
+	} else {
+		gz, err := gzip.NewReader(bytes.NewReader(archive))
+		if err != nil {
+			return nil, err
+		}
+		r := tar.NewReader(gz)
+		for {
+			h, err := r.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if h.Typeflag == tar.TypeReg && filepath.Base(h.Name) == binary {
+				return ioutil.ReadAll(io.LimitReader(r, releaseAssetLimit))
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s does not contain %s.", name, binary)
+}
+
+// replaceExecutable swaps the running binary for binary. The new binary is
+// written alongside the old one and renamed into place, so that a failed
+// update leaves the old one intact. Windows won't replace a running
+// executable, so there it is first moved aside.
+func replaceExecutable(binary []byte) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(exe), ".fastlyctl-update-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return "", err
+		}
+		if err := os.Rename(tmp.Name(), exe); err != nil {
+			os.Rename(old, exe)
+			return "", err
+		}
+		return exe, nil
+	}
+	return exe, os.Rename(tmp.Name(), exe)
+}
+
+func selfUpdate(c *cli.Context) error {
+	url := releasesURL + "/latest"
+	if tag := c.String("version"); tag != "" {
+		if !strings.HasPrefix(tag, "v") {
+			tag = "v" + tag
+		}
+		url = releasesURL + "/tags/" + tag
+	}
+	var r release
+	if _, err := fetchRelease(url, &r); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching release: %s", err), -1)
+	}
+
+	current := versionInfo.Version
+	if c.String("version") == "" && !c.Bool("force") && compareVersions(r.version(), current) <= 0 {
+		fmt.Printf("fastlyctl %s is up to date.\n", versionInfo.FullVersion())
+		return nil
+	}
+	if c.Bool("check") {
+		fmt.Printf("fastlyctl %s is available. Running %s.\n", r.version(), versionInfo.FullVersion())
+		return nil
+	}
+	if !c.GlobalBool("assume-yes") {
+		if err := checkInteractive(c); err != nil {
+			return err
+		}
+		proceed, err := util.Prompt(fmt.Sprintf("Update fastlyctl from %s to %s?", versionInfo.FullVersion(), r.version()))
+		if err != nil || !proceed {
+			return err
+		}
+	}
+
+	name := archiveName(r.version())
+	asset, ok := r.asset(name)
+	if !ok {
+		return cli.NewExitError(fmt.Sprintf("Release %s has no archive for %s/%s.", r.TagName, runtime.GOOS, runtime.GOARCH), -1)
+	}
+	checksumAsset, ok := r.asset(releaseChecksums)
+	if !ok {
+		return cli.NewExitError(fmt.Sprintf("Release %s has no %s, so it can't be verified.", r.TagName, releaseChecksums), -1)
+	}
+	checksums, err := fetchRelease(checksumAsset.URL, nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching %s: %s", releaseChecksums, err), -1)
+	}
+	var signature []byte
+	if sigAsset, ok := r.asset(releaseSignature); ok {
+		if signature, err = fetchRelease(sigAsset.URL, nil); err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error fetching %s: %s", releaseSignature, err), -1)
+		}
+	}
+	archive, err := fetchRelease(asset.URL, nil)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching %s: %s", name, err), -1)
+	}
+	if err := verifyArchive(name, archive, checksums, signature); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if versionInfo.ReleaseKey == "" {
+		fmt.Println("Warning: this build has no release key, so only the checksum of the release was verified.")
+	}
+	binary, err := extractBinary(name, archive)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error extracting %s: %s", name, err), -1)
+	}
+	exe, err := replaceExecutable(binary)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error replacing the fastlyctl binary: %s", err), -1)
+	}
+	fmt.Printf("Updated %s to fastlyctl %s\n", exe, r.version())
+	return nil
+}
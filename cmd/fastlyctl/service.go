@@ -3,14 +3,15 @@ package main
 import (
 	"fmt"
 
-	"github.com/alienth/go-fastly"
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
 	"github.com/urfave/cli"
 )
 
 func serviceList(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 
-	services, _, err := client.Service.List()
+	services, _, err := client.Service.List(nil)
 	if err != nil {
 		return cli.NewExitError(fmt.Sprintf("Error listing services: %s", err), -1)
 	}
@@ -21,3 +22,40 @@ func serviceList(c *cli.Context) error {
 
 	return nil
 }
+
+func serviceClone(c *cli.Context) error {
+	srcClient := util.NewClient(c)
+	dstClient := srcClient
+	if key := c.String("dst-key"); key != "" {
+		dstClient = util.ClientFactory(key)
+	}
+	srcParam := c.Args().Get(0)
+	dstParam := c.Args().Get(1)
+
+	src, err := util.GetServiceByNameOrID(srcClient, srcParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching source service %s: %s", srcParam, err), -1)
+	}
+	dst, err := util.GetServiceByNameOrID(dstClient, dstParam)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching destination service %s: %s. The service must be created in Fastly before it can be cloned into.", dstParam, err), -1)
+	}
+	if src.ID == dst.ID {
+		return cli.NewExitError("Source and destination services must differ.", -1)
+	}
+
+	version, err := util.GetActiveVersion(src)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	snapshot, err := fsync.FetchSnapshot(srcClient, src, version)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading version %d of service %s: %s", version, src.Name, err), -1)
+	}
+
+	fmt.Printf("Cloning version %d of %s into %s\n", version, src.Name, dst.Name)
+	if err = restoreSnapshot(c, dstClient, dst, snapshot, c.Bool("contents")); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error cloning into service %s: %s", dst.Name, err), -1)
+	}
+	return nil
+}
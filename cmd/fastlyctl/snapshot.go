@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/go-fastly"
+)
+
+const (
+	snapshotDictionaryItemsDir = "dictionary_items"
+	snapshotACLEntriesDir      = "acl_entries"
+)
+
+// snapshotFiles returns the name of each per-resource file within a snapshot
+// directory, along with the field it is stored from or loaded into. On disk, a
+// snapshot is a directory holding one JSON file per resource type, plus a file
+// per dictionary and ACL listing its contents.
+func snapshotFiles(s *fsync.Snapshot) map[string]interface{} {
+	return map[string]interface{}{
+		"service.json":          &s.Service,
+		"settings.json":         &s.Settings,
+		"domains.json":          &s.Domains,
+		"backends.json":         &s.Backends,
+		"directors.json":        &s.Directors,
+		"conditions.json":       &s.Conditions,
+		"cache_settings.json":   &s.CacheSettings,
+		"headers.json":          &s.Headers,
+		"s3s.json":              &s.S3s,
+		"syslogs.json":          &s.Syslogs,
+		"gzips.json":            &s.Gzips,
+		"healthchecks.json":     &s.HealthChecks,
+		"dictionaries.json":     &s.Dictionaries,
+		"acls.json":             &s.ACLs,
+		"vcls.json":             &s.VCLs,
+		"request_settings.json": &s.RequestSettings,
+		"response_objects.json": &s.ResponseObjects,
+	}
+}
+
+// readSnapshot loads the snapshot stored in dir.
+func readSnapshot(dir string) (*fsync.Snapshot, error) {
+	s := new(fsync.Snapshot)
+	for name, v := range snapshotFiles(s) {
+		body, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) && name != "service.json" {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, v); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", filepath.Join(dir, name), err)
+		}
+	}
+	s.Version = s.Service.Version
+
+	s.DictionaryItems = make(map[string][]*fastly.DictionaryItem)
+	for _, d := range s.Dictionaries {
+		var items []*fastly.DictionaryItem
+		if err := readSnapshotContents(filepath.Join(dir, snapshotDictionaryItemsDir, d.Name+".json"), &items); err != nil {
+			return nil, err
+		}
+		s.DictionaryItems[d.Name] = items
+	}
+	s.ACLEntries = make(map[string][]*fastly.ACLEntry)
+	for _, a := range s.ACLs {
+		var entries []*fastly.ACLEntry
+		if err := readSnapshotContents(filepath.Join(dir, snapshotACLEntriesDir, a.Name+".json"), &entries); err != nil {
+			return nil, err
+		}
+		s.ACLEntries[a.Name] = entries
+	}
+
+	return s, nil
+}
+
+// readSnapshotContents reads a dictionary or ACL contents file. Missing files
+// are not an error, as write-only dictionaries cannot be exported.
+func readSnapshotContents(file string, v interface{}) error {
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("Error parsing %s: %s", file, err)
+	}
+	return nil
+}
+
+// snapshotDir returns the directory holding the snapshot for the named
+// service within a directory of snapshots.
+func snapshotDir(root, serviceName string) string {
+	return filepath.Join(root, strings.Replace(serviceName, string(filepath.Separator), "_", -1))
+}
+
+// writeSnapshot stores the snapshot in dir, creating it if necessary.
+func writeSnapshot(dir string, s *fsync.Snapshot) error {
+	for _, sub := range []string{dir, filepath.Join(dir, snapshotDictionaryItemsDir), filepath.Join(dir, snapshotACLEntriesDir)} {
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			return err
+		}
+	}
+
+	s.Service.Version = s.Version
+	s.Service.Versions = nil
+	for name, v := range snapshotFiles(s) {
+		if err := writeSnapshotFile(filepath.Join(dir, name), v); err != nil {
+			return err
+		}
+	}
+	for name, items := range s.DictionaryItems {
+		if err := writeSnapshotFile(filepath.Join(dir, snapshotDictionaryItemsDir, name+".json"), items); err != nil {
+			return err
+		}
+	}
+	for name, entries := range s.ACLEntries {
+		if err := writeSnapshotFile(filepath.Join(dir, snapshotACLEntriesDir, name+".json"), entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSnapshotFile(file string, v interface{}) error {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding %s: %s", file, err)
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0644)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// originLatencyBuckets are Origin Inspector's latency histogram metrics, in
+// ascending order, along with the upper bound of each.
+var originLatencyBuckets = []struct {
+	metric, bound string
+}{
+	{"latency_0_to_1ms", "1ms"},
+	{"latency_1_to_5ms", "5ms"},
+	{"latency_5_to_10ms", "10ms"},
+	{"latency_10_to_50ms", "50ms"},
+	{"latency_50_to_100ms", "100ms"},
+	{"latency_100_to_250ms", "250ms"},
+	{"latency_250_to_500ms", "500ms"},
+	{"latency_500_to_1000ms", "1s"},
+	{"latency_1000_to_5000ms", "5s"},
+	{"latency_5000_to_10000ms", "10s"},
+	{"latency_10000_to_60000ms", "60s"},
+	{"latency_60000ms", "inf"},
+}
+
+// metricsTotal is the sum of each metric over a window, for one value of the
+// dimension the metrics were grouped by.
+type metricsTotal struct {
+	name   string
+	values fastly.MetricsPoint
+}
+
+// metricsInput builds the query for the window and filters given on the
+// command line. The resolution is chosen to keep the number of points small,
+// as they're summed over the window anyway.
+func metricsInput(c *cli.Context, metrics []string, groupBy string) (*fastly.MetricsInput, error) {
+	since := c.Duration("since")
+	if since <= 0 {
+		return nil, fmt.Errorf("--since must be positive.")
+	}
+	downsample := "minute"
+	if since > 7*24*time.Hour {
+		downsample = "day"
+	} else if since > 6*time.Hour {
+		downsample = "hour"
+	}
+	end := time.Now().UTC()
+	return &fastly.MetricsInput{
+		Start:      end.Add(-since),
+		End:        end,
+		Downsample: downsample,
+		Metrics:    metrics,
+		GroupBy:    []string{groupBy},
+		Region:     c.String("region"),
+		Datacenter: c.String("datacenter"),
+	}, nil
+}
+
+// sumMetrics fetches every page of metrics with get, summing each series over
+// the window. Totals are returned busiest first, by the metric named order.
+func sumMetrics(get func(*fastly.MetricsInput) (*fastly.MetricsResponse, error), input *fastly.MetricsInput, groupBy, order string) ([]metricsTotal, error) {
+	totals := make(map[string]fastly.MetricsPoint)
+	for {
+		resp, err := get(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, series := range resp.Data {
+			name := series.Dimensions[groupBy]
+			total, ok := totals[name]
+			if !ok {
+				total = make(fastly.MetricsPoint)
+				totals[name] = total
+			}
+			for _, point := range series.Values {
+				for metric, v := range point {
+					if metric != "timestamp" {
+						total[metric] += v
+					}
+				}
+			}
+		}
+		if resp.Meta.NextCursor == "" {
+			break
+		}
+		input.Cursor = resp.Meta.NextCursor
+	}
+
+	var out []metricsTotal
+	for name, values := range totals {
+		out = append(out, metricsTotal{name, values})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].values[order] != out[j].values[order] {
+			return out[i].values[order] > out[j].values[order]
+		}
+		return out[i].name < out[j].name
+	})
+	return out, nil
+}
+
+// percentage formats n as a percentage of total.
+func percentage(n, total float64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", 100*n/total)
+}
+
+// latencyPercentile returns the upper bound of the latency bucket holding the
+// pth percentile of responses.
+func latencyPercentile(values fastly.MetricsPoint, p float64) string {
+	var count float64
+	for _, b := range originLatencyBuckets {
+		count += values[b.metric]
+	}
+	if count == 0 {
+		return "-"
+	}
+	var seen float64
+	for _, b := range originLatencyBuckets {
+		seen += values[b.metric]
+		if seen >= count*p {
+			return "<" + b.bound
+		}
+	}
+	return "-"
+}
+
+func statsOrigins(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	service, err := util.GetServiceByNameOrID(client, c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	metrics := []string{"responses", "status_2xx", "status_4xx", "status_5xx"}
+	for _, b := range originLatencyBuckets {
+		metrics = append(metrics, b.metric)
+	}
+	input, err := metricsInput(c, metrics, "host")
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	get := func(i *fastly.MetricsInput) (*fastly.MetricsResponse, error) {
+		resp, _, err := client.OriginInspector.Get(service.ID, i)
+		return resp, err
+	}
+	totals, err := sumMetrics(get, input, "host", "responses")
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching origin metrics for service %s: %s", service.Name, err), -1)
+	}
+	if len(totals) == 0 {
+		fmt.Printf("No origin metrics for service %s in the last %s.\n", service.Name, c.Duration("since"))
+		return nil
+	}
+
+	fmt.Printf("Origins of service %s over the last %s:\n\n", service.Name, c.Duration("since"))
+	fmt.Printf("%-40s %10s %10s %10s %10s %8s %7s %7s %7s\n", "Host", "Responses", "2xx", "4xx", "5xx", "Errors", "p50", "p95", "p99")
+	for _, t := range totals {
+		v := t.values
+		fmt.Printf("%-40s %10.0f %10.0f %10.0f %10.0f %8s %7s %7s %7s\n", t.name, v["responses"], v["status_2xx"], v["status_4xx"], v["status_5xx"],
+			percentage(v["status_5xx"], v["responses"]), latencyPercentile(v, 0.5), latencyPercentile(v, 0.95), latencyPercentile(v, 0.99))
+	}
+	return nil
+}
+
+func statsDomains(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	service, err := util.GetServiceByNameOrID(client, c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	metrics := []string{"requests", "bandwidth", "edge_hit_requests", "edge_miss_requests", "status_4xx", "status_5xx"}
+	input, err := metricsInput(c, metrics, "domain")
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	get := func(i *fastly.MetricsInput) (*fastly.MetricsResponse, error) {
+		resp, _, err := client.DomainInspector.Get(service.ID, i)
+		return resp, err
+	}
+	totals, err := sumMetrics(get, input, "domain", "requests")
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching domain metrics for service %s: %s", service.Name, err), -1)
+	}
+	if len(totals) == 0 {
+		fmt.Printf("No domain metrics for service %s in the last %s.\n", service.Name, c.Duration("since"))
+		return nil
+	}
+
+	fmt.Printf("Domains of service %s over the last %s:\n\n", service.Name, c.Duration("since"))
+	fmt.Printf("%-40s %10s %12s %9s %10s %10s %8s\n", "Domain", "Requests", "Bandwidth", "Hit ratio", "4xx", "5xx", "Errors")
+	for _, t := range totals {
+		v := t.values
+		fmt.Printf("%-40s %10.0f %12s %9s %10.0f %10.0f %8s\n", t.name, v["requests"], formatBytes(uint64(v["bandwidth"])),
+			percentage(v["edge_hit_requests"], v["edge_hit_requests"]+v["edge_miss_requests"]), v["status_4xx"], v["status_5xx"], percentage(v["status_5xx"], v["requests"]))
+	}
+	return nil
+}
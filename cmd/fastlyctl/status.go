@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// serviceStatus prints the active version of s and who pushed it, returning
+// whether it was pushed from config. With plan set, the active version is
+// also planned against config, as the fingerprint only reflects the last
+// push, and not changes made outside of fastlyctl since.
+func serviceStatus(client *fastly.Client, configs map[string]fsync.SiteConfig, s *fastly.Service, plan bool) (bool, error) {
+	activeVersion, err := util.GetActiveVersion(s)
+	if err != nil {
+		return false, err
+	}
+	var active, latest *fastly.Version
+	for _, v := range s.Versions {
+		if v.Number == activeVersion {
+			active = v
+		}
+		if latest == nil || v.Number > latest.Number {
+			latest = v
+		}
+	}
+	if active == nil {
+		return false, fmt.Errorf("Active version %d of service %s not found.", activeVersion, s.Name)
+	}
+	pushed, user := fsync.ParseVersionComment(active.Comment)
+
+	fmt.Printf("Service %s (%s):\n", s.Name, s.ID)
+	if active.Updated != "" {
+		fmt.Printf("  %-16s %d, updated %s\n", "Active version:", active.Number, active.Updated)
+	} else {
+		fmt.Printf("  %-16s %d\n", "Active version:", active.Number)
+	}
+	if latest.Number > active.Number {
+		fmt.Printf("  %-16s %d, not yet active\n", "Latest version:", latest.Number)
+	}
+	if user == "" {
+		user = "unknown"
+	}
+	fmt.Printf("  %-16s %s\n", "Pushed by:", user)
+	if pushed == "" {
+		pushed = "none recorded"
+	}
+	fmt.Printf("  %-16s %s\n", "Pushed config:", pushed)
+
+	config, ok := configs[s.Name]
+	if !ok {
+		fmt.Printf("  %-16s not in config file\n", "Local config:")
+		return false, nil
+	}
+	local, err := fsync.Fingerprint(config)
+	if err != nil {
+		return false, fmt.Errorf("Error fingerprinting config of service %s: %s", s.Name, err)
+	}
+	matches := local == pushed
+	state := "matches the active version"
+	if !matches {
+		state = "differs from the active version"
+	}
+	fmt.Printf("  %-16s %s, %s\n", "Local config:", local, state)
+
+	if plan {
+		changes, _, err := planDrift(client, configs, s)
+		if err != nil {
+			return false, fmt.Errorf("Error planning service %s: %s", s.Name, err)
+		}
+		matches = len(changes) == 0
+		if matches {
+			fmt.Printf("  %-16s no changes\n", "Plan:")
+		} else {
+			fmt.Printf("  %-16s %s\n", "Plan:", changes.Summary())
+		}
+	}
+	return matches, nil
+}
+
+func status(c *cli.Context) error {
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), util.ExitError)
+	}
+	delete(configs, "_default_")
+
+	names := c.Args()
+	if c.Bool("all") {
+		names = nil
+		for name := range configs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	upToDate := true
+	for i, name := range names {
+		if i > 0 {
+			fmt.Println()
+		}
+		key, err := util.ResolveKey(configs[name].APIKey)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error resolving API key for service %s: %s", name, err), util.ExitError)
+		}
+		if key == "" {
+			key = c.GlobalString("fastly-key")
+		}
+		client := util.ClientFactory(key)
+
+		s, err := util.GetServiceByNameOrID(client, name)
+		if err != nil {
+			return cli.NewExitError(err.Error(), util.ExitError)
+		}
+		matches, err := serviceStatus(client, configs, s, c.Bool("plan"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), util.ExitError)
+		}
+		upToDate = upToDate && matches
+	}
+	if !upToDate {
+		return cli.NewExitError("", util.ExitChangesPending)
+	}
+	return nil
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	fsync "github.com/alienth/fastlyctl/sync"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/urfave/cli"
+)
+
+// Terraform variables which hold the S3 logging credentials, so that they
+// aren't written into HCL.
+const (
+	terraformS3AccessKeyVar = "fastly_s3_access_key"
+	terraformS3SecretKeyVar = "fastly_s3_secret_key"
+)
+
+var terraformNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// terraformName converts name into a valid Terraform resource name.
+func terraformName(name string) string {
+	n := terraformNameInvalid.ReplaceAllString(strings.ToLower(name), "_")
+	if n == "" || (n[0] >= '0' && n[0] <= '9') || n[0] == '-' {
+		n = "_" + n
+	}
+	return n
+}
+
+// hclString quotes s as an HCL string literal, escaping interpolation
+// sequences so they're kept literally.
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range escapeHCLTemplate(s) {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func escapeHCLTemplate(s string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(s)
+}
+
+// hclWriter writes HCL blocks, indenting the attributes of nested ones.
+// Empty strings and zero numbers are left out, as they match the provider's
+// defaults.
+type hclWriter struct {
+	buf   bytes.Buffer
+	depth int
+}
+
+func (w *hclWriter) line(format string, args ...interface{}) {
+	if format != "" {
+		w.buf.WriteString(strings.Repeat("  ", w.depth))
+		fmt.Fprintf(&w.buf, format, args...)
+	}
+	w.buf.WriteByte('\n')
+}
+
+func (w *hclWriter) open(header string) {
+	w.line("%s {", header)
+	w.depth++
+}
+
+func (w *hclWriter) close() {
+	w.depth--
+	w.line("}")
+}
+
+func (w *hclWriter) expr(name, expr string) {
+	w.line("%s = %s", name, expr)
+}
+
+func (w *hclWriter) str(name, value string) {
+	if value != "" {
+		w.expr(name, hclString(value))
+	}
+}
+
+// content writes a multi-line value as a heredoc, which keeps VCL readable.
+func (w *hclWriter) content(name, value string) {
+	if !strings.HasSuffix(value, "\n") {
+		w.str(name, value)
+		return
+	}
+	delimiter := "EOT"
+	for strings.Contains("\n"+value, "\n"+delimiter+"\n") {
+		delimiter += "_"
+	}
+	w.line("%s = <<%s", name, delimiter)
+	w.buf.WriteString(escapeHCLTemplate(value))
+	w.buf.WriteString(delimiter + "\n")
+}
+
+func (w *hclWriter) num(name string, value uint) {
+	if value != 0 {
+		w.expr(name, strconv.FormatUint(uint64(value), 10))
+	}
+}
+
+func (w *hclWriter) boolean(name string, value bool) {
+	w.expr(name, strconv.FormatBool(value))
+}
+
+func (w *hclWriter) list(name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = hclString(v)
+	}
+	w.expr(name, "["+strings.Join(quoted, ", ")+"]")
+}
+
+// text returns the name an enum is marshalled to in the API.
+func text(v interface{ MarshalText() ([]byte, error) }) string {
+	b, _ := v.MarshalText()
+	return string(b)
+}
+
+// writeTerraformService writes a fastly_service_vcl resource named after
+// service with the given config, applying the same substitutions as push.
+func writeTerraformService(w *hclWriter, service string, config fsync.SiteConfig) {
+	r := strings.NewReplacer("_servicename_", service, "_prefix_", config.IPPrefix, "_suffix_", config.IPSuffix)
+
+	w.open(fmt.Sprintf("resource \"fastly_service_vcl\" %s", hclString(terraformName(service))))
+	w.str("name", service)
+	w.str("comment", config.Comment)
+	w.num("default_ttl", config.Settings.DefaultTTL)
+	w.str("default_host", config.Settings.DefaultHost)
+
+	for _, d := range config.Domains {
+		w.line("")
+		w.open("domain")
+		w.str("name", d.Name)
+		w.str("comment", d.Comment)
+		w.close()
+	}
+	for _, b := range config.Backends {
+		address := b.Address
+		for _, a := range []string{b.Hostname, b.IPV4, b.IPV6} {
+			if address == "" {
+				address = a
+			}
+		}
+		w.line("")
+		w.open("backend")
+		w.str("name", b.Name)
+		w.str("address", r.Replace(address))
+		w.num("port", b.Port)
+		w.str("override_host", b.OverrideHost)
+		w.num("connect_timeout", b.ConnectTimeout)
+		w.num("first_byte_timeout", b.FirstByteTimeout)
+		w.num("between_bytes_timeout", b.BetweenBytesTimeout)
+		w.num("max_conn", b.MaxConn)
+		w.num("error_threshold", b.ErrorThreshold)
+		w.num("weight", b.Weight)
+		w.boolean("auto_loadbalance", b.AutoLoadbalance)
+		w.str("request_condition", b.RequestCondition)
+		w.str("healthcheck", b.HealthCheck)
+		w.str("shield", b.Shield)
+		w.boolean("use_ssl", b.UseSSL)
+		w.boolean("ssl_check_cert", b.SSLCheckCert)
+		w.str("ssl_hostname", b.SSLHostname)
+		w.str("ssl_cert_hostname", r.Replace(b.SSLCertHostname))
+		w.str("ssl_sni_hostname", r.Replace(b.SSLSNIHostname))
+		w.str("ssl_ciphers", b.SSLCiphers)
+		w.str("min_tls_version", b.MinTLSVersion)
+		w.str("max_tls_version", b.MaxTLSVersion)
+		w.close()
+	}
+	for _, d := range config.Directors {
+		w.line("")
+		w.open("director")
+		w.str("name", d.Name)
+		w.str("comment", d.Comment)
+		w.str("shield", d.Shield)
+		w.num("quorum", d.Quorum)
+		w.num("type", uint(d.Type))
+		w.num("retries", d.Retries)
+		w.list("backends", d.Backends)
+		w.close()
+	}
+	for _, h := range config.HealthChecks {
+		w.line("")
+		w.open("healthcheck")
+		w.str("name", h.Name)
+		w.str("host", h.Host)
+		w.str("path", h.Path)
+		w.str("method", h.Method)
+		w.str("http_version", h.HTTPVersion)
+		w.num("check_interval", h.CheckInterval)
+		w.num("expected_response", h.ExpectedResponse)
+		w.num("initial", h.Initial)
+		w.num("threshold", h.Threshold)
+		w.num("timeout", h.Timeout)
+		w.num("window", h.Window)
+		w.close()
+	}
+	for _, c := range config.Conditions {
+		w.line("")
+		w.open("condition")
+		w.str("name", c.Name)
+		w.str("type", text(&c.Type))
+		w.str("statement", c.Statement)
+		w.num("priority", c.Priority)
+		w.close()
+	}
+	for _, c := range config.CacheSettings {
+		w.line("")
+		w.open("cache_setting")
+		w.str("name", c.Name)
+		w.str("action", text(&c.Action))
+		w.str("cache_condition", c.CacheCondition)
+		w.num("ttl", c.TTL)
+		w.num("stale_ttl", c.StaleTTL)
+		w.close()
+	}
+	for _, h := range config.Headers {
+		w.line("")
+		w.open("header")
+		w.str("name", h.Name)
+		w.str("action", text(&h.Action))
+		w.str("type", text(&h.Type))
+		w.str("destination", h.Destination)
+		w.str("source", h.Source)
+		w.str("regex", h.Regex)
+		w.str("substitution", h.Substitution)
+		w.boolean("ignore_if_set", h.IgnoreIfSet.Bool())
+		w.num("priority", h.Priority)
+		w.str("request_condition", h.RequestCondition)
+		w.str("cache_condition", h.CacheCondition)
+		w.str("response_condition", h.ResponseCondition)
+		w.close()
+	}
+	for _, g := range config.Gzips {
+		w.line("")
+		w.open("gzip")
+		w.str("name", g.Name)
+		w.list("content_types", strings.Fields(g.ContentTypes))
+		w.list("extensions", strings.Fields(g.Extensions))
+		w.str("cache_condition", g.CacheCondition)
+		w.close()
+	}
+	for _, rs := range config.RequestSettings {
+		w.line("")
+		w.open("request_setting")
+		w.str("name", rs.Name)
+		w.str("action", rs.Action)
+		w.str("request_condition", rs.RequestCondition)
+		w.str("default_host", rs.DefaultHost)
+		w.str("hash_keys", rs.HashKeys)
+		w.str("xff", rs.XFF)
+		w.num("max_stale_age", uint(rs.MaxStaleAge))
+		w.boolean("force_miss", rs.ForceMiss.Bool())
+		w.boolean("force_ssl", rs.ForceSSL.Bool())
+		w.boolean("bypass_busy_wait", rs.BypassBusyWait.Bool())
+		w.boolean("geo_headers", rs.GeoHeaders.Bool())
+		w.boolean("timer_support", rs.TimerSupport.Bool())
+		w.close()
+	}
+	for _, ro := range config.ResponseObject {
+		w.line("")
+		w.open("response_object")
+		w.str("name", ro.Name)
+		if status, err := strconv.ParseUint(ro.Status, 10, 0); err == nil {
+			w.num("status", uint(status))
+		}
+		w.str("response", ro.Response)
+		contentType := ro.ContentType
+		if ro.ContentFile != "" {
+			w.expr("content", fmt.Sprintf("file(%s)", hclString(filepath.ToSlash(ro.ContentFile))))
+			if contentType == "" {
+				contentType = mime.TypeByExtension(filepath.Ext(ro.ContentFile))
+			}
+		} else {
+			w.content("content", ro.Content)
+		}
+		w.str("content_type", contentType)
+		w.str("request_condition", ro.RequestCondition)
+		w.str("cache_condition", ro.CacheCondition)
+		w.close()
+	}
+	for _, s3 := range config.S3s {
+		w.line("")
+		w.open("logging_s3")
+		w.str("name", s3.Name)
+		w.str("bucket_name", s3.BucketName)
+		w.str("domain", s3.Domain)
+		w.expr("s3_access_key", "var."+terraformS3AccessKeyVar)
+		w.expr("s3_secret_key", "var."+terraformS3SecretKeyVar)
+		w.str("path", strings.NewReplacer("_servicename_", service).Replace(s3.Path))
+		w.num("period", s3.Period)
+		w.num("gzip_level", s3.GzipLevel)
+		w.str("format", s3.Format)
+		w.str("message_type", text(&s3.MessageType))
+		w.str("timestamp_format", s3.TimestampFormat)
+		w.str("redundancy", s3.Redundancy)
+		w.str("response_condition", s3.ResponseCondition)
+		w.close()
+	}
+	for _, l := range config.Syslogs {
+		w.line("")
+		w.open("logging_syslog")
+		w.str("name", l.Name)
+		w.str("address", r.Replace(l.Address))
+		w.num("port", l.Port)
+		w.str("format", l.Format)
+		w.str("token", l.Token)
+		w.boolean("use_tls", l.UseTLS.Bool())
+		w.str("tls_hostname", r.Replace(l.TLSHostname))
+		w.content("tls_ca_cert", l.TLSCACert)
+		w.str("response_condition", l.ResponseCondition)
+		w.close()
+	}
+	for _, d := range config.Dictionaries {
+		w.line("")
+		w.open("dictionary")
+		w.str("name", d.Name)
+		w.boolean("write_only", d.WriteOnly)
+		w.close()
+	}
+	for _, a := range config.ACLs {
+		w.line("")
+		w.open("acl")
+		w.str("name", a.Name)
+		w.close()
+	}
+	for _, v := range config.VCLs {
+		if v == (fsync.VCL{}) {
+			continue
+		}
+		w.line("")
+		w.open("vcl")
+		w.str("name", v.Name)
+		if v.File != "" {
+			w.expr("content", fmt.Sprintf("file(%s)", hclString(filepath.ToSlash(v.File))))
+		} else {
+			w.content("content", v.Content)
+		}
+		w.boolean("main", v.Main)
+		w.close()
+	}
+	w.close()
+
+	var kinds []string
+	for kind := range config.Resources {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(os.Stderr, "Warning: %s resources of service %s have no Terraform equivalent, and were left out.\n", kind, service)
+	}
+}
+
+// writeTerraformVariables declares the variables referenced by the services
+// in configs.
+func writeTerraformVariables(w *hclWriter, configs map[string]fsync.SiteConfig) {
+	for _, config := range configs {
+		if len(config.S3s) == 0 {
+			continue
+		}
+		for _, name := range []string{terraformS3AccessKeyVar, terraformS3SecretKeyVar} {
+			w.line("")
+			w.open(fmt.Sprintf("variable %s", hclString(name)))
+			w.expr("type", "string")
+			w.boolean("sensitive", true)
+			w.close()
+		}
+		return
+	}
+}
+
+// dictionaryIDExpr is an expression for the ID of the dictionary or ACL
+// named name, within the given attribute of a fastly_service_vcl.
+func dictionaryIDExpr(service, block, attr, name string) string {
+	return fmt.Sprintf("one([for d in fastly_service_vcl.%s.%s : d.%s if d.name == %s])", terraformName(service), block, attr, hclString(name))
+}
+
+// writeTerraformSnapshot writes the service in snapshot, along with the
+// contents of its dictionaries and ACLs, and returns the commands importing
+// them into Terraform state.
+func writeTerraformSnapshot(w *hclWriter, snapshot *fsync.Snapshot) []string {
+	service := snapshot.Service.Name
+	config := snapshot.SiteConfig()
+	config.Comment = snapshot.Service.Comment
+	writeTerraformService(w, service, config)
+	name := terraformName(service)
+	imports := []string{fmt.Sprintf("terraform import fastly_service_vcl.%s %s", name, snapshot.Service.ID)}
+
+	for _, d := range snapshot.Dictionaries {
+		items, ok := snapshot.DictionaryItems[d.Name]
+		if !ok {
+			continue
+		}
+		resource := terraformName(service + "_" + d.Name)
+		w.line("")
+		w.open(fmt.Sprintf("resource \"fastly_service_dictionary_items\" %s", hclString(resource)))
+		w.expr("service_id", fmt.Sprintf("fastly_service_vcl.%s.id", name))
+		w.expr("dictionary_id", dictionaryIDExpr(service, "dictionary", "dictionary_id", d.Name))
+		w.boolean("manage_items", true)
+		w.open("items =")
+		for _, item := range items {
+			w.expr(hclString(item.Key), hclString(item.Value))
+		}
+		w.close()
+		w.close()
+		imports = append(imports, fmt.Sprintf("terraform import fastly_service_dictionary_items.%s %s/%s", resource, snapshot.Service.ID, d.ID))
+	}
+	for _, a := range snapshot.ACLs {
+		entries, ok := snapshot.ACLEntries[a.Name]
+		if !ok {
+			continue
+		}
+		resource := terraformName(service + "_" + a.Name)
+		w.line("")
+		w.open(fmt.Sprintf("resource \"fastly_service_acl_entries\" %s", hclString(resource)))
+		w.expr("service_id", fmt.Sprintf("fastly_service_vcl.%s.id", name))
+		w.expr("acl_id", dictionaryIDExpr(service, "acl", "acl_id", a.Name))
+		w.boolean("manage_entries", true)
+		for _, e := range entries {
+			w.line("")
+			w.open("entry")
+			w.str("ip", e.IP)
+			if e.Subnet != 0 {
+				w.str("subnet", strconv.Itoa(int(e.Subnet)))
+			}
+			w.boolean("negated", e.Negated.Bool())
+			w.str("comment", e.Comment)
+			w.close()
+		}
+		w.close()
+		imports = append(imports, fmt.Sprintf("terraform import fastly_service_acl_entries.%s %s/%s", resource, snapshot.Service.ID, a.ID))
+	}
+
+	writeTerraformVariables(w, map[string]fsync.SiteConfig{service: config})
+	return imports
+}
+
+// writeHCL writes the HCL in w to file, or to stdout if file is "-".
+func writeHCL(w *hclWriter, file string) error {
+	if file == "-" {
+		_, err := os.Stdout.Write(w.buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(file, w.buf.Bytes(), 0644)
+}
+
+// exportTerraform writes the snapshot as Terraform HCL, printing the
+// commands which import the live service into Terraform state.
+func exportTerraform(snapshot *fsync.Snapshot, out string) error {
+	if out == "" {
+		out = terraformName(snapshot.Service.Name) + ".tf"
+	}
+	w := new(hclWriter)
+	imports := writeTerraformSnapshot(w, snapshot)
+	if err := writeHCL(w, out); err != nil {
+		return fmt.Errorf("Error writing %s: %s", out, err)
+	}
+	// The HCL takes stdout when written there.
+	dest := os.Stderr
+	if out != "-" {
+		dest = os.Stdout
+		fmt.Printf("Exported version %d of service %s to %s\n", snapshot.Version, snapshot.Service.Name, out)
+	}
+	fmt.Fprintf(dest, "\nImport the service into Terraform state with:\n\n")
+	for _, cmd := range imports {
+		fmt.Fprintf(dest, "  %s\n", cmd)
+	}
+	return nil
+}
+
+func convertTerraform(c *cli.Context) error {
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), -1)
+	}
+	delete(configs, "_default_")
+
+	var names []string
+	for name := range configs {
+		if c.Bool("all") || util.StringInSlice(name, c.Args()) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range c.Args() {
+		if _, ok := configs[name]; !ok {
+			return cli.NewExitError(fmt.Sprintf("Service %s is not in the config file.", name), -1)
+		}
+	}
+	sort.Strings(names)
+
+	w := new(hclWriter)
+	selected := make(map[string]fsync.SiteConfig)
+	for i, name := range names {
+		if i > 0 {
+			w.line("")
+		}
+		writeTerraformService(w, name, configs[name])
+		selected[name] = configs[name]
+	}
+	writeTerraformVariables(w, selected)
+
+	out := c.String("out")
+	if err := writeHCL(w, out); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error writing %s: %s", out, err), -1)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/urfave/cli"
+)
+
+// usageRow is the traffic of one service within one region.
+type usageRow struct {
+	serviceID, service, region string
+	stats                      fastly.UsageStats
+}
+
+// usageRows flattens usage into rows ordered by service name and region.
+func usageRows(usage *fastly.MonthlyUsage) []usageRow {
+	var rows []usageRow
+	for id, s := range usage.Services {
+		name := s.Name
+		if name == "" {
+			// Deleted services are reported without a name.
+			name = id
+		}
+		for region, stats := range s.Regions {
+			rows = append(rows, usageRow{id, name, region, stats})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].service != rows[j].service {
+			return rows[i].service < rows[j].service
+		}
+		return rows[i].region < rows[j].region
+	})
+	return rows
+}
+
+// formatBytes formats a byte count in GB, as Fastly bills bandwidth.
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.2f GB", float64(n)/1e9)
+}
+
+// usageMonth returns the month given with --month, or the current month.
+func usageMonth(c *cli.Context) (time.Time, error) {
+	m := c.String("month")
+	if m == "" {
+		return time.Now().UTC(), nil
+	}
+	month, err := time.Parse("2006-01", m)
+	if err != nil {
+		return month, fmt.Errorf("Invalid --month %q. Must be given as YYYY-MM.", m)
+	}
+	return month, nil
+}
+
+func usageReport(c *cli.Context) error {
+	client := util.NewClient(c)
+
+	month, err := usageMonth(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	format := c.String("format")
+	if format != "table" && format != "csv" {
+		return cli.NewExitError(fmt.Sprintf("Invalid --format %q. Must be table or csv.", format), -1)
+	}
+
+	usage, _, err := client.Usage.ByMonth(month.Year(), month.Month())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error fetching usage for %s: %s", month.Format("2006-01"), err), -1)
+	}
+	rows := usageRows(usage)
+
+	if format == "csv" {
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"service_id", "service", "region", "bandwidth_bytes", "requests"})
+		for _, r := range rows {
+			w.Write([]string{r.serviceID, r.service, r.region, strconv.FormatUint(r.stats.Bandwidth, 10), strconv.FormatUint(r.stats.Requests, 10)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	fmt.Printf("Usage for %s:\n\n", month.Format("2006-01"))
+	fmt.Printf("%-30s %-15s %15s %15s\n", "Service", "Region", "Bandwidth", "Requests")
+	for _, r := range rows {
+		fmt.Printf("%-30s %-15s %15s %15d\n", r.service, r.region, formatBytes(r.stats.Bandwidth), r.stats.Requests)
+	}
+	var regions []string
+	for region := range usage.Total {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	fmt.Println()
+	for _, region := range regions {
+		stats := usage.Total[region]
+		fmt.Printf("%-30s %-15s %15s %15d\n", "Total", region, formatBytes(stats.Bandwidth), stats.Requests)
+	}
+
+	// Billing details need a token with billing access, which not every
+	// user of usage has.
+	invoice, _, err := client.Billing.Get(month.Year(), month.Month())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nUnable to fetch the invoice for %s: %s\n", month.Format("2006-01"), err)
+		return nil
+	}
+	fmt.Printf("\nInvoice for %s (%s):\n\n", month.Format("2006-01"), invoice.Status.Status)
+	for _, item := range invoice.LineItems {
+		fmt.Printf("%-46s %15.2f %15.2f\n", item.Description, item.Units, item.Amount)
+	}
+	fmt.Printf("%-46s %15.2f %15.2f\n", "Bandwidth ("+invoice.Total.BandwidthUnits+")", invoice.Total.Bandwidth, invoice.Total.BandwidthCost)
+	fmt.Printf("%-46s %15.0f %15.2f\n", "Requests", invoice.Total.Requests, invoice.Total.RequestsCost)
+	if invoice.Total.Discount != 0 {
+		fmt.Printf("%-46s %15s %15.2f\n", "Discount", "", -invoice.Total.Discount)
+	}
+	fmt.Printf("%-46s %15s %15.2f\n", "Total", "", invoice.Total.Cost)
+	return nil
+}
@@ -3,19 +3,62 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	fsync "github.com/alienth/fastlyctl/sync"
 	"github.com/alienth/fastlyctl/util"
 	"github.com/alienth/go-fastly"
 	"github.com/urfave/cli"
 )
 
 func versionList(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	if groups := c.StringSlice("group"); len(groups) > 0 {
+		return versionListGroups(c, groups)
+	}
+	client := util.NewClient(c)
 	serviceParam := c.Args().Get(0)
-	service, err := util.GetServiceByName(client, serviceParam)
+	service, err := util.GetServiceByNameOrID(client, serviceParam)
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
+	printVersions(service)
+	return nil
+}
+
+// versionListGroups lists the versions of each service in the config file
+// which belongs to any of groups.
+func versionListGroups(c *cli.Context, groups []string) error {
+	configs, err := fsync.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error reading config file: %s", err), -1)
+	}
+	names := fsync.GroupMembers(configs, groups)
+	if len(names) == 0 {
+		return cli.NewExitError(fmt.Sprintf("No services in the config file belong to group %s.", strings.Join(groups, ", ")), -1)
+	}
+	for i, name := range names {
+		if i > 0 {
+			fmt.Println()
+		}
+		key, err := util.ResolveKey(configs[name].APIKey)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error resolving API key for service %s: %s", name, err), -1)
+		}
+		if key == "" {
+			key = c.GlobalString("fastly-key")
+		}
+		service, err := util.GetServiceByNameOrID(util.ClientFactory(key), name)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		printVersions(service)
+	}
+	return nil
+}
+
+// printVersions lists the versions of service, marking the active version
+// with * and the staged version with s.
+func printVersions(service *fastly.Service) {
 	fmt.Printf("Versions for %s:\n\n", service.Name)
 	fmt.Printf("%5s %-27s %-27s %s\n", "ID", "Created", "Updated", "Comment")
 	for _, version := range service.Versions {
@@ -23,14 +66,15 @@ func versionList(c *cli.Context) error {
 		if version.Active {
 			active = "*"
 		}
+		if version.Staging {
+			active += "s"
+		}
 		fmt.Printf("%2s %4d %-27s %-27s %s\n", active, version.Number, version.Created, version.Updated, version.Comment)
 	}
-
-	return nil
 }
 
 func versionValidate(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 	serviceParam := c.Args().Get(0)
 	version, err := strconv.Atoi(c.Args().Get(1))
 	if err != nil {
@@ -38,19 +82,23 @@ func versionValidate(c *cli.Context) error {
 	}
 
 	var service *fastly.Service
-	if service, err = util.GetServiceByName(client, serviceParam); err != nil {
+	if service, err = util.GetServiceByNameOrID(client, serviceParam); err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
 
-	if err := util.ValidateVersion(client, service, uint(version)); err != nil {
+	warnings, err := util.CheckVersion(client, service, uint(version))
+	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
+	if len(warnings) > 0 {
+		return cli.NewExitError("", util.ExitValidationWarnings)
+	}
 
 	return nil
 }
 
 func versionActivate(c *cli.Context) error {
-	client := fastly.NewClient(nil, c.GlobalString("fastly-key"))
+	client := util.NewClient(c)
 	serviceParam := c.Args().Get(0)
 	version, err := strconv.Atoi(c.Args().Get(1))
 	if err != nil {
@@ -58,7 +106,7 @@ func versionActivate(c *cli.Context) error {
 	}
 
 	var service *fastly.Service
-	if service, err = util.GetServiceByName(client, serviceParam); err != nil {
+	if service, err = util.GetServiceByNameOrID(client, serviceParam); err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
 
@@ -67,6 +115,62 @@ func versionActivate(c *cli.Context) error {
 	} else {
 		fmt.Printf("Version %d on service %s successfully activated!\n", version, serviceParam)
 	}
+	if c.Bool("wait") {
+		if err = util.WaitForDeployment(client, service, uint(version), c.Duration("wait-timeout")); err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+
+	return nil
+}
+
+// versionStage activates a version in the staging environment, or if unstage
+// is set, deactivates it there.
+func versionStage(c *cli.Context, unstage bool) error {
+	client := util.NewClient(c)
+	serviceParam := c.Args().Get(0)
+	version, err := strconv.Atoi(c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError("Invalid version number.\n", -1)
+	}
+
+	var service *fastly.Service
+	if service, err = util.GetServiceByNameOrID(client, serviceParam); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	if unstage {
+		if _, _, err = client.Version.Unstage(service.ID, uint(version)); err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error unstaging version: %s", err), -1)
+		}
+		fmt.Printf("Version %d on service %s removed from staging.\n", version, service.Name)
+		return nil
+	}
+	if _, _, err = client.Version.Stage(service.ID, uint(version)); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Error staging version: %s", err), -1)
+	}
+	fmt.Printf("Version %d on service %s staged. Test it through the staging environment before activating it.\n", version, service.Name)
+	return nil
+}
+
+// editDraftVersion prepares a draft version of the given service, applies edit
+// to it, and then validates the result and prompts for its activation.
+func editDraftVersion(c *cli.Context, client *fastly.Client, service *fastly.Service, edit func(syncer *fsync.Syncer, version uint) error) error {
+	syncer := newSyncer(client, nil)
+	version, err := syncer.PrepareDraft(service)
+	if err != nil {
+		return fmt.Errorf("Error preparing new version for service %s: %s", service.Name, err)
+	}
+
+	if err = edit(syncer, version.Number); err != nil {
+		return err
+	}
 
+	if err = util.ValidateVersion(client, service, version.Number); err != nil {
+		return err
+	}
+	if _, err = util.ActivateVersion(c, client, service, &version, syncer.Changes(service)); err != nil {
+		return fmt.Errorf("Error activating version %d for service %s: %s", version.Number, service.Name, err)
+	}
 	return nil
 }
@@ -0,0 +1,66 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type BillingConfig config
+
+// Invoice is the bill for a single month. The bill for the current month
+// is an estimate which is updated as the month progresses.
+type Invoice struct {
+	CustomerID string        `json:"customer_id"`
+	InvoiceID  string        `json:"invoice_id"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Status     InvoiceStatus `json:"status"`
+	Total      InvoiceTotal  `json:"total"`
+	LineItems  []*LineItem   `json:"line_items"`
+}
+
+// InvoiceStatus describes whether an invoice has been sent.
+type InvoiceStatus struct {
+	Status string `json:"status"`
+}
+
+// InvoiceTotal summarizes the charges of an invoice. Bandwidth is given in
+// BandwidthUnits.
+type InvoiceTotal struct {
+	Bandwidth      float64 `json:"bandwidth"`
+	BandwidthCost  float64 `json:"bandwidth_cost"`
+	BandwidthUnits string  `json:"bandwidth_units"`
+	Requests       float64 `json:"requests"`
+	RequestsCost   float64 `json:"requests_cost"`
+	Discount       float64 `json:"discount"`
+	IncurredCost   float64 `json:"incurred_cost"`
+	Cost           float64 `json:"cost"`
+}
+
+// LineItem is a single charge on an invoice.
+type LineItem struct {
+	Description string  `json:"description"`
+	ProductName string  `json:"product_name"`
+	Units       float64 `json:"units"`
+	Rate        float64 `json:"rate"`
+	Amount      float64 `json:"amount"`
+}
+
+// Get retrieves the invoice for a month.
+func (c *BillingConfig) Get(year int, month time.Month) (*Invoice, *http.Response, error) {
+	u := fmt.Sprintf("/billing/v2/year/%04d/month/%02d", year, month)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invoice := new(Invoice)
+	resp, err := c.client.Do(req, invoice)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return invoice, resp, nil
+}
@@ -0,0 +1,198 @@
+package fastly
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// lookupCache memoizes lookups which are commonly repeated within a single
+// process. Concurrent lookups of the same key share one request, and every
+// entry is dropped whenever the client makes a request which may modify
+// something.
+type lookupCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done    chan struct{}
+	fetched time.Time
+	value   interface{}
+	resp    *http.Response
+	err     error
+}
+
+// EnableCache memoizes the results of Service.Search, Dictionary.Get, and
+// ACL.Get. Results are reused for up to ttl, or indefinitely if ttl is zero,
+// and are discarded when the client makes any request other than a GET.
+func (c *Client) EnableCache(ttl time.Duration) {
+	c.cache = &lookupCache{ttl: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// cached returns the result of fetch for key, calling it only if there is no
+// usable result already cached or in flight.
+func (c *Client) cached(key string, fetch func() (interface{}, *http.Response, error)) (interface{}, *http.Response, error) {
+	cache := c.cache
+	if cache == nil {
+		return fetch()
+	}
+
+	cache.mu.Lock()
+	entry, ok := cache.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			if cache.ttl != 0 && time.Since(entry.fetched) > cache.ttl {
+				ok = false
+			}
+		default:
+		}
+	}
+	if ok {
+		cache.mu.Unlock()
+		<-entry.done
+		return entry.value, entry.resp, entry.err
+	}
+	entry = &cacheEntry{done: make(chan struct{})}
+	cache.entries[key] = entry
+	cache.mu.Unlock()
+
+	entry.value, entry.resp, entry.err = fetch()
+	entry.fetched = time.Now()
+	close(entry.done)
+	if entry.err != nil {
+		cache.mu.Lock()
+		if cache.entries[key] == entry {
+			delete(cache.entries, key)
+		}
+		cache.mu.Unlock()
+	}
+	return entry.value, entry.resp, entry.err
+}
+
+// invalidateCache drops every cached lookup, and marks cached responses for
+// revalidation. Lookups in flight complete, but their results are not reused.
+func (c *Client) invalidateCache() {
+	for _, cache := range []*lookupCache{c.cache, c.parentCache} {
+		if cache == nil {
+			continue
+		}
+		cache.mu.Lock()
+		cache.entries = make(map[string]*cacheEntry)
+		cache.mu.Unlock()
+	}
+	for _, responses := range []*responseCache{c.responses, c.parentResponses} {
+		if responses != nil {
+			responses.invalidate()
+		}
+	}
+}
+
+// responseCache holds the bodies of GET responses, keyed by URL and API key,
+// so that they may be revalidated with If-None-Match rather than fetched in
+// full, or reused outright while fresh.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	etag    string
+	status  int
+	header  http.Header
+	body    []byte
+	fetched time.Time
+	// stale is set when the client makes a request which may modify
+	// something, after which the response must be revalidated.
+	stale bool
+}
+
+// EnableResponseCache keeps GET responses in memory. Responses carrying an
+// ETag are revalidated with a conditional GET, which the API answers without
+// a body if nothing has changed. A response is reused without revalidation
+// for up to ttl after it was fetched, unless the client has since made a
+// request other than a GET; a zero ttl always revalidates.
+func (c *Client) EnableResponseCache(ttl time.Duration) {
+	c.responses = &responseCache{ttl: ttl, entries: make(map[string]*cachedResponse)}
+}
+
+func responseCacheKey(req *http.Request) string {
+	return req.URL.String() + " " + req.Header.Get("Fastly-Key")
+}
+
+// lookup returns the fresh cached response for req, if there is one. Failing
+// that, req is made conditional on any cached ETag.
+func (rc *responseCache) lookup(req *http.Request) *http.Response {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[responseCacheKey(req)]
+	if !ok {
+		return nil
+	}
+	if !entry.stale && rc.ttl != 0 && time.Since(entry.fetched) < rc.ttl {
+		return entry.response(req)
+	}
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	return nil
+}
+
+// update records resp as the response to req, returning the response to use
+// in its place. A 304 is answered from the cache.
+func (rc *responseCache) update(req *http.Request, resp *http.Response) (*http.Response, error) {
+	key := responseCacheKey(req)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if resp.StatusCode == http.StatusNotModified {
+		entry, ok := rc.entries[key]
+		if !ok {
+			return resp, nil
+		}
+		resp.Body.Close()
+		entry.fetched = time.Now()
+		entry.stale = false
+		return entry.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		delete(rc.entries, key)
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" && rc.ttl == 0 {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry := &cachedResponse{etag: etag, status: resp.StatusCode, header: resp.Header, body: body, fetched: time.Now()}
+	rc.entries[key] = entry
+	return entry.response(req), nil
+}
+
+func (e *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode: e.status,
+		Header:     e.header.Clone(),
+		Body:       ioutil.NopCloser(bytes.NewReader(e.body)),
+		Request:    req,
+	}
+}
+
+// invalidate marks every cached response as needing revalidation.
+func (rc *responseCache) invalidate() {
+	rc.mu.Lock()
+	for _, entry := range rc.entries {
+		entry.stale = true
+	}
+	rc.mu.Unlock()
+}
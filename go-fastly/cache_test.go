@@ -0,0 +1,148 @@
+package fastly_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+// cachingClient returns a client of srv which keeps responses for ttl, and
+// the requests it sends, as "METHOD path status", with "conditional" added to
+// those carrying If-None-Match. Responses served from the cache aren't sent.
+func cachingClient(srv *fastlytest.Server, ttl time.Duration) (*fastly.Client, *[]string) {
+	var sent []string
+	client := srv.Client()
+	client.EnableResponseCache(ttl)
+	client.Hooks.Request = func(req *http.Request, resp *http.Response, d time.Duration, err error) {
+		s := fmt.Sprintf("%s %s %d", req.Method, req.URL.Path, resp.StatusCode)
+		if req.Header.Get("If-None-Match") != "" {
+			s += " conditional"
+		}
+		sent = append(sent, s)
+	}
+	return client, &sent
+}
+
+func TestResponseCacheRevalidates(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService("test")
+	client, sent := cachingClient(srv, 0)
+
+	first, _, err := client.Service.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, resp, err := client.Service.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Revalidated service = %+v, want %+v", second, first)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Status != "200 OK" {
+		t.Errorf("Revalidated response has status %d %q, want 200 \"200 OK\"", resp.StatusCode, resp.Status)
+	}
+	want := []string{"GET /service/" + s.ID + " 200", "GET /service/" + s.ID + " 304 conditional"}
+	if !reflect.DeepEqual(*sent, want) {
+		t.Errorf("Sent %q, want %q", *sent, want)
+	}
+}
+
+func TestResponseCacheRevalidatesAfterChange(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService("test")
+	client, sent := cachingClient(srv, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Service.Get(s.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := client.Service.Update(s.ID, &fastly.Service{Name: "test", Comment: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := client.Service.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Comment != "changed" {
+		t.Errorf("Comment after update = %q, want changed", got.Comment)
+	}
+	want := []string{
+		"GET /service/" + s.ID + " 200",
+		"PUT /service/" + s.ID + " 200",
+		"GET /service/" + s.ID + " 200 conditional",
+	}
+	if !reflect.DeepEqual(*sent, want) {
+		t.Errorf("Sent %q, want %q", *sent, want)
+	}
+}
+
+func TestResponseCacheInvalidatedByWith(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService("test")
+	client, sent := cachingClient(srv, time.Hour)
+
+	if _, _, err := client.Service.Get(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Changes made through a derived client must not leave the parent
+	// serving what it cached before them.
+	derived := client.With(fastly.WithHeader("X-Test", "1"))
+	if _, _, err := derived.Service.Update(s.ID, &fastly.Service{Name: "test", Comment: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := client.Service.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Comment != "changed" {
+		t.Errorf("Comment after update = %q, want changed", got.Comment)
+	}
+	want := []string{
+		"GET /service/" + s.ID + " 200",
+		"PUT /service/" + s.ID + " 200",
+		"GET /service/" + s.ID + " 200 conditional",
+	}
+	if !reflect.DeepEqual(*sent, want) {
+		t.Errorf("Sent %q, want %q", *sent, want)
+	}
+}
+
+func TestResponseCacheEvictsErrors(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService("test")
+	client, sent := cachingClient(srv, 0)
+
+	if _, _, err := client.Service.Get(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Deleted through another client, so the cache isn't told.
+	if _, err := srv.Client().Service.Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Service.Get(s.ID); err == nil {
+			t.Fatal("Getting a deleted service succeeded")
+		}
+	}
+	// Once the API stops returning the service, its cached ETag is
+	// dropped rather than sent again.
+	want := []string{
+		"GET /service/" + s.ID + " 200",
+		"GET /service/" + s.ID + " 404 conditional",
+		"GET /service/" + s.ID + " 404",
+	}
+	if !reflect.DeepEqual(*sent, want) {
+		t.Errorf("Sent %q, want %q", *sent, want)
+	}
+}
@@ -0,0 +1,534 @@
+package fastly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBaseURL is the default endpoint for Fastly. Since Fastly does not
+	// support an on-premise solution, this is likely to always be the default.
+	defaultBaseURL = "https://api.fastly.com/"
+
+	headerRateLimitRemaining = "Fastly-RateLimit-Remaining"
+	headerRateLimitReset     = "Fastly-RateLimit-Reset"
+)
+
+// ProjectURL is the url for this library.
+var ProjectURL = "github.com/alienth/go-fastly"
+
+// ProjectVersion is the version of this library.
+var ProjectVersion = "0.1"
+
+// UserAgent is the user agent for this particular client.
+var userAgent = fmt.Sprintf("alienth/go-fastly/%s (+%s; %s)",
+	ProjectVersion, ProjectURL, runtime.Version())
+
+// Client is the main entrypoint to the Fastly golang API library.
+type Client struct {
+	client *http.Client
+
+	// Base URL for API requests.
+	BaseURL *url.URL
+
+	UserAgent string
+
+	// ReadOnly makes the client refuse any request other than a GET or
+	// HEAD, returning a *ReadOnlyError without contacting the API.
+	ReadOnly bool
+
+	// Hooks are called as requests are made.
+	Hooks Hooks
+
+	common config // Reuse a single struct instead of allocating one for each service on the heap.
+
+	// Configs used for interacting with different parts of the Fastly API
+	ACL             *ACLConfig
+	ACLEntry        *ACLEntryConfig
+	Backend         *BackendConfig
+	Billing         *BillingConfig
+	CacheSetting    *CacheSettingConfig
+	Condition       *ConditionConfig
+	Datacenter      *DatacenterConfig
+	Dictionary      *DictionaryConfig
+	DictionaryItem  *DictionaryItemConfig
+	Diff            *DiffConfig
+	Director        *DirectorConfig
+	DirectorBackend *DirectorBackendConfig
+	Domain          *DomainConfig
+	DomainInspector *DomainInspectorConfig
+	Event           *EventConfig
+
+	Gzip            *GzipConfig
+	Header          *HeaderConfig
+	HealthCheck     *HealthCheckConfig
+	OriginInspector *OriginInspectorConfig
+	Purge           *PurgeConfig
+	RequestSetting  *RequestSettingConfig
+	ResponseObject  *ResponseObjectConfig
+	S3              *S3Config
+	Service         *ServiceConfig
+	Settings        *SettingsConfig
+	Syslog          *SyslogConfig
+	Token           *TokenConfig
+	Usage           *UsageConfig
+	User            *UserConfig
+	Version         *VersionConfig
+	VCL             *VCLConfig
+	// apiKey is the Fastly API key to authenticate requests.
+	apiKey string
+
+	rateMu    sync.Mutex
+	rateLimit Rate
+
+	cache       *lookupCache
+	parentCache *lookupCache
+
+	responses       *responseCache
+	parentResponses *responseCache
+
+	// options are applied to every request; see With.
+	options []RequestOption
+
+	tokens tokenState
+}
+
+type Rate struct {
+	Remaining int
+	Reset     time.Time
+}
+
+type config struct {
+	client *Client
+}
+
+// NewClient returns a new Fastly API client. If a nil httpClient is provided,
+// http.DefaultClient will be used.
+func NewClient(httpClient *http.Client, key string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	baseURL, _ := url.Parse(defaultBaseURL)
+
+	c := &Client{client: httpClient, BaseURL: baseURL, UserAgent: userAgent}
+	c.common.client = c
+	c.ACL = (*ACLConfig)(&c.common)
+	c.ACLEntry = (*ACLEntryConfig)(&c.common)
+	c.Backend = (*BackendConfig)(&c.common)
+	c.Billing = (*BillingConfig)(&c.common)
+	c.CacheSetting = (*CacheSettingConfig)(&c.common)
+	c.Condition = (*ConditionConfig)(&c.common)
+	c.Datacenter = (*DatacenterConfig)(&c.common)
+	c.Dictionary = (*DictionaryConfig)(&c.common)
+	c.DictionaryItem = (*DictionaryItemConfig)(&c.common)
+	c.Diff = (*DiffConfig)(&c.common)
+	c.Director = (*DirectorConfig)(&c.common)
+	c.DirectorBackend = (*DirectorBackendConfig)(&c.common)
+	c.Domain = (*DomainConfig)(&c.common)
+	c.DomainInspector = (*DomainInspectorConfig)(&c.common)
+	c.Event = (*EventConfig)(&c.common)
+
+	c.Gzip = (*GzipConfig)(&c.common)
+	c.Header = (*HeaderConfig)(&c.common)
+	c.HealthCheck = (*HealthCheckConfig)(&c.common)
+	c.OriginInspector = (*OriginInspectorConfig)(&c.common)
+	c.Purge = (*PurgeConfig)(&c.common)
+	c.RequestSetting = (*RequestSettingConfig)(&c.common)
+	c.ResponseObject = (*ResponseObjectConfig)(&c.common)
+	c.S3 = (*S3Config)(&c.common)
+	c.Service = (*ServiceConfig)(&c.common)
+	c.Settings = (*SettingsConfig)(&c.common)
+	c.Syslog = (*SyslogConfig)(&c.common)
+	c.Token = (*TokenConfig)(&c.common)
+	c.Usage = (*UsageConfig)(&c.common)
+	c.User = (*UserConfig)(&c.common)
+	c.Version = (*VersionConfig)(&c.common)
+	c.VCL = (*VCLConfig)(&c.common)
+	c.apiKey = key
+	return c
+}
+
+// NewRequest creates an API request. A relative URL can be provided in urlStr,
+// in which case it is resolved relative to the BaseURL of the Client.
+func (c *Client) NewRequest(method, urlStr string, body io.Reader) (*http.Request, error) {
+	// Paths are relative to BaseURL, which may have a path of its own,
+	// such as that of a proxy in front of the API.
+	rel, err := url.Parse(strings.TrimPrefix(urlStr, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.BaseURL.ResolveReference(rel)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	req.Header.Set("Fastly-Key", c.apiKey)
+	for _, opt := range c.options {
+		opt(req)
+	}
+	return req, nil
+}
+
+// NewJSONRequest creates an http.Request with a JSON body for use with the
+// fastly API. The item passed in `body` will be Marshalled into JSON.
+func (c *Client) NewJSONRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	var buf io.ReadWriter
+	if body != nil {
+		buf = new(bytes.Buffer)
+		err := json.NewEncoder(buf).Encode(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.NewRequest(method, urlStr, buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// NewFormRequest creates an http.Request with a form encoded body, for the
+// endpoints which don't accept JSON. The fields of `body` are encoded as
+// described by EncodeForm.
+func (c *Client) NewFormRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	values, err := EncodeForm(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.NewRequest(method, urlStr, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
+
+// Do sends an API request and returns the response. The response is JSON
+// decoded and stored in the value pointed to by v, or returned as an error if
+// an API error has occurred.
+// If rate limit is exceeded and reset time is in the future, Do returns
+// *RateLimitError immediately without making a network API call.
+func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
+	if c.ReadOnly && req.Method != "GET" && req.Method != "HEAD" {
+		return nil, &ReadOnlyError{Method: req.Method, URL: req.URL}
+	}
+
+	// If we've hit rate limit, don't make further requests before Reset time.
+	if err := c.checkRateLimitBeforeDo(req); err != nil {
+		c.Hooks.rateLimited(req, err)
+		return nil, err
+	}
+
+	cacheable := req.Method == "GET" && c.responses != nil
+	var resp *http.Response
+	if cacheable {
+		resp = c.responses.lookup(req)
+	}
+	if resp == nil {
+		var err error
+		start := time.Now()
+		resp, err = c.client.Do(req)
+		c.Hooks.request(req, resp, time.Since(start), err)
+		if req.Method != "GET" && req.Method != "HEAD" {
+			// Anything may have been modified, including by a failed request.
+			c.invalidateCache()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rate := parseRate(resp)
+		if rate != (Rate{}) {
+			c.rateMu.Lock()
+			c.rateLimit = rate
+			c.rateMu.Unlock()
+		}
+
+		if cacheable {
+			if resp, err = c.responses.update(req, resp); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	defer func() {
+		// Drain up to 512 bytes and close the body to let the Transport reuse the connection
+		io.CopyN(ioutil.Discard, resp.Body, 512)
+		resp.Body.Close()
+	}()
+
+	err := CheckResponse(resp)
+	if err != nil {
+		if e, ok := err.(*ScopeError); ok {
+			c.explainForbidden(e)
+		}
+		// return response regardless for caller inspection
+		return resp, err
+	}
+
+	if v != nil {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err == io.EOF {
+			err = nil // ignore EOF errors caused by empty response body
+		}
+	}
+
+	return resp, err
+}
+
+// DoPage makes a request for a page of results, decoding them into v as Do
+// does, and returns the URL of the next page named in the response's Link
+// header, or "" if it is the last.
+func (c *Client) DoPage(req *http.Request, v interface{}) (*http.Response, string, error) {
+	resp, err := c.Do(req, v)
+	if err != nil {
+		return resp, "", err
+	}
+	return resp, NextPage(resp), nil
+}
+
+// doAll makes a request for a list of results, following the Link headers of
+// the responses to any further pages and appending their results to the slice
+// v points to. If the request names a page, only that page is fetched. The
+// last response is returned.
+func (c *Client) doAll(req *http.Request, v interface{}) (*http.Response, error) {
+	if req.URL.Query().Get("page") != "" {
+		return c.Do(req, v)
+	}
+	results := reflect.ValueOf(v).Elem()
+	resp, next, err := c.DoPage(req, v)
+	seen := map[string]bool{req.URL.String(): true}
+	for err == nil && next != "" && !seen[next] {
+		seen[next] = true
+		var path string
+		if path, err = c.pageURL(next); err != nil {
+			return resp, err
+		}
+		if req, err = c.NewRequest("GET", path, nil); err != nil {
+			return resp, err
+		}
+		page := reflect.New(results.Type())
+		if resp, next, err = c.DoPage(req, page.Interface()); err == nil {
+			results.Set(reflect.AppendSlice(results, page.Elem()))
+		}
+	}
+	return resp, err
+}
+
+// pageURL returns next, the URL of a further page of results, relative to
+// BaseURL. Links are only followed to BaseURL, or to the API itself, whose
+// links a proxy at BaseURL may pass on unchanged, so that every page is
+// fetched through BaseURL and the key is never sent to another host.
+func (c *Client) pageURL(next string) (string, error) {
+	u, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("Invalid link to the next page %q: %s", next, err)
+	}
+	u = c.BaseURL.ResolveReference(u)
+	api, _ := url.Parse(defaultBaseURL)
+	for _, base := range []*url.URL{c.BaseURL, api} {
+		if u.Scheme == base.Scheme && u.Host == base.Host && strings.HasPrefix(u.Path, base.Path) {
+			rel := &url.URL{Path: strings.TrimPrefix(u.Path, base.Path), RawQuery: u.RawQuery}
+			return rel.String(), nil
+		}
+	}
+	return "", fmt.Errorf("Refusing to follow the link to the next page on %s://%s, which is not the API's host", u.Scheme, u.Host)
+}
+
+// NextPage returns the URL of the next page of results named in the Link
+// header of resp, or "" if there is none.
+func NextPage(resp *http.Response) string {
+	return ParseLinks(strings.Join(resp.Header.Values("Link"), ","))["next"]
+}
+
+// ParseLinks parses a Link header, as described by RFC 5988, returning the
+// target of each link by its relation type.
+func ParseLinks(header string) map[string]string {
+	links := make(map[string]string)
+	for {
+		start := strings.Index(header, "<")
+		end := strings.Index(header, ">")
+		if start < 0 || end < start {
+			return links
+		}
+		target := header[start+1 : end]
+		header = header[end+1:]
+		params := header
+		if next := strings.Index(header, "<"); next >= 0 {
+			params, header = header[:next], header[next:]
+		}
+		for _, param := range strings.Split(params, ";") {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(kv[1], `" ,`)) {
+				links[strings.ToLower(rel)] = target
+			}
+		}
+	}
+}
+
+// CheckResponse takes in an HTTP response containing a JSON-encoded error,
+// unmarshals the error, and returns it. Assumes no error if status code is
+// successful.
+// The error type will be *RateLimitError for rate limit exceeded errors,
+// and *ScopeError for forbidden requests.
+func CheckResponse(r *http.Response) error {
+	if c := r.StatusCode; 200 <= c && c <= 299 {
+		return nil
+	}
+	errorResponse := &ErrorResponse{Response: r}
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil && data != nil {
+		json.Unmarshal(data, errorResponse)
+	}
+
+	if c := r.StatusCode; c == 429 {
+		return &RateLimitError{
+			Rate:     parseRate(r),
+			Response: errorResponse.Response,
+			Message:  errorResponse.Message,
+		}
+	}
+
+	// 401 Unauthorized
+	// {"msg":"Provided credentials are missing or invalid"}
+	// 400 Bad Request
+	// {"msg":{"error":"2fa.verify","error_description":"Invalid one-time password."}}
+	// 403 Forbidden
+	// {"msg":"You are not authorized to perform this action"}
+
+	// The API doesn't say why a request is forbidden, which is usually
+	// that the token lacks a scope.
+	if r.StatusCode == http.StatusForbidden && r.Request != nil {
+		return &ScopeError{ErrorResponse: errorResponse, Required: RequiredScope(r.Request)}
+	}
+
+	return errorResponse
+}
+
+func parseRate(resp *http.Response) Rate {
+	var rate Rate
+	if remaining := resp.Header.Get(headerRateLimitRemaining); remaining != "" {
+		rate.Remaining, _ = strconv.Atoi(remaining)
+	}
+
+	if reset := resp.Header.Get(headerRateLimitReset); reset != "" {
+		if v, _ := strconv.ParseInt(reset, 10, 64); v != 0 {
+			rate.Reset = time.Unix(v, 0)
+		}
+	}
+
+	return rate
+}
+
+// checkRateLimitBeforeDo does not make any network calls, but uses existing knowledge from
+// current client state in order to quickly check if *RateLimitError can be immediately returned
+// from Client.Do, and if so, returns it so that Client.Do can skip making a network API call unnecessarily.
+// Otherwise it returns nil, and Client.Do should proceed normally.
+func (c *Client) checkRateLimitBeforeDo(req *http.Request) error {
+	// GETs and HEADs are not ratelimited
+	if req.Method == "GET" || req.Method == "HEAD" {
+		return nil
+	}
+	c.rateMu.Lock()
+	rate := c.rateLimit
+	c.rateMu.Unlock()
+	if !rate.Reset.IsZero() && rate.Remaining == 0 && time.Now().Before(rate.Reset) {
+		// Create a fake response.
+		resp := &http.Response{
+			Status:     http.StatusText(http.StatusForbidden),
+			StatusCode: http.StatusForbidden,
+			Request:    req,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+		return &RateLimitError{
+			Rate:     rate,
+			Response: resp,
+			Message:  fmt.Sprintf("API rate limit still exceeded until %v, not making remote request.", rate.Reset),
+		}
+	}
+
+	return nil
+}
+
+// RateLimitError occurs when Fastly returns 403 Forbidden response with a rate limit
+// remaining value of 0, and error message starts with "API rate limit exceeded for ".
+type RateLimitError struct {
+	Rate     Rate           // Rate specifies last known rate limit for the client
+	Response *http.Response // HTTP response that caused this error
+	Message  string         `json:"message"` // error message
+}
+
+func (r *RateLimitError) Error() string {
+	return fmt.Sprintf("%v %v: %d %v; rate reset in %v",
+		r.Response.Request.Method, r.Response.Request.URL,
+		r.Response.StatusCode, r.Message, r.Rate.Reset.Sub(time.Now()))
+}
+
+// RateLimits returns the rate limit for the current client. If a ratelimit
+// response has yet to be seen, returns nil.
+func (c *Client) RateLimit() *Rate {
+	c.rateMu.Lock()
+	rate := c.rateLimit
+	c.rateMu.Unlock()
+
+	if rate == (Rate{}) {
+		return nil
+	}
+
+	return &rate
+}
+
+// ReadOnlyError is returned for requests which would modify something, made
+// by a client in read-only mode.
+type ReadOnlyError struct {
+	Method string
+	URL    *url.URL
+}
+
+func (r *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%v %v: refused in read-only mode", r.Method, r.URL)
+}
+
+// ErrorResponse represents the error message sent back from Fastly.
+type ErrorResponse struct {
+	Response *http.Response // The response that held this error
+	Message  string         `json:"msg"`
+	Detail   string         `json:"detail"`
+	//	Message  *struct {
+	//	    Error string  `json:"error,omitempty"`
+	//	    ErrorDescription string  `json:"error_description,omitempty"`
+	//	} `json:"msg"`
+}
+
+// Error generates an error message based on an ErrorResponse.
+func (r *ErrorResponse) Error() string {
+	return fmt.Sprintf("%v %v: %d %v %v",
+		r.Response.Request.Method, r.Response.Request.URL,
+		r.Response.StatusCode, r.Message, r.Detail)
+}
@@ -0,0 +1,51 @@
+package fastly
+
+import (
+	"net/http"
+	"sort"
+)
+
+type DatacenterConfig config
+
+// Datacenter describes a Fastly POP.
+type Datacenter struct {
+	Code        string      `json:"code"`
+	Name        string      `json:"name"`
+	Group       string      `json:"group"`
+	Shield      string      `json:"shield"`
+	Coordinates Coordinates `json:"coordinates"`
+}
+
+// Coordinates locates a datacenter.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// datacentersByCode is a sortable list of datacenters.
+type datacentersByCode []*Datacenter
+
+// Len, Swap, and Less implement the sortable interface.
+func (s datacentersByCode) Len() int      { return len(s) }
+func (s datacentersByCode) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s datacentersByCode) Less(i, j int) bool {
+	return s[i].Code < s[j].Code
+}
+
+// List all Fastly datacenters.
+func (c *DatacenterConfig) List() ([]*Datacenter, *http.Response, error) {
+	req, err := c.client.NewRequest("GET", "/datacenters", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	datacenters := new([]*Datacenter)
+	resp, err := c.client.doAll(req, datacenters)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	sort.Stable(datacentersByCode(*datacenters))
+
+	return *datacenters, resp, nil
+}
@@ -0,0 +1,49 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type DiffConfig config
+
+type Diff struct {
+	// Read only
+	ServiceID string `json:"service_id,omitempty"`
+	Diff      string
+
+	// Read/Write
+	FromVersion uint       `json:"from,omitempty"`
+	ToVersion   uint       `json:"to,omitempty"`
+	Format      DiffFormat `json:"format,omitempty"`
+}
+
+type DiffFormat string
+
+const (
+	DiffFormatText       = "text"
+	DiffFormatHTML       = "html"
+	DiffFormatHTMLSimple = "html_simple"
+)
+
+// Get fetches the diff between two versions of a service, in format, or in
+// Fastly's default text format if format is empty.
+func (c *DiffConfig) Get(serviceID string, from, to uint, format DiffFormat) (*Diff, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/diff/from/%d/to/%d", serviceID, from, to)
+	if format != "" {
+		u += "?format=" + url.QueryEscape(string(format))
+	}
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	diff := new(Diff)
+	resp, err := c.client.Do(req, diff)
+	if err != nil {
+		return nil, resp, err
+	}
+	return diff, resp, nil
+}
@@ -0,0 +1,173 @@
+package fastly
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DirectorConfig config
+
+// DirectorType is the way a director chooses among its backends.
+type DirectorType uint8
+
+const (
+	DirectorTypeRandom DirectorType = 1
+	DirectorTypeHash   DirectorType = 3
+	DirectorTypeClient DirectorType = 4
+)
+
+// UnmarshalText accepts director types by name, such as "random", or by the
+// number the API uses for them.
+func (t *DirectorType) UnmarshalText(b []byte) error {
+	switch strings.ToLower(string(b)) {
+	case "random", "1":
+		*t = DirectorTypeRandom
+	case "hash", "3":
+		*t = DirectorTypeHash
+	case "client", "4":
+		*t = DirectorTypeClient
+	case "", "0":
+		*t = 0
+	default:
+		return fmt.Errorf("Unknown director type %q. Must be one of random, hash, or client.", b)
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts the numbers returned by the API, as well as the names
+// UnmarshalText does.
+func (t *DirectorType) UnmarshalJSON(b []byte) error {
+	return t.UnmarshalText(bytes.Trim(b, `"`))
+}
+
+func (t DirectorType) String() string {
+	switch t {
+	case DirectorTypeRandom:
+		return "random"
+	case DirectorTypeHash:
+		return "hash"
+	case DirectorTypeClient:
+		return "client"
+	}
+	return strconv.Itoa(int(t))
+}
+
+type Director struct {
+	ServiceID string `json:"service_id,omitempty"`
+	Version   uint   `json:"version,string,omitempty"`
+
+	Name    string       `json:"name,omitempty"`
+	Comment string       `json:"comment"`
+	Shield  string       `json:"shield"`
+	Quorum  uint         `json:"quorum"`
+	Type    DirectorType `json:"type"`
+	Retries uint         `json:"retries"`
+
+	// Backends names the members of the director. It is read-only, as
+	// members are added and removed with DirectorBackendConfig.
+	Backends []string `json:"backends,omitempty"`
+}
+
+// directorsByName is a sortable list of directors.
+type directorsByName []*Director
+
+// Len, Swap, and Less implement the sortable interface.
+func (s directorsByName) Len() int      { return len(s) }
+func (s directorsByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s directorsByName) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
+
+// List directors for a specific service and version.
+func (c *DirectorConfig) List(serviceID string, version uint) ([]*Director, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director", serviceID, version)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	directors := new([]*Director)
+	resp, err := c.client.doAll(req, directors)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	sort.Stable(directorsByName(*directors))
+
+	return *directors, resp, nil
+}
+
+// Get fetches a specific director by name.
+func (c *DirectorConfig) Get(serviceID string, version uint, name string) (*Director, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director/%s", serviceID, version, name)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	director := new(Director)
+	resp, err := c.client.Do(req, director)
+	if err != nil {
+		return nil, resp, err
+	}
+	return director, resp, nil
+}
+
+// Create a new director.
+func (c *DirectorConfig) Create(serviceID string, version uint, director *Director) (*Director, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director", serviceID, version)
+
+	req, err := c.client.NewJSONRequest("POST", u, director)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(Director)
+	resp, err := c.client.Do(req, b)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return b, resp, nil
+}
+
+// Update a director
+func (c *DirectorConfig) Update(serviceID string, version uint, name string, director *Director) (*Director, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director/%s", serviceID, version, name)
+
+	req, err := c.client.NewJSONRequest("PUT", u, director)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(Director)
+	resp, err := c.client.Do(req, b)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return b, resp, nil
+}
+
+// Delete a director
+func (c *DirectorConfig) Delete(serviceID string, version uint, name string) (*http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director/%s", serviceID, version, name)
+
+	req, err := c.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req, nil)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,69 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type DirectorBackendConfig config
+
+// DirectorBackend is the membership of a backend in a director.
+type DirectorBackend struct {
+	ServiceID string `json:"service_id,omitempty"`
+	Version   uint   `json:"version,string,omitempty"`
+
+	Director string `json:"director,omitempty"`
+	Backend  string `json:"backend,omitempty"`
+}
+
+// Get fetches the membership of a backend in a director.
+func (c *DirectorBackendConfig) Get(serviceID string, version uint, director, backend string) (*DirectorBackend, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director/%s/backend/%s", serviceID, version, director, backend)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(DirectorBackend)
+	resp, err := c.client.Do(req, b)
+	if err != nil {
+		return nil, resp, err
+	}
+	return b, resp, nil
+}
+
+// Create adds a backend to a director.
+func (c *DirectorBackendConfig) Create(serviceID string, version uint, director, backend string) (*DirectorBackend, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director/%s/backend/%s", serviceID, version, director, backend)
+
+	req, err := c.client.NewRequest("POST", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(DirectorBackend)
+	resp, err := c.client.Do(req, b)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return b, resp, nil
+}
+
+// Delete removes a backend from a director.
+func (c *DirectorBackendConfig) Delete(serviceID string, version uint, director, backend string) (*http.Response, error) {
+	u := fmt.Sprintf("/service/%s/version/%d/director/%s/backend/%s", serviceID, version, director, backend)
+
+	req, err := c.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req, nil)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
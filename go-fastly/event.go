@@ -0,0 +1,114 @@
+package fastly
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type EventConfig config
+
+// Event types recorded for version activations.
+const (
+	EventVersionActivate   = "version_activate"
+	EventVersionDeactivate = "version_deactivate"
+)
+
+// Event is an entry in the audit log of an account, such as the activation of
+// a version of a service.
+type Event struct {
+	ID          string                 `json:"-"`
+	Type        string                 `json:"event_type"`
+	Description string                 `json:"description"`
+	ServiceID   string                 `json:"service_id"`
+	UserID      string                 `json:"user_id"`
+	Admin       bool                   `json:"admin"`
+	IP          string                 `json:"ip"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
+// Version returns the number of the version an event concerns, or 0 if it
+// isn't about a version.
+func (e *Event) Version() uint {
+	switch v := e.Metadata["version"].(type) {
+	case float64:
+		return uint(v)
+	case string:
+		n, _ := strconv.ParseUint(v, 10, 32)
+		return uint(n)
+	}
+	return 0
+}
+
+// EventListInput filters the events returned by List. Unset fields don't
+// filter.
+type EventListInput struct {
+	ServiceID string
+	Type      string
+	// Since excludes events before it.
+	Since time.Time
+	// PageSize is the number of events fetched in each request.
+	PageSize int
+}
+
+func (i *EventListInput) values() url.Values {
+	v := url.Values{}
+	v.Set("sort", "created_at")
+	if i.ServiceID != "" {
+		v.Set("filter[service_id]", i.ServiceID)
+	}
+	if i.Type != "" {
+		v.Set("filter[event_type]", i.Type)
+	}
+	if !i.Since.IsZero() {
+		v.Set("filter[created_at][gte]", i.Since.UTC().Format(time.RFC3339))
+	}
+	if i.PageSize > 0 {
+		v.Set("page[size]", strconv.Itoa(i.PageSize))
+	}
+	return v
+}
+
+// eventsPage is a page of events, in the JSON:API form the events API uses.
+type eventsPage struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes Event  `json:"attributes"`
+	} `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// List returns the events matching i, oldest first, following the pages of
+// the results to their end.
+func (c *EventConfig) List(i *EventListInput) ([]*Event, *http.Response, error) {
+	if i == nil {
+		i = new(EventListInput)
+	}
+	u := "/events?" + i.values().Encode()
+
+	var events []*Event
+	var resp *http.Response
+	seen := make(map[string]bool)
+	for u != "" && !seen[u] {
+		seen[u] = true
+		req, err := c.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, resp, err
+		}
+		page := new(eventsPage)
+		if resp, err = c.client.Do(req, page); err != nil {
+			return nil, resp, err
+		}
+		for _, d := range page.Data {
+			e := d.Attributes
+			e.ID = d.ID
+			events = append(events, &e)
+		}
+		u = page.Links.Next
+	}
+	return events, resp, nil
+}
@@ -0,0 +1,76 @@
+package fastlytest
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decodeBody decodes a request body into v, which is a pointer to a go-fastly
+// resource. Form bodies set the fields named by their url tags, as encoded by
+// fastly.EncodeForm, and any other body is read as JSON.
+func decodeBody(r *http.Request, v interface{}) error {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/x-www-form-urlencoded" {
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("url"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if _, ok := r.PostForm[name]; !ok {
+			continue
+		}
+		if err := setFormField(rv.Field(i), r.PostForm.Get(name)); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func setFormField(f reflect.Value, value string) error {
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Ptr:
+		p := reflect.New(f.Type().Elem())
+		if err := setFormField(p.Elem(), value); err != nil {
+			return err
+		}
+		f.Set(p)
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
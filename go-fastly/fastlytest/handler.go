@@ -0,0 +1,899 @@
+package fastlytest
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alienth/go-fastly"
+)
+
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func notFound(format string, args ...interface{}) error {
+	return &apiError{http.StatusNotFound, fmt.Sprintf(format, args...)}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	var result interface{}
+	var err error
+	key := r.Header.Get(fastly.IdempotencyKeyHeader)
+	if forbidden := s.forbid(r); forbidden != nil {
+		err = forbidden
+	} else if previous, ok := s.idempotent[key]; ok && r.Method == "POST" {
+		result = previous
+	} else {
+		result, err = s.route(r)
+		if err == nil && r.Method == "POST" && key != "" {
+			s.idempotent[key] = result
+		}
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if e, ok := err.(*apiError); ok {
+			status = e.status
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if page, ok := result.(*pagedResult); ok {
+		if page.next != "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, page.next))
+		}
+		result = page.items
+	}
+	if result == nil {
+		result = map[string]string{"status": "ok"}
+	}
+	body, _ := json.Marshal(result)
+	if r.Method == "GET" {
+		// Like the real API, GETs carry an ETag and honor If-None-Match.
+		etag := fmt.Sprintf(`"%x"`, sha1.Sum(body))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Write(append(body, '\n'))
+}
+
+// forbid returns an error for requests which Token doesn't allow.
+func (s *Server) forbid(r *http.Request) error {
+	if s.Token == nil || r.URL.Path == "/tokens/self" {
+		return nil
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	covered := true
+	if len(parts) >= 2 && parts[0] == "service" {
+		// Like the real API, services which don't exist aren't found.
+		if _, ok := s.services[parts[1]]; ok {
+			covered = s.Token.CoversService(parts[1])
+		}
+	}
+	if !s.Token.HasScope(fastly.RequiredScope(r)) || !covered {
+		return &apiError{http.StatusForbidden, "You are not authorized to perform this action"}
+	}
+	return nil
+}
+
+func (s *Server) route(r *http.Request) (interface{}, error) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 2 && parts[0] == "tokens" && parts[1] == "self" && r.Method == "GET" {
+		if s.Token == nil {
+			return nil, notFound("no token")
+		}
+		token := *s.Token
+		return &token, nil
+	}
+	if len(parts) == 1 && parts[0] == "datacenters" && r.Method == "GET" {
+		out := append([]fastly.Datacenter{}, s.Datacenters...)
+		return out, nil
+	}
+	if parts[0] == "billing" && r.Method == "GET" {
+		if len(parts) != 6 || parts[1] != "v2" || parts[2] != "year" || parts[4] != "month" {
+			return nil, notFound("unknown path %s", r.URL.Path)
+		}
+		invoice, ok := s.Invoices[parts[3]+"-"+parts[5]]
+		if !ok {
+			return nil, notFound("no invoice for %s-%s", parts[3], parts[5])
+		}
+		return invoice, nil
+	}
+	if len(parts) == 2 && parts[0] == "stats" && parts[1] == "usage_by_month" && r.Method == "GET" {
+		month := r.URL.Query().Get("year") + "-" + r.URL.Query().Get("month")
+		usage, ok := s.Usage[month]
+		if !ok {
+			return nil, notFound("no usage for %s", month)
+		}
+		return map[string]interface{}{"status": "success", "data": usage}, nil
+	}
+	if len(parts) == 4 && parts[0] == "metrics" && parts[2] == "services" && r.Method == "GET" {
+		switch parts[1] {
+		case "origins":
+			return &fastly.MetricsResponse{Data: s.OriginMetrics[parts[3]]}, nil
+		case "domains":
+			return &fastly.MetricsResponse{Data: s.DomainMetrics[parts[3]]}, nil
+		}
+		return nil, notFound("unknown path %s", r.URL.Path)
+	}
+	if len(parts) == 1 && parts[0] == "events" && r.Method == "GET" {
+		return s.listEvents(r), nil
+	}
+	if len(parts) == 2 && parts[0] == "user" && r.Method == "GET" {
+		user, ok := s.Users[parts[1]]
+		if !ok {
+			return nil, notFound("Record not found: user %s", parts[1])
+		}
+		return &user, nil
+	}
+	if parts[0] == "purge" && len(parts) > 1 && r.Method == "POST" {
+		s.purges = append(s.purges, strings.TrimPrefix(r.URL.Path, "/purge/"))
+		return &fastly.Purge{Status: "ok", ID: s.newID()}, nil
+	}
+	if parts[0] != "service" {
+		return nil, notFound("unknown path %s", r.URL.Path)
+	}
+	parts = parts[1:]
+
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			return s.page(r, s.listServices()), nil
+		case "POST":
+			return s.createService(r)
+		}
+		return nil, badRequest("unsupported method %s", r.Method)
+	}
+	if parts[0] == "search" {
+		return s.searchService(r.URL.Query().Get("name"))
+	}
+
+	svc, ok := s.services[parts[0]]
+	if !ok {
+		return nil, notFound("unknown service %s", parts[0])
+	}
+	parts = parts[1:]
+
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			return copyService(svc.Service), nil
+		case "PUT":
+			if err := json.NewDecoder(r.Body).Decode(svc.Service); err != nil {
+				return nil, badRequest("%s", err)
+			}
+			return copyService(svc.Service), nil
+		case "DELETE":
+			delete(s.services, svc.ID)
+			return nil, nil
+		}
+		return nil, badRequest("unsupported method %s", r.Method)
+	}
+
+	switch parts[0] {
+	case "details":
+		if len(parts) != 1 || r.Method != "GET" {
+			return nil, notFound("unknown path %s", r.URL.Path)
+		}
+		return serviceDetails(svc), nil
+	case "version":
+		return s.routeVersion(r, svc, parts[1:])
+	case "diff":
+		if len(parts) != 5 || parts[1] != "from" || parts[3] != "to" {
+			return nil, notFound("unknown path %s", r.URL.Path)
+		}
+		return s.diff(svc, parts[2], parts[4], fastly.DiffFormat(r.URL.Query().Get("format")))
+	case "dictionary":
+		return s.routeDictionaryItems(r, svc, parts[1:])
+	case "acl":
+		return s.routeACLEntries(r, svc, parts[1:])
+	}
+	return nil, notFound("unknown path %s", r.URL.Path)
+}
+
+func serviceDetails(svc *service) *fastly.ServiceDetails {
+	c := copyService(svc.Service)
+	details := &fastly.ServiceDetails{ID: c.ID, Name: c.Name, Comment: c.Comment, CustomerID: c.CustomerID, Versions: c.Versions}
+	for _, v := range c.Versions {
+		if v.Active {
+			details.Active = v
+			details.Environments = []*fastly.Environment{{Name: "production", ServiceID: c.ID, ActiveVersion: v.Number}}
+		}
+		if details.Version == nil || v.Number > details.Version.Number {
+			details.Version = v
+		}
+	}
+	return details
+}
+
+func (s *Server) listServices() []*fastly.Service {
+	var out []*fastly.Service
+	for _, svc := range s.services {
+		out = append(out, copyService(svc.Service))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *Server) createService(r *http.Request) (interface{}, error) {
+	in := new(fastly.Service)
+	if err := json.NewDecoder(r.Body).Decode(in); err != nil {
+		return nil, badRequest("%s", err)
+	}
+	return s.addService(in.Name), nil
+}
+
+func (s *Server) searchService(name string) (interface{}, error) {
+	for _, svc := range s.services {
+		if svc.Name == name {
+			return copyService(svc.Service), nil
+		}
+	}
+	return nil, notFound("Record not found")
+}
+
+func (s *Server) routeVersion(r *http.Request, svc *service, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			return copyService(svc.Service).Versions, nil
+		case "POST":
+			return s.newVersion(svc, newVersionState()), nil
+		}
+		return nil, badRequest("unsupported method %s", r.Method)
+	}
+
+	number, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, badRequest("invalid version %s", parts[0])
+	}
+	meta := findVersion(svc, uint(number))
+	state, ok := svc.versions[uint(number)]
+	if !ok || meta == nil {
+		return nil, notFound("unknown version %d", number)
+	}
+	parts = parts[1:]
+
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			out := *meta
+			return &out, nil
+		case "PUT":
+			if meta.Locked {
+				return nil, badRequest("Version %d is locked", number)
+			}
+			update := *meta
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				return nil, badRequest("%s", err)
+			}
+			meta.Comment = update.Comment
+			out := *meta
+			return &out, nil
+		}
+		return nil, badRequest("unsupported method %s", r.Method)
+	}
+
+	switch parts[0] {
+	case "clone":
+		return s.newVersion(svc, cloneVersionState(state)), nil
+	case "activate":
+		if len(parts) > 1 {
+			if parts[1] != "staging" {
+				return nil, notFound("unknown environment %s", parts[1])
+			}
+			for _, v := range svc.Versions {
+				v.Staging = false
+			}
+			meta.Staging = true
+			meta.Locked = true
+			out := *meta
+			return &out, nil
+		}
+		for _, v := range svc.Versions {
+			v.Active = false
+		}
+		meta.Active = true
+		meta.Locked = true
+		meta.Deployed = true
+		svc.Version = meta.Number
+		s.logEvent(svc, fastly.EventVersionActivate, meta.Number, fmt.Sprintf("Version %d was activated", meta.Number))
+		out := *meta
+		return &out, nil
+	case "deactivate":
+		if len(parts) > 1 {
+			if parts[1] != "staging" {
+				return nil, notFound("unknown environment %s", parts[1])
+			}
+			meta.Staging = false
+			out := *meta
+			return &out, nil
+		}
+		meta.Active = false
+		s.logEvent(svc, fastly.EventVersionDeactivate, meta.Number, fmt.Sprintf("Version %d was deactivated", meta.Number))
+		out := *meta
+		return &out, nil
+	case "lock":
+		meta.Locked = true
+		out := *meta
+		return &out, nil
+	case "validate":
+		if s.Validate != nil {
+			if resp := s.Validate(svc.ID, meta.Number); resp != nil {
+				return resp, nil
+			}
+		}
+		return &fastly.ValidateResponse{Status: "ok"}, nil
+	case "generated_vcl":
+		return &fastly.VCL{ServiceID: svc.ID, Version: meta.Number, Content: generateVCL(state), Main: true}, nil
+	case "settings":
+		switch r.Method {
+		case "GET":
+			out := *state.settings
+			out.ServiceID = svc.ID
+			out.Version = meta.Number
+			return &out, nil
+		case "PUT":
+			if meta.Locked {
+				return nil, badRequest("Version %d is locked", number)
+			}
+			if err := decodeBody(r, state.settings); err != nil {
+				return nil, badRequest("%s", err)
+			}
+			out := *state.settings
+			out.ServiceID = svc.ID
+			out.Version = meta.Number
+			return &out, nil
+		}
+		return nil, badRequest("unsupported method %s", r.Method)
+	}
+
+	kind := parts[0]
+	parts = parts[1:]
+	if kind == "logging" && len(parts) > 0 {
+		kind = kind + "/" + parts[0]
+		parts = parts[1:]
+	}
+	if _, ok := kinds[kind]; !ok {
+		return nil, notFound("unknown resource %s", kind)
+	}
+	if kind == "dictionary" && len(parts) == 2 && parts[1] == "info" {
+		return s.dictionaryInfo(svc, state, parts[0])
+	}
+	if r.Method != "GET" && meta.Locked {
+		return nil, badRequest("Version %d is locked", number)
+	}
+	if kind == "director" && len(parts) == 3 && parts[1] == "backend" {
+		return s.directorBackend(r, svc, meta.Number, state, parts[0], parts[2])
+	}
+	return s.routeResource(r, svc, meta.Number, state, kind, parts)
+}
+
+// directorBackend adds, finds, or removes backend among the members of a
+// director. Members are listed in the director's Backends, which is replaced
+// rather than modified, as clones of a version share it.
+func (s *Server) directorBackend(r *http.Request, svc *service, number uint, state *versionState, name, backend string) (interface{}, error) {
+	rv, ok := state.resources["director"][name]
+	if !ok {
+		return nil, notFound("Record not found: director %s", name)
+	}
+	if _, ok := state.resources["backend"][backend]; !ok {
+		return nil, notFound("Record not found: backend %s", backend)
+	}
+	director := rv.Interface().(*fastly.Director)
+	var member bool
+	var others []string
+	for _, b := range director.Backends {
+		if b == backend {
+			member = true
+		} else {
+			others = append(others, b)
+		}
+	}
+	out := &fastly.DirectorBackend{ServiceID: svc.ID, Version: number, Director: name, Backend: backend}
+	switch r.Method {
+	case "GET":
+		if !member {
+			return nil, notFound("Record not found: backend %s of director %s", backend, name)
+		}
+		return out, nil
+	case "POST":
+		if member {
+			return nil, badRequest("Duplicate record: backend %s of director %s already exists", backend, name)
+		}
+		director.Backends = append(others, backend)
+		return out, nil
+	case "DELETE":
+		if !member {
+			return nil, notFound("Record not found: backend %s of director %s", backend, name)
+		}
+		director.Backends = others
+		return nil, nil
+	}
+	return nil, badRequest("unsupported method %s", r.Method)
+}
+
+func (s *Server) newVersion(svc *service, state *versionState) *fastly.Version {
+	var number uint
+	for n := range svc.versions {
+		if n > number {
+			number = n
+		}
+	}
+	number++
+	svc.versions[number] = state
+	for _, resources := range state.resources {
+		for _, rv := range resources {
+			rv.Elem().FieldByName("Version").SetUint(uint64(number))
+		}
+	}
+	meta := &fastly.Version{ServiceID: svc.ID, Number: number}
+	svc.Versions = append(svc.Versions, meta)
+	out := *meta
+	return &out
+}
+
+func cloneVersionState(state *versionState) *versionState {
+	out := newVersionState()
+	settings := *state.settings
+	out.settings = &settings
+	for kind, resources := range state.resources {
+		out.resources[kind] = make(map[string]reflect.Value)
+		for name, rv := range resources {
+			c := reflect.New(rv.Elem().Type())
+			c.Elem().Set(rv.Elem())
+			out.resources[kind][name] = c
+		}
+	}
+	return out
+}
+
+func findVersion(svc *service, number uint) *fastly.Version {
+	for _, v := range svc.Versions {
+		if v.Number == number {
+			return v
+		}
+	}
+	return nil
+}
+
+func (s *Server) routeResource(r *http.Request, svc *service, number uint, state *versionState, kind string, parts []string) (interface{}, error) {
+	resources := state.resources[kind]
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			out := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(kinds[kind])), 0, len(resources))
+			for _, name := range sortedNames(resources) {
+				out = reflect.Append(out, resources[name])
+			}
+			return out.Interface(), nil
+		case "POST":
+			rv := reflect.New(kinds[kind])
+			if err := decodeBody(r, rv.Interface()); err != nil {
+				return nil, badRequest("%s", err)
+			}
+			name := rv.Elem().FieldByName("Name").String()
+			if _, exists := resources[name]; exists {
+				return nil, badRequest("Duplicate record: %s %s already exists", kind, name)
+			}
+			// The members of a director are managed separately.
+			if d, ok := rv.Interface().(*fastly.Director); ok {
+				d.Backends = nil
+			}
+			s.store(svc.ID, number, state, kind, rv)
+			return rv.Interface(), nil
+		}
+		return nil, badRequest("unsupported method %s", r.Method)
+	}
+
+	name := parts[0]
+	rv, ok := resources[name]
+	if !ok {
+		return nil, notFound("Record not found: %s %s", kind, name)
+	}
+	switch r.Method {
+	case "GET":
+		return rv.Interface(), nil
+	case "PUT":
+		updated := reflect.New(rv.Elem().Type())
+		updated.Elem().Set(rv.Elem())
+		if err := decodeBody(r, updated.Interface()); err != nil {
+			return nil, badRequest("%s", err)
+		}
+		if d, ok := updated.Interface().(*fastly.Director); ok {
+			d.Backends = rv.Interface().(*fastly.Director).Backends
+		}
+		delete(resources, name)
+		s.store(svc.ID, number, state, kind, updated)
+		return updated.Interface(), nil
+	case "DELETE":
+		delete(resources, name)
+		return nil, nil
+	}
+	return nil, badRequest("unsupported method %s", r.Method)
+}
+
+func (s *Server) dictionaryInfo(svc *service, state *versionState, dictionaryID string) (interface{}, error) {
+	for _, rv := range state.resources["dictionary"] {
+		if rv.Elem().FieldByName("ID").String() == dictionaryID {
+			items := svc.items[dictionaryID]
+			return &fastly.DictionaryInfo{ItemCount: uint(len(items)), Digest: itemsDigest(items)}, nil
+		}
+	}
+	return nil, notFound("unknown dictionary %s", dictionaryID)
+}
+
+// itemsDigest returns a digest of the items of a dictionary, which changes
+// whenever they do.
+func itemsDigest(items map[string]*fastly.DictionaryItem) string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha1.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%q=%q\n", key, items[key].Value)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// diff renders a stable textual representation of each version. Identical
+// versions produce identical text, which is all that fastlyctl relies upon.
+// The HTML formats wrap the same text in a pre element.
+func (s *Server) diff(svc *service, from, to string, format fastly.DiffFormat) (interface{}, error) {
+	var rendered [2]string
+	for i, param := range []string{from, to} {
+		number, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, badRequest("invalid version %s", param)
+		}
+		state, ok := svc.versions[uint(number)]
+		if !ok {
+			return nil, notFound("unknown version %d", number)
+		}
+		rendered[i] = render(state)
+	}
+	diff := &fastly.Diff{ServiceID: svc.ID}
+	if rendered[0] == rendered[1] {
+		diff.Diff = rendered[0]
+	} else {
+		diff.Diff = "--- " + from + "\n" + rendered[0] + "+++ " + to + "\n" + rendered[1]
+	}
+	if format == fastly.DiffFormatHTML || format == fastly.DiffFormatHTMLSimple {
+		diff.Diff = "<pre>" + html.EscapeString(diff.Diff) + "</pre>"
+	}
+	return diff, nil
+}
+
+func render(state *versionState) string {
+	var b strings.Builder
+	settings := *state.settings
+	settings.ServiceID = ""
+	settings.Version = 0
+	data, _ := json.Marshal(&settings)
+	fmt.Fprintf(&b, "settings %s\n", data)
+
+	kindNames := make([]string, 0, len(state.resources))
+	for kind := range state.resources {
+		kindNames = append(kindNames, kind)
+	}
+	sort.Strings(kindNames)
+	for _, kind := range kindNames {
+		for _, name := range sortedNames(state.resources[kind]) {
+			rv := reflect.New(kinds[kind])
+			rv.Elem().Set(state.resources[kind][name].Elem())
+			rv.Elem().FieldByName("ServiceID").SetString("")
+			rv.Elem().FieldByName("Version").SetUint(0)
+			if f := rv.Elem().FieldByName("ID"); f.IsValid() {
+				f.SetString("")
+			}
+			data, _ := json.Marshal(rv.Interface())
+			fmt.Fprintf(&b, "%s %s\n", kind, data)
+		}
+	}
+	return b.String()
+}
+
+// pagedResult is a page of a list, and the URL of the next page if there is
+// one, which serveHTTP names in a Link header as the real API does.
+type pagedResult struct {
+	items interface{}
+	next  string
+}
+
+// page returns the page of the results in the slice out requested by the
+// page and per_page query parameters. If no page is requested, all results
+// are returned unless PageSize is set, in which case the first PageSize are.
+func (s *Server) page(r *http.Request, out interface{}) *pagedResult {
+	results := reflect.ValueOf(out)
+	n := results.Len()
+	query := r.URL.Query()
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		if s.PageSize <= 0 {
+			return &pagedResult{items: out}
+		}
+		page = 1
+		query.Set("per_page", strconv.Itoa(s.PageSize))
+	}
+	perPage, err := strconv.Atoi(query.Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 100
+	}
+	start := (page - 1) * perPage
+	if start > n {
+		start = n
+	}
+	end := start + perPage
+	if end > n {
+		end = n
+	}
+	result := &pagedResult{items: results.Slice(start, end).Interface()}
+	if end < n {
+		query.Set("page", strconv.Itoa(page+1))
+		result.next = s.URL + r.URL.Path + "?" + query.Encode()
+	}
+	return result
+}
+
+func (s *Server) routeDictionaryItems(r *http.Request, svc *service, parts []string) (interface{}, error) {
+	if len(parts) < 2 {
+		return nil, notFound("unknown path %s", r.URL.Path)
+	}
+	dictionaryID := parts[0]
+	if svc.items[dictionaryID] == nil {
+		svc.items[dictionaryID] = make(map[string]*fastly.DictionaryItem)
+	}
+	items := svc.items[dictionaryID]
+
+	switch {
+	case parts[1] == "items" && r.Method == "GET":
+		out := make([]*fastly.DictionaryItem, 0, len(items))
+		for _, item := range items {
+			i := *item
+			out = append(out, &i)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+		return s.page(r, out), nil
+	case parts[1] == "items" && r.Method == "PATCH":
+		batch := new(fastly.DictionaryItemBatchUpdate)
+		if err := json.NewDecoder(r.Body).Decode(batch); err != nil {
+			return nil, badRequest("%s", err)
+		}
+		for _, update := range batch.Items {
+			switch update.Operation {
+			case fastly.BatchOperationDelete:
+				delete(items, update.Key)
+			default:
+				items[update.Key] = &fastly.DictionaryItem{ServiceID: svc.ID, DictionaryID: dictionaryID, Key: update.Key, Value: update.Value}
+			}
+		}
+		return nil, nil
+	case parts[1] == "item" && len(parts) == 2 && r.Method == "POST":
+		item := new(fastly.DictionaryItem)
+		if err := decodeBody(r, item); err != nil {
+			return nil, badRequest("%s", err)
+		}
+		item.ServiceID = svc.ID
+		item.DictionaryID = dictionaryID
+		items[item.Key] = item
+		return item, nil
+	case parts[1] == "item" && len(parts) == 3:
+		item, ok := items[parts[2]]
+		if !ok {
+			return nil, notFound("Record not found: item %s", parts[2])
+		}
+		switch r.Method {
+		case "GET":
+			return item, nil
+		case "PATCH", "PUT":
+			if err := decodeBody(r, item); err != nil {
+				return nil, badRequest("%s", err)
+			}
+			return item, nil
+		case "DELETE":
+			delete(items, parts[2])
+			return nil, nil
+		}
+	}
+	return nil, notFound("unknown path %s", r.URL.Path)
+}
+
+func (s *Server) routeACLEntries(r *http.Request, svc *service, parts []string) (interface{}, error) {
+	if len(parts) < 2 {
+		return nil, notFound("unknown path %s", r.URL.Path)
+	}
+	aclID := parts[0]
+	if svc.entries[aclID] == nil {
+		svc.entries[aclID] = make(map[string]*fastly.ACLEntry)
+	}
+	entries := svc.entries[aclID]
+
+	switch {
+	case parts[1] == "entries" && r.Method == "GET":
+		out := make([]*fastly.ACLEntry, 0, len(entries))
+		for _, entry := range entries {
+			e := *entry
+			out = append(out, &e)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].IP < out[j].IP })
+		return s.page(r, out), nil
+	case parts[1] == "entries" && r.Method == "PATCH":
+		batch := new(fastly.ACLEntryBatchUpdate)
+		if err := json.NewDecoder(r.Body).Decode(batch); err != nil {
+			return nil, badRequest("%s", err)
+		}
+		for _, update := range batch.Entries {
+			switch update.Operation {
+			case fastly.BatchOperationDelete:
+				delete(entries, update.ID)
+			case fastly.BatchOperationCreate:
+				subnet, _ := strconv.Atoi(update.Subnet)
+				entry := &fastly.ACLEntry{ID: s.newID(), ServiceID: svc.ID, ACLID: aclID, IP: update.IP, Subnet: uint8(subnet), Comment: update.Comment, Negated: update.Negated.Or(fastly.CompatiboolFalse)}
+				entries[entry.ID] = entry
+			case fastly.BatchOperationUpdate:
+				entry, ok := entries[update.ID]
+				if !ok {
+					return nil, notFound("Record not found: entry %s", update.ID)
+				}
+				if update.IP != "" {
+					entry.IP = update.IP
+				}
+				if update.Subnet != "" {
+					subnet, _ := strconv.Atoi(update.Subnet)
+					entry.Subnet = uint8(subnet)
+				}
+				entry.Comment = update.Comment
+				entry.Negated = update.Negated.Or(entry.Negated)
+			}
+		}
+		return nil, nil
+	case parts[1] == "entry" && len(parts) == 2 && r.Method == "POST":
+		entry := new(fastly.ACLEntry)
+		if err := json.NewDecoder(r.Body).Decode(entry); err != nil {
+			return nil, badRequest("%s", err)
+		}
+		entry.ID = s.newID()
+		entry.Negated = entry.Negated.Or(fastly.CompatiboolFalse)
+		entry.ServiceID = svc.ID
+		entry.ACLID = aclID
+		entries[entry.ID] = entry
+		return entry, nil
+	case parts[1] == "entry" && len(parts) == 3:
+		entry, ok := entries[parts[2]]
+		if !ok {
+			return nil, notFound("Record not found: entry %s", parts[2])
+		}
+		switch r.Method {
+		case "GET":
+			return entry, nil
+		case "PATCH", "PUT":
+			if err := json.NewDecoder(r.Body).Decode(entry); err != nil {
+				return nil, badRequest("%s", err)
+			}
+			return entry, nil
+		case "DELETE":
+			delete(entries, parts[2])
+			return nil, nil
+		}
+	}
+	return nil, notFound("unknown path %s", r.URL.Path)
+}
+
+var (
+	includePattern = regexp.MustCompile(`^\s*include\s+"([^"]+)"\s*;`)
+	macroPattern   = regexp.MustCompile(`(?i)^\s*#FASTLY\s+(\w+)`)
+)
+
+// generateVCL imitates the VCL Fastly generates for a version: the main
+// custom VCL, with includes inlined and each #FASTLY macro expanded into a
+// marked block of placeholder boilerplate. Without a main VCL, the macros of
+// an empty vcl_recv are expanded.
+func generateVCL(state *versionState) string {
+	vcls := make(map[string]string)
+	main := "sub vcl_recv {\n#FASTLY recv\n}\n"
+	for name, rv := range state.resources["vcl"] {
+		vcl := rv.Interface().(*fastly.VCL)
+		vcls[name] = vcl.Content
+		if vcl.Main {
+			main = vcl.Content
+		}
+	}
+	var out []string
+	var expand func(content string, depth int)
+	expand = func(content string, depth int) {
+		for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if m := includePattern.FindStringSubmatch(line); m != nil && depth < 10 {
+				if included, ok := vcls[m[1]]; ok {
+					expand(included, depth+1)
+					continue
+				}
+			}
+			if m := macroPattern.FindStringSubmatch(line); m != nil {
+				sub := strings.ToUpper(m[1])
+				out = append(out, "#--FASTLY "+sub+" BEGIN", "  # boilerplate for vcl_"+strings.ToLower(sub), "#--FASTLY "+sub+" END")
+				continue
+			}
+			out = append(out, line)
+		}
+	}
+	expand(main, 0)
+	return strings.Join(out, "\n") + "\n"
+}
+
+// eventData is an event in the JSON:API form the events API uses.
+type eventData struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	Attributes fastly.Event `json:"attributes"`
+}
+
+// listEvents returns the events matching the filters of r, oldest first. A
+// page[size] splits them into pages, linked in the form the events API uses.
+func (s *Server) listEvents(r *http.Request) interface{} {
+	query := r.URL.Query()
+	since, _ := time.Parse(time.RFC3339, query.Get("filter[created_at][gte]"))
+	var data []eventData
+	for _, e := range s.events {
+		if id := query.Get("filter[service_id]"); id != "" && e.ServiceID != id {
+			continue
+		}
+		if t := query.Get("filter[event_type]"); t != "" && e.Type != t {
+			continue
+		}
+		if e.CreatedAt.Before(since) {
+			continue
+		}
+		data = append(data, eventData{ID: e.ID, Type: "event", Attributes: *e})
+	}
+
+	var next string
+	if size, err := strconv.Atoi(query.Get("page[size]")); err == nil && size > 0 {
+		number, _ := strconv.Atoi(query.Get("page[number]"))
+		if number < 1 {
+			number = 1
+		}
+		start, end := (number-1)*size, number*size
+		if start > len(data) {
+			start = len(data)
+		}
+		if end < len(data) {
+			query.Set("page[number]", strconv.Itoa(number+1))
+			next = s.URL + r.URL.Path + "?" + query.Encode()
+		} else {
+			end = len(data)
+		}
+		data = data[start:end]
+	}
+	out := map[string]interface{}{"data": data, "links": map[string]string{"next": next}}
+	if data == nil {
+		out["data"] = []eventData{}
+	}
+	return out
+}
@@ -0,0 +1,391 @@
+// Package fastlytest provides an in-memory fake of the Fastly API for use in
+// tests. It implements enough of the API for go-fastly's clients to create,
+// clone, modify, and activate service versions without live credentials.
+//
+// It isn't only for tests: fastlyctl plans pushes, including offline ones,
+// by syncing against it, so it is built into fastlyctl and mustn't import
+// package testing. It stores resources as they are sent, without most of the
+// defaults and validation of the real API, and plans made against it inherit
+// that gap.
+package fastlytest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alienth/go-fastly"
+)
+
+// kinds maps the URL segment of each versioned resource to the go-fastly type
+// used to represent it.
+var kinds = map[string]reflect.Type{
+	"acl":              reflect.TypeOf(fastly.ACL{}),
+	"backend":          reflect.TypeOf(fastly.Backend{}),
+	"cache_settings":   reflect.TypeOf(fastly.CacheSetting{}),
+	"condition":        reflect.TypeOf(fastly.Condition{}),
+	"dictionary":       reflect.TypeOf(fastly.Dictionary{}),
+	"director":         reflect.TypeOf(fastly.Director{}),
+	"domain":           reflect.TypeOf(fastly.Domain{}),
+	"gzip":             reflect.TypeOf(fastly.Gzip{}),
+	"header":           reflect.TypeOf(fastly.Header{}),
+	"healthcheck":      reflect.TypeOf(fastly.HealthCheck{}),
+	"logging/s3":       reflect.TypeOf(fastly.S3{}),
+	"logging/syslog":   reflect.TypeOf(fastly.Syslog{}),
+	"request_settings": reflect.TypeOf(fastly.RequestSetting{}),
+	"response_object":  reflect.TypeOf(fastly.ResponseObject{}),
+	"vcl":              reflect.TypeOf(fastly.VCL{}),
+}
+
+// KindOf returns the URL segment under which resources of the same type as
+// resource are stored, or an empty string if the type is not a known resource.
+func KindOf(resource interface{}) string {
+	t := reflect.TypeOf(resource)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for kind, kt := range kinds {
+		if kt == t {
+			return kind
+		}
+	}
+	return ""
+}
+
+// Kinds returns the URL segments of all supported resource kinds, sorted.
+func Kinds() []string {
+	out := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		out = append(out, kind)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Server is a fake Fastly API server. All methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// Validate, if set, determines the response to version validation
+	// requests. By default every version validates successfully.
+	Validate func(serviceID string, version uint) *fastly.ValidateResponse
+
+	// Datacenters is returned by requests for the datacenter list. It
+	// should be set before the server receives any requests.
+	Datacenters []fastly.Datacenter
+
+	// Invoices and Usage are returned by requests for the billing and
+	// usage of a month, keyed by the month in the form "2006-01". Months
+	// which aren't present are not found.
+	Invoices map[string]fastly.Invoice
+	Usage    map[string]fastly.MonthlyUsage
+
+	// OriginMetrics and DomainMetrics are returned by Origin Inspector and
+	// Domain Inspector requests, keyed by service ID. Every query of a
+	// service returns the same series, in a single page.
+	OriginMetrics map[string][]*fastly.MetricsSeries
+	DomainMetrics map[string][]*fastly.MetricsSeries
+
+	// PageSize, if set, is the number of services, dictionary items or
+	// ACL entries listed at a time when no page is requested, with the
+	// rest left to the pages named in Link headers. By default they are
+	// all listed at once.
+	PageSize int
+
+	// UserID is logged as the user behind the events recorded for version
+	// activations. Users are returned by user lookups, keyed by ID. Both
+	// should be set before the server receives any requests.
+	UserID string
+	Users  map[string]fastly.User
+
+	// Token, if set, is returned by requests for the token in use, and
+	// requests it lacks the scope or service for are forbidden. By default
+	// every request is allowed.
+	Token *fastly.Token
+
+	mu       sync.Mutex
+	nextID   int
+	services map[string]*service
+	requests []string
+	events   []*fastly.Event
+	purges   []string
+
+	// idempotent holds the results of POSTs made with an idempotency key,
+	// which are returned again for repeats of the same key.
+	idempotent map[string]interface{}
+}
+
+type service struct {
+	*fastly.Service
+	versions map[uint]*versionState
+	items    map[string]map[string]*fastly.DictionaryItem
+	entries  map[string]map[string]*fastly.ACLEntry
+}
+
+type versionState struct {
+	settings  *fastly.Settings
+	resources map[string]map[string]reflect.Value
+}
+
+func newVersionState() *versionState {
+	return &versionState{
+		settings:  new(fastly.Settings),
+		resources: make(map[string]map[string]reflect.Value),
+	}
+}
+
+// NewServer starts and returns a new fake server. Callers should call Close
+// when finished.
+func NewServer() *Server {
+	s := &Server{services: make(map[string]*service), idempotent: make(map[string]interface{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a go-fastly client which talks to this server.
+func (s *Server) Client() *fastly.Client {
+	client := fastly.NewClient(s.Server.Client(), "fastlytest")
+	client.BaseURL, _ = url.Parse(s.URL + "/")
+	return client
+}
+
+// Requests returns the method and path of every request received so far, in
+// the form "GET /service".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Purges returns the URLs purged so far, without their scheme, in the order
+// they were purged.
+func (s *Server) Purges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.purges...)
+}
+
+// logEvent records an event of the given type for a version of svc.
+func (s *Server) logEvent(svc *service, eventType string, version uint, description string) {
+	s.events = append(s.events, &fastly.Event{
+		ID:          s.newID(),
+		Type:        eventType,
+		Description: description,
+		ServiceID:   svc.ID,
+		UserID:      s.UserID,
+		CreatedAt:   time.Now().UTC(),
+		Metadata:    map[string]interface{}{"version": float64(version)},
+	})
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("fastlytest%06d", s.nextID)
+}
+
+// AddService creates a service with a single, active, version 1.
+func (s *Server) AddService(name string) *fastly.Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addService(name)
+}
+
+func (s *Server) addService(name string) *fastly.Service {
+	svc := &service{
+		Service: &fastly.Service{ID: s.newID(), Name: name, Version: 1},
+		versions: map[uint]*versionState{
+			1: newVersionState(),
+		},
+		items:   make(map[string]map[string]*fastly.DictionaryItem),
+		entries: make(map[string]map[string]*fastly.ACLEntry),
+	}
+	svc.Versions = []*fastly.Version{{ServiceID: svc.ID, Number: 1, Active: true, Locked: true}}
+	s.services[svc.ID] = svc
+	return copyService(svc.Service)
+}
+
+// AddResource stores a copy of resource, which must be a pointer to one of
+// the go-fastly resource types, in the given service version.
+func (s *Server) AddResource(serviceID string, version uint, resource interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kind := KindOf(resource)
+	if kind == "" {
+		return fmt.Errorf("unknown resource type %T", resource)
+	}
+	v, err := s.version(serviceID, version)
+	if err != nil {
+		return err
+	}
+	rv := reflect.New(kinds[kind])
+	rv.Elem().Set(reflect.ValueOf(resource).Elem())
+	s.store(serviceID, version, v, kind, rv)
+	return nil
+}
+
+// Resources returns copies of all resources of the given kind within a
+// service version, sorted by name. Each element is a pointer to the relevant
+// go-fastly type.
+func (s *Server) Resources(serviceID string, version uint, kind string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.version(serviceID, version)
+	if err != nil {
+		return nil
+	}
+	var out []interface{}
+	for _, name := range sortedNames(v.resources[kind]) {
+		rv := reflect.New(kinds[kind])
+		rv.Elem().Set(v.resources[kind][name].Elem())
+		out = append(out, rv.Interface())
+	}
+	return out
+}
+
+// SetSettings replaces the settings of a service version.
+func (s *Server) SetSettings(serviceID string, version uint, settings fastly.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.version(serviceID, version)
+	if err != nil {
+		return err
+	}
+	settings.ServiceID = serviceID
+	settings.Version = version
+	v.settings = &settings
+	return nil
+}
+
+// Settings returns the settings of a service version.
+func (s *Server) Settings(serviceID string, version uint) (fastly.Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.version(serviceID, version)
+	if err != nil {
+		return fastly.Settings{}, err
+	}
+	return *v.settings, nil
+}
+
+// AddDictionaryItem adds an item to a dictionary.
+func (s *Server) AddDictionaryItem(serviceID, dictionaryID, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svc, ok := s.services[serviceID]
+	if !ok {
+		return fmt.Errorf("unknown service %s", serviceID)
+	}
+	if svc.items[dictionaryID] == nil {
+		svc.items[dictionaryID] = make(map[string]*fastly.DictionaryItem)
+	}
+	svc.items[dictionaryID][key] = &fastly.DictionaryItem{ServiceID: serviceID, DictionaryID: dictionaryID, Key: key, Value: value}
+	return nil
+}
+
+// DictionaryItems returns copies of the items within a dictionary.
+func (s *Server) DictionaryItems(serviceID, dictionaryID string) []fastly.DictionaryItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []fastly.DictionaryItem
+	if svc, ok := s.services[serviceID]; ok {
+		for _, item := range svc.items[dictionaryID] {
+			out = append(out, *item)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// AddACLEntry adds an entry to an ACL, returning the new entry's ID.
+func (s *Server) AddACLEntry(serviceID, aclID string, entry fastly.ACLEntry) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svc, ok := s.services[serviceID]
+	if !ok {
+		return "", fmt.Errorf("unknown service %s", serviceID)
+	}
+	if svc.entries[aclID] == nil {
+		svc.entries[aclID] = make(map[string]*fastly.ACLEntry)
+	}
+	entry.ID = s.newID()
+	entry.Negated = entry.Negated.Or(fastly.CompatiboolFalse)
+	entry.ServiceID = serviceID
+	entry.ACLID = aclID
+	svc.entries[aclID][entry.ID] = &entry
+	return entry.ID, nil
+}
+
+// ACLEntries returns copies of the entries within an ACL.
+func (s *Server) ACLEntries(serviceID, aclID string) []fastly.ACLEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []fastly.ACLEntry
+	if svc, ok := s.services[serviceID]; ok {
+		for _, entry := range svc.entries[aclID] {
+			out = append(out, *entry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IP < out[j].IP })
+	return out
+}
+
+// store saves rv under its name, filling in the read-only fields the real API
+// would populate.
+var compatiboolType = reflect.TypeOf(fastly.CompatiboolUnset)
+
+func (s *Server) store(serviceID string, version uint, v *versionState, kind string, rv reflect.Value) string {
+	elem := rv.Elem()
+	elem.FieldByName("ServiceID").SetString(serviceID)
+	elem.FieldByName("Version").SetUint(uint64(version))
+	if f := elem.FieldByName("ID"); f.IsValid() && f.String() == "" {
+		f.SetString(s.newID())
+	}
+	// Fastly stores the booleans left out of a resource as false.
+	for i := 0; i < elem.NumField(); i++ {
+		if f := elem.Field(i); f.Type() == compatiboolType && f.Int() == int64(fastly.CompatiboolUnset) {
+			f.SetInt(int64(fastly.CompatiboolFalse))
+		}
+	}
+	name := elem.FieldByName("Name").String()
+	if v.resources[kind] == nil {
+		v.resources[kind] = make(map[string]reflect.Value)
+	}
+	v.resources[kind][name] = rv
+	return name
+}
+
+func (s *Server) version(serviceID string, number uint) (*versionState, error) {
+	svc, ok := s.services[serviceID]
+	if !ok {
+		return nil, fmt.Errorf("unknown service %s", serviceID)
+	}
+	v, ok := svc.versions[number]
+	if !ok {
+		return nil, fmt.Errorf("unknown version %d for service %s", number, serviceID)
+	}
+	return v, nil
+}
+
+func sortedNames(m map[string]reflect.Value) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func copyService(svc *fastly.Service) *fastly.Service {
+	out := *svc
+	out.Versions = make([]*fastly.Version, len(svc.Versions))
+	for i, v := range svc.Versions {
+		version := *v
+		out.Versions[i] = &version
+	}
+	return &out
+}
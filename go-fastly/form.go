@@ -0,0 +1,86 @@
+package fastly
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EncodeForm encodes the fields of the struct v, or a pointer to one, which
+// have url tags as form values. Tags name the form field, and may add
+// omitempty to leave the field out when it holds its zero value. Fields
+// without a tag aren't sent, nor are unset Compatibools.
+//
+// Some endpoints only act on form bodies. Settings, for instance, ignore an
+// empty general.default_host given as JSON, so it can't be cleared.
+func EncodeForm(v interface{}) (url.Values, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Cannot form encode %T", v)
+	}
+	values := make(url.Values)
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("url")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name, omitempty := parts[0], len(parts) > 1 && parts[1] == "omitempty"
+		f := rv.Field(i)
+		if omitempty && f.IsZero() {
+			continue
+		}
+		value, ok, err := formValue(f)
+		if err != nil {
+			return nil, fmt.Errorf("Error encoding field %s: %s", t.Field(i).Name, err)
+		}
+		if ok {
+			values.Set(name, value)
+		}
+	}
+	return values, nil
+}
+
+// formValue returns the form encoding of a field, and whether it should be
+// sent at all.
+func formValue(f reflect.Value) (string, bool, error) {
+	switch v := f.Interface().(type) {
+	case Compatibool:
+		if !v.IsSet() {
+			return "", false, nil
+		}
+		if v.Bool() {
+			return "1", true, nil
+		}
+		return "0", true, nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		return string(text), err == nil, err
+	}
+	if f.CanAddr() {
+		if m, ok := f.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err == nil, err
+		}
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return f.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10), true, nil
+	case reflect.Ptr:
+		if f.IsNil() {
+			return "", false, nil
+		}
+		return formValue(f.Elem())
+	}
+	return "", false, fmt.Errorf("Unsupported type %s", f.Type())
+}
@@ -0,0 +1,167 @@
+package fastly
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// formRequest is what the fake server saw of a request.
+type formRequest struct {
+	method, contentType, body string
+}
+
+// formServer starts a server which records the requests sent to it, and
+// returns a client which talks to it.
+func formServer(t *testing.T) (*Client, *formRequest) {
+	t.Helper()
+	seen := new(formRequest)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Error reading request body: %s", err)
+		}
+		*seen = formRequest{r.Method, r.Header.Get("Content-Type"), string(body)}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.Client(), "test")
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client, seen
+}
+
+type formFields struct {
+	Name     string        `url:"name,omitempty"`
+	Value    string        `url:"value"`
+	TTL      uint          `url:"ttl,omitempty"`
+	Negated  Compatibool   `url:"negated"`
+	Type     ConditionType `url:"type,omitempty"`
+	Priority *int          `url:"priority,omitempty"`
+	Comment  *string       `url:"comment"`
+	Untagged string
+	Ignored  string `url:"-"`
+}
+
+func TestNewFormRequest(t *testing.T) {
+	zero, comment := 0, "a comment"
+	tests := []struct {
+		name   string
+		fields formFields
+		want   string
+	}{
+		{
+			name: "zero values",
+			want: "value=",
+		},
+		{
+			name:   "omitempty fields which are set",
+			fields: formFields{Name: "n", TTL: 30},
+			want:   "name=n&ttl=30&value=",
+		},
+		{
+			name:   "untagged and ignored fields",
+			fields: formFields{Untagged: "u", Ignored: "i"},
+			want:   "value=",
+		},
+		{
+			name:   "false Compatibool",
+			fields: formFields{Negated: CompatiboolFalse},
+			want:   "negated=0&value=",
+		},
+		{
+			name:   "true Compatibool",
+			fields: formFields{Negated: CompatiboolTrue},
+			want:   "negated=1&value=",
+		},
+		{
+			name:   "TextMarshaler enum",
+			fields: formFields{Type: ConditionTypeCache},
+			want:   "type=CACHE&value=",
+		},
+		{
+			name:   "pointers to zero values are sent",
+			fields: formFields{Priority: &zero, Comment: &comment},
+			want:   "comment=a+comment&priority=0&value=",
+		},
+		{
+			name:   "escaping",
+			fields: formFields{Value: "a&b=c d"},
+			want:   "value=a%26b%3Dc+d",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, seen := formServer(t)
+			req, err := client.NewFormRequest("PUT", "/form", &test.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.Do(req, nil); err != nil {
+				t.Fatal(err)
+			}
+			if seen.method != "PUT" {
+				t.Errorf("Method = %s, want PUT", seen.method)
+			}
+			if seen.contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("Content-Type = %q, want application/x-www-form-urlencoded", seen.contentType)
+			}
+			if seen.body != test.want {
+				t.Errorf("Body = %q, want %q", seen.body, test.want)
+			}
+		})
+	}
+}
+
+func TestSettingsUpdateForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		want     string
+	}{
+		{
+			name:     "default host and ttl",
+			settings: Settings{DefaultHost: "example.com", DefaultTTL: 3600},
+			want:     "general.default_host=example.com&general.default_ttl=3600",
+		},
+		{
+			// An empty default host is sent, which clears it.
+			name:     "clear default host",
+			settings: Settings{DefaultTTL: 3600},
+			want:     "general.default_host=&general.default_ttl=3600",
+		},
+		{
+			name:     "service and version aren't sent",
+			settings: Settings{ServiceID: "svc", Version: 2},
+			want:     "general.default_host=",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, seen := formServer(t)
+			if _, _, err := client.Settings.Update("svc", 1, &test.settings); err != nil {
+				t.Fatal(err)
+			}
+			if seen.contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("Content-Type = %q, want application/x-www-form-urlencoded", seen.contentType)
+			}
+			if seen.body != test.want {
+				t.Errorf("Body = %q, want %q", seen.body, test.want)
+			}
+		})
+	}
+}
+
+func TestEncodeFormErrors(t *testing.T) {
+	if _, err := EncodeForm("string"); err == nil {
+		t.Error("EncodeForm of a string succeeded, want an error")
+	}
+	unsupported := struct {
+		Tags []string `url:"tags"`
+	}{}
+	if _, err := EncodeForm(unsupported); err == nil {
+		t.Error("EncodeForm of a slice field succeeded, want an error")
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/alienth/go-fastly
+
+go 1.14
+
+require github.com/BurntSushi/toml v0.3.1
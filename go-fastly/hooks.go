@@ -0,0 +1,30 @@
+package fastly
+
+import (
+	"net/http"
+	"time"
+)
+
+// Hooks are called by a client as it makes requests, such as to collect
+// metrics about them. Any may be nil.
+type Hooks struct {
+	// Request is called after each request sent to the API, with how long
+	// it took to be answered. resp is nil if the request failed to be
+	// sent. Responses served from the response cache aren't reported.
+	Request func(req *http.Request, resp *http.Response, d time.Duration, err error)
+	// RateLimited is called for each request the client refuses to make
+	// because the rate limit is exhausted until rate.Reset.
+	RateLimited func(req *http.Request, rate Rate)
+}
+
+func (h Hooks) request(req *http.Request, resp *http.Response, d time.Duration, err error) {
+	if h.Request != nil {
+		h.Request(req, resp, d, err)
+	}
+}
+
+func (h Hooks) rateLimited(req *http.Request, err error) {
+	if e, ok := err.(*RateLimitError); ok && h.RateLimited != nil {
+		h.RateLimited(req, e.Rate)
+	}
+}
@@ -0,0 +1,124 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OriginInspectorConfig config
+type DomainInspectorConfig config
+
+// MetricsInput selects the historical metrics fetched from Origin Inspector
+// or Domain Inspector. Unset fields take the API's defaults.
+type MetricsInput struct {
+	Start time.Time
+	End   time.Time
+	// Downsample is the resolution of each series, one of "minute",
+	// "hour", or "day".
+	Downsample string
+	Metrics    []string
+	GroupBy    []string
+	Region     string
+	Datacenter string
+	Limit      int
+	Cursor     string
+}
+
+func (i *MetricsInput) values() url.Values {
+	v := url.Values{}
+	if !i.Start.IsZero() {
+		v.Set("start", i.Start.UTC().Format(time.RFC3339))
+	}
+	if !i.End.IsZero() {
+		v.Set("end", i.End.UTC().Format(time.RFC3339))
+	}
+	if i.Downsample != "" {
+		v.Set("downsample", i.Downsample)
+	}
+	if len(i.Metrics) > 0 {
+		v.Set("metric", strings.Join(i.Metrics, ","))
+	}
+	if len(i.GroupBy) > 0 {
+		v.Set("group_by", strings.Join(i.GroupBy, ","))
+	}
+	if i.Region != "" {
+		v.Set("region", i.Region)
+	}
+	if i.Datacenter != "" {
+		v.Set("datacenter", i.Datacenter)
+	}
+	if i.Limit > 0 {
+		v.Set("limit", strconv.Itoa(i.Limit))
+	}
+	if i.Cursor != "" {
+		v.Set("cursor", i.Cursor)
+	}
+	return v
+}
+
+// MetricsSeries is the time series of metrics for a single combination of
+// the grouped dimensions, such as one origin host.
+type MetricsSeries struct {
+	Dimensions map[string]string `json:"dimensions"`
+	Values     []MetricsPoint    `json:"values"`
+}
+
+// MetricsPoint holds the value of each metric at a point in time, keyed by
+// metric name, along with its "timestamp".
+type MetricsPoint map[string]float64
+
+// Time returns the start of the period the point covers.
+func (p MetricsPoint) Time() time.Time {
+	return time.Unix(int64(p["timestamp"]), 0).UTC()
+}
+
+// MetricsMeta describes the query answered by a MetricsResponse.
+type MetricsMeta struct {
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Downsample string `json:"downsample"`
+	Metric     string `json:"metric"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// MetricsResponse is a page of historical metrics. Further pages are fetched
+// by setting MetricsInput.Cursor to Meta.NextCursor, until it's empty.
+type MetricsResponse struct {
+	Data []*MetricsSeries `json:"data"`
+	Meta MetricsMeta      `json:"meta"`
+}
+
+func (c *config) getMetrics(path string, i *MetricsInput) (*MetricsResponse, *http.Response, error) {
+	u := path
+	if i != nil {
+		u += "?" + i.values().Encode()
+	}
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metrics := new(MetricsResponse)
+	resp, err := c.client.Do(req, metrics)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return metrics, resp, nil
+}
+
+// Get retrieves historical metrics for the origins of a service.
+func (c *OriginInspectorConfig) Get(serviceID string, i *MetricsInput) (*MetricsResponse, *http.Response, error) {
+	return (*config)(c).getMetrics(fmt.Sprintf("/metrics/origins/services/%s", serviceID), i)
+}
+
+// Get retrieves historical metrics for the domains of a service.
+func (c *DomainInspectorConfig) Get(serviceID string, i *MetricsInput) (*MetricsResponse, *http.Response, error) {
+	return (*config)(c).getMetrics(fmt.Sprintf("/metrics/domains/services/%s", serviceID), i)
+}
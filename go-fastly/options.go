@@ -0,0 +1,68 @@
+package fastly
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListOptions specifies the optional parameters accepted by List methods
+// which support paging. A nil *ListOptions requests the API's defaults.
+type ListOptions struct {
+	// Page requests a single page of results, numbered from 1, of PerPage
+	// results each. Without it, List methods follow the further pages named
+	// in the API's Link headers, and return the results of them all.
+	Page    uint
+	PerPage uint
+
+	// Sort names the field to order results by, and Direction is either
+	// "ascend" or "descend". When Sort is set, results are returned in the
+	// order the API provides rather than being sorted client-side.
+	Sort      string
+	Direction string
+
+	// Filters restrict the results to those whose fields match the given
+	// values. Each is sent as a filter[FIELD]=VALUE parameter.
+	Filters map[string]string
+}
+
+// encode appends the options to u as query parameters.
+func (o *ListOptions) encode(u string) string {
+	if o == nil {
+		return u
+	}
+	values := url.Values{}
+	if o.Page != 0 {
+		values.Set("page", strconv.FormatUint(uint64(o.Page), 10))
+	}
+	if o.PerPage != 0 {
+		values.Set("per_page", strconv.FormatUint(uint64(o.PerPage), 10))
+	}
+	if o.Sort != "" {
+		values.Set("sort", o.Sort)
+	}
+	if o.Direction != "" {
+		values.Set("direction", o.Direction)
+	}
+	var fields []string
+	for field := range o.Filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		values.Set("filter["+field+"]", o.Filters[field])
+	}
+	if len(values) == 0 {
+		return u
+	}
+	if strings.Contains(u, "?") {
+		return u + "&" + values.Encode()
+	}
+	return u + "?" + values.Encode()
+}
+
+// sorted returns true if the caller requested the API's ordering.
+func (o *ListOptions) sorted() bool {
+	return o != nil && o.Sort != ""
+}
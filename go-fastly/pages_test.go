@@ -0,0 +1,161 @@
+package fastly_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		header string
+		want   map[string]string
+	}{
+		{``, map[string]string{}},
+		{`<https://api.fastly.com/service?page=2>; rel="next"`, map[string]string{"next": "https://api.fastly.com/service?page=2"}},
+		{`</service?page=2>; rel=next`, map[string]string{"next": "/service?page=2"}},
+		{
+			`</service?page=2>; rel="next", </service?page=5>; rel="last"`,
+			map[string]string{"next": "/service?page=2", "last": "/service?page=5"},
+		},
+		{`</service?page=2>; rel="next last"`, map[string]string{"next": "/service?page=2", "last": "/service?page=2"}},
+		{`</service?page=2>; title="more"; REL="Next"`, map[string]string{"next": "/service?page=2"}},
+		{`</service?page=2>; title="more"`, map[string]string{}},
+		{`no links here`, map[string]string{}},
+	}
+	for _, test := range tests {
+		if got := fastly.ParseLinks(test.header); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseLinks(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
+func serviceNames(services []*fastly.Service) []string {
+	var names []string
+	for _, s := range services {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestListFollowsPages(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	srv.PageSize = 2
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		srv.AddService(name)
+	}
+
+	services, _, err := srv.Client().Service.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := serviceNames(services), []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Listed %v, want %v", got, want)
+	}
+	if got := len(srv.Requests()); got != 3 {
+		t.Errorf("Made %d requests, want 3: %v", got, srv.Requests())
+	}
+}
+
+// pagedServer serves a service for each page requested, and the Link header
+// which links returns for the page query parameter. It records the path and
+// query of each request.
+func pagedServer(t *testing.T, links func(page string) string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		if r.Header.Get("Fastly-Key") != "key" {
+			t.Errorf("Request for %s has key %q, want key", r.URL, r.Header.Get("Fastly-Key"))
+		}
+		page := r.URL.Query().Get("page")
+		if link := links(page); link != "" {
+			w.Header().Set("Link", link)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[{"id": "%s", "name": "page%s"}]`, page, page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func clientFor(t *testing.T, srv *httptest.Server, path string) *fastly.Client {
+	t.Helper()
+	client := fastly.NewClient(srv.Client(), "key")
+	client.BaseURL, _ = url.Parse(srv.URL + path)
+	return client
+}
+
+func TestListStopsAtLoop(t *testing.T) {
+	var base string
+	srv, requests := pagedServer(t, func(page string) string {
+		switch page {
+		case "":
+			return fmt.Sprintf(`<%s/service?page=2>; rel="next"`, base)
+		case "2":
+			return fmt.Sprintf(`<%s/service?page=3>; rel="next"`, base)
+		}
+		// The last page links back to the second.
+		return fmt.Sprintf(`<%s/service?page=2>; rel="next"`, base)
+	})
+	base = srv.URL
+
+	services, _, err := clientFor(t, srv, "/").Service.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := serviceNames(services), []string{"page", "page2", "page3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Listed %v, want %v", got, want)
+	}
+	if want := []string{"/service", "/service?page=2", "/service?page=3"}; !reflect.DeepEqual(*requests, want) {
+		t.Errorf("Requested %v, want %v", *requests, want)
+	}
+}
+
+func TestListRefusesCrossHostLink(t *testing.T) {
+	other, otherRequests := pagedServer(t, func(string) string { return "" })
+	srv, _ := pagedServer(t, func(page string) string {
+		if page == "" {
+			return fmt.Sprintf(`<%s/service?page=2>; rel="next"`, other.URL)
+		}
+		return ""
+	})
+
+	_, _, err := clientFor(t, srv, "/").Service.List(nil)
+	if err == nil || !strings.Contains(err.Error(), "Refusing to follow") {
+		t.Errorf("List returned %v, want an error refusing the link", err)
+	}
+	if len(*otherRequests) != 0 {
+		t.Errorf("Sent %v to the linked host, want nothing", *otherRequests)
+	}
+}
+
+func TestListRebasesAPILinks(t *testing.T) {
+	// A proxy in front of the API may pass on the API's own links, which
+	// are followed through the proxy.
+	srv, requests := pagedServer(t, func(page string) string {
+		if page == "" {
+			return `<https://api.fastly.com/service?page=2>; rel="next"`
+		}
+		return ""
+	})
+
+	services, _, err := clientFor(t, srv, "/gateway/").Service.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := serviceNames(services), []string{"page", "page2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Listed %v, want %v", got, want)
+	}
+	if want := []string{"/gateway/service", "/gateway/service?page=2"}; !reflect.DeepEqual(*requests, want) {
+		t.Errorf("Requested %v, want %v", *requests, want)
+	}
+}
@@ -0,0 +1,43 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type PurgeConfig config
+
+// Purge is the result of a purge request.
+type Purge struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+}
+
+// URL purges the object cached for a URL from every POP.
+func (c *PurgeConfig) URL(rawURL string) (*Purge, *http.Response, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if target.Host == "" {
+		return nil, nil, fmt.Errorf("Cannot purge %s, as it has no host.", rawURL)
+	}
+	// The URL to purge is given without its scheme.
+	u := "/purge/" + target.Host + target.EscapedPath()
+	if target.RawQuery != "" {
+		u += "%3F" + url.PathEscape(target.RawQuery)
+	}
+
+	req, err := c.client.NewRequest("POST", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	purge := new(Purge)
+	resp, err := c.client.Do(req, purge)
+	if err != nil {
+		return nil, resp, err
+	}
+	return purge, resp, nil
+}
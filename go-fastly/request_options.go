@@ -0,0 +1,68 @@
+package fastly
+
+import (
+	"context"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header carrying the key set by
+// WithIdempotencyKey.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RequestOption customizes each request made by a client returned from
+// Client.With.
+type RequestOption func(req *http.Request)
+
+// WithHeader sets a header on every request.
+func WithHeader(name, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(name, value)
+	}
+}
+
+// WithAPIKey authenticates requests with key rather than the client's own.
+func WithAPIKey(key string) RequestOption {
+	return WithHeader("Fastly-Key", key)
+}
+
+// WithContext makes every request with ctx, so that they are abandoned once
+// it is cancelled or its deadline passes.
+func WithContext(ctx context.Context) RequestOption {
+	return func(req *http.Request) {
+		*req = *req.WithContext(ctx)
+	}
+}
+
+// WithIdempotencyKey marks POST requests with key, so that a create which is
+// retried after an ambiguous failure isn't applied twice. Use a distinct key
+// for each logical operation; a client carrying this option should be used
+// for a single create.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(req *http.Request) {
+		if req.Method == "POST" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+	}
+}
+
+// With returns a client which applies opts, after any of this client's own
+// options, to each request it makes. The new client shares this client's
+// HTTP client. As its options may change what the API returns, it doesn't
+// use this client's lookup or response caches, but does invalidate it on mutation.
+func (c *Client) With(opts ...RequestOption) *Client {
+	n := NewClient(c.client, c.apiKey)
+	n.BaseURL = c.BaseURL
+	n.UserAgent = c.UserAgent
+	n.ReadOnly = c.ReadOnly
+	n.Hooks = c.Hooks
+	n.parentCache = c.cache
+	if n.parentCache == nil {
+		n.parentCache = c.parentCache
+	}
+	n.parentResponses = c.responses
+	if n.parentResponses == nil {
+		n.parentResponses = c.parentResponses
+	}
+	n.options = append(append([]RequestOption(nil), c.options...), opts...)
+	return n
+}
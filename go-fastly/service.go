@@ -0,0 +1,193 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+type ServiceConfig config
+
+type Service struct {
+	ID string `json:"id,omitempty"`
+
+	Version    uint       `json:"version,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Comment    string     `json:"comment"`
+	CustomerID string     `json:"customer_id,omitempty"`
+	Versions   []*Version `json:"versions,omitempty"`
+}
+
+// ServiceDetails describes a service along with its versions, as returned by
+// the details endpoint. Unlike services returned by Search, the active version
+// is always included.
+type ServiceDetails struct {
+	ID           string         `json:"id,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Comment      string         `json:"comment"`
+	CustomerID   string         `json:"customer_id,omitempty"`
+	Version      *Version       `json:"version,omitempty"`
+	Active       *Version       `json:"active_version,omitempty"`
+	Versions     []*Version     `json:"versions,omitempty"`
+	Environments []*Environment `json:"environments,omitempty"`
+}
+
+// Environment records the version of a service active in an environment.
+type Environment struct {
+	Name          string `json:"name"`
+	ServiceID     string `json:"service_id,omitempty"`
+	ActiveVersion uint   `json:"active_version,omitempty"`
+}
+
+// Service returns the service described, with Version set to its active
+// version, or zero if no version is active.
+func (d *ServiceDetails) Service() *Service {
+	s := &Service{ID: d.ID, Name: d.Name, Comment: d.Comment, CustomerID: d.CustomerID, Versions: d.Versions}
+	if d.Active != nil {
+		s.Version = d.Active.Number
+	}
+	return s
+}
+
+// servicesByName is a sortable list of services.
+type servicesByName []*Service
+
+// Len, Swap, and Less implement the sortable interface.
+func (s servicesByName) Len() int      { return len(s) }
+func (s servicesByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s servicesByName) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
+
+// List services.
+func (c *ServiceConfig) List(opts *ListOptions) ([]*Service, *http.Response, error) {
+	u := opts.encode("/service")
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	services := new([]*Service)
+	resp, err := c.client.doAll(req, services)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if !opts.sorted() {
+		sort.Stable(servicesByName(*services))
+	}
+
+	return *services, resp, nil
+}
+
+// Get fetches a specific service by ID.
+func (c *ServiceConfig) Get(serviceID string) (*Service, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s", serviceID)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service := new(Service)
+	resp, err := c.client.Do(req, service)
+	if err != nil {
+		return nil, resp, err
+	}
+	return service, resp, nil
+}
+
+// Details fetches a service along with its active and latest versions.
+func (c *ServiceConfig) Details(serviceID string) (*ServiceDetails, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s/details", serviceID)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details := new(ServiceDetails)
+	resp, err := c.client.Do(req, details)
+	if err != nil {
+		return nil, resp, err
+	}
+	return details, resp, nil
+}
+
+// Search fetches a specific service by name.
+func (c *ServiceConfig) Search(name string) (*Service, *http.Response, error) {
+	u := fmt.Sprintf("/service/search?name=%s", name)
+
+	v, resp, err := c.client.cached(u, func() (interface{}, *http.Response, error) {
+		req, err := c.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		service := new(Service)
+		resp, err := c.client.Do(req, service)
+		if err != nil {
+			return nil, resp, err
+		}
+		return service, resp, nil
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+	service := *v.(*Service)
+	return &service, resp, nil
+}
+
+// Create a new service.
+func (c *ServiceConfig) Create(service *Service) (*Service, *http.Response, error) {
+	u := "/service"
+
+	req, err := c.client.NewJSONRequest("POST", u, service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(Service)
+	resp, err := c.client.Do(req, b)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return b, resp, nil
+}
+
+// Update a service
+func (c *ServiceConfig) Update(serviceID string, service *Service) (*Service, *http.Response, error) {
+	u := fmt.Sprintf("/service/%s", serviceID)
+
+	req, err := c.client.NewJSONRequest("PUT", u, service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(Service)
+	resp, err := c.client.Do(req, b)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return b, resp, nil
+}
+
+// Delete a service
+func (c *ServiceConfig) Delete(serviceID string) (*http.Response, error) {
+	u := fmt.Sprintf("/service/%s", serviceID)
+
+	req, err := c.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req, nil)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
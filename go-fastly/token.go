@@ -0,0 +1,148 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type TokenConfig config
+
+// Token is an API token, as described by the API to its bearer.
+type Token struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	// Services are the IDs of the services the token is limited to. A
+	// token without any may act on every service.
+	Services []string `json:"services,omitempty"`
+	// Scope is the space-separated list of the token's scopes.
+	Scope     string `json:"scope,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// Token scopes, as named by the API.
+const (
+	ScopeGlobal      = "global"
+	ScopeGlobalRead  = "global:read"
+	ScopePurgeSelect = "purge_select"
+	ScopePurgeAll    = "purge_all"
+)
+
+// Scopes returns the scopes of the token.
+func (t *Token) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// HasScope reports whether the token grants scope. The global scope grants
+// every other.
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes() {
+		if s == scope || s == ScopeGlobal {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversService reports whether the token may act on the service.
+func (t *Token) CoversService(serviceID string) bool {
+	if len(t.Services) == 0 {
+		return true
+	}
+	for _, id := range t.Services {
+		if id == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// Self fetches the token the client authenticates with.
+func (c *TokenConfig) Self() (*Token, *http.Response, error) {
+	req, err := c.client.NewRequest("GET", "/tokens/self", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := new(Token)
+	resp, err := c.client.Do(req, token)
+	if err != nil {
+		return nil, resp, err
+	}
+	return token, resp, nil
+}
+
+// RequiredScope returns the scope a token needs to make req: purge_all or
+// purge_select for purges, global:read for other reads, and global for
+// anything else.
+func RequiredScope(req *http.Request) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case parts[0] == "purge":
+		return ScopePurgeSelect
+	case len(parts) >= 3 && parts[0] == "service" && parts[2] == "purge_all":
+		return ScopePurgeAll
+	case len(parts) >= 3 && parts[0] == "service" && parts[2] == "purge":
+		return ScopePurgeSelect
+	case req.Method == "GET" || req.Method == "HEAD":
+		return ScopeGlobalRead
+	}
+	return ScopeGlobal
+}
+
+// requestServiceID returns the ID of the service req acts on, if any.
+func requestServiceID(req *http.Request) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "service" && parts[1] != "search" {
+		return parts[1]
+	}
+	return ""
+}
+
+// ScopeError is returned for requests the API forbids, explaining which
+// scope of the token is missing for them.
+type ScopeError struct {
+	*ErrorResponse
+	// Required is the scope the request needed.
+	Required string
+	// Token is the client's token, or nil if it couldn't be fetched.
+	Token *Token
+}
+
+func (e *ScopeError) Error() string {
+	serviceID := requestServiceID(e.Response.Request)
+	msg := strings.TrimSpace(e.ErrorResponse.Error())
+	switch {
+	case e.Token == nil:
+		return fmt.Sprintf("%s; this needs an API token with the %s scope", msg, e.Required)
+	case !e.Token.HasScope(e.Required):
+		scopes := strings.Join(e.Token.Scopes(), ", ")
+		if scopes == "" {
+			scopes = "none"
+		}
+		return fmt.Sprintf("%s; the API token lacks the %s scope this needs, having only %s", msg, e.Required, scopes)
+	case serviceID != "" && !e.Token.CoversService(serviceID):
+		return fmt.Sprintf("%s; the API token is limited to services %s", msg, strings.Join(e.Token.Services, ", "))
+	}
+	return fmt.Sprintf("%s; the API token has the %s scope this needs, so its user may lack permission", msg, e.Required)
+}
+
+// tokenState holds the client's token, fetched the first time a request is
+// forbidden, so that requests which succeed cost nothing extra.
+type tokenState struct {
+	once  sync.Once
+	token *Token
+}
+
+// explainForbidden fills in the token of a ScopeError.
+func (c *Client) explainForbidden(e *ScopeError) {
+	if strings.Trim(e.Response.Request.URL.Path, "/") == "tokens/self" {
+		return
+	}
+	c.tokens.once.Do(func() {
+		c.tokens.token, _, _ = c.Token.Self()
+	})
+	e.Token = c.tokens.token
+}
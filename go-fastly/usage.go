@@ -0,0 +1,91 @@
+package fastly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type UsageConfig config
+
+// UsageStats is the traffic served within a region. Bandwidth is in bytes.
+type UsageStats struct {
+	Bandwidth uint64 `json:"bandwidth"`
+	Requests  uint64 `json:"requests"`
+}
+
+// ServiceUsage is the traffic served for a single service, keyed by region.
+type ServiceUsage struct {
+	Name    string
+	Regions map[string]UsageStats
+}
+
+// The API returns a service's name alongside its regions, in the same object.
+func (u *ServiceUsage) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	u.Regions = make(map[string]UsageStats)
+	for k, v := range fields {
+		if k == "name" {
+			if err := json.Unmarshal(v, &u.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		var stats UsageStats
+		if err := json.Unmarshal(v, &stats); err != nil {
+			return err
+		}
+		u.Regions[k] = stats
+	}
+	return nil
+}
+
+func (u ServiceUsage) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{})
+	for k, v := range u.Regions {
+		fields[k] = v
+	}
+	fields["name"] = u.Name
+	return json.Marshal(fields)
+}
+
+// MonthlyUsage is the traffic served for each service during a month, keyed
+// by service ID. Total is keyed by region.
+type MonthlyUsage struct {
+	CustomerID string                   `json:"customer_id"`
+	Services   map[string]*ServiceUsage `json:"services"`
+	Total      map[string]UsageStats    `json:"total"`
+}
+
+// usageResponse wraps the data returned by the stats API.
+type usageResponse struct {
+	Status  string        `json:"status"`
+	Message string        `json:"msg"`
+	Data    *MonthlyUsage `json:"data"`
+}
+
+// ByMonth retrieves the traffic served by each service in a month, broken
+// down by region.
+func (c *UsageConfig) ByMonth(year int, month time.Month) (*MonthlyUsage, *http.Response, error) {
+	u := fmt.Sprintf("/stats/usage_by_month?year=%04d&month=%02d", year, month)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usage := new(usageResponse)
+	resp, err := c.client.Do(req, usage)
+	if err != nil {
+		return nil, resp, err
+	}
+	if usage.Status != "success" || usage.Data == nil {
+		return nil, resp, fmt.Errorf("Error fetching usage: %s", usage.Message)
+	}
+
+	return usage.Data, resp, nil
+}
@@ -0,0 +1,33 @@
+package fastly
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type UserConfig config
+
+// User is a user of a Fastly account.
+type User struct {
+	ID    string `json:"id,omitempty"`
+	Login string `json:"login,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+// Get fetches a user by ID.
+func (c *UserConfig) Get(id string) (*User, *http.Response, error) {
+	u := fmt.Sprintf("/user/%s", id)
+
+	req, err := c.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := new(User)
+	resp, err := c.client.Do(req, user)
+	if err != nil {
+		return nil, resp, err
+	}
+	return user, resp, nil
+}
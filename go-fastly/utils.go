@@ -0,0 +1,131 @@
+package fastly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Compatibool is a boolean which may also be unset. Its zero value is unset,
+// so that a config which leaves a field out can be told apart from one which
+// sets it to false. Unset fields are left out when marshalled.
+type Compatibool int8
+
+const (
+	CompatiboolUnset Compatibool = iota
+	CompatiboolFalse
+	CompatiboolTrue
+)
+
+var _ json.Marshaler = CompatiboolUnset
+var _ json.Unmarshaler = new(Compatibool)
+
+// NewCompatibool returns the set Compatibool of v.
+func NewCompatibool(v bool) Compatibool {
+	if v {
+		return CompatiboolTrue
+	}
+	return CompatiboolFalse
+}
+
+// Bool reports whether b is set and true.
+func (b Compatibool) Bool() bool {
+	return b == CompatiboolTrue
+}
+
+// IsSet reports whether b has been set, to either true or false.
+func (b Compatibool) IsSet() bool {
+	return b != CompatiboolUnset
+}
+
+// Or returns b if it is set, and otherwise def.
+func (b Compatibool) Or(def Compatibool) Compatibool {
+	if b.IsSet() {
+		return b
+	}
+	return def
+}
+
+func (b Compatibool) String() string {
+	switch b {
+	case CompatiboolTrue:
+		return "true"
+	case CompatiboolFalse:
+		return "false"
+	}
+	return "unset"
+}
+
+// Occasionally these bools come down from fastly in '0'/'1', or even 0/1 form.
+func (b *Compatibool) UnmarshalJSON(t []byte) error {
+	if bytes.Equal(t, []byte("null")) {
+		*b = CompatiboolUnset
+		return nil
+	}
+	return b.UnmarshalText(bytes.Trim(t, `"`))
+}
+
+// UnmarshalText accepts the forms of UnmarshalJSON, without quotes, as TOML
+// passes its booleans. An empty string, as the API gives for some fields it
+// holds no value for, is unset rather than false.
+func (b *Compatibool) UnmarshalText(t []byte) error {
+	switch string(t) {
+	case "":
+		*b = CompatiboolUnset
+	case "1", "true":
+		*b = CompatiboolTrue
+	case "0", "false":
+		*b = CompatiboolFalse
+	default:
+		return fmt.Errorf("Invalid boolean %q", t)
+	}
+	return nil
+}
+
+func (b Compatibool) MarshalJSON() ([]byte, error) {
+	switch b {
+	case CompatiboolTrue:
+		return []byte("1"), nil
+	case CompatiboolFalse:
+		return []byte("0"), nil
+	}
+	return []byte("null"), nil
+}
+
+type BatchOperation int
+
+const (
+	_                                   = iota
+	BatchOperationUpdate BatchOperation = iota
+	BatchOperationCreate
+	BatchOperationDelete
+	BatchOperationUpsert
+)
+
+func (s *BatchOperation) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "update":
+		*s = BatchOperationUpdate
+	case "create":
+		*s = BatchOperationCreate
+	case "delete":
+		*s = BatchOperationDelete
+	case "upsert":
+		*s = BatchOperationUpsert
+	}
+	return nil
+}
+
+func (s *BatchOperation) MarshalText() ([]byte, error) {
+	switch *s {
+	case BatchOperationUpdate:
+		return []byte("update"), nil
+	case BatchOperationCreate:
+		return []byte("create"), nil
+	case BatchOperationDelete:
+		return []byte("delete"), nil
+	case BatchOperationUpsert:
+		return []byte("upsert"), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,111 @@
+package fastly
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestCompatiboolUnmarshal(t *testing.T) {
+	var value struct {
+		V Compatibool `json:"v" toml:"v"`
+	}
+	tests := []struct {
+		format, doc string
+		want        Compatibool
+	}{
+		{"json", `{}`, CompatiboolUnset},
+		{"json", `{"v": null}`, CompatiboolUnset},
+		{"json", `{"v": ""}`, CompatiboolUnset},
+		{"json", `{"v": "0"}`, CompatiboolFalse},
+		{"json", `{"v": 0}`, CompatiboolFalse},
+		{"json", `{"v": false}`, CompatiboolFalse},
+		{"json", `{"v": "false"}`, CompatiboolFalse},
+		{"json", `{"v": "1"}`, CompatiboolTrue},
+		{"json", `{"v": 1}`, CompatiboolTrue},
+		{"json", `{"v": true}`, CompatiboolTrue},
+		{"json", `{"v": "true"}`, CompatiboolTrue},
+		{"toml", ``, CompatiboolUnset},
+		{"toml", `v = ""`, CompatiboolUnset},
+		{"toml", `v = "0"`, CompatiboolFalse},
+		{"toml", `v = 0`, CompatiboolFalse},
+		{"toml", `v = false`, CompatiboolFalse},
+		{"toml", `v = "1"`, CompatiboolTrue},
+		{"toml", `v = 1`, CompatiboolTrue},
+		{"toml", `v = true`, CompatiboolTrue},
+	}
+	for _, test := range tests {
+		value.V = CompatiboolUnset
+		var err error
+		switch test.format {
+		case "json":
+			err = json.Unmarshal([]byte(test.doc), &value)
+		case "toml":
+			_, err = toml.Decode(test.doc, &value)
+		}
+		if err != nil {
+			t.Errorf("%s %s: %s", test.format, test.doc, err)
+		} else if value.V != test.want {
+			t.Errorf("%s %s = %s, want %s", test.format, test.doc, value.V, test.want)
+		}
+	}
+}
+
+func TestCompatiboolUnmarshalInvalid(t *testing.T) {
+	var value struct {
+		V Compatibool `json:"v" toml:"v"`
+	}
+	for _, doc := range []string{`{"v": "yes"}`, `{"v": 2}`} {
+		if err := json.Unmarshal([]byte(doc), &value); err == nil {
+			t.Errorf("json %s: decoded as %s, want an error", doc, value.V)
+		}
+	}
+	for _, doc := range []string{`v = "yes"`, `v = 2`} {
+		if _, err := toml.Decode(doc, &value); err == nil {
+			t.Errorf("toml %s: decoded as %s, want an error", doc, value.V)
+		}
+	}
+}
+
+func TestCompatiboolMarshalJSON(t *testing.T) {
+	tests := []struct {
+		value               Compatibool
+		want, wantOmitempty string
+	}{
+		{CompatiboolUnset, `{"v":null}`, `{}`},
+		{CompatiboolFalse, `{"v":0}`, `{"v":0}`},
+		{CompatiboolTrue, `{"v":1}`, `{"v":1}`},
+	}
+	for _, test := range tests {
+		got, err := json.Marshal(struct {
+			V Compatibool `json:"v"`
+		}{test.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("Marshal(%s) = %s, want %s", test.value, got, test.want)
+		}
+		got, err = json.Marshal(struct {
+			V Compatibool `json:"v,omitempty"`
+		}{test.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.wantOmitempty {
+			t.Errorf("Marshal(%s) with omitempty = %s, want %s", test.value, got, test.wantOmitempty)
+		}
+
+		// What is marshalled decodes to the same value.
+		var back struct {
+			V Compatibool `json:"v"`
+		}
+		if err := json.Unmarshal([]byte(test.want), &back); err != nil {
+			t.Fatal(err)
+		}
+		if back.V != test.value {
+			t.Errorf("Unmarshal(%s) = %s, want %s", test.want, back.V, test.value)
+		}
+	}
+}
@@ -10,5 +10,7 @@ require (
 	github.com/urfave/cli v1.18.0
 	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
 	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405 // indirect
-	gopkg.in/yaml.v1 v1.0.0-20140924161607-9f9df34309c0 // indirect
+	gopkg.in/yaml.v1 v1.0.0-20140924161607-9f9df34309c0
 )
+
+replace github.com/alienth/go-fastly => ./go-fastly
@@ -0,0 +1,374 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alienth/fastlyctl/log"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+// ACL allows the entries of an ACL to be declared in a file. If EntriesFile
+// is set, push makes the ACL's entries match those in the file, which is
+// read as ReadACLEntries does. As with dictionary items, entries aren't
+// versioned, so they are written as the ACL is synced.
+//
+// If Aggregate is set, the entries are collapsed into as few ranges as
+// cover the same addresses before they are written, so that long lists of
+// addresses stay under Fastly's limit on entries. Negated entries, and those
+// overlapping them, are written as they are.
+type ACL struct {
+	fastly.ACL
+	EntriesFile string `json:",omitempty"`
+	Aggregate   bool   `json:",omitempty"`
+}
+
+// ACLEntryKind is the kind of the changes recorded for the entries written
+// from an EntriesFile.
+const ACLEntryKind = "acl entry"
+
+// IsUnversioned reports whether changes of kind are made outside of a
+// version, so take effect as soon as they are synced.
+func IsUnversioned(kind string) bool {
+	return kind == DictionaryItemKind || kind == ACLEntryKind
+}
+
+// checkACLs reports each ACL in configs whose entries can't be synced.
+func checkACLs(configs map[string]SiteConfig) error {
+	for name, config := range configs {
+		for _, a := range config.ACLs {
+			if a.Aggregate && a.EntriesFile == "" {
+				return fmt.Errorf("Service %s: acl %s: Aggregate requires an EntriesFile.", name, a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// SplitIPMask splits an address with an optional mask, such as
+// 192.0.2.0/24, into the address and mask.
+func SplitIPMask(ipParam string) (string, uint8, error) {
+	var subnet uint8
+	ipSplit := strings.Split(ipParam, "/")
+	if len(ipSplit) == 2 {
+		s, err := strconv.Atoi(ipSplit[1])
+		if err != nil {
+			return "", 0, fmt.Errorf("Invalid subnet mask specified: %s", err)
+		}
+		subnet = uint8(s)
+	}
+	return ipSplit[0], subnet, nil
+}
+
+// EntryNet returns the range matched by an acl entry. An entry without a
+// subnet matches its address alone.
+func EntryNet(entry *fastly.ACLEntry) (*net.IPNet, error) {
+	ip := net.ParseIP(entry.IP)
+	if ip == nil {
+		return nil, fmt.Errorf("%s is not a valid IP address.", entry.IP)
+	}
+	bits := 128
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 32
+	}
+	ones := int(entry.Subnet)
+	if ones == 0 {
+		ones = bits
+	}
+	if ones > bits {
+		return nil, fmt.Errorf("Invalid subnet mask /%d for %s.", ones, entry.IP)
+	}
+	mask := net.CIDRMask(ones, bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// ReadACLEntries reads ACL entries from file. A JSON file holds a list of
+// entries in the format written by export. Any other file holds an entry per
+// line, as an address and optional mask, prefixed with ! to negate it and
+// followed by an optional comment. Blank lines and lines beginning with #
+// are skipped.
+func ReadACLEntries(file string) ([]*fastly.ACLEntry, error) {
+	body, err := ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*fastly.ACLEntry
+	if filepath.Ext(file) == ".json" {
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", file, err)
+		}
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		entry := new(fastly.ACLEntry)
+		if strings.HasPrefix(fields[0], "!") {
+			entry.Negated = fastly.CompatiboolTrue
+			fields[0] = fields[0][1:]
+		}
+		if entry.IP, entry.Subnet, err = SplitIPMask(fields[0]); err != nil {
+			return nil, fmt.Errorf("Line %d: %s", line, err)
+		}
+		if net.ParseIP(entry.IP) == nil {
+			return nil, fmt.Errorf("Line %d: %s is not a valid IP address.", line, entry.IP)
+		}
+		if len(fields) == 2 {
+			entry.Comment = strings.TrimSpace(fields[1])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// AggregateACLEntries returns entries with the ranges of those which aren't
+// negated collapsed: ranges within another are dropped, and adjacent ranges
+// which together form a larger one are merged into it, keeping their
+// comment if they share one. Entries overlapping a negated entry are left
+// alone, as collapsing them could change which addresses it excludes.
+func AggregateACLEntries(entries []*fastly.ACLEntry) ([]*fastly.ACLEntry, error) {
+	type aggregate struct {
+		net   *net.IPNet
+		entry *fastly.ACLEntry
+	}
+	var negated []*net.IPNet
+	for _, e := range entries {
+		if e.Negated.Bool() {
+			n, err := EntryNet(e)
+			if err != nil {
+				return nil, err
+			}
+			negated = append(negated, n)
+		}
+	}
+
+	var out []*fastly.ACLEntry
+	var candidates []aggregate
+	for _, e := range entries {
+		if e.Negated.Bool() {
+			out = append(out, e)
+			continue
+		}
+		n, err := EntryNet(e)
+		if err != nil {
+			return nil, err
+		}
+		fixed := false
+		for _, neg := range negated {
+			if n.Contains(neg.IP) || neg.Contains(n.IP) {
+				fixed = true
+				break
+			}
+		}
+		if fixed {
+			out = append(out, e)
+		} else {
+			candidates = append(candidates, aggregate{n, e})
+		}
+	}
+
+	// Once sorted by address, then by size, a range within another follows
+	// it, and ranges which merge are next to one another.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].net, candidates[j].net
+		if len(a.IP) != len(b.IP) {
+			return len(a.IP) < len(b.IP)
+		}
+		if c := bytes.Compare(a.IP, b.IP); c != 0 {
+			return c < 0
+		}
+		aOnes, _ := a.Mask.Size()
+		bOnes, _ := b.Mask.Size()
+		return aOnes < bOnes
+	})
+	var merged []aggregate
+	for _, c := range candidates {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1].net
+			if len(last.IP) == len(c.net.IP) && last.Contains(c.net.IP) {
+				log.Debug(fmt.Sprintf("Dropping acl entry %s, which is within %s.\n", c.net, last))
+				continue
+			}
+		}
+		merged = append(merged, c)
+		for len(merged) > 1 {
+			a, b := merged[len(merged)-2], merged[len(merged)-1]
+			ones, bits := a.net.Mask.Size()
+			bOnes, _ := b.net.Mask.Size()
+			if len(a.net.IP) != len(b.net.IP) || ones != bOnes || ones == 0 {
+				break
+			}
+			mask := net.CIDRMask(ones-1, bits)
+			if !a.net.IP.Mask(mask).Equal(b.net.IP.Mask(mask)) {
+				break
+			}
+			parent := &net.IPNet{IP: a.net.IP.Mask(mask), Mask: mask}
+			log.Debug(fmt.Sprintf("Merging acl entries %s and %s into %s.\n", a.net, b.net, parent))
+			entry := &fastly.ACLEntry{IP: parent.IP.String(), Subnet: uint8(ones - 1)}
+			if a.entry.Comment == b.entry.Comment {
+				entry.Comment = a.entry.Comment
+			}
+			merged = append(merged[:len(merged)-2], aggregate{parent, entry})
+		}
+	}
+	for _, m := range merged {
+		out = append(out, m.entry)
+	}
+	return out, nil
+}
+
+// ACLEntryKey identifies an ACL entry by the address range it covers.
+func ACLEntryKey(e *fastly.ACLEntry) string {
+	return fmt.Sprintf("%s/%d", e.IP, e.Subnet)
+}
+
+// ACLEntryOps returns the batch operations which make an ACL's existing
+// entries match entries, along with the plan they carry out. Existing entries
+// which aren't in entries are only deleted if replace is set.
+func ACLEntryOps(existingEntries, entries []*fastly.ACLEntry, replace bool) ([]fastly.ACLEntryUpdate, util.BatchPlan) {
+	existing := make(map[string]*fastly.ACLEntry)
+	for _, e := range existingEntries {
+		existing[ACLEntryKey(e)] = e
+	}
+
+	var ops []fastly.ACLEntryUpdate
+	var plan util.BatchPlan
+	wanted := make(map[string]bool)
+	for _, e := range entries {
+		key := ACLEntryKey(e)
+		wanted[key] = true
+		op := fastly.ACLEntryUpdate{IP: e.IP, Comment: e.Comment, Negated: e.Negated}
+		if e.Subnet != 0 {
+			op.Subnet = fmt.Sprint(e.Subnet)
+		}
+		if old, ok := existing[key]; !ok {
+			log.Debug(fmt.Sprintf("Creating missing acl entry %s.\n", key))
+			op.Operation = fastly.BatchOperationCreate
+			ops = append(ops, op)
+			plan.Add = append(plan.Add, key)
+		} else if old.Comment != e.Comment || e.Negated.Or(old.Negated) != old.Negated {
+			log.Debug(fmt.Sprintf("Found mismatched acl entry %s. Updating.\n", key))
+			op.Operation = fastly.BatchOperationUpdate
+			op.ID = old.ID
+			ops = append(ops, op)
+			plan.Update = append(plan.Update, key)
+		}
+	}
+	if replace {
+		for _, e := range existingEntries {
+			if key := ACLEntryKey(e); !wanted[key] {
+				log.Debug(fmt.Sprintf("Found non-matching acl entry %s. Deleting.\n", key))
+				ops = append(ops, fastly.ACLEntryUpdate{Operation: fastly.BatchOperationDelete, ID: e.ID})
+				plan.Delete = append(plan.Delete, key)
+			}
+		}
+	}
+	return ops, plan
+}
+
+// BatchUpdateACL applies ops to an ACL BatchLimit at a time, returning the
+// number applied.
+func BatchUpdateACL(api util.ACLEntryAPI, serviceID, aclID string, ops []fastly.ACLEntryUpdate) (int, error) {
+	for i := 0; i < len(ops); i += BatchLimit {
+		end := i + BatchLimit
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if _, err := api.BatchUpdate(serviceID, aclID, ops[i:end]); err != nil {
+			return i, err
+		}
+	}
+	return len(ops), nil
+}
+
+// syncACLEntries makes the entries of each ACL with an EntriesFile match the
+// file, recording a change for each entry written or removed.
+func (sy *Syncer) syncACLEntries(s *fastly.Service, acls []ACL) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+	existingACLs, _, err := sy.api.ACL.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, acl := range existingACLs {
+		ids[acl.Name] = acl.ID
+	}
+
+	for _, a := range acls {
+		if a.EntriesFile == "" {
+			continue
+		}
+		if sy.SkipContents {
+			fmt.Printf("Not syncing entries of acl %s on %s, as they would be written before activation.\n", a.Name, s.Name)
+			continue
+		}
+		entries, err := ReadACLEntries(a.EntriesFile)
+		if err != nil {
+			return fmt.Errorf("Error reading entries of acl %s: %s", a.Name, err)
+		}
+		read := len(entries)
+		if a.Aggregate {
+			if entries, err = AggregateACLEntries(entries); err != nil {
+				return fmt.Errorf("Error aggregating entries of acl %s: %s", a.Name, err)
+			}
+		}
+		seen := make(map[string]bool)
+		for _, e := range entries {
+			key := ACLEntryKey(e)
+			if seen[key] {
+				return fmt.Errorf("Entry %s appears more than once in %s", key, a.EntriesFile)
+			}
+			seen[key] = true
+		}
+		existingEntries, _, err := sy.api.ACLEntry.List(s.ID, ids[a.Name], nil)
+		if err != nil {
+			return fmt.Errorf("Error listing entries in acl %s: %s", a.Name, err)
+		}
+		ops, plan := ACLEntryOps(existingEntries, entries, true)
+		for _, key := range plan.Add {
+			sy.RecordChange(s, ACLEntryKind, util.ChangeAdded, a.Name+" "+key)
+		}
+		for _, key := range plan.Update {
+			sy.RecordChange(s, ACLEntryKind, util.ChangeChanged, a.Name+" "+key)
+		}
+		for _, key := range plan.Delete {
+			sy.RecordChange(s, ACLEntryKind, util.ChangeRemoved, a.Name+" "+key)
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		if sy.CheckContents != nil {
+			target := fmt.Sprintf("acl %s on service %s", a.Name, s.Name)
+			if err = sy.CheckContents(target, "entries", plan, len(existingEntries)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := BatchUpdateACL(sy.api.ACLEntry, s.ID, ids[a.Name], ops); err != nil {
+			return fmt.Errorf("Error updating entries in acl %s: %s", a.Name, err)
+		}
+		summary := fmt.Sprintf("%d entries", len(entries))
+		if a.Aggregate {
+			summary = fmt.Sprintf("%d entries aggregated to %d", read, len(entries))
+		}
+		fmt.Printf("Entries of acl %s on %s from %s (%s): %d added, %d updated, %d removed\n", a.Name, s.Name, a.EntriesFile, summary, len(plan.Add), len(plan.Update), len(plan.Delete))
+	}
+	return nil
+}
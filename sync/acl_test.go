@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+func TestACLEntryKey(t *testing.T) {
+	tests := []struct {
+		entry fastly.ACLEntry
+		want  string
+	}{
+		{fastly.ACLEntry{IP: "192.0.2.1"}, "192.0.2.1/0"},
+		{fastly.ACLEntry{IP: "198.51.100.0", Subnet: 24}, "198.51.100.0/24"},
+		{fastly.ACLEntry{IP: "2001:db8::", Subnet: 32, Negated: fastly.CompatiboolTrue}, "2001:db8::/32"},
+	}
+	for _, test := range tests {
+		if got := ACLEntryKey(&test.entry); got != test.want {
+			t.Errorf("ACLEntryKey(%+v) = %s, want %s", test.entry, got, test.want)
+		}
+	}
+}
+
+func TestACLEntryOps(t *testing.T) {
+	existing := []*fastly.ACLEntry{
+		{ID: "1", IP: "192.0.2.1"},
+		{ID: "2", IP: "192.0.2.2"},
+		{ID: "3", IP: "192.0.2.3", Comment: "old"},
+		{ID: "4", IP: "192.0.2.4"},
+	}
+	entries := []*fastly.ACLEntry{
+		{IP: "192.0.2.1"},
+		{IP: "192.0.2.2", Negated: fastly.CompatiboolTrue},
+		{IP: "192.0.2.3", Comment: "new"},
+		{IP: "198.51.100.0", Subnet: 24},
+	}
+	changed := []fastly.ACLEntryUpdate{
+		{Operation: fastly.BatchOperationUpdate, ID: "2", IP: "192.0.2.2", Negated: fastly.CompatiboolTrue},
+		{Operation: fastly.BatchOperationUpdate, ID: "3", IP: "192.0.2.3", Comment: "new"},
+		{Operation: fastly.BatchOperationCreate, IP: "198.51.100.0", Subnet: "24"},
+	}
+	tests := []struct {
+		replace bool
+		ops     []fastly.ACLEntryUpdate
+		plan    util.BatchPlan
+	}{
+		{
+			replace: false,
+			ops:     changed,
+			plan:    util.BatchPlan{Add: []string{"198.51.100.0/24"}, Update: []string{"192.0.2.2/0", "192.0.2.3/0"}},
+		},
+		{
+			replace: true,
+			ops:     append(changed[:len(changed):len(changed)], fastly.ACLEntryUpdate{Operation: fastly.BatchOperationDelete, ID: "4"}),
+			plan:    util.BatchPlan{Add: []string{"198.51.100.0/24"}, Update: []string{"192.0.2.2/0", "192.0.2.3/0"}, Delete: []string{"192.0.2.4/0"}},
+		},
+	}
+	for _, test := range tests {
+		ops, plan := ACLEntryOps(existing, entries, test.replace)
+		if !reflect.DeepEqual(ops, test.ops) {
+			t.Errorf("replace %t: ops = %+v, want %+v", test.replace, ops, test.ops)
+		}
+		if !reflect.DeepEqual(plan, test.plan) {
+			t.Errorf("replace %t: plan = %+v, want %+v", test.replace, plan, test.plan)
+		}
+	}
+}
+
+// aclBatchRecorder records the size of each batch update made of it,
+// failing the update numbered failAt, counting from 1.
+type aclBatchRecorder struct {
+	batches []int
+	failAt  int
+}
+
+func (b *aclBatchRecorder) List(serviceID, aclID string, opts *fastly.ListOptions) ([]*fastly.ACLEntry, *http.Response, error) {
+	return nil, nil, nil
+}
+
+func (b *aclBatchRecorder) BatchUpdate(serviceID, aclID string, entries []fastly.ACLEntryUpdate) (*http.Response, error) {
+	b.batches = append(b.batches, len(entries))
+	if len(b.batches) == b.failAt {
+		return nil, fmt.Errorf("batch %d failed", b.failAt)
+	}
+	return nil, nil
+}
+
+func TestBatchUpdateACL(t *testing.T) {
+	ops := make([]fastly.ACLEntryUpdate, 2*BatchLimit+1)
+
+	api := new(aclBatchRecorder)
+	n, err := BatchUpdateACL(api, "svc", "acl", ops)
+	if err != nil || n != len(ops) {
+		t.Errorf("BatchUpdateACL = %d, %v, want %d, nil", n, err, len(ops))
+	}
+	if want := []int{BatchLimit, BatchLimit, 1}; !reflect.DeepEqual(api.batches, want) {
+		t.Errorf("Batches = %v, want %v", api.batches, want)
+	}
+
+	api = &aclBatchRecorder{failAt: 2}
+	n, err = BatchUpdateACL(api, "svc", "acl", ops)
+	if err == nil || n != BatchLimit {
+		t.Errorf("BatchUpdateACL = %d, %v, want %d and an error", n, err, BatchLimit)
+	}
+	if want := []int{BatchLimit, BatchLimit}; !reflect.DeepEqual(api.batches, want) {
+		t.Errorf("Batches = %v, want %v", api.batches, want)
+	}
+}
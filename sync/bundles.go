@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// bundlesKey is the top-level entry of a config file which holds bundles,
+// rather than the config of a service.
+const bundlesKey = "bundles"
+
+// expandBundles appends the resources of each bundle config uses to its own.
+// Only the lists of resources, such as Headers, are taken from a bundle. A
+// resource which shares its name with another of the same type, in the
+// config itself or an earlier bundle, is an error, as one would silently
+// replace the other.
+func expandBundles(name string, config *SiteConfig, bundles map[string]SiteConfig) error {
+	v := reflect.ValueOf(config).Elem()
+	owners := make(map[string]string)
+	claim := func(field string, list reflect.Value, owner string) error {
+		for i := 0; i < list.Len(); i++ {
+			resource := list.Index(i).FieldByName("Name").String()
+			if resource == "" {
+				continue
+			}
+			key := field + "/" + resource
+			if other, ok := owners[key]; ok {
+				return fmt.Errorf("Service %s: %s defines %s %q, which %s also defines", name, owner, field, resource, other)
+			}
+			owners[key] = owner
+		}
+		return nil
+	}
+	for _, field := range resourceLists() {
+		if err := claim(field, v.FieldByName(field), "its config"); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range config.Bundles {
+		bundle, ok := bundles[b]
+		if !ok {
+			return fmt.Errorf("Service %s uses bundle %s, which is not defined", name, b)
+		}
+		owner := "bundle " + b
+		bv := reflect.ValueOf(bundle)
+		for _, field := range resourceLists() {
+			list := bv.FieldByName(field)
+			if err := claim(field, list, owner); err != nil {
+				return err
+			}
+			v.FieldByName(field).Set(reflect.AppendSlice(v.FieldByName(field), list))
+		}
+	}
+	return nil
+}
+
+// resourceLists returns the names of the fields of SiteConfig which list
+// named resources.
+func resourceLists() []string {
+	var out []string
+	t := reflect.TypeOf(SiteConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.Slice || f.Type.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if _, ok := f.Type.Elem().FieldByName("Name"); ok {
+			out = append(out, f.Name)
+		}
+	}
+	return out
+}
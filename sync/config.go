@@ -0,0 +1,267 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/alienth/go-fastly"
+	"github.com/imdario/mergo"
+	"gopkg.in/yaml.v1"
+)
+
+// SiteConfig is the desired configuration of a single service. A config file
+// maps service names to SiteConfigs, with the _default_ entry merged into
+// each of the others.
+type SiteConfig struct {
+	// Comment is the service's own comment, shown in the Fastly UI. It is
+	// not versioned, so changes to it take effect as soon as they are
+	// pushed. An empty Comment leaves the existing comment untouched.
+	Comment string
+
+	Settings fastly.Settings
+	Domains  []fastly.Domain
+	Backends []fastly.Backend
+	// Directors list their member backends by name in Backends. Quorum,
+	// Retries and Type default to those Fastly gives new directors: 75, 5,
+	// and random.
+	Directors     []fastly.Director
+	Conditions    []fastly.Condition
+	CacheSettings []fastly.CacheSetting
+	Headers       []fastly.Header
+	S3s           []fastly.S3
+	//	FTPs             []fastly.CreateFTPInput
+	//	GCSs             []fastly.CreateGCSInput
+	//	Papertrails      []fastly.CreatePapertrailInput
+	//	Sumologics       []fastly.CreateSumologicInput
+	Syslogs         []fastly.Syslog
+	Gzips           []fastly.Gzip
+	HealthChecks    []fastly.HealthCheck
+	Dictionaries    []Dictionary
+	ACLs            []ACL
+	VCLs            []VCL
+	RequestSettings []fastly.RequestSetting
+	ResponseObject  []ResponseObject
+
+	IPPrefix string
+	IPSuffix string
+
+	S3AccessKey string
+	S3SecretKey string
+
+	// Resources holds the config of resource types added with Register,
+	// keyed by their kind.
+	Resources map[string]interface{}
+
+	// APIKey overrides the global Fastly API key for services which live
+	// under a different account. See util.ResolveKey for the references
+	// which may be used in place of a literal key.
+	APIKey string
+
+	// Groups tags the service for commands which take --group, so that
+	// related services can be addressed together.
+	Groups []string
+
+	// Bundles names the entries of the config file's bundles table whose
+	// resources are added to the service's own, such as a standard set of
+	// logging endpoints shared by many services.
+	Bundles []string
+}
+
+// InGroups reports whether the service belongs to any of groups.
+func (c SiteConfig) InGroups(groups []string) bool {
+	for _, group := range c.Groups {
+		for _, g := range groups {
+			if group == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GroupMembers returns the sorted names of the services in configs which
+// belong to any of groups.
+func GroupMembers(configs map[string]SiteConfig, groups []string) []string {
+	var names []string
+	for name, config := range configs {
+		if name != "_default_" && config.InGroups(groups) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type VCL struct {
+	Name    string
+	Content string
+	File    string
+	Main    bool
+}
+
+// ReadFile reads a file referenced by a config, such as the File of a VCL.
+// Paths may be written with forward slashes, so that configs are portable to
+// Windows, and are relative to the working directory.
+func ReadFile(file string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.FromSlash(file))
+}
+
+// ResponseObject allows the content of a response object to be read from
+// ContentFile, rather than given inline. If ContentType is unset, it is
+// inferred from the file's extension.
+type ResponseObject struct {
+	fastly.ResponseObject
+	ContentFile string
+}
+
+// ReadConfig reads the service configs in file, choosing its format from its
+// extension.
+func ReadConfig(file string) (map[string]SiteConfig, error) {
+	body, format, err := readConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(body, format)
+}
+
+// readConfigFile returns the contents of a config file and its format.
+func readConfigFile(file string) ([]byte, string, error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	var format string
+	switch filepath.Ext(file) {
+	case ".toml":
+		format = "toml"
+	case ".json":
+		format = "json"
+	case ".yaml", ".yml":
+		format = "yaml"
+	default:
+		return nil, "", fmt.Errorf("Unknown config file type for file %s\n", file)
+	}
+	return body, format, nil
+}
+
+// decodeEntries calls decode with each top-level entry of a config, along
+// with a function which decodes the entry into a value.
+func decodeEntries(body []byte, format string, decode func(name string, into func(v interface{}) error) error) error {
+	switch format {
+	case "toml":
+		var entries map[string]toml.Primitive
+		md, err := toml.Decode(string(body), &entries)
+		if err != nil {
+			return fmt.Errorf("toml parsing error: %s\n", err)
+		}
+		for name, entry := range entries {
+			entry := entry
+			if err := decode(name, func(v interface{}) error { return md.PrimitiveDecode(entry, v) }); err != nil {
+				return fmt.Errorf("toml parsing error in %s: %s\n", name, err)
+			}
+		}
+	case "json", "yaml":
+		if format == "yaml" {
+			var doc interface{}
+			if err := yaml.Unmarshal(body, &doc); err != nil {
+				return fmt.Errorf("yaml parsing error: %s\n", err)
+			}
+			converted, err := json.Marshal(yamlToJSON(doc))
+			if err != nil {
+				return fmt.Errorf("yaml parsing error: %s\n", err)
+			}
+			body = converted
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return fmt.Errorf("%s parsing error: %s\n", format, err)
+		}
+		for name, entry := range entries {
+			entry := entry
+			if err := decode(name, func(v interface{}) error { return json.Unmarshal(entry, v) }); err != nil {
+				return fmt.Errorf("%s parsing error in %s: %s\n", format, name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("Unknown config format %s\n", format)
+	}
+	return nil
+}
+
+// ParseConfig reads service configs in the given format, expanding the
+// bundles they use and merging _default_ into each of them, and checks the
+// conditions, health checks and backends they refer to, and the ValueTypes
+// of their dictionaries. YAML documents are
+// read with the same field names as JSON ones.
+func ParseConfig(body []byte, format string) (map[string]SiteConfig, error) {
+	configs := make(map[string]SiteConfig)
+	bundles := make(map[string]SiteConfig)
+	err := decodeEntries(body, format, func(name string, into func(v interface{}) error) error {
+		switch name {
+		case bundlesKey:
+			return into(&bundles)
+		case lintKey:
+			return nil
+		}
+		var config SiteConfig
+		if err := into(&config); err != nil {
+			return err
+		}
+		configs[name] = config
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, config := range configs {
+		if err := expandBundles(name, &config, bundles); err != nil {
+			return nil, err
+		}
+		configs[name] = config
+	}
+
+	for name, config := range configs {
+		if name == "_default_" {
+			continue
+		}
+
+		if err := mergo.Merge(&config, configs["_default_"]); err != nil {
+			return nil, err
+		}
+		configs[name] = config
+	}
+
+	if err := checkReferences(configs); err != nil {
+		return nil, err
+	}
+	if err := checkACLs(configs); err != nil {
+		return nil, err
+	}
+	if err := checkDictionaries(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// yamlToJSON converts the maps of a decoded YAML document, which may have
+// keys of any type, into maps with string keys which can be encoded as JSON.
+func yamlToJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = yamlToJSON(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = yamlToJSON(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,345 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alienth/fastlyctl/log"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+// Dictionary allows the values of a dictionary's items to be checked before
+// they are written, so that VCL reading them with table.lookup_integer and
+// the like never finds a malformed value. ValueType is one of json, int,
+// bool, or regex: followed by a pattern which whole values must match. An
+// empty ValueType allows any value.
+//
+// If ItemsFile is set, push makes the dictionary's items match those in the
+// file, which is read as ReadDictionaryItems does. Items aren't versioned, so
+// they are written as the dictionary is synced, rather than on activation.
+type Dictionary struct {
+	fastly.Dictionary
+	ValueType string `json:",omitempty"`
+	ItemsFile string `json:",omitempty"`
+}
+
+// DictionaryItemKind is the kind of the changes recorded for the items
+// written from an ItemsFile.
+const DictionaryItemKind = "dictionary item"
+
+// BatchLimit is the most dictionary items or ACL entries Fastly accepts in
+// a batch update.
+const BatchLimit = 1000
+
+// Fastly's limits on dictionary items. The item count limit may be raised for
+// an account on request.
+const (
+	MaxDictionaryKeyLength    = 256
+	MaxDictionaryValueLength  = 8000
+	DefaultMaxDictionaryItems = 1000
+)
+
+// CheckDictionaryItems returns an error listing each item which exceeds
+// Fastly's size limits, identified by its row counting from 1, and whether
+// the dictionary would hold more than maxItems once total items are written.
+func CheckDictionaryItems(name string, items []*fastly.DictionaryItem, total, maxItems int) error {
+	var problems []string
+	for i, item := range items {
+		if item.Key == "" {
+			problems = append(problems, fmt.Sprintf("row %d: key is empty", i+1))
+		} else if n := utf8.RuneCountInString(item.Key); n > MaxDictionaryKeyLength {
+			problems = append(problems, fmt.Sprintf("row %d: key %.40q is %d characters, over the limit of %d", i+1, item.Key, n, MaxDictionaryKeyLength))
+		}
+		if n := utf8.RuneCountInString(item.Value); n > MaxDictionaryValueLength {
+			problems = append(problems, fmt.Sprintf("row %d: value of key %.40q is %d characters, over the limit of %d", i+1, item.Key, n, MaxDictionaryValueLength))
+		}
+	}
+	if total > maxItems {
+		problems = append(problems, fmt.Sprintf("dictionary would hold %d items, over the limit of %d", total, maxItems))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Dictionary %s would exceed Fastly's limits:\n  %s", name, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+const valueTypeRegex = "regex:"
+
+// valueChecker returns a function which checks a value against valueType.
+func valueChecker(valueType string) (func(value string) error, error) {
+	switch {
+	case valueType == "":
+		return func(string) error { return nil }, nil
+	case valueType == "json":
+		return func(value string) error {
+			if !json.Valid([]byte(value)) {
+				return fmt.Errorf("is not valid JSON")
+			}
+			return nil
+		}, nil
+	case valueType == "int":
+		return func(value string) error {
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return fmt.Errorf("is not an integer")
+			}
+			return nil
+		}, nil
+	case valueType == "bool":
+		return func(value string) error {
+			if value != "true" && value != "false" {
+				return fmt.Errorf("is not true or false")
+			}
+			return nil
+		}, nil
+	case strings.HasPrefix(valueType, valueTypeRegex):
+		pattern := strings.TrimPrefix(valueType, valueTypeRegex)
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ValueType pattern %q: %s", pattern, err)
+		}
+		return func(value string) error {
+			if !re.MatchString(value) {
+				return fmt.Errorf("does not match %s", pattern)
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("Unknown ValueType %q. Must be json, int, bool, or regex:<pattern>.", valueType)
+}
+
+// CheckValues returns an error listing each item whose value isn't of the
+// dictionary's ValueType.
+func (d Dictionary) CheckValues(items []*fastly.DictionaryItem) error {
+	check, err := valueChecker(d.ValueType)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, item := range items {
+		if err := check(item.Value); err != nil {
+			problems = append(problems, fmt.Sprintf("value %.40q of key %.40q %s", item.Value, item.Key, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Dictionary %s only holds values of ValueType %s:\n  %s", d.Name, d.ValueType, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// checkDictionaries reports each dictionary in configs with an invalid
+// ValueType, or an ItemsFile which can't be synced.
+func checkDictionaries(configs map[string]SiteConfig) error {
+	for name, config := range configs {
+		for _, d := range config.Dictionaries {
+			if _, err := valueChecker(d.ValueType); err != nil {
+				return fmt.Errorf("Service %s: dictionary %s: %s", name, d.Name, err)
+			}
+			if d.ItemsFile != "" && d.WriteOnly {
+				return fmt.Errorf("Service %s: dictionary %s: ItemsFile cannot be set on a write-only dictionary, as its existing items can't be listed.", name, d.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadDictionaryItems reads dictionary items from file. A CSV file holds a
+// key and value per row, and a JSON file a list of items in the format
+// written by dictionary export.
+func ReadDictionaryItems(file string) ([]*fastly.DictionaryItem, error) {
+	body, err := ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*fastly.DictionaryItem
+	switch filepath.Ext(file) {
+	case ".csv":
+		r := csv.NewReader(bytes.NewReader(body))
+		r.FieldsPerRecord = 2
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			items = append(items, &fastly.DictionaryItem{Key: record[0], Value: record[1]})
+		}
+	case ".json":
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", file, err)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown item file type for file %s. Must be .csv or .json.", file)
+	}
+	return items, nil
+}
+
+// DictionaryItemOps returns the batch operations which make a dictionary's
+// existing items match items, along with the plan they carry out. Existing
+// items which aren't in items are only deleted if replace is set.
+func DictionaryItemOps(existingItems, items []*fastly.DictionaryItem, replace bool) ([]fastly.DictionaryItemUpdate, util.BatchPlan) {
+	existing := make(map[string]string)
+	for _, item := range existingItems {
+		existing[item.Key] = item.Value
+	}
+
+	var ops []fastly.DictionaryItemUpdate
+	var plan util.BatchPlan
+	wanted := make(map[string]bool)
+	for _, item := range items {
+		wanted[item.Key] = true
+		value, ok := existing[item.Key]
+		if !ok {
+			log.Debug(fmt.Sprintf("Creating missing dictionary item %s.\n", item.Key))
+			ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationCreate, Key: item.Key, Value: item.Value})
+			plan.Add = append(plan.Add, item.Key)
+		} else if value != item.Value {
+			log.Debug(fmt.Sprintf("Found mismatched dictionary item %s. Updating.\n", item.Key))
+			ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationUpdate, Key: item.Key, Value: item.Value})
+			plan.Update = append(plan.Update, item.Key)
+		}
+	}
+	if replace {
+		for _, item := range existingItems {
+			if !wanted[item.Key] {
+				log.Debug(fmt.Sprintf("Found non-matching dictionary item %s. Deleting.\n", item.Key))
+				ops = append(ops, fastly.DictionaryItemUpdate{Operation: fastly.BatchOperationDelete, Key: item.Key})
+				plan.Delete = append(plan.Delete, item.Key)
+			}
+		}
+	}
+	return ops, plan
+}
+
+// BatchUpdateDictionary applies ops to a dictionary BatchLimit at a time,
+// returning the number applied.
+func BatchUpdateDictionary(api util.DictionaryItemAPI, serviceID, dictionaryID string, ops []fastly.DictionaryItemUpdate) (int, error) {
+	for i := 0; i < len(ops); i += BatchLimit {
+		end := i + BatchLimit
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if _, err := api.BatchUpdate(serviceID, dictionaryID, ops[i:end]); err != nil {
+			return i, err
+		}
+	}
+	return len(ops), nil
+}
+
+// maxDictionaryItems returns the most items a synced dictionary may hold.
+func (sy *Syncer) maxDictionaryItems() int {
+	if sy.MaxDictionaryItems > 0 {
+		return sy.MaxDictionaryItems
+	}
+	return DefaultMaxDictionaryItems
+}
+
+// syncDictionaryItems makes the items of each dictionary with an ItemsFile
+// match the file, recording a change for each item written or removed.
+func (sy *Syncer) syncDictionaryItems(s *fastly.Service, dictionaries []Dictionary) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+	existingDictionaries, _, err := sy.api.Dictionary.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for _, dictionary := range existingDictionaries {
+		ids[dictionary.Name] = dictionary.ID
+	}
+
+	for _, d := range dictionaries {
+		if d.ItemsFile == "" {
+			continue
+		}
+		if sy.SkipContents {
+			fmt.Printf("Not syncing items of dictionary %s on %s, as they would be written before activation.\n", d.Name, s.Name)
+			continue
+		}
+		items, err := ReadDictionaryItems(d.ItemsFile)
+		if err != nil {
+			return fmt.Errorf("Error reading items of dictionary %s: %s", d.Name, err)
+		}
+		if err = d.CheckValues(items); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, item := range items {
+			if seen[item.Key] {
+				return fmt.Errorf("Key %s appears more than once in %s", item.Key, d.ItemsFile)
+			}
+			seen[item.Key] = true
+		}
+		// The file replaces the dictionary's items, so it holds as many
+		// as the file does.
+		if err = CheckDictionaryItems(d.Name, items, len(items), sy.maxDictionaryItems()); err != nil {
+			return err
+		}
+		existingItems, _, err := sy.api.DictionaryItem.List(s.ID, ids[d.Name], nil)
+		if err != nil {
+			return fmt.Errorf("Error listing items in dictionary %s: %s", d.Name, err)
+		}
+		ops, plan := DictionaryItemOps(existingItems, items, true)
+		for _, key := range plan.Add {
+			sy.RecordChange(s, DictionaryItemKind, util.ChangeAdded, d.Name+"/"+key)
+		}
+		for _, key := range plan.Update {
+			sy.RecordChange(s, DictionaryItemKind, util.ChangeChanged, d.Name+"/"+key)
+		}
+		for _, key := range plan.Delete {
+			sy.RecordChange(s, DictionaryItemKind, util.ChangeRemoved, d.Name+"/"+key)
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		if sy.CheckContents != nil {
+			target := fmt.Sprintf("dictionary %s on service %s", d.Name, s.Name)
+			if err = sy.CheckContents(target, "items", plan, len(existingItems)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := BatchUpdateDictionary(sy.api.DictionaryItem, s.ID, ids[d.Name], ops); err != nil {
+			return fmt.Errorf("Error updating items in dictionary %s: %s", d.Name, err)
+		}
+		fmt.Printf("Items of dictionary %s on %s from %s: %d added, %d updated, %d removed\n", d.Name, s.Name, d.ItemsFile, len(plan.Add), len(plan.Update), len(plan.Delete))
+	}
+	return nil
+}
+
+// ConfiguredDictionary returns the config of a service's dictionary in the
+// config file, so that commands which write items can check them. It is nil
+// if the file doesn't exist or doesn't define the dictionary.
+func ConfiguredDictionary(file, service, dictionary string) (*Dictionary, error) {
+	configs, err := ReadConfig(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Error reading config file: %s", err)
+	}
+	return LookupDictionary(configs, service, dictionary), nil
+}
+
+// LookupDictionary returns the config of a service's dictionary, or nil if
+// configs don't define it.
+func LookupDictionary(configs map[string]SiteConfig, service, dictionary string) *Dictionary {
+	for _, d := range configs[service].Dictionaries {
+		if d.Name == dictionary {
+			return &d
+		}
+	}
+	return nil
+}
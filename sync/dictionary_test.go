@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+func TestDictionaryItemOps(t *testing.T) {
+	existing := []*fastly.DictionaryItem{
+		{Key: "kept", Value: "1"},
+		{Key: "changed", Value: "1"},
+		{Key: "other", Value: "1"},
+	}
+	items := []*fastly.DictionaryItem{
+		{Key: "kept", Value: "1"},
+		{Key: "changed", Value: "2"},
+		{Key: "added", Value: "1"},
+	}
+	tests := []struct {
+		replace bool
+		ops     []fastly.DictionaryItemUpdate
+		plan    util.BatchPlan
+	}{
+		{
+			replace: false,
+			ops: []fastly.DictionaryItemUpdate{
+				{Operation: fastly.BatchOperationUpdate, Key: "changed", Value: "2"},
+				{Operation: fastly.BatchOperationCreate, Key: "added", Value: "1"},
+			},
+			plan: util.BatchPlan{Add: []string{"added"}, Update: []string{"changed"}},
+		},
+		{
+			replace: true,
+			ops: []fastly.DictionaryItemUpdate{
+				{Operation: fastly.BatchOperationUpdate, Key: "changed", Value: "2"},
+				{Operation: fastly.BatchOperationCreate, Key: "added", Value: "1"},
+				{Operation: fastly.BatchOperationDelete, Key: "other"},
+			},
+			plan: util.BatchPlan{Add: []string{"added"}, Update: []string{"changed"}, Delete: []string{"other"}},
+		},
+	}
+	for _, test := range tests {
+		ops, plan := DictionaryItemOps(existing, items, test.replace)
+		if !reflect.DeepEqual(ops, test.ops) {
+			t.Errorf("replace %t: ops = %+v, want %+v", test.replace, ops, test.ops)
+		}
+		if !reflect.DeepEqual(plan, test.plan) {
+			t.Errorf("replace %t: plan = %+v, want %+v", test.replace, plan, test.plan)
+		}
+	}
+}
+
+// batchRecorder records the size of each batch update made of it, failing
+// the update numbered failAt, counting from 1.
+type batchRecorder struct {
+	batches []int
+	failAt  int
+}
+
+func (b *batchRecorder) List(serviceID, dictionaryID string, opts *fastly.ListOptions) ([]*fastly.DictionaryItem, *http.Response, error) {
+	return nil, nil, nil
+}
+
+func (b *batchRecorder) BatchUpdate(serviceID, dictionaryID string, items []fastly.DictionaryItemUpdate) (*http.Response, error) {
+	b.batches = append(b.batches, len(items))
+	if len(b.batches) == b.failAt {
+		return nil, fmt.Errorf("batch %d failed", b.failAt)
+	}
+	return nil, nil
+}
+
+func TestBatchUpdateDictionary(t *testing.T) {
+	ops := make([]fastly.DictionaryItemUpdate, 2*BatchLimit+1)
+
+	api := new(batchRecorder)
+	n, err := BatchUpdateDictionary(api, "svc", "dict", ops)
+	if err != nil || n != len(ops) {
+		t.Errorf("BatchUpdateDictionary = %d, %v, want %d, nil", n, err, len(ops))
+	}
+	if want := []int{BatchLimit, BatchLimit, 1}; !reflect.DeepEqual(api.batches, want) {
+		t.Errorf("Batches = %v, want %v", api.batches, want)
+	}
+
+	api = &batchRecorder{failAt: 2}
+	n, err = BatchUpdateDictionary(api, "svc", "dict", ops)
+	if err == nil || n != BatchLimit {
+		t.Errorf("BatchUpdateDictionary = %d, %v, want %d and an error", n, err, BatchLimit)
+	}
+	if want := []int{BatchLimit, BatchLimit}; !reflect.DeepEqual(api.batches, want) {
+		t.Errorf("Batches = %v, want %v", api.batches, want)
+	}
+}
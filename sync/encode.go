@@ -0,0 +1,180 @@
+package sync
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+	"gopkg.in/yaml.v1"
+)
+
+// ConfigFormats are the formats config files may be written in, as chosen
+// by their extension.
+var ConfigFormats = []string{"toml", "json", "yaml"}
+
+// EncodeConfig writes the config of the named service as a config file in
+// format, which ParseConfig reads back as the same SiteConfig. Fields left
+// at their zero value are left out.
+func EncodeConfig(name string, config SiteConfig, format string) ([]byte, error) {
+	switch format {
+	case "toml":
+		doc := map[string]interface{}{name: configValue(reflect.ValueOf(config), false)}
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "json":
+		doc := map[string]interface{}{name: configValue(reflect.ValueOf(config), true)}
+		body, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(body, '\n'), nil
+	case "yaml":
+		// YAML configs are read by way of JSON, so hold the same document.
+		doc := map[string]interface{}{name: configValue(reflect.ValueOf(config), true)}
+		return yaml.Marshal(doc)
+	}
+	return nil, fmt.Errorf("Unknown config format %s. Must be one of %s.", format, strings.Join(ConfigFormats, ", "))
+}
+
+// configValue converts v into a document which decodes back into it, or nil
+// if v should be left out. Structs become tables keyed by field name, or by
+// their JSON names if jsonNames is set, with embedded structs flattened into
+// them. Compatibools become booleans, and go-fastly's enumerations their
+// names.
+func configValue(v reflect.Value, jsonNames bool) interface{} {
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	// Take an addressable copy, so that methods with pointer receivers,
+	// such as MarshalText of HeaderAction, are found.
+	addressable := reflect.New(v.Type()).Elem()
+	addressable.Set(v)
+	v = addressable
+	switch i := v.Addr().Interface().(type) {
+	case *fastly.Compatibool:
+		if !i.IsSet() {
+			return nil
+		}
+		return i.Bool()
+	case encoding.TextMarshaler:
+		text, err := i.MarshalText()
+		if err != nil {
+			return nil
+		}
+		return string(text)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return configValue(v.Elem(), jsonNames)
+	case reflect.Struct:
+		return configFields(v, jsonNames, make(map[string]interface{}))
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			// Lists of tables must hold every element, even those
+			// which are empty.
+			tables := make([]map[string]interface{}, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				tables[i] = configFields(v.Index(i), jsonNames, make(map[string]interface{}))
+			}
+			return tables
+		}
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if value := configValue(v.Index(i), jsonNames); value != nil {
+				values = append(values, value)
+			}
+		}
+		return values
+	case reflect.Map:
+		table := make(map[string]interface{})
+		for _, key := range v.MapKeys() {
+			if value := configValue(v.MapIndex(key), jsonNames); value != nil {
+				table[fmt.Sprint(key.Interface())] = value
+			}
+		}
+		return table
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	}
+	return v.Interface()
+}
+
+// configFields adds the exported fields of the struct v to table, returning
+// it.
+func configFields(v reflect.Value, jsonNames bool, table map[string]interface{}) map[string]interface{} {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("toml")
+		if jsonNames {
+			tag = f.Tag.Get("json")
+		}
+		opts := strings.Split(tag, ",")
+		if opts[0] == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && opts[0] == "" {
+			configFields(v.Field(i), jsonNames, table)
+			continue
+		}
+		name := f.Name
+		if opts[0] != "" {
+			name = opts[0]
+		}
+		value := configValue(v.Field(i), jsonNames)
+		if value == nil {
+			continue
+		}
+		// Fields tagged with the string option are read from JSON
+		// strings.
+		if jsonNames && util.StringInSlice("string", opts[1:]) {
+			value = fmt.Sprint(value)
+		}
+		table[name] = value
+	}
+	return table
+}
+
+// RoundTrip writes the config of a snapshot in format, reads it back, and
+// pushes it to a copy of the snapshot in an in-process fake of the API,
+// returning any changes made. A config which faithfully records the
+// snapshot makes none. As with Plan, the fake is fastlytest, so fields the
+// real API would change on write aren't caught.
+func RoundTrip(snapshot *Snapshot, format string) (util.ChangeLog, error) {
+	name := snapshot.Service.Name
+	body, err := EncodeConfig(name, snapshot.SiteConfig(), format)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := ParseConfig(body, format)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading back %s config: %s", format, err)
+	}
+
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s, err := LoadSnapshot(srv, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	sy := NewSyncer(srv.Client(), map[string]SiteConfig{name: configs[name]})
+	// The fake knows no POPs, and shields are compared as they are.
+	sy.api.Datacenter = nil
+	if err = sy.sync(s); err != nil {
+		return nil, err
+	}
+	return sy.Changes(s), nil
+}
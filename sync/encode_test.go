@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	// Fields are left at their zero value, or false, where they can be.
+	// The fake stores resources as given, so those the API fills in, such
+	// as a header's priority, are set as it would.
+	resources := []interface{}{
+		&fastly.Dictionary{Name: "secrets", WriteOnly: true},
+		&fastly.Dictionary{Name: "plain"},
+		&fastly.Gzip{Name: "gzip"},
+		&fastly.Header{Name: "header", Action: fastly.HeaderActionSet, Type: fastly.HeaderTypeRequest, Destination: "http.X", Source: "\"\"", IgnoreIfSet: fastly.CompatiboolFalse, Priority: defaultHeaderPriority},
+		&fastly.RequestSetting{Name: "request", ForceMiss: fastly.CompatiboolFalse, ForceSSL: fastly.CompatiboolFalse, BypassBusyWait: fastly.CompatiboolFalse},
+		&fastly.CacheSetting{Name: "cache", Action: fastly.CacheSettingActionPass},
+		&fastly.Syslog{Name: "syslog", Address: "logs.example.com", UseTLS: fastly.CompatiboolFalse},
+		&fastly.Backend{Name: "origin", Address: "origin.example.com", Hostname: "origin.example.com", Shield: ""},
+		&fastly.Director{Name: "pool", Shield: "", Quorum: defaultDirectorQuorum, Retries: defaultDirectorRetries, Type: fastly.DirectorTypeRandom},
+	}
+	for _, r := range resources {
+		if err := srv.AddResource(s.ID, s.Version, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srv.SetSettings(s.ID, s.Version, fastly.Settings{DefaultTTL: 3600}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := FetchSnapshot(srv.Client(), s, s.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range ConfigFormats {
+		t.Run(format, func(t *testing.T) {
+			body, err := EncodeConfig(testService, snapshot.SiteConfig(), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			configs, err := ParseConfig(body, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A dictionary can't be made write-only once created, so a
+			// config which loses the flag can't be pushed.
+			if d := LookupDictionary(configs, testService, "secrets"); d == nil || !d.WriteOnly {
+				t.Errorf("Dictionary secrets read back as %+v, want it write-only", d)
+			}
+
+			changes, err := RoundTrip(snapshot, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, change := range changes {
+				t.Errorf("Round trip %s %s %s", change.Action, change.Kind, change.Name)
+			}
+		})
+	}
+}
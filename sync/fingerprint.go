@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/alienth/go-fastly"
+)
+
+// Tags recorded in the comment of each draft, identifying the config it was
+// synced from and who synced it.
+const (
+	fingerprintTag = "config="
+	pushedByTag    = "pushed-by="
+)
+
+// Fingerprint returns a short hash identifying config, including the
+// contents of the VCL and response object files it references. Its API key
+// and groups, the ValueTypes and ItemsFiles of its dictionaries, and the
+// EntriesFiles of its ACLs, which have no bearing on the version, are left
+// out.
+func Fingerprint(config SiteConfig) (string, error) {
+	config.APIKey = ""
+	config.Groups = nil
+	dictionaries := make([]Dictionary, len(config.Dictionaries))
+	for i, d := range config.Dictionaries {
+		d.ValueType, d.ItemsFile = "", ""
+		dictionaries[i] = d
+	}
+	config.Dictionaries = dictionaries
+	acls := make([]ACL, len(config.ACLs))
+	for i, a := range config.ACLs {
+		a.EntriesFile, a.Aggregate = "", false
+		acls[i] = a
+	}
+	config.ACLs = acls
+	vcls := make([]VCL, len(config.VCLs))
+	for i, v := range config.VCLs {
+		if v.File != "" {
+			content, err := ReadFile(v.File)
+			if err != nil {
+				return "", err
+			}
+			v.Content, v.File = string(content), ""
+		}
+		vcls[i] = v
+	}
+	config.VCLs = vcls
+	responseObjects := make([]ResponseObject, len(config.ResponseObject))
+	for i, ro := range config.ResponseObject {
+		if ro.ContentFile != "" {
+			content, err := ReadFile(ro.ContentFile)
+			if err != nil {
+				return "", err
+			}
+			ro.Content, ro.ContentFile = string(content), ""
+		}
+		responseObjects[i] = ro
+	}
+	config.ResponseObject = responseObjects
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])[:12], nil
+}
+
+// ParseVersionComment returns the config fingerprint and user recorded in a
+// version comment by Apply, which are empty if they weren't recorded.
+func ParseVersionComment(comment string) (fingerprint, user string) {
+	for _, field := range strings.Fields(comment) {
+		if strings.HasPrefix(field, fingerprintTag) {
+			fingerprint = strings.TrimPrefix(field, fingerprintTag)
+		} else if strings.HasPrefix(field, pushedByTag) {
+			user = strings.TrimPrefix(field, pushedByTag)
+		}
+	}
+	return fingerprint, user
+}
+
+// tagComment replaces the fingerprint and user recorded in comment.
+func tagComment(comment, fingerprint, user string) string {
+	var fields []string
+	for _, field := range strings.Split(comment, " ") {
+		if !strings.HasPrefix(field, fingerprintTag) && !strings.HasPrefix(field, pushedByTag) {
+			fields = append(fields, field)
+		}
+	}
+	fields = append(fields, fingerprintTag+fingerprint)
+	if user != "" {
+		fields = append(fields, pushedByTag+user)
+	}
+	return strings.Join(fields, " ")
+}
+
+// recordFingerprint tags the draft of s with the fingerprint of its config.
+func (sy *Syncer) recordFingerprint(s *fastly.Service, version *fastly.Version) error {
+	fingerprint, err := Fingerprint(sy.Config(s.Name))
+	if err != nil {
+		return err
+	}
+	comment := tagComment(version.Comment, fingerprint, strings.Join(strings.Fields(sy.User), "_"))
+	if comment == version.Comment {
+		return nil
+	}
+	update := *version
+	update.Comment = comment
+	update.Updated, update.Created = "", ""
+	if _, _, err := sy.api.Version.Update(s.ID, version.Number, &update); err != nil {
+		return err
+	}
+	version.Comment = comment
+	sy.SetDraft(s, *version)
+	return nil
+}
@@ -0,0 +1,175 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lintKey is the top-level entry of a config file which sets the severity of
+// lint rules, rather than the config of a service.
+const lintKey = "lint"
+
+// LintSeverity is whether breaking a lint rule is an error, which push
+// refuses, or only a warning.
+type LintSeverity string
+
+const (
+	LintOff     LintSeverity = "off"
+	LintWarning LintSeverity = "warning"
+	LintError   LintSeverity = "error"
+)
+
+// LintRule is a policy which each service config is checked against.
+type LintRule struct {
+	Name        string
+	Description string
+	// Check describes each way config breaks the rule.
+	Check func(config SiteConfig) []string
+}
+
+// LintRules are the built-in rules, all of which are warnings unless the
+// config file's lint table says otherwise.
+var LintRules = []LintRule{
+	{
+		Name:        "backend-health-check",
+		Description: "Every backend has a health check.",
+		Check: func(config SiteConfig) []string {
+			var out []string
+			for _, b := range config.Backends {
+				if b.Name != "" && b.HealthCheck == "" {
+					out = append(out, fmt.Sprintf("backend %s has no health check", b.Name))
+				}
+			}
+			return out
+		},
+	},
+	{
+		Name:        "request-setting-force-tls",
+		Description: "Every request setting forces TLS.",
+		Check: func(config SiteConfig) []string {
+			var out []string
+			for _, r := range config.RequestSettings {
+				if r.Name != "" && !r.ForceSSL.Bool() {
+					out = append(out, fmt.Sprintf("request setting %s doesn't set ForceSSL", r.Name))
+				}
+			}
+			return out
+		},
+	},
+	{
+		Name:        "no-wildcard-domains",
+		Description: "No domain is a wildcard, such as *.example.com.",
+		Check: func(config SiteConfig) []string {
+			var out []string
+			for _, d := range config.Domains {
+				if strings.Contains(d.Name, "*") {
+					out = append(out, fmt.Sprintf("domain %s is a wildcard", d.Name))
+				}
+			}
+			return out
+		},
+	},
+	{
+		Name:        "logging-endpoint",
+		Description: "Every service has a logging endpoint, such as an S3 bucket or syslog.",
+		Check: func(config SiteConfig) []string {
+			for _, s := range config.S3s {
+				if s.Name != "" {
+					return nil
+				}
+			}
+			for _, s := range config.Syslogs {
+				if s.Name != "" {
+					return nil
+				}
+			}
+			return []string{"no logging endpoint is configured"}
+		},
+	},
+}
+
+// LintPolicy sets the severity of lint rules by name, as read from the lint
+// table of a config file. Rules it leaves out are warnings.
+type LintPolicy map[string]LintSeverity
+
+// Severity returns the severity of the named rule.
+func (p LintPolicy) Severity(rule string) LintSeverity {
+	if severity, ok := p[rule]; ok {
+		return severity
+	}
+	return LintWarning
+}
+
+func (p LintPolicy) validate() error {
+	for rule, severity := range p {
+		var known bool
+		for _, r := range LintRules {
+			known = known || r.Name == rule
+		}
+		if !known {
+			return fmt.Errorf("Unknown lint rule %s", rule)
+		}
+		switch severity {
+		case LintOff, LintWarning, LintError:
+		default:
+			return fmt.Errorf("Lint rule %s has severity %q. Must be one of off, warning, or error.", rule, severity)
+		}
+	}
+	return nil
+}
+
+// ReadLintPolicy reads the lint table of a config file, which is empty if the
+// file has none.
+func ReadLintPolicy(file string) (LintPolicy, error) {
+	body, format, err := readConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := make(LintPolicy)
+	err = decodeEntries(body, format, func(name string, into func(v interface{}) error) error {
+		if name != lintKey {
+			return nil
+		}
+		return into(&policy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// LintFinding is a way a service breaks a lint rule.
+type LintFinding struct {
+	Service  string
+	Rule     string
+	Severity LintSeverity
+	Message  string
+}
+
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Severity, f.Service, f.Message, f.Rule)
+}
+
+// Lint checks the named services against the rules policy leaves on,
+// returning the findings by service, in the order of the rules.
+func Lint(configs map[string]SiteConfig, names []string, policy LintPolicy) []LintFinding {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	var findings []LintFinding
+	for _, name := range names {
+		for _, rule := range LintRules {
+			severity := policy.Severity(rule.Name)
+			if severity == LintOff {
+				continue
+			}
+			for _, message := range rule.Check(configs[name]) {
+				findings = append(findings, LintFinding{Service: name, Rule: rule.Name, Severity: severity, Message: message})
+			}
+		}
+	}
+	return findings
+}
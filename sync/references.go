@@ -0,0 +1,124 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alienth/go-fastly"
+)
+
+// conditionRef is a condition named by a resource, which must be a condition
+// of want's type.
+type conditionRef struct {
+	resource string
+	field    string
+	name     string
+	want     fastly.ConditionType
+}
+
+// conditionRefs returns the conditions named by the resources of config.
+func conditionRefs(config SiteConfig) []conditionRef {
+	var refs []conditionRef
+	add := func(resource, field, name string, want fastly.ConditionType) {
+		if name != "" {
+			refs = append(refs, conditionRef{resource, field, name, want})
+		}
+	}
+	for _, b := range config.Backends {
+		add("backend "+b.Name, "RequestCondition", b.RequestCondition, fastly.ConditionTypeRequest)
+	}
+	for _, h := range config.Headers {
+		add("header "+h.Name, "RequestCondition", h.RequestCondition, fastly.ConditionTypeRequest)
+		add("header "+h.Name, "CacheCondition", h.CacheCondition, fastly.ConditionTypeCache)
+		add("header "+h.Name, "ResponseCondition", h.ResponseCondition, fastly.ConditionTypeResponse)
+	}
+	for _, c := range config.CacheSettings {
+		add("cache setting "+c.Name, "CacheCondition", c.CacheCondition, fastly.ConditionTypeCache)
+	}
+	for _, g := range config.Gzips {
+		add("gzip "+g.Name, "CacheCondition", g.CacheCondition, fastly.ConditionTypeCache)
+	}
+	for _, r := range config.RequestSettings {
+		add("request setting "+r.Name, "RequestCondition", r.RequestCondition, fastly.ConditionTypeRequest)
+	}
+	for _, r := range config.ResponseObject {
+		add("response object "+r.Name, "RequestCondition", r.RequestCondition, fastly.ConditionTypeRequest)
+		add("response object "+r.Name, "CacheCondition", r.CacheCondition, fastly.ConditionTypeCache)
+	}
+	for _, s := range config.S3s {
+		add("s3 "+s.Name, "ResponseCondition", s.ResponseCondition, fastly.ConditionTypeResponse)
+	}
+	for _, s := range config.Syslogs {
+		add("syslog "+s.Name, "ResponseCondition", s.ResponseCondition, fastly.ConditionTypeResponse)
+	}
+	return refs
+}
+
+// brokenReferences describes each condition, health check and backend named in
+// config which it doesn't define, or which is a condition of the wrong type. As
+// resources absent from a config are removed by the sync, one defined only in
+// Fastly doesn't count.
+func brokenReferences(config SiteConfig) []string {
+	conditions := make(map[string]fastly.ConditionType)
+	for _, c := range config.Conditions {
+		// Conditions without a type are created as request conditions.
+		if c.Type == 0 {
+			c.Type = fastly.ConditionTypeRequest
+		}
+		conditions[c.Name] = c.Type
+	}
+	healthChecks := make(map[string]bool)
+	for _, h := range config.HealthChecks {
+		healthChecks[h.Name] = true
+	}
+
+	var broken []string
+	for _, ref := range conditionRefs(config) {
+		have, ok := conditions[ref.name]
+		if !ok {
+			broken = append(broken, fmt.Sprintf("%s: %s %q is not a defined condition", ref.resource, ref.field, ref.name))
+		} else if have != ref.want {
+			haveText, _ := have.MarshalText()
+			wantText, _ := ref.want.MarshalText()
+			broken = append(broken, fmt.Sprintf("%s: %s %q is a %s condition, not %s", ref.resource, ref.field, ref.name, haveText, wantText))
+		}
+	}
+	backends := make(map[string]bool)
+	for _, b := range config.Backends {
+		backends[b.Name] = true
+		if b.HealthCheck != "" && !healthChecks[b.HealthCheck] {
+			broken = append(broken, fmt.Sprintf("backend %s: HealthCheck %q is not a defined health check", b.Name, b.HealthCheck))
+		}
+	}
+	for _, d := range config.Directors {
+		for _, b := range d.Backends {
+			if !backends[b] {
+				broken = append(broken, fmt.Sprintf("director %s: backend %q is not a defined backend", d.Name, b))
+			}
+		}
+	}
+	return broken
+}
+
+// checkReferences reports every broken reference among configs at once, so
+// that they can all be fixed before any change is made.
+func checkReferences(configs map[string]SiteConfig) error {
+	var names []string
+	for name := range configs {
+		if name != "_default_" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var broken []string
+	for _, name := range names {
+		for _, problem := range brokenReferences(configs[name]) {
+			broken = append(broken, name+": "+problem)
+		}
+	}
+	if len(broken) == 0 {
+		return nil
+	}
+	return fmt.Errorf("The config has broken references:\n  %s", strings.Join(broken, "\n  "))
+}
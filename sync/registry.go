@@ -0,0 +1,96 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/alienth/go-fastly"
+)
+
+// ResourceSyncer syncs a resource type which isn't built into SiteConfig,
+// such as a logging endpoint go-fastly doesn't yet support. Its config is
+// read from the Resources of each service, keyed by Kind.
+type ResourceSyncer interface {
+	// Kind names the resource type in config and change logs.
+	Kind() string
+
+	// Sync makes the resources of the draft version of s match config,
+	// which is nil for services which don't configure any. Changes should
+	// be recorded with RecordChange. The returned bool reports changes
+	// which don't show in the version diff, so that the draft is kept
+	// regardless.
+	Sync(sy *Syncer, s *fastly.Service, version uint, config interface{}) (bool, error)
+}
+
+// registry holds the registered resource syncers, in registration order.
+var registry []ResourceSyncer
+
+// Register adds a resource syncer, which Apply runs after the built-in
+// resource types. It panics if a syncer is already registered for the same
+// kind.
+func Register(r ResourceSyncer) {
+	for _, existing := range registry {
+		if existing.Kind() == r.Kind() {
+			panic(fmt.Sprintf("sync: Register called twice for resource type %s", r.Kind()))
+		}
+	}
+	registry = append(registry, r)
+}
+
+// Steps returns the number of resource types synced by Apply.
+func Steps() int {
+	return builtinSteps + len(registry)
+}
+
+// DecodeResource decodes the config given to a ResourceSyncer into v, using
+// the same field names as a JSON config.
+func DecodeResource(config interface{}, v interface{}) error {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// checkResources returns an error if config includes resources of a type no
+// syncer is registered for, which would otherwise be silently ignored.
+func checkResources(config SiteConfig) error {
+	var unknown []string
+	for kind := range config.Resources {
+		var found bool
+		for _, r := range registry {
+			if r.Kind() == kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, kind)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("No syncer is registered for resource type %s", unknown[0])
+	}
+	return nil
+}
+
+// syncResources runs each registered resource syncer against s, counting its
+// steps on from start.
+func (sy *Syncer) syncResources(s *fastly.Service, config SiteConfig, start int) (bool, error) {
+	var changesMade bool
+	for i, r := range registry {
+		sy.step(s, start+i, r.Kind())
+		version, err := sy.PrepareDraft(s)
+		if err != nil {
+			return changesMade, err
+		}
+		changed, err := r.Sync(sy, s, version.Number, config.Resources[r.Kind()])
+		if err != nil {
+			return changesMade, fmt.Errorf("Error syncing %s: %s", r.Kind(), err)
+		}
+		changesMade = changesMade || changed
+	}
+	return changesMade, nil
+}
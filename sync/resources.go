@@ -0,0 +1,1261 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alienth/fastlyctl/log"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+const (
+	defaultHealthCheckHTTPVersion = "1.1"
+	defaultS3TimestampFormat      = "%Y-%m-%dT%H:%M:%S.000"
+
+	// Priorities Fastly assigns to headers and conditions created without one.
+	defaultHeaderPriority    = 100
+	defaultConditionPriority = 10
+
+	// Quorum percentage and retries Fastly assigns to directors created
+	// without them.
+	defaultDirectorQuorum  = 75
+	defaultDirectorRetries = 5
+)
+
+// EffectiveValues describes the fields of a resource, given as a value or a
+// pointer, which Fastly defaults when config leaves them out, such as the
+// priority of a header, so that plans show the values which are pushed. It
+// returns "" for resources without such fields.
+func EffectiveValues(resource interface{}) string {
+	switch r := resource.(type) {
+	case *fastly.Header:
+		return EffectiveValues(*r)
+	case *fastly.Condition:
+		return EffectiveValues(*r)
+	case fastly.Header:
+		return fmt.Sprintf("priority %d", r.Priority)
+	case fastly.Condition:
+		return fmt.Sprintf("priority %d", r.Priority)
+	}
+	return ""
+}
+
+// ContentHash returns a digest of content, used to compare and report on
+// potentially large bodies such as VCL and response object content.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func vclsEqual(a, b fastly.VCL) bool {
+	ah, bh := ContentHash(a.Content), ContentHash(b.Content)
+	a.Content, b.Content = "", ""
+	return a == b && ah == bh
+}
+
+// sortedFields sorts a space-separated list, so that lists may be compared
+// regardless of the order Fastly returns them in.
+func sortedFields(list string) string {
+	fields := strings.Fields(list)
+	sort.Strings(fields)
+	return strings.Join(fields, " ")
+}
+
+// gzipsEqual compares gzips, treating their content types and extensions as
+// unordered sets.
+func gzipsEqual(a, b fastly.Gzip) bool {
+	a.ContentTypes, b.ContentTypes = sortedFields(a.ContentTypes), sortedFields(b.ContentTypes)
+	a.Extensions, b.Extensions = sortedFields(a.Extensions), sortedFields(b.Extensions)
+	return a == b
+}
+
+func responseObjectsEqual(a, b fastly.ResponseObject) bool {
+	ah, bh := ContentHash(a.Content), ContentHash(b.Content)
+	a.Content, b.Content = "", ""
+	return a == b && ah == bh
+}
+
+func (sy *Syncer) syncVCLs(s *fastly.Service, vcls []VCL) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	var newVCLs []fastly.VCL
+
+	for _, vcl := range vcls {
+		if vcl == (VCL{}) {
+			continue
+		}
+		var newVCL fastly.VCL
+		if vcl.File != "" && vcl.Content != "" {
+			return fmt.Errorf("Cannot specify both a File and Content for VCL %s", vcl.Name)
+		}
+		if vcl.File != "" {
+			var content []byte
+			if content, err = ReadFile(vcl.File); err != nil {
+				return err
+			}
+			newVCL.Content = string(content)
+		} else if vcl.Content != "" {
+			newVCL.Content = vcl.Content
+		} else {
+			return fmt.Errorf("No Content or File specified for VCL %s", vcl.Name)
+		}
+		newVCL.Main = vcl.Main
+		newVCL.Name = vcl.Name
+		newVCLs = append(newVCLs, newVCL)
+	}
+
+	existingVCLs, _, err := sy.api.VCL.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, vcl := range existingVCLs {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		vcl.ServiceID = ""
+		vcl.Version = 0
+		for i, newVCL := range newVCLs {
+			if vclsEqual(*vcl, newVCL) {
+				log.Debug(fmt.Sprintf("Found matching vcl %s. Not creating.\n", vcl.Name))
+				newVCLs = append(newVCLs[:i], newVCLs[i+1:]...)
+				match = true
+				break
+			} else if vcl.Name == newVCL.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing vcl %s (content %.12s, want %.12s). Updating.\n", vcl.Name, ContentHash(vcl.Content), ContentHash(newVCL.Content)))
+				sy.RecordDiff(s, "vcl", util.ChangeChanged, vcl.Name, vcl, &newVCL)
+				if _, _, err := sy.api.VCL.Update(s.ID, newversion.Number, vcl.Name, &newVCL); err != nil {
+					return err
+				}
+				newVCLs = append(newVCLs[:i], newVCLs[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching vcl %s. Deleting.\n", vcl.Name))
+			sy.RecordChange(s, "vcl", util.ChangeRemoved, vcl.Name)
+			_, err := sy.api.VCL.Delete(s.ID, newversion.Number, vcl.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, vcl := range newVCLs {
+		log.Debug(fmt.Sprintf("Creating missing vcl %s.\n", vcl.Name))
+		sy.RecordDiff(s, "vcl", util.ChangeAdded, vcl.Name, nil, &vcl)
+		_, _, err := sy.api.VCL.Create(s.ID, newversion.Number, &vcl)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncHealthChecks(s *fastly.Service, newHealthChecks []fastly.HealthCheck) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	for i := range newHealthChecks {
+		if newHealthChecks[i].HTTPVersion == "" {
+			newHealthChecks[i].HTTPVersion = defaultHealthCheckHTTPVersion
+		}
+	}
+
+	existingHealthChecks, _, err := sy.api.HealthCheck.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, healthCheck := range existingHealthChecks {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		healthCheck.ServiceID = ""
+		healthCheck.Version = 0
+		for i, newHealthCheck := range newHealthChecks {
+			if *healthCheck == newHealthCheck {
+				log.Debug(fmt.Sprintf("Found matching healthCheck %s. Not creating.\n", healthCheck.Name))
+				newHealthChecks = append(newHealthChecks[:i], newHealthChecks[i+1:]...)
+				match = true
+				break
+			} else if healthCheck.Name == newHealthCheck.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing healthCheck %s. Updating.\n", healthCheck.Name))
+				sy.RecordDiff(s, "health check", util.ChangeChanged, healthCheck.Name, healthCheck, &newHealthCheck)
+				if _, _, err := sy.api.HealthCheck.Update(s.ID, newversion.Number, healthCheck.Name, &newHealthCheck); err != nil {
+					return err
+				}
+				newHealthChecks = append(newHealthChecks[:i], newHealthChecks[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching healthCheck %s. Deleting.\n", healthCheck.Name))
+			sy.RecordChange(s, "health check", util.ChangeRemoved, healthCheck.Name)
+			_, err := sy.api.HealthCheck.Delete(s.ID, newversion.Number, healthCheck.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, healthCheck := range newHealthChecks {
+		if healthCheck == (fastly.HealthCheck{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing healthCheck %s.\n", healthCheck.Name))
+		sy.RecordDiff(s, "health check", util.ChangeAdded, healthCheck.Name, nil, &healthCheck)
+		_, _, err := sy.api.HealthCheck.Create(s.ID, newversion.Number, &healthCheck)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Caveat: contentTypes is autogenerated by fastly
+func (sy *Syncer) syncGzips(s *fastly.Service, newGzips []fastly.Gzip) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	existingGzips, _, err := sy.api.Gzip.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, gzip := range existingGzips {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		gzip.ServiceID = ""
+		gzip.Version = 0
+		for i, newGzip := range newGzips {
+			if gzipsEqual(*gzip, newGzip) {
+				log.Debug(fmt.Sprintf("Found matching gzip %s. Not creating.\n", gzip.Name))
+				newGzips = append(newGzips[:i], newGzips[i+1:]...)
+				match = true
+				break
+			} else if gzip.Name == newGzip.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing gzip %s. Updating.\n", gzip.Name))
+				sy.RecordDiff(s, "gzip", util.ChangeChanged, gzip.Name, gzip, &newGzip)
+				if _, _, err := sy.api.Gzip.Update(s.ID, newversion.Number, gzip.Name, &newGzip); err != nil {
+					return err
+				}
+				newGzips = append(newGzips[:i], newGzips[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching gzip %s. Deleting.\n", gzip.Name))
+			sy.RecordChange(s, "gzip", util.ChangeRemoved, gzip.Name)
+			_, err := sy.api.Gzip.Delete(s.ID, newversion.Number, gzip.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, gzip := range newGzips {
+		if gzip == (fastly.Gzip{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing gzip %s.\n", gzip.Name))
+		sy.RecordDiff(s, "gzip", util.ChangeAdded, gzip.Name, nil, &gzip)
+		_, _, err := sy.api.Gzip.Create(s.ID, newversion.Number, &gzip)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncSettings(s *fastly.Service, newSettings fastly.Settings) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	existingSettings, _, err := sy.api.Settings.Get(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+
+	// Zero out read-only fields that we don't want to compare
+	existingSettings.ServiceID = ""
+	existingSettings.Version = 0
+	if newSettings != *existingSettings {
+		log.Debug("Mismatched settings. Updating.\n")
+		sy.RecordDiff(s, "settings", util.ChangeChanged, "", existingSettings, &newSettings)
+		if _, _, err = sy.api.Settings.Update(s.ID, newversion.Number, &newSettings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sy *Syncer) syncDomains(s *fastly.Service, newDomains []fastly.Domain) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	r := strings.NewReplacer("_servicename_", s.Name)
+	for i := range newDomains {
+		newDomains[i].Name = r.Replace(newDomains[i].Name)
+	}
+
+	existingDomains, _, err := sy.api.Domain.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, domain := range existingDomains {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		domain.ServiceID = ""
+		domain.Version = 0
+		for i, newDomain := range newDomains {
+			if *domain == newDomain {
+				log.Debug(fmt.Sprintf("Found matching domain %s. Not creating.\n", domain.Name))
+				newDomains = append(newDomains[:i], newDomains[i+1:]...)
+				match = true
+				break
+			} else if domain.Name == newDomain.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing domain %s. Updating.\n", domain.Name))
+				sy.RecordDiff(s, "domain", util.ChangeChanged, domain.Name, domain, &newDomain)
+				if _, _, err := sy.api.Domain.Update(s.ID, newversion.Number, domain.Name, &newDomain); err != nil {
+					return err
+				}
+				newDomains = append(newDomains[:i], newDomains[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching domain %s. Deleting.\n", domain.Name))
+			sy.RecordChange(s, "domain", util.ChangeRemoved, domain.Name)
+			_, err := sy.api.Domain.Delete(s.ID, newversion.Number, domain.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, domain := range newDomains {
+		if domain == (fastly.Domain{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing domain %s.\n", domain.Name))
+		sy.RecordDiff(s, "domain", util.ChangeAdded, domain.Name, nil, &domain)
+		_, _, err := sy.api.Domain.Create(s.ID, newversion.Number, &domain)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncSyslogs(s *fastly.Service, newSyslogs []fastly.Syslog) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	r := strings.NewReplacer("_servicename_", s.Name, "_prefix_", sy.Configs[s.Name].IPPrefix, "_suffix_", sy.Configs[s.Name].IPSuffix)
+	for i := range newSyslogs {
+		newSyslogs[i].TLSHostname = r.Replace(newSyslogs[i].TLSHostname)
+		newSyslogs[i].Address = r.Replace(newSyslogs[i].Address)
+	}
+
+	existingSyslogs, _, err := sy.api.Syslog.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, syslog := range existingSyslogs {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		syslog.ServiceID = ""
+		syslog.Version = 0
+		for i, newSyslog := range newSyslogs {
+			if syslog.Name == newSyslog.Name {
+				newSyslog.UseTLS = newSyslog.UseTLS.Or(syslog.UseTLS)
+			}
+			if *syslog == newSyslog {
+				log.Debug(fmt.Sprintf("Found matching syslog %s. Not creating.\n", syslog.Name))
+				newSyslogs = append(newSyslogs[:i], newSyslogs[i+1:]...)
+				match = true
+				break
+			} else if syslog.Name == newSyslog.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing syslog %s. Updating.\n", syslog.Name))
+				sy.RecordDiff(s, "syslog", util.ChangeChanged, syslog.Name, syslog, &newSyslog)
+				if _, _, err := sy.api.Syslog.Update(s.ID, newversion.Number, syslog.Name, &newSyslog); err != nil {
+					return err
+				}
+				newSyslogs = append(newSyslogs[:i], newSyslogs[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching syslog %s. Deleting.\n", syslog.Name))
+			sy.RecordChange(s, "syslog", util.ChangeRemoved, syslog.Name)
+			_, err := sy.api.Syslog.Delete(s.ID, newversion.Number, syslog.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, syslog := range newSyslogs {
+		if syslog == (fastly.Syslog{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing syslog %s.\n", syslog.Name))
+		sy.RecordDiff(s, "syslog", util.ChangeAdded, syslog.Name, nil, &syslog)
+		_, _, err := sy.api.Syslog.Create(s.ID, newversion.Number, &syslog)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncS3s(s *fastly.Service, newS3s []fastly.S3) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	accessKey := os.Getenv("FASTLY_S3_ACCESS_KEY")
+	secretKey := os.Getenv("FASTLY_S3_SECRET_KEY")
+	if accessKey == "" {
+		accessKey = sy.Configs[s.Name].S3AccessKey
+	}
+	if secretKey == "" {
+		secretKey = sy.Configs[s.Name].S3SecretKey
+	}
+
+	r := strings.NewReplacer("_servicename_", s.Name, "_s3accesskey_", accessKey, "_s3secretkey_", secretKey)
+	for i := range newS3s {
+		if newS3s[i].TimestampFormat == "" {
+			newS3s[i].TimestampFormat = defaultS3TimestampFormat
+		}
+		newS3s[i].Path = r.Replace(newS3s[i].Path)
+		newS3s[i].BucketName = r.Replace(newS3s[i].BucketName)
+	}
+
+	existingS3s, _, err := sy.api.S3.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, s3 := range existingS3s {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		s3.ServiceID = ""
+		s3.Version = 0
+		for i, newS3 := range newS3s {
+			if *s3 == newS3 {
+				log.Debug(fmt.Sprintf("Found matching s3 %s. Not creating.\n", s3.Name))
+				newS3s = append(newS3s[:i], newS3s[i+1:]...)
+				match = true
+				break
+			} else if s3.Name == newS3.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing s3 %s. Updating.\n", s3.Name))
+				sy.RecordDiff(s, "s3", util.ChangeChanged, s3.Name, s3, &newS3)
+				if _, _, err := sy.api.S3.Update(s.ID, newversion.Number, s3.Name, &newS3); err != nil {
+					return err
+				}
+				newS3s = append(newS3s[:i], newS3s[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching s3 %s. Deleting.\n", s3.Name))
+			sy.RecordChange(s, "s3", util.ChangeRemoved, s3.Name)
+			_, err := sy.api.S3.Delete(s.ID, newversion.Number, s3.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s3 := range newS3s {
+		if s3 == (fastly.S3{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing s3 %s.\n", s3.Name))
+		sy.RecordDiff(s, "s3", util.ChangeAdded, s3.Name, nil, &s3)
+		_, _, err := sy.api.S3.Create(s.ID, newversion.Number, &s3)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncHeaders(s *fastly.Service, newHeaders []fastly.Header) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	for i := range newHeaders {
+		if newHeaders[i].Priority == 0 && newHeaders[i].Name != "" {
+			newHeaders[i].Priority = defaultHeaderPriority
+		}
+	}
+
+	existingHeaders, _, err := sy.api.Header.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, header := range existingHeaders {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		header.ServiceID = ""
+		header.Version = 0
+		for i, newHeader := range newHeaders {
+			if header.Name == newHeader.Name {
+				newHeader.IgnoreIfSet = newHeader.IgnoreIfSet.Or(header.IgnoreIfSet)
+			}
+			if *header == newHeader {
+				log.Debug(fmt.Sprintf("Found matching header %s. Not creating.\n", header.Name))
+				newHeaders = append(newHeaders[:i], newHeaders[i+1:]...)
+				match = true
+				break
+			} else if header.Name == newHeader.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing header %s. Updating.\n", header.Name))
+				sy.RecordDiff(s, "header", util.ChangeChanged, header.Name, header, &newHeader)
+				if _, _, err := sy.api.Header.Update(s.ID, newversion.Number, header.Name, &newHeader); err != nil {
+					return err
+				}
+				newHeaders = append(newHeaders[:i], newHeaders[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching header %s. Deleting.\n", header.Name))
+			sy.RecordChange(s, "header", util.ChangeRemoved, header.Name)
+			_, err := sy.api.Header.Delete(s.ID, newversion.Number, header.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, header := range newHeaders {
+		if header == (fastly.Header{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing header %s.\n", header.Name))
+		sy.RecordDiff(s, "header", util.ChangeAdded, header.Name, nil, &header)
+		_, _, err := sy.api.Header.Create(s.ID, newversion.Number, &header)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncCacheSettings(s *fastly.Service, newCacheSettings []fastly.CacheSetting) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	existingCacheSettings, _, err := sy.api.CacheSetting.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, cacheSetting := range existingCacheSettings {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		cacheSetting.ServiceID = ""
+		cacheSetting.Version = 0
+		for i, newCacheSetting := range newCacheSettings {
+			if *cacheSetting == newCacheSetting {
+				log.Debug(fmt.Sprintf("Found matching cache setting %s. Not creating.\n", cacheSetting.Name))
+				newCacheSettings = append(newCacheSettings[:i], newCacheSettings[i+1:]...)
+				match = true
+				break
+			} else if cacheSetting.Name == newCacheSetting.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing cache setting %s. Updating.\n", cacheSetting.Name))
+				sy.RecordDiff(s, "cache setting", util.ChangeChanged, cacheSetting.Name, cacheSetting, &newCacheSetting)
+				if _, _, err := sy.api.CacheSetting.Update(s.ID, newversion.Number, cacheSetting.Name, &newCacheSetting); err != nil {
+					return err
+				}
+				newCacheSettings = append(newCacheSettings[:i], newCacheSettings[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching cache setting %s. Deleting.\n", cacheSetting.Name))
+			sy.RecordChange(s, "cache setting", util.ChangeRemoved, cacheSetting.Name)
+			_, err := sy.api.CacheSetting.Delete(s.ID, newversion.Number, cacheSetting.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, cacheSetting := range newCacheSettings {
+		if cacheSetting == (fastly.CacheSetting{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing cache setting %s.\n", cacheSetting.Name))
+		sy.RecordDiff(s, "cache setting", util.ChangeAdded, cacheSetting.Name, nil, &cacheSetting)
+		_, _, err := sy.api.CacheSetting.Create(s.ID, newversion.Number, &cacheSetting)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inheritRequestSetting sets the flags which r leaves unset to those of
+// existing, since Fastly leaves them as they are.
+func inheritRequestSetting(r, existing *fastly.RequestSetting) {
+	r.BypassBusyWait = r.BypassBusyWait.Or(existing.BypassBusyWait)
+	r.ForceMiss = r.ForceMiss.Or(existing.ForceMiss)
+	r.ForceSSL = r.ForceSSL.Or(existing.ForceSSL)
+	r.GeoHeaders = r.GeoHeaders.Or(existing.GeoHeaders)
+	r.TimerSupport = r.TimerSupport.Or(existing.TimerSupport)
+}
+
+func (sy *Syncer) syncRequestSettings(s *fastly.Service, newRequestSettings []fastly.RequestSetting) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	existingRequestSettings, _, err := sy.api.RequestSetting.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, requestSetting := range existingRequestSettings {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		requestSetting.ServiceID = ""
+		requestSetting.Version = 0
+		for i, newRequestSetting := range newRequestSettings {
+			if requestSetting.Name == newRequestSetting.Name {
+				inheritRequestSetting(&newRequestSetting, requestSetting)
+			}
+			if *requestSetting == newRequestSetting {
+				log.Debug(fmt.Sprintf("Found matching request setting %s. Not creating.\n", requestSetting.Name))
+				newRequestSettings = append(newRequestSettings[:i], newRequestSettings[i+1:]...)
+				match = true
+				break
+			} else if requestSetting.Name == newRequestSetting.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing request setting %s. Updating.\n", requestSetting.Name))
+				sy.RecordDiff(s, "request setting", util.ChangeChanged, requestSetting.Name, requestSetting, &newRequestSetting)
+				if _, _, err := sy.api.RequestSetting.Update(s.ID, newversion.Number, requestSetting.Name, &newRequestSetting); err != nil {
+					return err
+				}
+				newRequestSettings = append(newRequestSettings[:i], newRequestSettings[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching request setting %s. Deleting.\n", requestSetting.Name))
+			sy.RecordChange(s, "request setting", util.ChangeRemoved, requestSetting.Name)
+			_, err := sy.api.RequestSetting.Delete(s.ID, newversion.Number, requestSetting.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, requestSetting := range newRequestSettings {
+		if requestSetting == (fastly.RequestSetting{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing request setting %s.\n", requestSetting.Name))
+		sy.RecordDiff(s, "request setting", util.ChangeAdded, requestSetting.Name, nil, &requestSetting)
+		_, _, err := sy.api.RequestSetting.Create(s.ID, newversion.Number, &requestSetting)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncResponseObjects(s *fastly.Service, responseObjects []ResponseObject) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	var newResponseObjects []fastly.ResponseObject
+	for _, ro := range responseObjects {
+		newResponseObject := ro.ResponseObject
+		if ro.ContentFile != "" {
+			if ro.Content != "" {
+				return fmt.Errorf("Cannot specify both a ContentFile and Content for response object %s", ro.Name)
+			}
+			content, err := ReadFile(ro.ContentFile)
+			if err != nil {
+				return err
+			}
+			newResponseObject.Content = string(content)
+			if newResponseObject.ContentType == "" {
+				newResponseObject.ContentType = mime.TypeByExtension(filepath.Ext(ro.ContentFile))
+			}
+		}
+		newResponseObjects = append(newResponseObjects, newResponseObject)
+	}
+
+	existingResponseObjects, _, err := sy.api.ResponseObject.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, responseObject := range existingResponseObjects {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		responseObject.ServiceID = ""
+		responseObject.Version = 0
+		for i, newResponseObject := range newResponseObjects {
+			if responseObjectsEqual(*responseObject, newResponseObject) {
+				log.Debug(fmt.Sprintf("Found matching response object %s. Not creating.\n", responseObject.Name))
+				newResponseObjects = append(newResponseObjects[:i], newResponseObjects[i+1:]...)
+				match = true
+				break
+			} else if responseObject.Name == newResponseObject.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing response object %s (content %.12s, want %.12s). Updating.\n", responseObject.Name, ContentHash(responseObject.Content), ContentHash(newResponseObject.Content)))
+				sy.RecordDiff(s, "response object", util.ChangeChanged, responseObject.Name, responseObject, &newResponseObject)
+				if _, _, err := sy.api.ResponseObject.Update(s.ID, newversion.Number, responseObject.Name, &newResponseObject); err != nil {
+					return err
+				}
+				newResponseObjects = append(newResponseObjects[:i], newResponseObjects[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching response object %s. Deleting.\n", responseObject.Name))
+			sy.RecordChange(s, "response object", util.ChangeRemoved, responseObject.Name)
+			_, err := sy.api.ResponseObject.Delete(s.ID, newversion.Number, responseObject.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, responseObject := range newResponseObjects {
+		if responseObject == (fastly.ResponseObject{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing response object %s.\n", responseObject.Name))
+		sy.RecordDiff(s, "response object", util.ChangeAdded, responseObject.Name, nil, &responseObject)
+		_, _, err := sy.api.ResponseObject.Create(s.ID, newversion.Number, &responseObject)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncConditions(s *fastly.Service, newConditions []fastly.Condition) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	for i := range newConditions {
+		if newConditions[i].Name == "" {
+			continue
+		}
+		if newConditions[i].Priority == 0 {
+			newConditions[i].Priority = defaultConditionPriority
+		}
+		// Conditions without a type are created as request conditions, so
+		// compare them as such.
+		if newConditions[i].Type == 0 {
+			newConditions[i].Type = fastly.ConditionTypeRequest
+		}
+	}
+
+	existingConditions, _, err := sy.api.Condition.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, condition := range existingConditions {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		condition.ServiceID = ""
+		condition.Version = 0
+		for i, newCondition := range newConditions {
+			if *condition == newCondition {
+				log.Debug(fmt.Sprintf("Found matching condition %s. Not creating.\n", condition.Name))
+				newConditions = append(newConditions[:i], newConditions[i+1:]...)
+				match = true
+				break
+			} else if condition.Name == newCondition.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing condition %s. Updating.\n", condition.Name))
+				sy.RecordDiff(s, "condition", util.ChangeChanged, condition.Name, condition, &newCondition)
+				if _, _, err := sy.api.Condition.Update(s.ID, newversion.Number, condition.Name, &newCondition); err != nil {
+					return err
+				}
+				newConditions = append(newConditions[:i], newConditions[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching condition %s. Deleting.\n", condition.Name))
+			sy.RecordChange(s, "condition", util.ChangeRemoved, condition.Name)
+			_, err := sy.api.Condition.Delete(s.ID, newversion.Number, condition.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, condition := range newConditions {
+		if condition == (fastly.Condition{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing condition %s.\n", condition.Name))
+		sy.RecordDiff(s, "condition", util.ChangeAdded, condition.Name, nil, &condition)
+		_, _, err := sy.api.Condition.Create(s.ID, newversion.Number, &condition)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true if we made any changes, as that means we are activatable
+// despite there being no diff.
+func (sy *Syncer) syncDictionaries(s *fastly.Service, newDictionaries []fastly.Dictionary) (bool, error) {
+	var changesMade bool
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return changesMade, err
+	}
+
+	existingDictionaries, _, err := sy.api.Dictionary.List(s.ID, newversion.Number)
+	if err != nil {
+		return changesMade, err
+	}
+	for _, dictionary := range existingDictionaries {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		dictionary.ServiceID = ""
+		dictionary.Version = 0
+		dictionary.ID = ""
+		for i, newDictionary := range newDictionaries {
+			if *dictionary == newDictionary {
+				log.Debug(fmt.Sprintf("Found matching dictionary %s. Not creating.\n", dictionary.Name))
+				newDictionaries = append(newDictionaries[:i], newDictionaries[i+1:]...)
+				match = true
+				break
+			} else if dictionary.Name == newDictionary.Name {
+				if dictionary.WriteOnly != newDictionary.WriteOnly {
+					return changesMade, fmt.Errorf("WriteOnly cannot be changed on existing dictionary %s. The dictionary must be removed and recreated.", dictionary.Name)
+				}
+				log.Debug(fmt.Sprintf("Found mismatched existing dictionary %s. Updating.\n", dictionary.Name))
+				sy.RecordDiff(s, "dictionary", util.ChangeChanged, dictionary.Name, dictionary, &newDictionary)
+				if _, _, err := sy.api.Dictionary.Update(s.ID, newversion.Number, dictionary.Name, &newDictionary); err != nil {
+					return changesMade, err
+				}
+				changesMade = true
+				newDictionaries = append(newDictionaries[:i], newDictionaries[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching dictionary %s. Deleting.\n", dictionary.Name))
+			sy.RecordChange(s, "dictionary", util.ChangeRemoved, dictionary.Name)
+			_, err := sy.api.Dictionary.Delete(s.ID, newversion.Number, dictionary.Name)
+			if err != nil {
+				return changesMade, err
+			}
+			changesMade = true
+		}
+	}
+
+	for _, dictionary := range newDictionaries {
+		if dictionary == (fastly.Dictionary{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing dictionary %s.\n", dictionary.Name))
+		sy.RecordDiff(s, "dictionary", util.ChangeAdded, dictionary.Name, nil, &dictionary)
+		_, _, err := sy.api.Dictionary.Create(s.ID, newversion.Number, &dictionary)
+		if err != nil {
+			return changesMade, err
+		}
+		changesMade = true
+	}
+	return changesMade, nil
+}
+
+// Returns true if we made any changes, as that means we are activatable
+// despite there being no diff.
+func (sy *Syncer) syncACLs(s *fastly.Service, newACLs []fastly.ACL) (bool, error) {
+	var changesMade bool
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return changesMade, err
+	}
+
+	existingACLs, _, err := sy.api.ACL.List(s.ID, newversion.Number)
+	if err != nil {
+		return changesMade, err
+	}
+	for _, acl := range existingACLs {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		acl.ServiceID = ""
+		acl.Version = 0
+		acl.ID = ""
+		for i, newACL := range newACLs {
+			if *acl == newACL {
+				log.Debug(fmt.Sprintf("Found matching acl %s. Not creating.\n", acl.Name))
+				newACLs = append(newACLs[:i], newACLs[i+1:]...)
+				match = true
+				break
+			} else if acl.Name == newACL.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing acl %s. Updating.\n", acl.Name))
+				sy.RecordDiff(s, "acl", util.ChangeChanged, acl.Name, acl, &newACL)
+				if _, _, err := sy.api.ACL.Update(s.ID, newversion.Number, acl.Name, &newACL); err != nil {
+					return changesMade, err
+				}
+				changesMade = true
+				newACLs = append(newACLs[:i], newACLs[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching acl %s. Deleting.\n", acl.Name))
+			sy.RecordChange(s, "acl", util.ChangeRemoved, acl.Name)
+			_, err := sy.api.ACL.Delete(s.ID, newversion.Number, acl.Name)
+			if err != nil {
+				return changesMade, err
+			}
+			changesMade = true
+		}
+	}
+
+	for _, acl := range newACLs {
+		if acl == (fastly.ACL{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing acl %s.\n", acl.Name))
+		sy.RecordDiff(s, "acl", util.ChangeAdded, acl.Name, nil, &acl)
+		_, _, err := sy.api.ACL.Create(s.ID, newversion.Number, &acl)
+		if err != nil {
+			return changesMade, err
+		}
+		changesMade = true
+	}
+	return changesMade, nil
+}
+
+// Checks to ensure that only one of the given parameters has a non-zero value.
+// Returns true if the parameters meet this requirement.
+func checkMutuallyExclusive(a, b, c, d string) bool {
+	count := 0
+	if a != "" {
+		count++
+	}
+	if b != "" {
+		count++
+	}
+	if c != "" {
+		count++
+	}
+	if d != "" {
+		count++
+	}
+	if count > 1 {
+		return false
+	}
+	return true
+}
+
+// normalizeBackendAddress fills in the address fields of a backend the way
+// the API does. The Address field is automatically filled by the API with the
+// Hostname, IPV4, or IPV6 value if one of those are specified, and vice versa.
+// We must duplicate this logic locally so the comparison works properly.
+func normalizeBackendAddress(b *fastly.Backend) {
+	if b.Address != "" {
+		if parsed := net.ParseIP(b.Address); parsed == nil {
+			b.Hostname = b.Address
+		} else if parsed.To4() == nil {
+			b.IPV6 = parsed.String()
+		} else {
+			b.IPV4 = parsed.String()
+		}
+	} else if b.Hostname != "" {
+		b.Address = b.Hostname
+	} else if b.IPV4 != "" {
+		b.Address = b.IPV4
+	} else if b.IPV6 != "" {
+		b.Address = b.IPV6
+	}
+}
+
+var tlsVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+// validateBackendTLS rejects TLS options which would be ignored or refused by
+// the API.
+func validateBackendTLS(b fastly.Backend) error {
+	fields := []struct{ name, value string }{
+		{"SSLSNIHostname", b.SSLSNIHostname},
+		{"SSLCiphers", b.SSLCiphers},
+		{"MinTLSVersion", b.MinTLSVersion},
+		{"MaxTLSVersion", b.MaxTLSVersion},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if !b.UseSSL {
+			return fmt.Errorf("Backend %s sets %s, which requires UseSSL.", b.Name, f.name)
+		}
+		if strings.HasSuffix(f.name, "TLSVersion") && !util.StringInSlice(f.value, tlsVersions) {
+			return fmt.Errorf("Backend %s has invalid %s %q. Must be one of %s.", b.Name, f.name, f.value, strings.Join(tlsVersions, ", "))
+		}
+	}
+	if b.MinTLSVersion != "" && b.MaxTLSVersion != "" && b.MinTLSVersion > b.MaxTLSVersion {
+		return fmt.Errorf("Backend %s has MinTLSVersion %s greater than MaxTLSVersion %s.", b.Name, b.MinTLSVersion, b.MaxTLSVersion)
+	}
+	return nil
+}
+
+// backendsEqual compares an existing backend with one from config. The API
+// omits SSLHostname, SSLCiphers, MinTLSVersion and MaxTLSVersion from
+// updates when they are empty, so they can't be cleared once set. Leaving
+// them out of config keeps the existing values rather than causing an update
+// on every push.
+func backendsEqual(existing, b fastly.Backend) bool {
+	if b.SSLHostname == "" {
+		b.SSLHostname = existing.SSLHostname
+	}
+	if b.SSLCiphers == "" {
+		b.SSLCiphers = existing.SSLCiphers
+	}
+	if b.MinTLSVersion == "" {
+		b.MinTLSVersion = existing.MinTLSVersion
+	}
+	if b.MaxTLSVersion == "" {
+		b.MaxTLSVersion = existing.MaxTLSVersion
+	}
+	return existing == b
+}
+
+func (sy *Syncer) syncBackends(s *fastly.Service, newBackends []fastly.Backend) (bool, error) {
+	var changesMade bool
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return changesMade, err
+	}
+
+	r := strings.NewReplacer("_servicename_", s.Name, "_prefix_", sy.Configs[s.Name].IPPrefix, "_suffix_", sy.Configs[s.Name].IPSuffix)
+	for i, b := range newBackends {
+		newBackends[i].Address = r.Replace(b.Address)
+		newBackends[i].Hostname = r.Replace(b.Hostname)
+		newBackends[i].IPV4 = r.Replace(b.IPV4)
+		newBackends[i].IPV6 = r.Replace(b.IPV6)
+		newBackends[i].SSLCertHostname = r.Replace(b.SSLCertHostname)
+	}
+	for i, b := range newBackends {
+		if !checkMutuallyExclusive(b.Address, b.Hostname, b.IPV4, b.IPV6) {
+			return changesMade, fmt.Errorf("Backend %s can only have one of Address, Hostname, IPV4, or IPV6 specified.", b.Name)
+		}
+		if err := validateBackendTLS(b); err != nil {
+			return changesMade, err
+		}
+		normalizeBackendAddress(&newBackends[i])
+	}
+	if err := validateShields(sy.api, newBackends); err != nil {
+		return changesMade, err
+	}
+
+	existingBackends, _, err := sy.api.Backend.List(s.ID, newversion.Number)
+	if err != nil {
+		return changesMade, err
+	}
+	for _, backend := range existingBackends {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		backend.ServiceID = ""
+		backend.Version = 0
+		for i, newBackend := range newBackends {
+			if backendsEqual(*backend, newBackend) {
+				log.Debug(fmt.Sprintf("Found matching backend %s. Not creating.\n", backend.Name))
+				newBackends = append(newBackends[:i], newBackends[i+1:]...)
+				match = true
+				break
+			} else if backend.Name == newBackend.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing backend %s. Updating.\n", backend.Name))
+				sy.RecordDiff(s, "backend", util.ChangeChanged, backend.Name, backend, &newBackend)
+				if _, _, err := sy.api.Backend.Update(s.ID, newversion.Number, backend.Name, &newBackend); err != nil {
+					return changesMade, err
+				}
+				changesMade = true
+				newBackends = append(newBackends[:i], newBackends[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching backend %s. Deleting.\n", backend.Name))
+			sy.RecordChange(s, "backend", util.ChangeRemoved, backend.Name)
+			_, err := sy.api.Backend.Delete(s.ID, newversion.Number, backend.Name)
+			if err != nil {
+				return changesMade, err
+			}
+			changesMade = true
+		}
+	}
+
+	for _, backend := range newBackends {
+		if backend == (fastly.Backend{}) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing backend %s.\n", backend.Name))
+		sy.RecordDiff(s, "backend", util.ChangeAdded, backend.Name, nil, &backend)
+		_, _, err := sy.api.Backend.Create(s.ID, newversion.Number, &backend)
+		if err != nil {
+			return changesMade, err
+		}
+		changesMade = true
+	}
+	return changesMade, nil
+}
+
+// directorsEqual compares directors, treating their backends as an unordered
+// set.
+func directorsEqual(a, b fastly.Director) bool {
+	ab := append([]string(nil), a.Backends...)
+	bb := append([]string(nil), b.Backends...)
+	sort.Strings(ab)
+	sort.Strings(bb)
+	a.Backends, b.Backends = nil, nil
+	return reflect.DeepEqual(a, b) && strings.Join(ab, "\n") == strings.Join(bb, "\n")
+}
+
+// syncDirectorBackends adds and removes the members of a director, so that
+// they become those of want.
+func (sy *Syncer) syncDirectorBackends(s *fastly.Service, version uint, name string, have, want []string) error {
+	for _, backend := range want {
+		if !util.StringInSlice(backend, have) {
+			log.Debug(fmt.Sprintf("Adding backend %s to director %s.\n", backend, name))
+			if _, _, err := sy.api.DirectorBackend.Create(s.ID, version, name, backend); err != nil {
+				return err
+			}
+		}
+	}
+	for _, backend := range have {
+		if !util.StringInSlice(backend, want) {
+			log.Debug(fmt.Sprintf("Removing backend %s from director %s.\n", backend, name))
+			if _, err := sy.api.DirectorBackend.Delete(s.ID, version, name, backend); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sy *Syncer) syncDirectors(s *fastly.Service, newDirectors []fastly.Director) error {
+	newversion, err := sy.PrepareDraft(s)
+	if err != nil {
+		return err
+	}
+
+	for i := range newDirectors {
+		if newDirectors[i].Name == "" {
+			continue
+		}
+		// Fill in the defaults Fastly assigns to directors created
+		// without them, so that they compare as equal.
+		if newDirectors[i].Quorum == 0 {
+			newDirectors[i].Quorum = defaultDirectorQuorum
+		}
+		if newDirectors[i].Retries == 0 {
+			newDirectors[i].Retries = defaultDirectorRetries
+		}
+		if newDirectors[i].Type == 0 {
+			newDirectors[i].Type = fastly.DirectorTypeRandom
+		}
+	}
+
+	existingDirectors, _, err := sy.api.Director.List(s.ID, newversion.Number)
+	if err != nil {
+		return err
+	}
+	for _, director := range existingDirectors {
+		var match bool
+		// Zero out read-only fields that we don't want to compare
+		director.ServiceID = ""
+		director.Version = 0
+		for i, newDirector := range newDirectors {
+			if directorsEqual(*director, newDirector) {
+				log.Debug(fmt.Sprintf("Found matching director %s. Not creating.\n", director.Name))
+				newDirectors = append(newDirectors[:i], newDirectors[i+1:]...)
+				match = true
+				break
+			} else if director.Name == newDirector.Name {
+				log.Debug(fmt.Sprintf("Found mismatched existing director %s. Updating.\n", director.Name))
+				sy.RecordDiff(s, "director", util.ChangeChanged, director.Name, director, &newDirector)
+				update := newDirector
+				update.Backends = nil
+				if _, _, err := sy.api.Director.Update(s.ID, newversion.Number, director.Name, &update); err != nil {
+					return err
+				}
+				if err := sy.syncDirectorBackends(s, newversion.Number, newDirector.Name, director.Backends, newDirector.Backends); err != nil {
+					return err
+				}
+				newDirectors = append(newDirectors[:i], newDirectors[i+1:]...)
+				match = true
+				break
+			}
+		}
+		if !match {
+			log.Debug(fmt.Sprintf("Found non-matching director %s. Deleting.\n", director.Name))
+			sy.RecordChange(s, "director", util.ChangeRemoved, director.Name)
+			_, err := sy.api.Director.Delete(s.ID, newversion.Number, director.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, director := range newDirectors {
+		if director.Name == "" {
+			continue
+		}
+		log.Debug(fmt.Sprintf("Creating missing director %s.\n", director.Name))
+		sy.RecordDiff(s, "director", util.ChangeAdded, director.Name, nil, &director)
+		create := director
+		create.Backends = nil
+		if _, _, err := sy.api.Director.Create(s.ID, newversion.Number, &create); err != nil {
+			return err
+		}
+		if err := sy.syncDirectorBackends(s, newversion.Number, director.Name, nil, director.Backends); err != nil {
+			return err
+		}
+	}
+	return nil
+}
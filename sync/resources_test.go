@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/alienth/go-fastly"
+)
+
+func TestNormalizeBackendAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		in, want fastly.Backend
+	}{
+		{
+			name: "hostname address",
+			in:   fastly.Backend{Address: "origin.example.com"},
+			want: fastly.Backend{Address: "origin.example.com", Hostname: "origin.example.com"},
+		},
+		{
+			name: "IPv4 address",
+			in:   fastly.Backend{Address: "192.0.2.1"},
+			want: fastly.Backend{Address: "192.0.2.1", IPV4: "192.0.2.1"},
+		},
+		{
+			name: "IPv6 loopback address",
+			in:   fastly.Backend{Address: "::1"},
+			want: fastly.Backend{Address: "::1", IPV6: "::1"},
+		},
+		{
+			name: "IPv6 address",
+			in:   fastly.Backend{Address: "2001:db8::1"},
+			want: fastly.Backend{Address: "2001:db8::1", IPV6: "2001:db8::1"},
+		},
+		{
+			name: "IPv6 address in long form",
+			in:   fastly.Backend{Address: "2001:0db8:0000:0000:0000:0000:0000:0001"},
+			want: fastly.Backend{Address: "2001:0db8:0000:0000:0000:0000:0000:0001", IPV6: "2001:db8::1"},
+		},
+		{
+			// Mapped addresses are IPv4 addresses, and are stored as such.
+			name: "IPv4-mapped IPv6 address",
+			in:   fastly.Backend{Address: "::ffff:192.0.2.1"},
+			want: fastly.Backend{Address: "::ffff:192.0.2.1", IPV4: "192.0.2.1"},
+		},
+		{
+			name: "hostname",
+			in:   fastly.Backend{Hostname: "origin.example.com"},
+			want: fastly.Backend{Address: "origin.example.com", Hostname: "origin.example.com"},
+		},
+		{
+			name: "IPV4",
+			in:   fastly.Backend{IPV4: "192.0.2.1"},
+			want: fastly.Backend{Address: "192.0.2.1", IPV4: "192.0.2.1"},
+		},
+		{
+			name: "IPV6",
+			in:   fastly.Backend{IPV6: "2001:db8::1"},
+			want: fastly.Backend{Address: "2001:db8::1", IPV6: "2001:db8::1"},
+		},
+		{
+			name: "address takes precedence",
+			in:   fastly.Backend{Address: "192.0.2.1", Hostname: "origin.example.com"},
+			want: fastly.Backend{Address: "192.0.2.1", Hostname: "origin.example.com", IPV4: "192.0.2.1"},
+		},
+		{
+			name: "no address",
+			in:   fastly.Backend{Name: "b"},
+			want: fastly.Backend{Name: "b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.in
+			normalizeBackendAddress(&got)
+			if got != test.want {
+				t.Errorf("normalizeBackendAddress(%+v) = %+v, want %+v", test.in, got, test.want)
+			}
+		})
+	}
+}
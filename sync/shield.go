@@ -0,0 +1,162 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/alienth/fastlyctl/log"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+)
+
+// datacenterCacheTTL is how long the datacenter list fetched from the API is
+// reused before being refreshed.
+const datacenterCacheTTL = 24 * time.Hour
+
+type datacenterCache struct {
+	Fetched     time.Time
+	Datacenters []*fastly.Datacenter
+}
+
+// datacenters holds the datacenter list once it has been loaded by this
+// process. It is guarded by datacentersMu.
+var (
+	datacenters   []*fastly.Datacenter
+	datacentersMu gosync.Mutex
+)
+
+func datacenterCacheFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fastlyctl", "datacenters.json"), nil
+}
+
+func readDatacenterCache(file string) (*datacenterCache, error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cache := new(datacenterCache)
+	if err := json.Unmarshal(body, cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func writeDatacenterCache(file string, cache *datacenterCache) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	body, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, body, 0644)
+}
+
+// getDatacenters returns the list of Fastly datacenters, using the local cache
+// while it is fresh. A stale cache is used if the list can't be refreshed.
+func getDatacenters(client *util.API) ([]*fastly.Datacenter, error) {
+	datacentersMu.Lock()
+	defer datacentersMu.Unlock()
+	if datacenters != nil {
+		return datacenters, nil
+	}
+
+	file, err := datacenterCacheFile()
+	var cache *datacenterCache
+	if err == nil {
+		cache, err = readDatacenterCache(file)
+		if err != nil && !os.IsNotExist(err) {
+			log.Debug(fmt.Sprintf("Ignoring unreadable datacenter cache %s: %s\n", file, err))
+		}
+	}
+	if cache != nil && time.Since(cache.Fetched) < datacenterCacheTTL {
+		datacenters = cache.Datacenters
+		return datacenters, nil
+	}
+
+	list, _, err := client.Datacenter.List()
+	if err != nil {
+		if cache != nil {
+			log.Debug(fmt.Sprintf("Error refreshing datacenters, using cached list: %s\n", err))
+			datacenters = cache.Datacenters
+			return datacenters, nil
+		}
+		return nil, err
+	}
+	datacenters = list
+	if file != "" {
+		if err := writeDatacenterCache(file, &datacenterCache{Fetched: time.Now().UTC(), Datacenters: list}); err != nil {
+			log.Debug(fmt.Sprintf("Error writing datacenter cache %s: %s\n", file, err))
+		}
+	}
+	return datacenters, nil
+}
+
+// validateShields checks that the Shield of every backend names a Fastly
+// POP, suggesting the closest match for any which don't.
+func validateShields(client *util.API, backends []fastly.Backend) error {
+	var shielded bool
+	for _, b := range backends {
+		if b.Shield != "" {
+			shielded = true
+		}
+	}
+	if !shielded || client.Datacenter == nil {
+		return nil
+	}
+
+	list, err := getDatacenters(client)
+	if err != nil {
+		return fmt.Errorf("Error fetching datacenters to validate shields: %s", err)
+	}
+	valid := make(map[string]bool)
+	for _, dc := range list {
+		if dc.Shield != "" {
+			valid[dc.Shield] = true
+		}
+	}
+	for _, b := range backends {
+		if b.Shield == "" || valid[b.Shield] {
+			continue
+		}
+		if suggestion := nearestShield(b.Shield, list); suggestion != "" {
+			return fmt.Errorf("Backend %s has unknown Shield %q. Did you mean %q?", b.Name, b.Shield, suggestion)
+		}
+		return fmt.Errorf("Backend %s has unknown Shield %q.", b.Name, b.Shield)
+	}
+	return nil
+}
+
+// nearestShield returns the shield code most similar to shield. A POP code,
+// such as "AMS", maps directly to that POP's shield code.
+func nearestShield(shield string, list []*fastly.Datacenter) string {
+	var best string
+	bestDistance := -1
+	for _, dc := range list {
+		if dc.Shield == "" {
+			continue
+		}
+		if strings.EqualFold(shield, dc.Code) {
+			return dc.Shield
+		}
+		d := util.EditDistance(strings.ToLower(shield), dc.Shield)
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = dc.Shield, d
+		}
+	}
+	// Don't suggest something which bears no resemblance to the input.
+	if bestDistance < 0 || bestDistance > len(best)/2 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,289 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+// Snapshot is a point-in-time copy of a single version of a service, including
+// the contents of its dictionaries and ACLs.
+type Snapshot struct {
+	Service fastly.Service
+	Version uint
+
+	Settings        fastly.Settings
+	Domains         []*fastly.Domain
+	Backends        []*fastly.Backend
+	Directors       []*fastly.Director
+	Conditions      []*fastly.Condition
+	CacheSettings   []*fastly.CacheSetting
+	Headers         []*fastly.Header
+	S3s             []*fastly.S3
+	Syslogs         []*fastly.Syslog
+	Gzips           []*fastly.Gzip
+	HealthChecks    []*fastly.HealthCheck
+	Dictionaries    []*fastly.Dictionary
+	ACLs            []*fastly.ACL
+	VCLs            []*fastly.VCL
+	RequestSettings []*fastly.RequestSetting
+	ResponseObjects []*fastly.ResponseObject
+
+	// Keyed by dictionary and ACL name respectively.
+	DictionaryItems map[string][]*fastly.DictionaryItem
+	ACLEntries      map[string][]*fastly.ACLEntry
+}
+
+// resources returns a pointer to every versioned resource in the snapshot,
+// other than its settings.
+func (s *Snapshot) resources() []interface{} {
+	var out []interface{}
+	for _, r := range s.Domains {
+		out = append(out, r)
+	}
+	for _, r := range s.Backends {
+		out = append(out, r)
+	}
+	for _, r := range s.Directors {
+		out = append(out, r)
+	}
+	for _, r := range s.Conditions {
+		out = append(out, r)
+	}
+	for _, r := range s.CacheSettings {
+		out = append(out, r)
+	}
+	for _, r := range s.Headers {
+		out = append(out, r)
+	}
+	for _, r := range s.S3s {
+		out = append(out, r)
+	}
+	for _, r := range s.Syslogs {
+		out = append(out, r)
+	}
+	for _, r := range s.Gzips {
+		out = append(out, r)
+	}
+	for _, r := range s.HealthChecks {
+		out = append(out, r)
+	}
+	for _, r := range s.Dictionaries {
+		out = append(out, r)
+	}
+	for _, r := range s.ACLs {
+		out = append(out, r)
+	}
+	for _, r := range s.VCLs {
+		out = append(out, r)
+	}
+	for _, r := range s.RequestSettings {
+		out = append(out, r)
+	}
+	for _, r := range s.ResponseObjects {
+		out = append(out, r)
+	}
+	return out
+}
+
+// FetchSnapshot retrieves the full configuration of a service version,
+// including the contents of its dictionaries and ACLs. The contents of
+// write-only dictionaries cannot be read, and are skipped.
+func FetchSnapshot(client *fastly.Client, service *fastly.Service, version uint) (*Snapshot, error) {
+	s := &Snapshot{Service: *service, Version: version}
+	id := service.ID
+	var err error
+
+	settings, _, err := client.Settings.Get(id, version)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching settings: %s", err)
+	}
+	s.Settings = *settings
+	if s.Domains, _, err = client.Domain.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching domains: %s", err)
+	}
+	if s.Backends, _, err = client.Backend.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching backends: %s", err)
+	}
+	if s.Directors, _, err = client.Director.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching directors: %s", err)
+	}
+	if s.Conditions, _, err = client.Condition.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching conditions: %s", err)
+	}
+	if s.CacheSettings, _, err = client.CacheSetting.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching cache settings: %s", err)
+	}
+	if s.Headers, _, err = client.Header.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching headers: %s", err)
+	}
+	if s.S3s, _, err = client.S3.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching s3s: %s", err)
+	}
+	if s.Syslogs, _, err = client.Syslog.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching syslogs: %s", err)
+	}
+	if s.Gzips, _, err = client.Gzip.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching gzips: %s", err)
+	}
+	if s.HealthChecks, _, err = client.HealthCheck.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching health checks: %s", err)
+	}
+	if s.Dictionaries, _, err = client.Dictionary.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching dictionaries: %s", err)
+	}
+	if s.ACLs, _, err = client.ACL.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching ACLs: %s", err)
+	}
+	if s.VCLs, _, err = client.VCL.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching VCLs: %s", err)
+	}
+	if s.RequestSettings, _, err = client.RequestSetting.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching request settings: %s", err)
+	}
+	if s.ResponseObjects, _, err = client.ResponseObject.List(id, version); err != nil {
+		return nil, fmt.Errorf("Error fetching response objects: %s", err)
+	}
+
+	s.DictionaryItems = make(map[string][]*fastly.DictionaryItem)
+	for _, d := range s.Dictionaries {
+		if d.WriteOnly {
+			fmt.Printf("%s Skipping its items.\n", util.WriteOnlyError(service.Name, d))
+			continue
+		}
+		items, _, err := client.DictionaryItem.List(id, d.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching items for dictionary %s: %s", d.Name, err)
+		}
+		s.DictionaryItems[d.Name] = items
+	}
+	s.ACLEntries = make(map[string][]*fastly.ACLEntry)
+	for _, a := range s.ACLs {
+		entries, _, err := client.ACLEntry.List(id, a.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching entries for ACL %s: %s", a.Name, err)
+		}
+		s.ACLEntries[a.Name] = entries
+	}
+
+	return s, nil
+}
+
+// LoadSnapshot recreates the snapshotted service as version 1 of a new
+// service on the fake server, returning that service. Resources are stored as
+// snapshotted; the fake fills in none of the defaults the API would.
+func LoadSnapshot(srv *fastlytest.Server, snapshot *Snapshot) (*fastly.Service, error) {
+	s := srv.AddService(snapshot.Service.Name)
+	if err := srv.SetSettings(s.ID, 1, snapshot.Settings); err != nil {
+		return nil, err
+	}
+	for _, r := range snapshot.resources() {
+		if err := srv.AddResource(s.ID, 1, r); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range snapshot.Dictionaries {
+		for _, item := range snapshot.DictionaryItems[d.Name] {
+			if err := srv.AddDictionaryItem(s.ID, d.ID, item.Key, item.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, a := range snapshot.ACLs {
+		for _, entry := range snapshot.ACLEntries[a.Name] {
+			if _, err := srv.AddACLEntry(s.ID, a.ID, *entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// SiteConfig converts the snapshot into the equivalent local configuration,
+// suitable for pushing with the sync engine.
+func (s *Snapshot) SiteConfig() SiteConfig {
+	var config SiteConfig
+	config.Settings = s.Settings
+	config.Settings.ServiceID = ""
+	config.Settings.Version = 0
+	for _, r := range s.Domains {
+		d := *r
+		d.ServiceID, d.Version = "", 0
+		config.Domains = append(config.Domains, d)
+	}
+	for _, r := range s.Backends {
+		b := *r
+		b.ServiceID, b.Version = "", 0
+		// The API fills in Hostname, IPV4, or IPV6 from Address. Only
+		// one may be specified locally, and the sync engine re-derives
+		// the others from it.
+		b.Hostname, b.IPV4, b.IPV6 = "", "", ""
+		config.Backends = append(config.Backends, b)
+	}
+	for _, r := range s.Directors {
+		d := *r
+		d.ServiceID, d.Version = "", 0
+		config.Directors = append(config.Directors, d)
+	}
+	for _, r := range s.Conditions {
+		c := *r
+		c.ServiceID, c.Version = "", 0
+		config.Conditions = append(config.Conditions, c)
+	}
+	for _, r := range s.CacheSettings {
+		c := *r
+		c.ServiceID, c.Version = "", 0
+		config.CacheSettings = append(config.CacheSettings, c)
+	}
+	for _, r := range s.Headers {
+		h := *r
+		h.ServiceID, h.Version = "", 0
+		config.Headers = append(config.Headers, h)
+	}
+	for _, r := range s.S3s {
+		s3 := *r
+		s3.ServiceID, s3.Version = "", 0
+		config.S3s = append(config.S3s, s3)
+	}
+	for _, r := range s.Syslogs {
+		l := *r
+		l.ServiceID, l.Version = "", 0
+		config.Syslogs = append(config.Syslogs, l)
+	}
+	for _, r := range s.Gzips {
+		g := *r
+		g.ServiceID, g.Version = "", 0
+		config.Gzips = append(config.Gzips, g)
+	}
+	for _, r := range s.HealthChecks {
+		h := *r
+		h.ServiceID, h.Version = "", 0
+		config.HealthChecks = append(config.HealthChecks, h)
+	}
+	for _, r := range s.Dictionaries {
+		d := *r
+		d.ServiceID, d.Version, d.ID = "", 0, ""
+		config.Dictionaries = append(config.Dictionaries, Dictionary{Dictionary: d})
+	}
+	for _, r := range s.ACLs {
+		a := *r
+		a.ServiceID, a.Version, a.ID = "", 0, ""
+		config.ACLs = append(config.ACLs, ACL{ACL: a})
+	}
+	for _, r := range s.VCLs {
+		config.VCLs = append(config.VCLs, VCL{Name: r.Name, Content: r.Content, Main: r.Main})
+	}
+	for _, r := range s.RequestSettings {
+		rs := *r
+		rs.ServiceID, rs.Version = "", 0
+		config.RequestSettings = append(config.RequestSettings, rs)
+	}
+	for _, r := range s.ResponseObjects {
+		ro := *r
+		ro.ServiceID, ro.Version = "", 0
+		config.ResponseObject = append(config.ResponseObject, ResponseObject{ResponseObject: ro})
+	}
+	return config
+}
@@ -0,0 +1,489 @@
+// Package sync is the engine behind fastlyctl push: it brings the versioned
+// configuration of Fastly services in line with a SiteConfig, preparing the
+// changes in draft versions which the caller may then validate and activate.
+package sync
+
+import (
+	"fmt"
+	"strings"
+	gosync "sync"
+
+	versionInfo "github.com/alienth/fastlyctl/_version"
+	"github.com/alienth/fastlyctl/log"
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+// VersionMarker begins the comment of every version created by this version
+// of fastlyctl, and identifies the drafts which later syncs may reuse.
+var VersionMarker = "fastlyctl-" + versionInfo.FullVersion()
+
+// IsToolDraft returns true if comment is that of a version created by this
+// version of fastlyctl.
+func IsToolDraft(comment string) bool {
+	return comment == VersionMarker || strings.HasPrefix(comment, VersionMarker+" ")
+}
+
+// builtinSteps is the number of resource types built into SiteConfig.
+const builtinSteps = 16
+
+// Syncer syncs services managed with a single API key to their configs. The
+// draft version prepared for each service, and the changes made to it, are
+// kept until the Syncer is discarded, so a service synced twice reuses its
+// draft. Different services may be synced concurrently from separate
+// goroutines.
+type Syncer struct {
+	// Configs holds the config of each service, keyed by service name.
+	// Services without a config of their own use _default_.
+	Configs map[string]SiteConfig
+
+	// VersionComment is the comment given to new draft versions. It
+	// should begin with VersionMarker, or the drafts won't be reused.
+	VersionComment string
+
+	// User, if set, is recorded in the comment of each draft as the user
+	// who synced it, alongside the fingerprint of its config.
+	User string
+
+	// Progress, if set, is called as Apply begins syncing each resource
+	// type of a service, with step counting from 1 to Steps().
+	Progress func(s *fastly.Service, step int, kind string)
+
+	// CheckContents, if set, is called before the items of a dictionary
+	// or the entries of an ACL are synced from a file, with the changes to
+	// be made and the number of existing items or entries, named by noun.
+	// An error prevents them being written.
+	CheckContents func(target, noun string, plan util.BatchPlan, existing int) error
+
+	// SkipContents leaves the items of dictionaries and the entries of
+	// ACLs unsynced, as they are written immediately rather than on
+	// activation.
+	SkipContents bool
+
+	// MaxDictionaryItems is the most items a dictionary synced from an
+	// ItemsFile may hold. Zero means DefaultMaxDictionaryItems.
+	MaxDictionaryItems int
+
+	client *fastly.Client
+	api    *util.API
+
+	// mu guards drafts and changes, which are shared with the Syncers
+	// returned by WithClient.
+	mu      *gosync.Mutex
+	drafts  map[string]fastly.Version
+	changes map[string]util.ChangeLog
+}
+
+// NewSyncer returns a Syncer which manages services through client.
+func NewSyncer(client *fastly.Client, configs map[string]SiteConfig) *Syncer {
+	return &Syncer{
+		Configs:        configs,
+		VersionComment: VersionMarker,
+		client:         client,
+		api:            util.NewAPI(client),
+		mu:             new(gosync.Mutex),
+		drafts:         make(map[string]fastly.Version),
+		changes:        make(map[string]util.ChangeLog),
+	}
+}
+
+// WithClient returns a Syncer which manages services through client, sharing
+// the drafts and changes of sy. It allows the requests made while syncing one
+// service to be bounded, such as by a deadline.
+func (sy *Syncer) WithClient(client *fastly.Client) *Syncer {
+	n := *sy
+	n.client = client
+	n.api = util.NewAPI(client)
+	return &n
+}
+
+// Config returns the config of the named service.
+func (sy *Syncer) Config(name string) SiteConfig {
+	if config, ok := sy.Configs[name]; ok {
+		return config
+	}
+	return sy.Configs["_default_"]
+}
+
+// Apply syncs the versioned config of s into a draft version, returning the
+// draft, or nil if s already matches its config. The draft's comment records
+// the Fingerprint of the config, and is left for the caller to validate and
+// activate.
+func (sy *Syncer) Apply(s *fastly.Service) (*fastly.Version, error) {
+	if err := sy.sync(s); err != nil {
+		return nil, err
+	}
+	if version, ok := sy.Draft(s); ok {
+		if err := sy.recordFingerprint(s, &version); err != nil {
+			return nil, fmt.Errorf("Error recording config fingerprint: %s", err)
+		}
+		return &version, nil
+	}
+	return nil, nil
+}
+
+// Plan returns the changes Apply would make to the active version of s,
+// without modifying anything in Fastly. The active version is loaded into an
+// in-process fake of the API, go-fastly's fastlytest server, and synced
+// there. The plan is only as faithful as the fake: anything the real API
+// would default, reject or rewrite, and the fake doesn't, goes unplanned.
+func (sy *Syncer) Plan(s *fastly.Service) (util.ChangeLog, error) {
+	activeVersion, err := util.GetActiveVersion(s)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := FetchSnapshot(sy.client, s, activeVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	planned, err := LoadSnapshot(srv, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	plan := NewSyncer(srv.Client(), map[string]SiteConfig{planned.Name: sy.Config(s.Name)})
+	plan.VersionComment = sy.VersionComment
+	plan.Progress = sy.Progress
+	plan.MaxDictionaryItems = sy.MaxDictionaryItems
+	if err = plan.sync(planned); err != nil {
+		return nil, err
+	}
+	return plan.Changes(planned), nil
+}
+
+// Draft returns the draft version prepared for s, if there is one.
+func (sy *Syncer) Draft(s *fastly.Service) (fastly.Version, bool) {
+	sy.mu.Lock()
+	defer sy.mu.Unlock()
+	version, ok := sy.drafts[s.ID]
+	return version, ok
+}
+
+// SetDraft makes version the draft which changes to s are made in, such as to
+// resume a push which failed part way through.
+func (sy *Syncer) SetDraft(s *fastly.Service, version fastly.Version) {
+	sy.mu.Lock()
+	defer sy.mu.Unlock()
+	sy.drafts[s.ID] = version
+}
+
+// discardDraft forgets the draft version of s and the changes made to it.
+// Unversioned changes, such as to dictionary items, are kept, returning
+// whether there were any.
+func (sy *Syncer) discardDraft(s *fastly.Service) bool {
+	sy.mu.Lock()
+	defer sy.mu.Unlock()
+	delete(sy.drafts, s.ID)
+	var kept util.ChangeLog
+	for _, change := range sy.changes[s.ID] {
+		if IsUnversioned(change.Kind) {
+			kept = append(kept, change)
+		}
+	}
+	sy.changes[s.ID] = kept
+	return len(kept) > 0
+}
+
+// Client returns the client through which services are managed, for use by
+// resource syncers.
+func (sy *Syncer) Client() *fastly.Client {
+	return sy.client
+}
+
+// Changes returns the changes made to the draft version of s.
+func (sy *Syncer) Changes(s *fastly.Service) util.ChangeLog {
+	sy.mu.Lock()
+	defer sy.mu.Unlock()
+	if len(sy.changes[s.ID]) == 0 {
+		return nil
+	}
+	return append(util.ChangeLog(nil), sy.changes[s.ID]...)
+}
+
+// RecordChange adds a change made to the draft version of s to its change
+// log.
+func (sy *Syncer) RecordChange(s *fastly.Service, kind string, action util.ChangeAction, name string) {
+	sy.mu.Lock()
+	defer sy.mu.Unlock()
+	sy.changes[s.ID] = append(sy.changes[s.ID], util.Change{Kind: kind, Name: name, Action: action})
+}
+
+// RecordDiff adds a change to the change log of s as RecordChange does, along
+// with the fields which differ between the old and new resource, either of
+// which may be nil. The effective values of new are recorded with it.
+func (sy *Syncer) RecordDiff(s *fastly.Service, kind string, action util.ChangeAction, name string, old, new interface{}) {
+	change := util.Change{Kind: kind, Name: name, Action: action, Fields: util.DiffFields(old, new)}
+	if action != util.ChangeRemoved && new != nil {
+		change.Effective = EffectiveValues(new)
+	}
+	sy.mu.Lock()
+	defer sy.mu.Unlock()
+	sy.changes[s.ID] = append(sy.changes[s.ID], change)
+}
+
+// PrepareDraft returns the draft version which changes to s are made in. An
+// existing fastlyctl draft newer than the active version is reused, otherwise
+// the active version is cloned.
+func (sy *Syncer) PrepareDraft(s *fastly.Service) (fastly.Version, error) {
+	// See if we've already prepared a version
+	if version, ok := sy.Draft(s); ok {
+		return version, nil
+	}
+
+	// Look for an inactive version higher than our current version. Locked
+	// drafts can no longer be changed, so are passed over.
+	versions, _, err := sy.api.Version.List(s.ID)
+	if err != nil {
+		return fastly.Version{}, err
+	}
+	for _, v := range versions {
+		if v.Number > s.Version && IsToolDraft(v.Comment) && !v.Active {
+			if v.Locked {
+				fmt.Printf("Draft version %d of %s is locked, so a new draft will be made instead.\n", v.Number, s.Name)
+				continue
+			}
+			sy.SetDraft(s, *v)
+			return *v, nil
+		}
+	}
+
+	// Otherwise, create a new version
+	newversion, err := sy.newDraft(s, versions)
+	if err != nil {
+		return fastly.Version{}, err
+	}
+	newversion.Comment = sy.VersionComment
+	// Zero out unwritable fields
+	newversion.Updated = ""
+	newversion.Created = ""
+	if _, _, err := sy.api.Version.Update(s.ID, newversion.Number, newversion); err != nil {
+		return *newversion, err
+	}
+	sy.SetDraft(s, *newversion)
+	return *newversion, nil
+}
+
+// newDraft clones the active version of s. A service with no active version
+// has nothing to clone, so the latest unlocked version of versions is cloned
+// instead, and failing that an empty version is created, for the sync to fill
+// in.
+func (sy *Syncer) newDraft(s *fastly.Service, versions []*fastly.Version) (*fastly.Version, error) {
+	var base *fastly.Version
+	for _, v := range versions {
+		if v.Number == s.Version && v.Active {
+			version, _, err := sy.api.Version.Clone(s.ID, s.Version)
+			return version, err
+		}
+		if !v.Locked && (base == nil || v.Number > base.Number) {
+			base = v
+		}
+	}
+	if base != nil {
+		version, _, err := sy.api.Version.Clone(s.ID, base.Number)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("%s has no active version, so version %d, the latest unlocked version, was cloned for the sync to bring in line with the config.\n", s.Name, base.Number)
+		return version, nil
+	}
+	version, _, err := sy.api.Version.Create(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("%s has no active or unlocked version to clone, so empty version %d was created. Its whole config will be synced from scratch.\n", s.Name, version.Number)
+	return version, nil
+}
+
+// ApplyMetadata updates the unversioned attributes of a service, such as its
+// comment, to match its config. If noop is set the differences are printed
+// rather than applied.
+func (sy *Syncer) ApplyMetadata(s *fastly.Service, noop bool) (bool, error) {
+	config := sy.Config(s.Name)
+	if config.Comment == "" || config.Comment == s.Comment {
+		return false, nil
+	}
+	if noop {
+		fmt.Printf("Would update comment for service %s: %q -> %q\n", s.Name, s.Comment, config.Comment)
+		return true, nil
+	}
+	update := fastly.Service{Name: s.Name, Comment: config.Comment}
+	if _, _, err := sy.client.Service.Update(s.ID, &update); err != nil {
+		return false, err
+	}
+	fmt.Printf("Updated comment for service %s: %q -> %q\n", s.Name, s.Comment, config.Comment)
+	s.Comment = config.Comment
+	return true, nil
+}
+
+func (sy *Syncer) step(s *fastly.Service, step int, kind string) {
+	log.Debug(fmt.Sprintf("Syncing %s\n", kind))
+	if sy.Progress != nil {
+		sy.Progress(s, step, kind)
+	}
+}
+
+func (sy *Syncer) sync(s *fastly.Service) error {
+	activeVersion, err := util.GetActiveVersion(s)
+	if err != nil {
+		return err
+	}
+	config := sy.Config(s.Name)
+	if err := checkResources(config); err != nil {
+		return err
+	}
+
+	// If this var is set to true, then we must prompt for an activation
+	// regardless of diff results. Some changes, such as ACL and Dict
+	// creation, have no affect on the diff.
+	var changesMade bool
+	var dictionaryChangesMade, aclChangesMade, backendChangesMade bool
+	// Dictionaries, Conditions, health checks, and cache settings must be
+	// sync'd first, as if they're referenced in any other object the API
+	// will balk if they don't exist.
+	sy.step(s, 1, "Dictionaries")
+	dictionaries := make([]fastly.Dictionary, len(config.Dictionaries))
+	for i, d := range config.Dictionaries {
+		dictionaries[i] = d.Dictionary
+	}
+	if dictionaryChangesMade, err = sy.syncDictionaries(s, dictionaries); err != nil {
+		return fmt.Errorf("Error syncing Dictionaries: %s", err)
+	}
+	if err = sy.syncDictionaryItems(s, config.Dictionaries); err != nil {
+		return fmt.Errorf("Error syncing dictionary items: %s", err)
+	}
+
+	sy.step(s, 2, "ACLs")
+	acls := make([]fastly.ACL, len(config.ACLs))
+	for i, a := range config.ACLs {
+		acls[i] = a.ACL
+	}
+	if aclChangesMade, err = sy.syncACLs(s, acls); err != nil {
+		return fmt.Errorf("Error syncing ACLs: %s", err)
+	}
+	if err = sy.syncACLEntries(s, config.ACLs); err != nil {
+		return fmt.Errorf("Error syncing acl entries: %s", err)
+	}
+
+	sy.step(s, 3, "conditions")
+	conditions := make([]fastly.Condition, len(config.Conditions))
+	copy(conditions, config.Conditions)
+	if err := sy.syncConditions(s, conditions); err != nil {
+		return fmt.Errorf("Error syncing conditions: %s", err)
+	}
+
+	sy.step(s, 4, "health checks")
+	healthChecks := make([]fastly.HealthCheck, len(config.HealthChecks))
+	copy(healthChecks, config.HealthChecks)
+	if err := sy.syncHealthChecks(s, healthChecks); err != nil {
+		return fmt.Errorf("Error syncing health checks: %s", err)
+	}
+
+	sy.step(s, 5, "cache settings")
+	cacheSettings := make([]fastly.CacheSetting, len(config.CacheSettings))
+	copy(cacheSettings, config.CacheSettings)
+	if err := sy.syncCacheSettings(s, cacheSettings); err != nil {
+		return fmt.Errorf("Error syncing cache settings: %s", err)
+	}
+
+	sy.step(s, 6, "response objects")
+	responseObjects := make([]ResponseObject, len(config.ResponseObject))
+	copy(responseObjects, config.ResponseObject)
+	if err = sy.syncResponseObjects(s, responseObjects); err != nil {
+		return fmt.Errorf("Error syncing response objects: %s", err)
+	}
+
+	sy.step(s, 7, "request settings")
+	requestSettings := make([]fastly.RequestSetting, len(config.RequestSettings))
+	copy(requestSettings, config.RequestSettings)
+	if err = sy.syncRequestSettings(s, requestSettings); err != nil {
+		return fmt.Errorf("Error syncing request settings: %s", err)
+	}
+
+	sy.step(s, 8, "backends")
+	backends := make([]fastly.Backend, len(config.Backends))
+	copy(backends, config.Backends)
+	if backendChangesMade, err = sy.syncBackends(s, backends); err != nil {
+		return fmt.Errorf("Error syncing backends: %s", err)
+	}
+
+	sy.step(s, 9, "directors")
+	directors := make([]fastly.Director, len(config.Directors))
+	copy(directors, config.Directors)
+	if err := sy.syncDirectors(s, directors); err != nil {
+		return fmt.Errorf("Error syncing directors: %s", err)
+	}
+
+	sy.step(s, 10, "headers")
+	headers := make([]fastly.Header, len(config.Headers))
+	copy(headers, config.Headers)
+	if err := sy.syncHeaders(s, headers); err != nil {
+		return fmt.Errorf("Error syncing headers: %s", err)
+	}
+
+	sy.step(s, 11, "syslogs")
+	syslogs := make([]fastly.Syslog, len(config.Syslogs))
+	copy(syslogs, config.Syslogs)
+	if err := sy.syncSyslogs(s, syslogs); err != nil {
+		return fmt.Errorf("Error syncing syslogs: %s", err)
+	}
+
+	sy.step(s, 12, "S3s")
+	s3s := make([]fastly.S3, len(config.S3s))
+	copy(s3s, config.S3s)
+	if err := sy.syncS3s(s, s3s); err != nil {
+		return fmt.Errorf("Error syncing s3s: %s", err)
+	}
+
+	sy.step(s, 13, "domains")
+	domains := make([]fastly.Domain, len(config.Domains))
+	copy(domains, config.Domains)
+	if err := sy.syncDomains(s, domains); err != nil {
+		return fmt.Errorf("Error syncing domains: %s", err)
+	}
+
+	sy.step(s, 14, "settings")
+	if err := sy.syncSettings(s, config.Settings); err != nil {
+		return fmt.Errorf("Error syncing settings: %s", err)
+	}
+
+	sy.step(s, 15, "gzips")
+	gzips := make([]fastly.Gzip, len(config.Gzips))
+	copy(gzips, config.Gzips)
+	if err := sy.syncGzips(s, gzips); err != nil {
+		return fmt.Errorf("Error syncing gzips: %s", err)
+	}
+
+	sy.step(s, 16, "VCLs")
+	vcls := make([]VCL, len(config.VCLs))
+	copy(vcls, config.VCLs)
+	if err := sy.syncVCLs(s, vcls); err != nil {
+		return fmt.Errorf("Error syncing VCLs: %s", err)
+	}
+
+	resourceChangesMade, err := sy.syncResources(s, config, builtinSteps+1)
+	if err != nil {
+		return err
+	}
+
+	changesMade = backendChangesMade || dictionaryChangesMade || aclChangesMade || resourceChangesMade
+
+	if version, ok := sy.Draft(s); ok {
+		equal, err := util.VersionsEqual(sy.api.Diff, s, activeVersion, version.Number)
+		if err != nil {
+			return err
+		}
+		if equal && !changesMade {
+			if sy.discardDraft(s) {
+				fmt.Printf("No changes to the config version of service %s\n", s.Name)
+			} else {
+				fmt.Printf("No changes for service %s\n", s.Name)
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,515 @@
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alienth/fastlyctl/util"
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+const testService = "test"
+
+// syncTo syncs s to config through srv, activating the draft if one is made,
+// and returns the changes made.
+func syncTo(t *testing.T, srv *fastlytest.Server, s *fastly.Service, config SiteConfig) util.ChangeLog {
+	t.Helper()
+	client := srv.Client()
+	sy := NewSyncer(client, map[string]SiteConfig{testService: config})
+	draft, err := sy.Apply(s)
+	if err != nil {
+		t.Fatalf("Apply: %s", err)
+	}
+	if draft != nil {
+		if _, _, err := client.Version.Activate(s.ID, draft.Number); err != nil {
+			t.Fatalf("Activate: %s", err)
+		}
+		s.Version = draft.Number
+	}
+	return sy.Changes(s)
+}
+
+// changeSummary describes changes as "kind action name", sorted.
+func changeSummary(changes util.ChangeLog) []string {
+	var out []string
+	for _, c := range changes {
+		out = append(out, c.Kind+" "+string(c.Action)+" "+c.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TestSyncResources syncs a service to a config, and then to a second config
+// which keeps one resource as it is, changes another, drops a third and adds
+// a fourth, checking that only the last three are touched.
+func TestSyncResources(t *testing.T) {
+	tests := []struct {
+		kind          string
+		before, after SiteConfig
+		// suffix ends the name of each resource.
+		suffix string
+		// noUpdate is set for resources with nothing which can be
+		// changed in place.
+		noUpdate bool
+	}{
+		{
+			kind:   "domain",
+			suffix: ".example.com",
+			before: SiteConfig{Domains: []fastly.Domain{
+				{Name: "kept.example.com"}, {Name: "changed.example.com"}, {Name: "removed.example.com"},
+			}},
+			after: SiteConfig{Domains: []fastly.Domain{
+				{Name: "kept.example.com"}, {Name: "changed.example.com", Comment: "new"}, {Name: "added.example.com"},
+			}},
+		},
+		{
+			kind: "backend",
+			before: SiteConfig{Backends: []fastly.Backend{
+				{Name: "kept", Address: "kept.example.com", Port: 443},
+				{Name: "changed", Address: "192.0.2.1", Port: 80},
+				{Name: "removed", Address: "2001:db8::1", Port: 80},
+			}},
+			after: SiteConfig{Backends: []fastly.Backend{
+				{Name: "kept", Address: "kept.example.com", Port: 443},
+				{Name: "changed", Address: "192.0.2.2", Port: 80},
+				{Name: "added", Hostname: "added.example.com", Port: 443},
+			}},
+		},
+		{
+			kind: "director",
+			before: SiteConfig{
+				Backends: []fastly.Backend{{Name: "a", Address: "a.example.com"}, {Name: "b", Address: "b.example.com"}},
+				Directors: []fastly.Director{
+					{Name: "kept", Backends: []string{"a", "b"}},
+					{Name: "changed", Backends: []string{"a"}},
+					{Name: "removed", Backends: []string{"b"}},
+				},
+			},
+			after: SiteConfig{
+				Backends: []fastly.Backend{{Name: "a", Address: "a.example.com"}, {Name: "b", Address: "b.example.com"}},
+				Directors: []fastly.Director{
+					{Name: "kept", Backends: []string{"b", "a"}},
+					{Name: "changed", Backends: []string{"b"}, Quorum: 50},
+					{Name: "added", Backends: []string{"a"}},
+				},
+			},
+		},
+		{
+			kind: "condition",
+			before: SiteConfig{Conditions: []fastly.Condition{
+				{Name: "kept", Statement: "req.url ~ \"^/a\""},
+				{Name: "changed", Statement: "req.url ~ \"^/b\"", Type: fastly.ConditionTypeCache},
+				{Name: "removed", Statement: "req.url ~ \"^/c\""},
+			}},
+			after: SiteConfig{Conditions: []fastly.Condition{
+				{Name: "kept", Statement: "req.url ~ \"^/a\""},
+				{Name: "changed", Statement: "req.url ~ \"^/b/\"", Type: fastly.ConditionTypeCache},
+				{Name: "added", Statement: "req.url ~ \"^/d\""},
+			}},
+		},
+		{
+			kind: "health check",
+			before: SiteConfig{HealthChecks: []fastly.HealthCheck{
+				{Name: "kept", Path: "/a"}, {Name: "changed", Path: "/b"}, {Name: "removed", Path: "/c"},
+			}},
+			after: SiteConfig{HealthChecks: []fastly.HealthCheck{
+				{Name: "kept", Path: "/a"}, {Name: "changed", Path: "/b", HTTPVersion: "1.0"}, {Name: "added", Path: "/d"},
+			}},
+		},
+		{
+			kind: "cache setting",
+			before: SiteConfig{CacheSettings: []fastly.CacheSetting{
+				{Name: "kept", TTL: 60}, {Name: "changed", TTL: 60}, {Name: "removed", TTL: 60},
+			}},
+			after: SiteConfig{CacheSettings: []fastly.CacheSetting{
+				{Name: "kept", TTL: 60}, {Name: "changed", TTL: 120}, {Name: "added", TTL: 60},
+			}},
+		},
+		{
+			kind: "header",
+			before: SiteConfig{Headers: []fastly.Header{
+				{Name: "kept", Action: fastly.HeaderActionSet, Type: fastly.HeaderTypeResponse, Destination: "http.A", Source: "\"a\""},
+				{Name: "changed", Action: fastly.HeaderActionSet, Type: fastly.HeaderTypeResponse, Destination: "http.B", Source: "\"b\""},
+				{Name: "removed", Action: fastly.HeaderActionDelete, Type: fastly.HeaderTypeResponse, Destination: "http.C"},
+			}},
+			after: SiteConfig{Headers: []fastly.Header{
+				{Name: "kept", Action: fastly.HeaderActionSet, Type: fastly.HeaderTypeResponse, Destination: "http.A", Source: "\"a\""},
+				{Name: "changed", Action: fastly.HeaderActionSet, Type: fastly.HeaderTypeResponse, Destination: "http.B", Source: "\"b2\""},
+				{Name: "added", Action: fastly.HeaderActionDelete, Type: fastly.HeaderTypeResponse, Destination: "http.D"},
+			}},
+		},
+		{
+			kind: "s3",
+			before: SiteConfig{S3s: []fastly.S3{
+				{Name: "kept", BucketName: "a"}, {Name: "changed", BucketName: "b"}, {Name: "removed", BucketName: "c"},
+			}},
+			after: SiteConfig{S3s: []fastly.S3{
+				{Name: "kept", BucketName: "a"}, {Name: "changed", BucketName: "b2"}, {Name: "added", BucketName: "d"},
+			}},
+		},
+		{
+			kind: "syslog",
+			before: SiteConfig{Syslogs: []fastly.Syslog{
+				{Name: "kept", Address: "a.example.com", Port: 514},
+				{Name: "changed", Address: "b.example.com", Port: 514},
+				{Name: "removed", Address: "c.example.com", Port: 514},
+			}},
+			after: SiteConfig{Syslogs: []fastly.Syslog{
+				{Name: "kept", Address: "a.example.com", Port: 514},
+				{Name: "changed", Address: "b.example.com", Port: 6514, UseTLS: fastly.CompatiboolTrue},
+				{Name: "added", Address: "d.example.com", Port: 514},
+			}},
+		},
+		{
+			kind: "gzip",
+			before: SiteConfig{Gzips: []fastly.Gzip{
+				{Name: "kept", ContentTypes: "text/html text/css"},
+				{Name: "changed", Extensions: "js"},
+				{Name: "removed", Extensions: "css"},
+			}},
+			after: SiteConfig{Gzips: []fastly.Gzip{
+				{Name: "kept", ContentTypes: "text/css text/html"},
+				{Name: "changed", Extensions: "js mjs"},
+				{Name: "added", Extensions: "svg"},
+			}},
+		},
+		{
+			kind: "request setting",
+			before: SiteConfig{RequestSettings: []fastly.RequestSetting{
+				{Name: "kept", ForceSSL: fastly.CompatiboolTrue},
+				{Name: "changed", ForceSSL: fastly.CompatiboolTrue},
+				{Name: "removed", XFF: "append"},
+			}},
+			after: SiteConfig{RequestSettings: []fastly.RequestSetting{
+				{Name: "kept"},
+				{Name: "changed", ForceSSL: fastly.CompatiboolFalse},
+				{Name: "added", XFF: "append"},
+			}},
+		},
+		{
+			kind: "response object",
+			before: SiteConfig{ResponseObject: []ResponseObject{
+				{ResponseObject: fastly.ResponseObject{Name: "kept", Status: "200", Content: "a"}},
+				{ResponseObject: fastly.ResponseObject{Name: "changed", Status: "200", Content: "b"}},
+				{ResponseObject: fastly.ResponseObject{Name: "removed", Status: "404"}},
+			}},
+			after: SiteConfig{ResponseObject: []ResponseObject{
+				{ResponseObject: fastly.ResponseObject{Name: "kept", Status: "200", Content: "a"}},
+				{ResponseObject: fastly.ResponseObject{Name: "changed", Status: "200", Content: "b2"}},
+				{ResponseObject: fastly.ResponseObject{Name: "added", Status: "404"}},
+			}},
+		},
+		{
+			kind: "vcl",
+			before: SiteConfig{VCLs: []VCL{
+				{Name: "kept", Content: "sub vcl_recv {}", Main: true},
+				{Name: "changed", Content: "sub b {}"},
+				{Name: "removed", Content: "sub c {}"},
+			}},
+			after: SiteConfig{VCLs: []VCL{
+				{Name: "kept", Content: "sub vcl_recv {}", Main: true},
+				{Name: "changed", Content: "sub b { set req.http.B = \"1\"; }"},
+				{Name: "added", Content: "sub d {}"},
+			}},
+		},
+		{
+			kind: "dictionary",
+			before: SiteConfig{Dictionaries: []Dictionary{
+				{Dictionary: fastly.Dictionary{Name: "kept"}}, {Dictionary: fastly.Dictionary{Name: "removed", WriteOnly: true}},
+			}},
+			after: SiteConfig{Dictionaries: []Dictionary{
+				{Dictionary: fastly.Dictionary{Name: "kept"}}, {Dictionary: fastly.Dictionary{Name: "added", WriteOnly: true}},
+			}},
+			noUpdate: true,
+		},
+		{
+			kind: "acl",
+			before: SiteConfig{ACLs: []ACL{
+				{ACL: fastly.ACL{Name: "kept"}}, {ACL: fastly.ACL{Name: "removed"}},
+			}},
+			after: SiteConfig{ACLs: []ACL{
+				{ACL: fastly.ACL{Name: "kept"}}, {ACL: fastly.ACL{Name: "added"}},
+			}},
+			noUpdate: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			srv := fastlytest.NewServer()
+			defer srv.Close()
+			s := srv.AddService(testService)
+
+			syncTo(t, srv, s, test.before)
+			if changes := syncTo(t, srv, s, test.before); changes != nil {
+				t.Fatalf("Resyncing an unchanged config made changes: %v", changeSummary(changes))
+			}
+
+			got := changeSummary(syncTo(t, srv, s, test.after))
+			want := []string{test.kind + " added added" + test.suffix, test.kind + " removed removed" + test.suffix}
+			if !test.noUpdate {
+				want = append(want, test.kind+" changed changed"+test.suffix)
+			}
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Changes = %q, want %q", got, want)
+			}
+			if changes := syncTo(t, srv, s, test.after); changes != nil {
+				t.Errorf("Resyncing the changed config made changes: %v", changeSummary(changes))
+			}
+		})
+	}
+}
+
+func TestSyncSettings(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	config := SiteConfig{Settings: fastly.Settings{DefaultTTL: 3600, DefaultHost: "example.com"}}
+	got := changeSummary(syncTo(t, srv, s, config))
+	if want := []string{"settings changed "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes = %q, want %q", got, want)
+	}
+	if changes := syncTo(t, srv, s, config); changes != nil {
+		t.Errorf("Resyncing unchanged settings made changes: %v", changeSummary(changes))
+	}
+	settings, err := srv.Settings(s.ID, s.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.DefaultTTL != 3600 || settings.DefaultHost != "example.com" {
+		t.Errorf("Settings = %+v, want those of the config", settings)
+	}
+}
+
+// TestPrepareDraft checks which version a draft is made from: the active
+// version, or for a service without one the latest unlocked version, or an
+// empty version should every version be locked.
+func TestPrepareDraft(t *testing.T) {
+	tests := []struct {
+		name string
+		// deactivate leaves the service without an active version.
+		deactivate bool
+		// unlocked adds a second, unlocked version holding a domain.
+		unlocked bool
+		// domains lists the domains the draft should be cloned with.
+		domains []string
+		number  uint
+	}{
+		{name: "active version", unlocked: true, number: 3},
+		{name: "no active version", deactivate: true, unlocked: true, domains: []string{"unlocked.example.com"}, number: 3},
+		{name: "no active or unlocked version", deactivate: true, number: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := fastlytest.NewServer()
+			defer srv.Close()
+			client := srv.Client()
+			s := srv.AddService(testService)
+			if test.unlocked {
+				v, _, err := client.Version.Clone(s.ID, s.Version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := srv.AddResource(s.ID, v.Number, &fastly.Domain{Name: "unlocked.example.com"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.deactivate {
+				if _, _, err := client.Version.Deactivate(s.ID, s.Version); err != nil {
+					t.Fatal(err)
+				}
+				s.Version = 0
+			}
+
+			sy := NewSyncer(client, map[string]SiteConfig{testService: {}})
+			draft, err := sy.PrepareDraft(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if draft.Number != test.number || draft.Comment != sy.VersionComment {
+				t.Errorf("Draft = version %d with comment %q, want version %d with %q", draft.Number, draft.Comment, test.number, sy.VersionComment)
+			}
+			var domains []string
+			for _, d := range srv.Resources(s.ID, draft.Number, "domain") {
+				domains = append(domains, d.(*fastly.Domain).Name)
+			}
+			if !reflect.DeepEqual(domains, test.domains) {
+				t.Errorf("Draft has domains %q, want %q", domains, test.domains)
+			}
+		})
+	}
+}
+
+// TestSyncEffectivePriorities checks that the changes made to headers and
+// conditions give the priorities which are pushed, including defaulted ones.
+func TestSyncEffectivePriorities(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	config := SiteConfig{
+		Conditions: []fastly.Condition{{Name: "defaulted", Statement: "req.url ~ \"^/a\""}},
+		Headers: []fastly.Header{
+			{Name: "defaulted", Action: fastly.HeaderActionDelete, Type: fastly.HeaderTypeResponse, Destination: "http.A"},
+			{Name: "given", Action: fastly.HeaderActionDelete, Type: fastly.HeaderTypeResponse, Destination: "http.B", Priority: 5},
+		},
+	}
+	var got []string
+	for _, c := range syncTo(t, srv, s, config) {
+		got = append(got, c.Kind+" "+c.Name+": "+c.Effective)
+	}
+	sort.Strings(got)
+	want := []string{"condition defaulted: priority 10", "header defaulted: priority 100", "header given: priority 5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Effective values = %q, want %q", got, want)
+	}
+}
+
+func TestSyncDictionaryWriteOnlyChange(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	syncTo(t, srv, s, SiteConfig{Dictionaries: []Dictionary{{Dictionary: fastly.Dictionary{Name: "d"}}}})
+	sy := NewSyncer(srv.Client(), map[string]SiteConfig{testService: {
+		Dictionaries: []Dictionary{{Dictionary: fastly.Dictionary{Name: "d", WriteOnly: true}}},
+	}})
+	if _, err := sy.Apply(s); err == nil {
+		t.Error("Making an existing dictionary write-only succeeded, want an error")
+	}
+}
+
+// writeFile writes content to a file named name in a temporary directory,
+// returning its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "fastlyctl-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestSyncDictionaryItems(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	config := func(items string) SiteConfig {
+		return SiteConfig{Dictionaries: []Dictionary{{
+			Dictionary: fastly.Dictionary{Name: "d"},
+			ItemsFile:  writeFile(t, "items.csv", items),
+		}}}
+	}
+	syncTo(t, srv, s, config("kept,1\nchanged,1\nremoved,1\n"))
+	got := changeSummary(syncTo(t, srv, s, config("kept,1\nchanged,2\nadded,1\n")))
+	want := []string{
+		DictionaryItemKind + " added d/added",
+		DictionaryItemKind + " changed d/changed",
+		DictionaryItemKind + " removed d/removed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes = %q, want %q", got, want)
+	}
+
+	dictionaries := srv.Resources(s.ID, s.Version, "dictionary")
+	items := srv.DictionaryItems(s.ID, dictionaries[0].(*fastly.Dictionary).ID)
+	values := make(map[string]string)
+	for _, item := range items {
+		values[item.Key] = item.Value
+	}
+	if want := map[string]string{"kept": "1", "changed": "2", "added": "1"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Items = %v, want %v", values, want)
+	}
+}
+
+func TestSyncDictionaryItemsRejected(t *testing.T) {
+	const limits = "exceed Fastly's limits"
+	tests := []struct {
+		name     string
+		items    string
+		maxItems int
+		err      string
+	}{
+		{name: "key too long", items: strings.Repeat("k", MaxDictionaryKeyLength+1) + ",1\n", err: limits},
+		{name: "value too long", items: "k," + strings.Repeat("v", MaxDictionaryValueLength+1) + "\n", err: limits},
+		{name: "empty key", items: ",1\n", err: limits},
+		{name: "too many items", items: "a,1\nb,1\nc,1\n", maxItems: 2, err: limits},
+		{name: "duplicate key", items: "a,1\nb,1\na,2\n", err: "appears more than once"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := fastlytest.NewServer()
+			defer srv.Close()
+			s := srv.AddService(testService)
+
+			sy := NewSyncer(srv.Client(), map[string]SiteConfig{testService: {
+				Dictionaries: []Dictionary{{
+					Dictionary: fastly.Dictionary{Name: "d"},
+					ItemsFile:  writeFile(t, "items.csv", test.items),
+				}},
+			}})
+			sy.MaxDictionaryItems = test.maxItems
+			if _, err := sy.Apply(s); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Apply returned %v, want an error containing %q", err, test.err)
+			}
+			for _, change := range sy.Changes(s) {
+				if change.Kind == DictionaryItemKind {
+					t.Errorf("Recorded %s %s %s, want no items written", change.Kind, change.Action, change.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncACLEntries(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	config := func(entries string) SiteConfig {
+		return SiteConfig{ACLs: []ACL{{
+			ACL:         fastly.ACL{Name: "a"},
+			EntriesFile: writeFile(t, "entries.txt", entries),
+		}}}
+	}
+	syncTo(t, srv, s, config("192.0.2.1\n192.0.2.2\n192.0.2.3\n"))
+	got := changeSummary(syncTo(t, srv, s, config("192.0.2.1\n!192.0.2.2\n198.51.100.0/24\n")))
+	want := []string{
+		ACLEntryKind + " added a 198.51.100.0/24",
+		ACLEntryKind + " changed a 192.0.2.2/0",
+		ACLEntryKind + " removed a 192.0.2.3/0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes = %q, want %q", got, want)
+	}
+}
+
+func TestSyncACLEntriesDuplicate(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	s := srv.AddService(testService)
+
+	sy := NewSyncer(srv.Client(), map[string]SiteConfig{testService: {
+		ACLs: []ACL{{
+			ACL:         fastly.ACL{Name: "a"},
+			EntriesFile: writeFile(t, "entries.txt", "192.0.2.1\n!192.0.2.1 again\n"),
+		}},
+	}})
+	if _, err := sy.Apply(s); err == nil || !strings.Contains(err.Error(), "appears more than once") {
+		t.Errorf("Apply returned %v, want an error about the duplicate entry", err)
+	}
+}
@@ -0,0 +1,103 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alienth/go-fastly"
+	"github.com/alienth/go-fastly/fastlytest"
+	"github.com/urfave/cli"
+)
+
+// activate runs ActivateVersion on a draft adding a domain to a service, with
+// the given global flags, returning what it printed and whether the draft
+// was left active. Tests run without a terminal, so are never interactive.
+func activate(t *testing.T, args ...string) (string, bool, error) {
+	t.Helper()
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	s := srv.AddService("test")
+	draft, _, err := client.Version.Clone(s.ID, s.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AddResource(s.ID, draft.Number, &fastly.Domain{Name: "www.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	printed := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		printed <- buf.String()
+	}()
+
+	var activateErr error
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{Name: "assume-yes"},
+		cli.BoolFlag{Name: "auto-approve"},
+		cli.BoolFlag{Name: "skip-diff"},
+	}
+	app.Action = func(c *cli.Context) error {
+		_, activateErr = ActivateVersion(c, client, s, draft, nil)
+		return nil
+	}
+	runErr := app.Run(append([]string{"fastlyctl"}, args...))
+	w.Close()
+	os.Stdout = stdout
+	out := <-printed
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	service, _, err := client.Service.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := GetActiveVersion(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out, v == draft.Number, activateErr
+}
+
+func TestActivateVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		err       bool
+		activated bool
+		fullDiff  bool
+	}{
+		{name: "non-interactive", err: true, fullDiff: true},
+		{name: "non-interactive with --skip-diff", args: []string{"--skip-diff"}, err: true},
+		{name: "--assume-yes", args: []string{"--assume-yes"}, activated: true},
+		{name: "--auto-approve", args: []string{"--auto-approve", "--assume-yes"}, activated: true, fullDiff: true},
+		{name: "--auto-approve with --skip-diff", args: []string{"--auto-approve", "--assume-yes", "--skip-diff"}, activated: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, activated, err := activate(t, test.args...)
+			if (err != nil) != test.err {
+				t.Errorf("ActivateVersion returned %v, want error %t", err, test.err)
+			}
+			if activated != test.activated {
+				t.Errorf("Activated = %t, want %t", activated, test.activated)
+			}
+			if got := strings.Contains(out, "Diff for test:") && strings.Contains(out, "www.example.com"); got != test.fullDiff {
+				t.Errorf("Printed the full diff = %t, want %t. Output:\n%s", got, test.fullDiff, out)
+			}
+		})
+	}
+}
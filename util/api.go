@@ -0,0 +1,206 @@
+package util
+
+import (
+	"net/http"
+
+	"github.com/alienth/go-fastly"
+)
+
+// The interfaces below describe the subset of each go-fastly config used by
+// the sync engine. They allow the engine to be driven by something other
+// than a live *fastly.Client, such as a mock or a recording client.
+
+type ACLAPI interface {
+	List(serviceID string, version uint) ([]*fastly.ACL, *http.Response, error)
+	Create(serviceID string, version uint, acl *fastly.ACL) (*fastly.ACL, *http.Response, error)
+	Update(serviceID string, version uint, name string, acl *fastly.ACL) (*fastly.ACL, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type ACLEntryAPI interface {
+	List(serviceID, aclID string, opts *fastly.ListOptions) ([]*fastly.ACLEntry, *http.Response, error)
+	BatchUpdate(serviceID, aclID string, entries []fastly.ACLEntryUpdate) (*http.Response, error)
+}
+
+type BackendAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Backend, *http.Response, error)
+	Create(serviceID string, version uint, backend *fastly.Backend) (*fastly.Backend, *http.Response, error)
+	Update(serviceID string, version uint, name string, backend *fastly.Backend) (*fastly.Backend, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type CacheSettingAPI interface {
+	List(serviceID string, version uint) ([]*fastly.CacheSetting, *http.Response, error)
+	Create(serviceID string, version uint, setting *fastly.CacheSetting) (*fastly.CacheSetting, *http.Response, error)
+	Update(serviceID string, version uint, name string, setting *fastly.CacheSetting) (*fastly.CacheSetting, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type ConditionAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Condition, *http.Response, error)
+	Create(serviceID string, version uint, condition *fastly.Condition) (*fastly.Condition, *http.Response, error)
+	Update(serviceID string, version uint, name string, condition *fastly.Condition) (*fastly.Condition, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type DatacenterAPI interface {
+	List() ([]*fastly.Datacenter, *http.Response, error)
+}
+
+type DictionaryAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Dictionary, *http.Response, error)
+	Create(serviceID string, version uint, dictionary *fastly.Dictionary) (*fastly.Dictionary, *http.Response, error)
+	Update(serviceID string, version uint, name string, dictionary *fastly.Dictionary) (*fastly.Dictionary, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type DictionaryItemAPI interface {
+	List(serviceID, dictionaryID string, opts *fastly.ListOptions) ([]*fastly.DictionaryItem, *http.Response, error)
+	BatchUpdate(serviceID, dictionaryID string, items []fastly.DictionaryItemUpdate) (*http.Response, error)
+}
+
+type DiffAPI interface {
+	Get(serviceID string, from, to uint, format fastly.DiffFormat) (*fastly.Diff, *http.Response, error)
+}
+
+type DirectorAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Director, *http.Response, error)
+	Create(serviceID string, version uint, director *fastly.Director) (*fastly.Director, *http.Response, error)
+	Update(serviceID string, version uint, name string, director *fastly.Director) (*fastly.Director, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type DirectorBackendAPI interface {
+	Create(serviceID string, version uint, director, backend string) (*fastly.DirectorBackend, *http.Response, error)
+	Delete(serviceID string, version uint, director, backend string) (*http.Response, error)
+}
+
+type DomainAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Domain, *http.Response, error)
+	Create(serviceID string, version uint, domain *fastly.Domain) (*fastly.Domain, *http.Response, error)
+	Update(serviceID string, version uint, name string, domain *fastly.Domain) (*fastly.Domain, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type GzipAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Gzip, *http.Response, error)
+	Create(serviceID string, version uint, gzip *fastly.Gzip) (*fastly.Gzip, *http.Response, error)
+	Update(serviceID string, version uint, name string, gzip *fastly.Gzip) (*fastly.Gzip, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type HeaderAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Header, *http.Response, error)
+	Create(serviceID string, version uint, header *fastly.Header) (*fastly.Header, *http.Response, error)
+	Update(serviceID string, version uint, name string, header *fastly.Header) (*fastly.Header, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type HealthCheckAPI interface {
+	List(serviceID string, version uint) ([]*fastly.HealthCheck, *http.Response, error)
+	Create(serviceID string, version uint, healthCheck *fastly.HealthCheck) (*fastly.HealthCheck, *http.Response, error)
+	Update(serviceID string, version uint, name string, healthCheck *fastly.HealthCheck) (*fastly.HealthCheck, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type RequestSettingAPI interface {
+	List(serviceID string, version uint) ([]*fastly.RequestSetting, *http.Response, error)
+	Create(serviceID string, version uint, requestSetting *fastly.RequestSetting) (*fastly.RequestSetting, *http.Response, error)
+	Update(serviceID string, version uint, name string, requestSetting *fastly.RequestSetting) (*fastly.RequestSetting, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type ResponseObjectAPI interface {
+	List(serviceID string, version uint) ([]*fastly.ResponseObject, *http.Response, error)
+	Create(serviceID string, version uint, responseObject *fastly.ResponseObject) (*fastly.ResponseObject, *http.Response, error)
+	Update(serviceID string, version uint, name string, responseObject *fastly.ResponseObject) (*fastly.ResponseObject, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type S3API interface {
+	List(serviceID string, version uint) ([]*fastly.S3, *http.Response, error)
+	Create(serviceID string, version uint, s3 *fastly.S3) (*fastly.S3, *http.Response, error)
+	Update(serviceID string, version uint, name string, s3 *fastly.S3) (*fastly.S3, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type SettingsAPI interface {
+	Get(serviceID string, version uint) (*fastly.Settings, *http.Response, error)
+	Update(serviceID string, version uint, settings *fastly.Settings) (*fastly.Settings, *http.Response, error)
+}
+
+type SyslogAPI interface {
+	List(serviceID string, version uint) ([]*fastly.Syslog, *http.Response, error)
+	Create(serviceID string, version uint, syslog *fastly.Syslog) (*fastly.Syslog, *http.Response, error)
+	Update(serviceID string, version uint, name string, syslog *fastly.Syslog) (*fastly.Syslog, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type VCLAPI interface {
+	List(serviceID string, version uint) ([]*fastly.VCL, *http.Response, error)
+	Create(serviceID string, version uint, vcl *fastly.VCL) (*fastly.VCL, *http.Response, error)
+	Update(serviceID string, version uint, name string, vcl *fastly.VCL) (*fastly.VCL, *http.Response, error)
+	Delete(serviceID string, version uint, name string) (*http.Response, error)
+}
+
+type VersionAPI interface {
+	List(serviceID string) ([]*fastly.Version, *http.Response, error)
+	Clone(serviceID string, versionNumber uint) (*fastly.Version, *http.Response, error)
+	Create(serviceID string) (*fastly.Version, *http.Response, error)
+	Update(serviceID string, versionNumber uint, version *fastly.Version) (*fastly.Version, *http.Response, error)
+}
+
+// API groups the per-resource interfaces consumed by the sync engine. Field
+// names match those of *fastly.Client.
+type API struct {
+	ACL             ACLAPI
+	ACLEntry        ACLEntryAPI
+	Backend         BackendAPI
+	CacheSetting    CacheSettingAPI
+	Condition       ConditionAPI
+	Datacenter      DatacenterAPI
+	Dictionary      DictionaryAPI
+	DictionaryItem  DictionaryItemAPI
+	Diff            DiffAPI
+	Director        DirectorAPI
+	DirectorBackend DirectorBackendAPI
+	Domain          DomainAPI
+	Gzip            GzipAPI
+	Header          HeaderAPI
+	HealthCheck     HealthCheckAPI
+	RequestSetting  RequestSettingAPI
+	ResponseObject  ResponseObjectAPI
+	S3              S3API
+	Settings        SettingsAPI
+	Syslog          SyslogAPI
+	VCL             VCLAPI
+	Version         VersionAPI
+}
+
+// NewAPI returns an API backed by the given client.
+func NewAPI(client *fastly.Client) *API {
+	return &API{
+		ACL:             client.ACL,
+		ACLEntry:        client.ACLEntry,
+		Backend:         client.Backend,
+		CacheSetting:    client.CacheSetting,
+		Condition:       client.Condition,
+		Datacenter:      client.Datacenter,
+		Dictionary:      client.Dictionary,
+		DictionaryItem:  client.DictionaryItem,
+		Diff:            client.Diff,
+		Director:        client.Director,
+		DirectorBackend: client.DirectorBackend,
+		Domain:          client.Domain,
+		Gzip:            client.Gzip,
+		Header:          client.Header,
+		HealthCheck:     client.HealthCheck,
+		RequestSetting:  client.RequestSetting,
+		ResponseObject:  client.ResponseObject,
+		S3:              client.S3,
+		Settings:        client.Settings,
+		Syslog:          client.Syslog,
+		VCL:             client.VCL,
+		Version:         client.Version,
+	}
+}
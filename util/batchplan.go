@@ -0,0 +1,37 @@
+package util
+
+import "fmt"
+
+// batchPlanSample is the number of keys of each action shown when a
+// BatchPlan is printed.
+const batchPlanSample = 10
+
+// BatchPlan lists, by key, the changes a bulk dictionary or ACL operation
+// would make.
+type BatchPlan struct {
+	Add, Update, Delete []string
+}
+
+// Print describes the plan for target, such as "dictionary foo on service
+// bar", counting the changes of each action and showing a sample of their
+// keys.
+func (p *BatchPlan) Print(target string) {
+	fmt.Printf("Dry run for %s: %d to add, %d to update, %d to delete\n", target, len(p.Add), len(p.Update), len(p.Delete))
+	for _, group := range []struct {
+		action ChangeAction
+		keys   []string
+		verb   string
+	}{
+		{ChangeAdded, p.Add, "add"},
+		{ChangeChanged, p.Update, "update"},
+		{ChangeRemoved, p.Delete, "delete"},
+	} {
+		for i, key := range group.keys {
+			if i == batchPlanSample {
+				fmt.Printf("  ... and %d more to %s\n", len(group.keys)-i, group.verb)
+				break
+			}
+			fmt.Printf("  %s %s\n", changeSymbols[group.action], key)
+		}
+	}
+}
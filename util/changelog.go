@@ -0,0 +1,209 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeAction describes what was done to a resource in a draft version.
+type ChangeAction string
+
+const (
+	ChangeAdded   ChangeAction = "added"
+	ChangeChanged ChangeAction = "changed"
+	ChangeRemoved ChangeAction = "removed"
+)
+
+var changeSymbols = map[ChangeAction]string{
+	ChangeAdded:   "+",
+	ChangeChanged: "~",
+	ChangeRemoved: "-",
+}
+
+// Change records a single resource modified in a draft version.
+type Change struct {
+	Kind   string       `json:"kind"`
+	Name   string       `json:"name"`
+	Action ChangeAction `json:"action"`
+	// Fields lists the fields which were set on an added resource, or
+	// changed on a changed one, where they are known.
+	Fields []FieldChange `json:"fields,omitempty"`
+	// Effective describes values which apply to an added or changed
+	// resource without being given in config, such as a default
+	// priority.
+	Effective string `json:"effective,omitempty"`
+}
+
+// FieldChange is the old and new value of a field of a resource.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ignoredFields are read-only fields which identify a resource rather than
+// describe it.
+var ignoredFields = []string{"ServiceID", "Version", "ID"}
+
+// redactedFields are the fields, by their name in the API, whose values are
+// secrets. Changes to them are shown without the values.
+var redactedFields = []string{"access_key", "secret_key", "token", "password"}
+
+// DiffFields compares two resources of the same struct type, given as
+// pointers, returning the fields whose values differ. Either may be nil, as
+// for resources which are being added or removed.
+func DiffFields(old, new interface{}) []FieldChange {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	if ov.Kind() == reflect.Ptr && ov.IsNil() || !ov.IsValid() {
+		ov = reflect.Value{}
+	}
+	if nv.Kind() == reflect.Ptr && nv.IsNil() || !nv.IsValid() {
+		nv = reflect.Value{}
+	}
+	if !ov.IsValid() && !nv.IsValid() {
+		return nil
+	}
+	if !ov.IsValid() {
+		ov = reflect.New(nv.Type().Elem())
+	} else if !nv.IsValid() {
+		nv = reflect.New(ov.Type().Elem())
+	}
+	ov, nv = ov.Elem(), nv.Elem()
+	if ov.Kind() != reflect.Struct || ov.Type() != nv.Type() {
+		return nil
+	}
+
+	var out []FieldChange
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || StringInSlice(f.Name, ignoredFields) {
+			continue
+		}
+		a, b := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+		change := FieldChange{Field: name, Old: formatField(ov.Field(i)), New: formatField(nv.Field(i))}
+		if StringInSlice(name, redactedFields) {
+			change.Old, change.New = redact(change.Old), redact(change.New)
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+// formatField renders the value of a field for display. Multi-line strings,
+// such as VCL, are summarized by their length and digest.
+func formatField(v reflect.Value) string {
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	if m, ok := p.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil && len(text) > 0 {
+			return string(text)
+		}
+	}
+	if s, ok := p.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.Kind() == reflect.String {
+		str := v.String()
+		if lines := strings.Count(strings.TrimSuffix(str, "\n"), "\n") + 1; lines > 1 {
+			sum := sha256.Sum256([]byte(str))
+			return fmt.Sprintf("(%d lines, sha256 %s)", lines, hex.EncodeToString(sum[:6]))
+		}
+		return fmt.Sprintf("%q", str)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func redact(value string) string {
+	if value == `""` {
+		return value
+	}
+	return "(redacted)"
+}
+
+// ChangeLog lists the changes made to a draft version, in the order they were
+// made.
+type ChangeLog []Change
+
+// Summary counts the changes by kind and action, returning a string such as
+// "2 backends changed, 1 header added".
+func (l ChangeLog) Summary() string {
+	type key struct {
+		kind   string
+		action ChangeAction
+	}
+	var order []key
+	counts := make(map[key]int)
+	for _, change := range l {
+		k := key{change.Kind, change.Action}
+		if counts[k] == 0 {
+			order = append(order, k)
+		}
+		counts[k]++
+	}
+
+	var parts []string
+	for _, k := range order {
+		parts = append(parts, fmt.Sprintf("%d %s %s", counts[k], pluralKind(k.kind, counts[k]), k.action))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// pluralKind returns the name of a kind of resource for a count of n.
+func pluralKind(kind string, n int) string {
+	if n == 1 {
+		return kind
+	}
+	if strings.HasSuffix(kind, "y") {
+		return strings.TrimSuffix(kind, "y") + "ies"
+	} else if !strings.HasSuffix(kind, "s") {
+		return kind + "s"
+	}
+	return kind
+}
+
+// Print writes the summary of the change log followed by a line per change.
+func (l ChangeLog) Print(serviceName string) {
+	l.print(serviceName, false)
+}
+
+// PrintFields writes the change log as Print does, adding the old and new
+// values of the fields of each change beneath it.
+func (l ChangeLog) PrintFields(serviceName string) {
+	l.print(serviceName, true)
+}
+
+func (l ChangeLog) print(serviceName string, fields bool) {
+	if len(l) == 0 {
+		return
+	}
+	fmt.Printf("Changes to %s: %s\n", serviceName, l.Summary())
+	for _, change := range l {
+		line := strings.TrimSpace(fmt.Sprintf("%s %s %s", changeSymbols[change.Action], change.Kind, change.Name))
+		if change.Effective != "" {
+			line += " (" + change.Effective + ")"
+		}
+		fmt.Println("  " + line)
+		if !fields {
+			continue
+		}
+		for _, f := range change.Fields {
+			if change.Action == ChangeAdded {
+				fmt.Printf("      %s: %s\n", f.Field, f.New)
+			} else {
+				fmt.Printf("      %s: %s -> %s\n", f.Field, f.Old, f.New)
+			}
+		}
+	}
+}
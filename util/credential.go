@@ -0,0 +1,66 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// credentialHelperPrefix is prepended to the names of credential helpers
+// which aren't given as a full command, so that 'osxkeychain' runs
+// 'fastlyctl-credential-osxkeychain'.
+const credentialHelperPrefix = "fastlyctl-credential-"
+
+// credentialHelperCommand returns the command run for a credential helper.
+// A bare name such as 'osxkeychain' refers to a fastlyctl-credential-NAME
+// executable in PATH, while anything else is run by the shell as given.
+func credentialHelperCommand(helper string) *exec.Cmd {
+	if !strings.ContainsAny(helper, " /\\") {
+		return exec.Command(credentialHelperPrefix+helper, "get")
+	}
+	return exec.Command("sh", "-c", helper+" get")
+}
+
+// RunCredentialHelper fetches a Fastly API key from an external credential
+// helper. The helper is run with the argument 'get', and must print the key
+// on the first line of its output. Its stderr is passed through, so that it
+// may prompt for a passphrase.
+func RunCredentialHelper(helper string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := credentialHelperCommand(helper)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error running credential helper %s: %s", helper, err)
+	}
+	key := strings.TrimSpace(strings.SplitN(stdout.String(), "\n", 2)[0])
+	if key == "" {
+		return "", fmt.Errorf("Credential helper %s returned an empty key.", helper)
+	}
+	return key, nil
+}
+
+// GetCredentialHelper returns the default credential helper, read from the
+// 'credential_helper' file in CWD.
+func GetCredentialHelper() string {
+	return readDefaultFile("credential_helper")
+}
+
+// resolveCredentialHelper sets the global Fastly API key from the
+// credential helper when no key was given by other means.
+func resolveCredentialHelper(c *cli.Context) error {
+	helper := c.GlobalString("credential-helper")
+	if c.GlobalString("fastly-key") != "" || helper == "" {
+		return nil
+	}
+	key, err := RunCredentialHelper(helper)
+	if err != nil {
+		return err
+	}
+	return c.GlobalSet("fastly-key", key)
+}
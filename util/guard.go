@@ -0,0 +1,74 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeletionGuard requires a typed confirmation before an operation deletes
+// more than MaxDeletions versioned resources, or more than MaxPercent of the
+// contents of a dictionary or ACL. Pushing the wrong config file would
+// otherwise quietly remove everything it doesn't mention. The confirmation is
+// required even with --assume-yes.
+type DeletionGuard struct {
+	MaxDeletions int
+	MaxPercent   float64
+
+	// Allow skips the guard, as with --allow-destructive.
+	Allow bool
+}
+
+// CheckChanges guards the resources removed from a service by changes.
+func (g DeletionGuard) CheckChanges(service string, changes ChangeLog) error {
+	var removals ChangeLog
+	for _, change := range changes {
+		if change.Action == ChangeRemoved {
+			removals = append(removals, change)
+		}
+	}
+	if g.Allow || len(removals) <= g.MaxDeletions {
+		return nil
+	}
+
+	phrase := fmt.Sprintf("delete %d resources", len(removals))
+	kind := removals[0].Kind
+	for _, change := range removals {
+		if change.Kind != kind {
+			kind = ""
+			break
+		}
+	}
+	if kind != "" {
+		phrase = fmt.Sprintf("delete %d %s", len(removals), pluralKind(kind, len(removals)))
+	}
+	return confirmDeletion(fmt.Sprintf("This would remove more than %d resources from service %s: %s.", g.MaxDeletions, service, removals.Summary()), phrase)
+}
+
+// CheckContents guards the deletions plan would make to a dictionary or ACL,
+// described by target, holding existing items or entries, named by noun.
+func (g DeletionGuard) CheckContents(target, noun string, plan BatchPlan, existing int) error {
+	deletions := len(plan.Delete)
+	if g.Allow || deletions == 0 || existing == 0 {
+		return nil
+	}
+	percent := 100 * float64(deletions) / float64(existing)
+	if percent <= g.MaxPercent {
+		return nil
+	}
+	return confirmDeletion(fmt.Sprintf("This would delete %d of the %d %s in %s (%.0f%%).", deletions, existing, noun, target, percent), fmt.Sprintf("delete %d %s", deletions, noun))
+}
+
+func confirmDeletion(warning, phrase string) error {
+	fmt.Printf("Warning: %s\n", warning)
+	if !IsInteractive() {
+		return fmt.Errorf("Refusing to %s without confirmation. Pass --allow-destructive to proceed.", phrase)
+	}
+	answer, err := Ask(fmt.Sprintf("Type '%s' to proceed", phrase), "")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(answer) != phrase {
+		return fmt.Errorf("Confirmation did not match '%s'. Not proceeding.", phrase)
+	}
+	return nil
+}
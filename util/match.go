@@ -0,0 +1,129 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alienth/go-fastly"
+)
+
+// maxSimilarServices bounds how many close matches are offered for a service
+// name which wasn't found.
+const maxSimilarServices = 10
+
+// similarServices returns the services whose names resemble name, closest
+// first. Names which match ignoring case come first, then those containing
+// or contained in name, then those within a small edit distance of it.
+func similarServices(services []*fastly.Service, name string) []*fastly.Service {
+	type match struct {
+		service        *fastly.Service
+		rank, distance int
+	}
+	query := strings.ToLower(name)
+	threshold := len(query) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+	var matches []match
+	for _, s := range services {
+		candidate := strings.ToLower(s.Name)
+		d := EditDistance(query, candidate)
+		switch {
+		case candidate == query:
+			matches = append(matches, match{s, 0, d})
+		case strings.Contains(candidate, query) || strings.Contains(query, candidate):
+			matches = append(matches, match{s, 1, d})
+		case d <= threshold:
+			matches = append(matches, match{s, 2, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].service.Name < matches[j].service.Name
+	})
+	if len(matches) > maxSimilarServices {
+		matches = matches[:maxSimilarServices]
+	}
+	similar := make([]*fastly.Service, len(matches))
+	for i, m := range matches {
+		similar[i] = m.service
+	}
+	return similar
+}
+
+// chooseSimilarService is used when no service is named name. It offers the
+// services with similar names to choose from, or, if there's no terminal to
+// prompt on, fails with them as suggestions.
+func chooseSimilarService(client *fastly.Client, name string) (*fastly.Service, error) {
+	services, _, err := client.Service.List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Service %s not found.", name)
+	}
+	similar := similarServices(services, name)
+	if len(similar) == 0 {
+		return nil, fmt.Errorf("Service %s not found.", name)
+	}
+	var names []string
+	for _, s := range similar {
+		names = append(names, s.Name)
+	}
+	if !IsInteractive() {
+		return nil, fmt.Errorf("Service %s not found. Did you mean one of: %s?", name, strings.Join(names, ", "))
+	}
+
+	fmt.Printf("Service %s not found. Similarly named services:\n\n", name)
+	for i, n := range names {
+		fmt.Printf("  %d) %s\n", i+1, n)
+	}
+	fmt.Println()
+	answer, err := Ask(fmt.Sprintf("Choose a service [1-%d], or press enter to cancel", len(names)), "")
+	if err != nil {
+		return nil, err
+	}
+	if answer == "" {
+		return nil, fmt.Errorf("Service %s not found.", name)
+	}
+	i, err := strconv.Atoi(answer)
+	if err != nil || i < 1 || i > len(similar) {
+		return nil, fmt.Errorf("Invalid choice %q.", answer)
+	}
+	return similar[i-1], nil
+}
+
+// EditDistance returns the Levenshtein distance between a and b.
+func EditDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
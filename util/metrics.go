@@ -0,0 +1,133 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric is a family of samples exposed in the Prometheus text format.
+type metric struct {
+	name, help, kind string
+	labels           []string
+	samples          map[string]float64
+}
+
+var (
+	metricsMu sync.Mutex
+
+	apiRequests        = newMetric("fastlyctl_api_requests_total", "Fastly API requests made, by method and response status.", "counter", "method", "status")
+	rateLimitRemaining = newMetric("fastlyctl_ratelimit_remaining", "Fastly API rate limit remaining as of the last response.", "gauge")
+	syncDuration       = newMetric("fastlyctl_sync_duration_seconds", "Duration of the last sync of each service.", "gauge", "service")
+	changesApplied     = newMetric("fastlyctl_changes_applied_total", "Changes applied, by service.", "counter", "service")
+	operationErrors    = newMetric("fastlyctl_errors_total", "Failed operations, by command.", "counter", "command")
+
+	allMetrics = []*metric{apiRequests, rateLimitRemaining, syncDuration, changesApplied, operationErrors}
+)
+
+func newMetric(name, help, kind string, labels ...string) *metric {
+	return &metric{name: name, help: help, kind: kind, labels: labels, samples: make(map[string]float64)}
+}
+
+func (m *metric) key(values []string) string {
+	var pairs []string
+	for i, label := range m.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", label, strconv.Quote(values[i])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *metric) add(delta float64, values ...string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	m.samples[m.key(values)] += delta
+}
+
+func (m *metric) set(value float64, values ...string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	m.samples[m.key(values)] = value
+}
+
+// CountAPIRequest records a request made to the Fastly API.
+func CountAPIRequest(method string, status int) {
+	apiRequests.add(1, method, strconv.Itoa(status))
+}
+
+// SetRateLimitRemaining records the rate limit reported by the API.
+func SetRateLimitRemaining(remaining int) {
+	rateLimitRemaining.set(float64(remaining))
+}
+
+// ObserveSync records how long syncing a service took.
+func ObserveSync(service string, d time.Duration) {
+	syncDuration.set(d.Seconds(), service)
+}
+
+// CountChangesApplied records changes applied to a service.
+func CountChangesApplied(service string, n int) {
+	changesApplied.add(float64(n), service)
+}
+
+// CountError records a failed operation.
+func CountError(command string) {
+	operationErrors.add(1, command)
+}
+
+func writeMetrics(w http.ResponseWriter, r *http.Request) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range allMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		var keys []string
+		for k := range m.samples {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k == "" {
+				fmt.Fprintf(w, "%s %g\n", m.name, m.samples[k])
+			} else {
+				fmt.Fprintf(w, "%s{%s} %g\n", m.name, k, m.samples[k])
+			}
+		}
+	}
+}
+
+// ServeMetrics exposes metrics for Prometheus at /metrics on addr, in the
+// background, for as long as the process runs.
+func ServeMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Error listening for metrics on %s: %s", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", writeMetrics)
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// metricsTransport counts the requests made through it, and records the rate
+// limit reported in their responses.
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		CountAPIRequest(req.Method, 0)
+		return resp, err
+	}
+	CountAPIRequest(req.Method, resp.StatusCode)
+	if remaining, err := strconv.Atoi(resp.Header.Get("Fastly-RateLimit-Remaining")); err == nil {
+		SetRateLimitRemaining(remaining)
+	}
+	return resp, nil
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// Progress draws a single, continually rewritten status line with a spinner
+// on stderr. A nil *Progress is valid, and draws nothing.
+type Progress struct {
+	mu      sync.Mutex
+	status  string
+	frame   int
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewProgress returns a Progress if stderr is a terminal and we don't appear
+// to be running under CI, or nil otherwise.
+func NewProgress() *Progress {
+	if !terminal.IsTerminal(int(os.Stderr.Fd())) || os.Getenv("CI") != "" {
+		return nil
+	}
+	return &Progress{}
+}
+
+// Update replaces the status line, starting the spinner if it isn't running.
+func (p *Progress) Update(status string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+		p.stopped = make(chan struct{})
+		go p.spin(p.stop, p.stopped)
+	}
+	p.draw()
+}
+
+// Clear stops the spinner and erases the status line, so that other output
+// may be written. A later Update redraws it.
+func (p *Progress) Clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	stop, stopped := p.stop, p.stopped
+	p.stop, p.stopped = nil, nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-stopped
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
+
+func (p *Progress) spin(stop, stopped chan struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.frame = (p.frame + 1) % len(spinnerFrames)
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// draw must be called with p.mu held.
+func (p *Progress) draw() {
+	fmt.Fprintf(os.Stderr, "\r\033[K%s %s", spinnerFrames[p.frame], p.status)
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrPromptTimeout is returned by prompts left unanswered for PromptTimeout.
+var ErrPromptTimeout = errors.New("Timed out waiting for an answer. Aborting.")
+
+// PromptInput is where answers to prompts are read from.
+var PromptInput io.Reader = os.Stdin
+
+// PromptTimeout bounds how long a prompt waits for an answer. Zero waits
+// forever.
+var PromptTimeout time.Duration
+
+// readLine reads a line from r a byte at a time rather than through a
+// buffered reader, so that input intended for later prompts isn't consumed.
+// io.EOF is returned only if r is closed before anything is read.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(line)), nil
+}
+
+// readAnswer reads an answer from PromptInput, giving up after
+// PromptTimeout.
+func readAnswer() (string, error) {
+	if PromptTimeout <= 0 {
+		return readLine(PromptInput)
+	}
+	type result struct {
+		answer string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		answer, err := readLine(PromptInput)
+		done <- result{answer, err}
+	}()
+	select {
+	case r := <-done:
+		return r.answer, r.err
+	case <-time.After(PromptTimeout):
+		fmt.Println()
+		return "", ErrPromptTimeout
+	}
+}
+
+// Prompt asks a yes or no question, repeating it until answered. Closed
+// input is taken as "no".
+func Prompt(question string) (bool, error) {
+	return prompt(question, "y/n", nil)
+}
+
+// PromptDefault asks a yes or no question, taking an empty answer as def.
+func PromptDefault(question string, def bool) (bool, error) {
+	if def {
+		return prompt(question, "Y/n", &def)
+	}
+	return prompt(question, "y/N", &def)
+}
+
+func prompt(question, choices string, def *bool) (bool, error) {
+	for {
+		fmt.Printf("%s (%s): ", question, choices)
+		input, err := readAnswer()
+		if err == io.EOF {
+			fmt.Println()
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(input) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		case "":
+			if def != nil {
+				return *def, nil
+			}
+		default:
+			fmt.Printf("Invalid input: %s\n", input)
+		}
+	}
+}
+
+// Ask prompts for a line of input, returning def if the answer is empty.
+func Ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := readAnswer()
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
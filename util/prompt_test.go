@@ -0,0 +1,110 @@
+package util
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withInput answers prompts from r for the rest of the test.
+func withInput(t *testing.T, r io.Reader) {
+	t.Helper()
+	old := PromptInput
+	PromptInput = r
+	t.Cleanup(func() { PromptInput = old })
+}
+
+func TestPrompt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		def   *bool
+		want  bool
+		// rest is the input expected to be left for later prompts.
+		rest string
+	}{
+		{name: "yes", input: "y\n", want: true},
+		{name: "no", input: "no\n", want: false},
+		{name: "case insensitive", input: "YES\n", want: true},
+		{name: "default yes on empty answer", input: "\n", def: newBool(true), want: true},
+		{name: "default no on empty answer", input: "\n", def: newBool(false), want: false},
+		{name: "empty answer without default re-prompts", input: "\ny\n", want: true},
+		{name: "invalid answer re-prompts", input: "maybe\nyes\n", want: true},
+		{name: "EOF before any input", input: "", want: false},
+		{name: "EOF before any input with default", input: "", def: newBool(true), want: false},
+		{name: "EOF after partial line", input: "y", want: true},
+		{name: "later input is left unread", input: "y\nn\n", want: true, rest: "n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := strings.NewReader(test.input)
+			withInput(t, input)
+			var got bool
+			var err error
+			if test.def != nil {
+				got, err = PromptDefault("Continue?", *test.def)
+			} else {
+				got, err = Prompt("Continue?")
+			}
+			if err != nil {
+				t.Fatalf("Prompt returned error: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("Prompt = %t, want %t", got, test.want)
+			}
+			if rest := test.input[len(test.input)-input.Len():]; rest != test.rest {
+				t.Errorf("Left %q unread, want %q", rest, test.rest)
+			}
+		})
+	}
+}
+
+func newBool(b bool) *bool {
+	return &b
+}
+
+func TestAsk(t *testing.T) {
+	tests := []struct {
+		name, input, def, want string
+	}{
+		{name: "answer", input: "alice\n", want: "alice"},
+		{name: "answer is trimmed", input: "  alice \r\n", want: "alice"},
+		{name: "default on empty answer", input: "\n", def: "bob", want: "bob"},
+		{name: "answer overrides default", input: "alice\n", def: "bob", want: "alice"},
+		{name: "EOF after partial line", input: "alice", want: "alice"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withInput(t, strings.NewReader(test.input))
+			got, err := Ask("Name", test.def)
+			if err != nil {
+				t.Fatalf("Ask returned error: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("Ask = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	withInput(t, strings.NewReader(""))
+	if _, err := Ask("Name", "bob"); err != io.EOF {
+		t.Errorf("Ask on closed input returned %v, want io.EOF", err)
+	}
+}
+
+func TestPromptTimeout(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	withInput(t, r)
+	old := PromptTimeout
+	PromptTimeout = 10 * time.Millisecond
+	defer func() { PromptTimeout = old }()
+
+	if _, err := Prompt("Continue?"); err != ErrPromptTimeout {
+		t.Errorf("Prompt returned %v, want ErrPromptTimeout", err)
+	}
+	if _, err := Ask("Name", ""); err != ErrPromptTimeout {
+		t.Errorf("Ask returned %v, want ErrPromptTimeout", err)
+	}
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alienth/go-fastly"
+)
+
+// RateLimitStatus is the write quota of an API key as last reported by
+// Fastly. Fastly only reports it in response to writes, so it is recorded for
+// commands which don't make any.
+type RateLimitStatus struct {
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Seen      time.Time `json:"seen"`
+}
+
+// rateLimitFile is keyed by a fingerprint of each API key, so as not to
+// store the keys themselves.
+func rateLimitFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fastlyctl", "ratelimit.json"), nil
+}
+
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+func readRateLimits(file string) (map[string]RateLimitStatus, error) {
+	statuses := make(map[string]RateLimitStatus)
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return statuses, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %s", file, err)
+	}
+	return statuses, nil
+}
+
+// RecordRateLimit stores the write quota which client, using key, has seen
+// reported, if any.
+func RecordRateLimit(key string, client *fastly.Client) error {
+	rate := client.RateLimit()
+	if rate == nil {
+		return nil
+	}
+	file, err := rateLimitFile()
+	if err != nil {
+		return err
+	}
+	statuses, err := readRateLimits(file)
+	if err != nil {
+		return err
+	}
+	statuses[keyFingerprint(key)] = RateLimitStatus{Remaining: rate.Remaining, Reset: rate.Reset, Seen: time.Now()}
+	body, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, body, 0600)
+}
+
+// LastRateLimit returns the write quota last recorded for key, or nil if none
+// has been.
+func LastRateLimit(key string) (*RateLimitStatus, error) {
+	file, err := rateLimitFile()
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := readRateLimits(file)
+	if err != nil {
+		return nil, err
+	}
+	status, ok := statuses[keyFingerprint(key)]
+	if !ok {
+		return nil, nil
+	}
+	return &status, nil
+}
+
+// WarnRateLimit prints a banner if client has fewer than threshold writes
+// left before its quota resets, returning whether it did.
+func WarnRateLimit(client *fastly.Client, threshold int) bool {
+	rate := client.RateLimit()
+	if rate == nil || rate.Remaining >= threshold || !time.Now().Before(rate.Reset) {
+		return false
+	}
+	fmt.Printf("\n!!! Only %d API writes remain until the rate limit resets at %s. Writes will fail once they run out.\n\n", rate.Remaining, rate.Reset.Format(time.RFC3339))
+	return true
+}
@@ -0,0 +1,209 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exchange is a request made to the Fastly API and the response to it, with
+// secrets redacted.
+type Exchange struct {
+	Time           time.Time
+	Method         string
+	URL            string
+	RequestHeader  http.Header `json:",omitempty"`
+	RequestBody    string      `json:",omitempty"`
+	Status         int         `json:",omitempty"`
+	ResponseHeader http.Header `json:",omitempty"`
+	ResponseBody   string      `json:",omitempty"`
+	// Error is set instead of the response if none was received.
+	Error string `json:",omitempty"`
+}
+
+// Session is the exchanges recorded by a single run of fastlyctl.
+type Session struct {
+	Recorded  time.Time
+	Args      []string
+	Exchanges []Exchange
+}
+
+// redactedHeaders are the headers whose values are secrets.
+var redactedHeaders = []string{"Fastly-Key", "Authorization", "Cookie", "Set-Cookie"}
+
+var (
+	recordMu  sync.Mutex
+	recording *Session
+	recordTo  string
+)
+
+// StartRecording records every request made by clients from the default
+// ClientFactory, to be written to file by StopRecording.
+func StartRecording(file string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	recording = &Session{Recorded: time.Now().UTC(), Args: redactArgs(os.Args)}
+	recordTo = file
+}
+
+// StopRecording writes the recorded session, if any, and stops recording.
+func StopRecording() error {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if recording == nil {
+		return nil
+	}
+	session, file := recording, recordTo
+	recording = nil
+	body, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(body, '\n'), 0600)
+}
+
+// ReadSession reads a session written by StopRecording.
+func ReadSession(file string) (*Session, error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	session := new(Session)
+	if err := json.Unmarshal(body, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// recordTransport adds the requests made through it to the recording, if
+// one has been started.
+type recordTransport struct {
+	base http.RoundTripper
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordMu.Lock()
+	active := recording != nil
+	recordMu.Unlock()
+	if !active {
+		return t.base.RoundTrip(req)
+	}
+
+	e := Exchange{Time: time.Now().UTC(), Method: req.Method, URL: req.URL.String(), RequestHeader: redactHeader(req.Header)}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		e.RequestBody = redactBody(req.Header.Get("Content-Type"), body)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		e.Status = resp.StatusCode
+		e.ResponseHeader = redactHeader(resp.Header)
+		e.ResponseBody = redactBody(resp.Header.Get("Content-Type"), body)
+	}
+
+	recordMu.Lock()
+	if recording != nil {
+		recording.Exchanges = append(recording.Exchanges, e)
+	}
+	recordMu.Unlock()
+	return resp, err
+}
+
+// redactArgs replaces the API key in command line arguments.
+func redactArgs(args []string) []string {
+	out := append([]string(nil), args...)
+	for i, arg := range out {
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case arg == name:
+		case name == "K" || name == "fastly-key":
+			if i+1 < len(out) && out[i+1] != "-" {
+				out[i+1] = "(redacted)"
+			}
+		case strings.HasPrefix(name, "K=") || strings.HasPrefix(name, "fastly-key="):
+			out[i] = arg[:strings.Index(arg, "=")+1] + "(redacted)"
+		}
+	}
+	return out
+}
+
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range redactedHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, "(redacted)")
+		}
+	}
+	return out
+}
+
+// redactBody replaces the values of redactedFields in a JSON or form body.
+func redactBody(contentType string, body []byte) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		for _, field := range redactedFields {
+			if values.Get(field) != "" {
+				values.Set(field, "(redacted)")
+			}
+		}
+		return values.Encode()
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return string(body)
+	}
+	if !redactJSON(doc) {
+		return string(body)
+	}
+	redacted, err := json.Marshal(doc)
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+// redactJSON redacts the secrets in a decoded JSON document in place,
+// reporting whether there were any.
+func redactJSON(doc interface{}) bool {
+	var found bool
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range doc {
+			if s, ok := value.(string); ok && s != "" && StringInSlice(key, redactedFields) {
+				doc[key] = "(redacted)"
+				found = true
+			} else if redactJSON(value) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, value := range doc {
+			if redactJSON(value) {
+				found = true
+			}
+		}
+	}
+	return found
+}
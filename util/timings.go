@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alienth/go-fastly"
+)
+
+// timings collects how the requests of a command went, through the hooks of
+// clients from the default ClientFactory while Timings is set.
+var timings struct {
+	mu        sync.Mutex
+	durations []time.Duration
+	failed    int
+	retries   int
+	limited   int
+	// unsettled holds the requests whose last attempt failed, or was
+	// refused by the rate limit, so that repeating them counts as a retry.
+	unsettled map[string]bool
+}
+
+func requestKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+func timeRequest(req *http.Request, resp *http.Response, d time.Duration, err error) {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	if timings.unsettled == nil {
+		timings.unsettled = make(map[string]bool)
+	}
+	key := requestKey(req)
+	if timings.unsettled[key] {
+		timings.retries++
+	}
+	timings.durations = append(timings.durations, d)
+	failed := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	if failed {
+		timings.failed++
+	}
+	timings.unsettled[key] = failed
+}
+
+func countRateLimited(req *http.Request, rate fastly.Rate) {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	if timings.unsettled == nil {
+		timings.unsettled = make(map[string]bool)
+	}
+	timings.limited++
+	timings.unsettled[requestKey(req)] = true
+}
+
+// Timings makes clients from the default ClientFactory collect the summary
+// written by PrintTimings. It is off by default, as every request made is
+// kept until the command ends.
+var Timings bool
+
+// timingHooks are installed in clients from the default ClientFactory while
+// Timings is set.
+var timingHooks = fastly.Hooks{Request: timeRequest, RateLimited: countRateLimited}
+
+// PrintTimings writes a summary of the API requests made so far to w: how
+// many were made, their total and 95th percentile latency, how many were
+// retries, and how many the client didn't send because the rate limit was
+// exhausted until it resets.
+func PrintTimings(w io.Writer) {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	durations := append([]time.Duration(nil), timings.durations...)
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total, p95 time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	if len(durations) > 0 {
+		// The nearest-rank percentile, so that small samples report a
+		// latency which was actually seen.
+		p95 = durations[(len(durations)*95+99)/100-1]
+	}
+	fmt.Fprintf(w, "API calls: %d (%d failed), total %s, p95 %s, %d retries, %d not sent as the rate limit was exhausted\n",
+		len(durations), timings.failed, total.Round(time.Millisecond), p95.Round(time.Millisecond), timings.retries, timings.limited)
+}
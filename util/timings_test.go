@@ -0,0 +1,36 @@
+package util
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alienth/go-fastly/fastlytest"
+)
+
+func TestClientFactoryTimings(t *testing.T) {
+	srv := fastlytest.NewServer()
+	defer srv.Close()
+	oldEndpoint, oldTimings := APIEndpoint, Timings
+	defer func() { APIEndpoint, Timings = oldEndpoint, oldTimings }()
+	APIEndpoint, _ = url.Parse(srv.URL + "/")
+
+	for _, enabled := range []bool{false, true} {
+		timings.durations, timings.failed, timings.retries, timings.limited, timings.unsettled = nil, 0, 0, 0, nil
+		Timings = enabled
+		client := ClientFactory("key")
+		if _, _, err := client.Service.List(nil); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		PrintTimings(&buf)
+		want := "API calls: 0 "
+		if enabled {
+			want = "API calls: 1 "
+		}
+		if !strings.HasPrefix(buf.String(), want) {
+			t.Errorf("With Timings %t, printed %q, want it to begin %q", enabled, buf.String(), want)
+		}
+	}
+}
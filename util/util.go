@@ -3,33 +3,135 @@ package util
 import (
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alienth/go-fastly"
 	"github.com/pmezard/go-difflib/difflib"
 	"github.com/urfave/cli"
 )
 
-var ErrNonInteractive = errors.New("In non-interactive shell and --assume-yes not used.")
+var ErrNonInteractive = errors.New("In non-interactive shell and neither --assume-yes nor --auto-approve used.")
 
+// WriteOnlyError returns the error reported when attempting to read the items
+// of a write-only dictionary.
+func WriteOnlyError(serviceName string, d *fastly.Dictionary) error {
+	return fmt.Errorf("Dictionary %s on service %s is write-only. Its items cannot be read through the API.", d.Name, serviceName)
+}
+
+// lookupCacheTTL bounds how long service, dictionary and ACL lookups are
+// reused, so that long-running commands notice changes made elsewhere.
+const lookupCacheTTL = 30 * time.Second
+
+// ClientFactory constructs the Fastly API client used by commands. Tests may
+// replace it to direct requests at a fake API, such as the one provided by
+// go-fastly's fastlytest package.
+var ClientFactory = func(key string) *fastly.Client {
+	client := fastly.NewClient(&http.Client{Transport: metricsTransport{recordTransport{http.DefaultTransport}}}, key)
+	client.EnableCache(lookupCacheTTL)
+	client.EnableResponseCache(0)
+	client.ReadOnly = ReadOnly
+	if Timings {
+		client.Hooks = timingHooks
+	}
+	if APIEndpoint != nil {
+		client.BaseURL = APIEndpoint
+	}
+	if UserAgentSuffix != "" {
+		client.UserAgent += " " + UserAgentSuffix
+	}
+	return client
+}
+
+// ReadOnly makes clients from the default ClientFactory refuse requests which
+// would modify anything in Fastly.
+var ReadOnly bool
+
+// APIEndpoint, if set, is the base URL of the API for clients from the
+// default ClientFactory, such as that of a proxy in front of it.
+var APIEndpoint *url.URL
+
+// UserAgentSuffix is appended to the user agent of clients from the default
+// ClientFactory, so that their requests can be told apart in Fastly's logs.
+var UserAgentSuffix string
+
+// ParseAPIEndpoint parses the base URL given for the API. Request paths are
+// resolved against it, so it is given a trailing slash if it lacks one.
+func ParseAPIEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid API endpoint %q. Must be an http or https URL.", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+// NewClient returns a Fastly API client using the key given to the app.
+func NewClient(c *cli.Context) *fastly.Client {
+	return ClientFactory(c.GlobalString("fastly-key"))
+}
+
+// GetServiceByName looks up a service by name. The service returned has its
+// active version in Version, as Search alone doesn't reliably include it. If
+// there is no such service, services with similar names are offered instead.
 func GetServiceByName(client *fastly.Client, name string) (*fastly.Service, error) {
-	var service *fastly.Service
-	service, _, err := client.Service.Search(name)
+	service, resp, err := client.Service.Search(name)
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+		if service, err = chooseSimilarService(client, name); err != nil {
+			return nil, err
+		}
+	}
+	details, _, err := client.Service.Details(service.ID)
 	if err != nil {
 		return nil, err
 	}
-	return service, nil
+	return details.Service(), nil
+}
+
+// ServiceIDsOnly restricts GetServiceByNameOrID to looking services up by ID.
+var ServiceIDsOnly bool
+
+// serviceIDPattern matches strings which could be a service ID.
+var serviceIDPattern = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// GetServiceByNameOrID looks up a service by ID, falling back to looking it
+// up by name. Exact ID matches are preferred, as they are unaffected by
+// renames or ambiguous names.
+func GetServiceByNameOrID(client *fastly.Client, nameOrID string) (*fastly.Service, error) {
+	if !serviceIDPattern.MatchString(nameOrID) {
+		if ServiceIDsOnly {
+			return nil, fmt.Errorf("%q is not a service ID.", nameOrID)
+		}
+		return GetServiceByName(client, nameOrID)
+	}
+	details, _, err := client.Service.Details(nameOrID)
+	if err == nil {
+		return details.Service(), nil
+	}
+	if ServiceIDsOnly {
+		return nil, fmt.Errorf("Error fetching service %s: %s", nameOrID, err)
+	}
+	return GetServiceByName(client, nameOrID)
 }
 
 func GetDictionaryByName(client *fastly.Client, serviceName, dictName string) (*fastly.Dictionary, error) {
 	var err error
-	service, err := GetServiceByName(client, serviceName)
+	service, err := GetServiceByNameOrID(client, serviceName)
 	if err != nil {
 		return nil, err
 	}
@@ -65,58 +167,97 @@ func GetActiveVersion(service *fastly.Service) (uint, error) {
 	return 0, fmt.Errorf("Unable to find the active version for service %s", service.Name)
 }
 
-func Prompt(question string) (bool, error) {
-	var input string
-	for {
-		fmt.Printf("%s (y/n): ", question)
-		if _, err := fmt.Scanln(&input); err != nil {
-			return false, err
-		}
-		if input == "y" {
-			return true, nil
-		} else if input == "n" {
-			return false, nil
-		} else {
-			fmt.Printf("Invalid input: %s", input)
-		}
-	}
-}
-
 func CountChanges(diff *string) (int, int) {
 	removals := regexp.MustCompile(`(^|\n)\-`)
 	additions := regexp.MustCompile(`(^|\n)\+`)
 	return len(additions.FindAllString(*diff, -1)), len(removals.FindAllString(*diff, -1))
 }
 
-func ActivateVersion(c *cli.Context, client *fastly.Client, s *fastly.Service, v *fastly.Version) error {
+// DiffMode selects how ActivateVersion shows the changes in a version: as
+// Fastly's diff of the generated VCL, as the old and new values of the fields
+// of each changed resource, or both.
+type DiffMode string
+
+const (
+	DiffModeVCL      DiffMode = "vcl"
+	DiffModeSemantic DiffMode = "semantic"
+	DiffModeBoth     DiffMode = "both"
+)
+
+// ParseDiffMode parses the value of --diff-mode, which defaults to vcl.
+func ParseDiffMode(mode string) (DiffMode, error) {
+	switch DiffMode(mode) {
+	case "", DiffModeVCL:
+		return DiffModeVCL, nil
+	case DiffModeSemantic, DiffModeBoth:
+		return DiffMode(mode), nil
+	}
+	return "", fmt.Errorf("Unknown diff mode %s. Use vcl, semantic or both.", mode)
+}
+
+// ActivateVersion shows the changes in a version and prompts for its
+// activation, returning whether the version was activated. changes describes
+// the changes made by the caller, and may be nil if they aren't known, in
+// which case Fastly's diff is shown whatever the --diff-mode. --skip-diff
+// leaves Fastly's diff out, as does --assume-yes unless --auto-approve is
+// also given.
+func ActivateVersion(c *cli.Context, client *fastly.Client, s *fastly.Service, v *fastly.Version, changes ChangeLog) (bool, error) {
 	activeVersion, err := GetActiveVersion(s)
 	if err != nil {
-		return err
+		return false, err
 	}
-	assumeYes := c.GlobalBool("assume-yes")
-	diff, err := GetUnifiedDiff(client, s, activeVersion, v.Number)
+	mode, err := ParseDiffMode(c.String("diff-mode"))
 	if err != nil {
-		return err
+		return false, err
+	}
+	if changes == nil {
+		mode = DiffModeVCL
+	}
+	assumeYes := c.GlobalBool("assume-yes")
+	autoApprove := c.GlobalBool("auto-approve")
+	showDiff := mode != DiffModeSemantic && !c.GlobalBool("skip-diff")
+	var diff string
+	if showDiff {
+		if diff, err = GetUnifiedDiff(client, s, activeVersion, v.Number); err != nil {
+			return false, err
+		}
 	}
 
 	interactive := IsInteractive()
-	if !interactive && !assumeYes {
-		return cli.NewExitError(ErrNonInteractive.Error(), -1)
-	}
 	pager := GetPager()
 
 	fmt.Printf("Diff URL: %s\n", GetDiffUrl(s, activeVersion, v.Number).String())
 
+	if mode == DiffModeVCL {
+		changes.Print(s.Name)
+	} else {
+		changes.PrintFields(s.Name)
+	}
 	additions, removals := CountChanges(&diff)
+
+	// Without a terminal to prompt on, the diff is printed before refusing,
+	// so that the output shows what would have been activated.
+	if !interactive && !assumeYes {
+		if showDiff {
+			fmt.Printf("Diff for %s:\n\n", s.Name)
+			fmt.Println(diff)
+		}
+		return false, cli.NewExitError(ErrNonInteractive.Error(), -1)
+	}
+
 	var proceed bool
-	if !assumeYes {
-		if proceed, err = Prompt(fmt.Sprintf("%d additions and %d removals in diff. View?", additions, removals)); err != nil {
-			return err
+	if !assumeYes && showDiff {
+		if proceed, err = PromptDefault(fmt.Sprintf("%d additions and %d removals in diff. View?", additions, removals), true); err != nil {
+			return false, err
 		}
+	} else if assumeYes && !autoApprove && showDiff {
+		fmt.Printf("%d additions and %d removals in diff.\n", additions, removals)
 	}
 
-	if proceed || assumeYes {
-		if pager != nil && interactive && !assumeYes {
+	// --auto-approve prints the diff in full rather than paging it, so
+	// that it is kept in the output of unattended runs.
+	if (proceed || autoApprove) && showDiff {
+		if pager != nil && interactive && !autoApprove {
 			r, stdin := io.Pipe()
 			pager.Stdin = r
 			pager.Stdout = os.Stdout
@@ -140,39 +281,99 @@ func ActivateVersion(c *cli.Context, client *fastly.Client, s *fastly.Service, v
 	if !c.Bool("noop") {
 		if !assumeYes {
 			if proceed, err = Prompt("Activate version " + strconv.Itoa(int(v.Number)) + " for service " + s.Name + "?"); err != nil {
-				return err
+				return false, err
 			}
 		}
 		if proceed || assumeYes {
 			if _, _, err = client.Version.Activate(s.ID, v.Number); err != nil {
-				return err
+				return false, err
 			}
 			fmt.Printf("Activated version %d for %s. Old version: %d\n", v.Number, s.Name, activeVersion)
+			if c.Bool("wait") {
+				if err = WaitForDeployment(client, s, v.Number, c.Duration("wait-timeout")); err != nil {
+					return true, err
+				}
+			}
+			return true, nil
 		}
 	}
-	return nil
+	return false, nil
+}
+
+// Exit codes returned by push, so that wrapper scripts can branch on the
+// outcome.
+const (
+	ExitNoChanges      = 0
+	ExitError          = 1
+	ExitChangesApplied = 2
+	ExitChangesPending = 3
+)
+
+// ExitValidationWarnings is returned by version validate when a version is
+// valid, but Fastly reported warnings about it.
+const ExitValidationWarnings = 4
+
+// deploymentPollInterval is how often WaitForDeployment checks the status of
+// a version.
+const deploymentPollInterval = 5 * time.Second
+
+// WaitForDeployment polls a version of a service until Fastly reports it as
+// active and deployed, or returns an error once timeout has elapsed.
+func WaitForDeployment(client *fastly.Client, s *fastly.Service, version uint, timeout time.Duration) error {
+	fmt.Printf("Waiting for version %d of %s to deploy...\n", version, s.Name)
+	start := time.Now()
+	for {
+		v, _, err := client.Version.Get(s.ID, version)
+		if err != nil {
+			return fmt.Errorf("Error fetching status of version %d: %s", version, err)
+		}
+		if v.Active && v.Deployed {
+			fmt.Printf("Version %d of %s deployed after %s.\n", version, s.Name, time.Since(start).Round(time.Second))
+			return nil
+		}
+		if time.Since(start) >= timeout {
+			return fmt.Errorf("Timed out after %s waiting for version %d of %s to deploy.", timeout, version, s.Name)
+		}
+		time.Sleep(deploymentPollInterval)
+	}
 }
 
 // validateVersion takes in a service and version number and returns an
 // error if the version is invalid.
 func ValidateVersion(client *fastly.Client, service *fastly.Service, version uint) error {
+	_, err := CheckVersion(client, service, version)
+	return err
+}
+
+// CheckVersion validates a version, printing every warning Fastly reports
+// and returning them. Errors, along with any warnings, are returned as the
+// error.
+func CheckVersion(client *fastly.Client, service *fastly.Service, version uint) ([]string, error) {
 	validationResponse, _, err := client.Version.Validate(service.ID, version)
 	if err != nil {
-		return fmt.Errorf("Error validating version: %s", err)
+		return nil, fmt.Errorf("Error validating version: %s", err)
 	}
 
 	prefix := fmt.Sprintf("Version %d on service %s", version, service.Name)
+	warnings, errors := validationResponse.Problems()
+	var problems []string
+	for _, e := range errors {
+		problems = append(problems, "  error: "+e)
+	}
+	for _, w := range warnings {
+		problems = append(problems, "  warning: "+w)
+	}
 	if validationResponse.Status == "error" {
-		return fmt.Errorf("%s failed to validate:\n%s\n", prefix, validationResponse.Message)
-	} else if len(validationResponse.Warnings) > 0 {
-		fmt.Printf("%s validated with warnings:\n%s\n", prefix, validationResponse.Message)
-		return nil
+		return warnings, fmt.Errorf("%s failed to validate:\n%s", prefix, strings.Join(problems, "\n"))
+	} else if len(warnings) > 0 {
+		fmt.Printf("%s validated with %d warnings:\n%s\n", prefix, len(warnings), strings.Join(problems, "\n"))
+		return warnings, nil
 	} else if validationResponse.Status == "ok" {
 		fmt.Printf("%s successfully validated!\n", prefix)
-		return nil
+		return nil, nil
 	}
 
-	return fmt.Errorf("Unexpected validation response: %+v", validationResponse)
+	return nil, fmt.Errorf("Unexpected validation response: %+v", validationResponse)
 }
 
 // Returns true if two versions of a given service are identical.  Generated
@@ -182,12 +383,12 @@ func ValidateVersion(client *fastly.Client, service *fastly.Service, version uin
 // comparing a version with itself, and then generating a diff between the from
 // and to versions.  If the two diffs are identical, then there is no
 // difference between from and to.
-func VersionsEqual(c *fastly.Client, s *fastly.Service, from, to uint) (bool, error) {
-	noDiff, _, err := c.Diff.Get(s.ID, from, from, "text")
+func VersionsEqual(c DiffAPI, s *fastly.Service, from, to uint) (bool, error) {
+	noDiff, _, err := c.Get(s.ID, from, from, "text")
 	if err != nil {
 		return false, err
 	}
-	diff, _, err := c.Diff.Get(s.ID, from, to, "text")
+	diff, _, err := c.Get(s.ID, from, to, "text")
 	if err != nil {
 		return false, err
 	}
@@ -217,6 +418,26 @@ func GetUnifiedDiff(c *fastly.Client, s *fastly.Service, from, to uint) (string,
 	return unified, nil
 }
 
+// SaveHTMLDiff writes Fastly's HTML diff between two versions of s to a page
+// in dir, for reviewers who don't use a terminal, returning its path.
+func SaveHTMLDiff(c *fastly.Client, s *fastly.Service, from, to uint, dir string) (string, error) {
+	diff, _, err := c.Diff.Get(s.ID, from, to, fastly.DiffFormatHTML)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%d-%d.html", strings.Replace(s.Name, string(filepath.Separator), "_", -1), from, to)
+	file := filepath.Join(dir, name)
+	title := html.EscapeString(fmt.Sprintf("%s: version %d to %d", s.Name, from, to))
+	page := fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n<h1>%s</h1>\n%s\n</body>\n</html>\n", title, title, diff.Diff)
+	if err := ioutil.WriteFile(file, []byte(page), 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
 func StringInSlice(check string, slice []string) bool {
 	for _, element := range slice {
 		if element == check {
@@ -226,34 +447,135 @@ func StringInSlice(check string, slice []string) bool {
 	return false
 }
 
+// GetPager returns the command used to page diffs: $PAGER, which may
+// include arguments, or else the first of pager, less and more found. more
+// is the only one Windows has by default.
 func GetPager() *exec.Cmd {
-	for _, pager := range [3]string{os.Getenv("PAGER"), "pager", "less"} {
+	for _, pager := range [4]string{os.Getenv("PAGER"), "pager", "less", "more"} {
+		args := strings.Fields(pager)
+		if len(args) == 0 {
+			continue
+		}
 		// we expect some NotFounds, so ignore errors
-		path, _ := exec.LookPath(pager)
+		path, _ := exec.LookPath(args[0])
 		if path != "" {
-			return exec.Command(path)
+			return exec.Command(path, args[1:]...)
 		}
 	}
 	return nil
 }
 
 func CheckFastlyKey(c *cli.Context) *cli.ExitError {
+	if err := resolveKeyFile(c); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if err := resolveCredentialHelper(c); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
 	if c.GlobalString("fastly-key") == "" {
 		return cli.NewExitError("Error: Fastly API key must be set.", -1)
 	}
 	return nil
 }
 
+// resolveKeyFile sets the global Fastly API key from --fastly-key-file, or
+// from stdin when the key is given as '-'.
+func resolveKeyFile(c *cli.Context) error {
+	file := c.GlobalString("fastly-key-file")
+	if file != "" && c.GlobalIsSet("fastly-key") {
+		return fmt.Errorf("Error: --fastly-key and --fastly-key-file may not be used together.")
+	}
+	if file == "" {
+		if c.GlobalString("fastly-key") != "-" {
+			return nil
+		}
+		file = "-"
+	}
+	key, err := ReadKey(file)
+	if err != nil {
+		return err
+	}
+	return c.GlobalSet("fastly-key", key)
+}
+
+// ReadKey reads a Fastly API key from file, or from stdin if file is '-'.
+// Surrounding whitespace is trimmed, and an empty key is an error.
+func ReadKey(file string) (string, error) {
+	var contents []byte
+	var err error
+	if file == "-" {
+		contents, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		contents, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading API key: %s", err)
+	}
+	key := strings.TrimSpace(string(contents))
+	if key == "" {
+		if file == "-" {
+			file = "stdin"
+		}
+		return "", fmt.Errorf("API key read from %s is empty.", file)
+	}
+	return key, nil
+}
+
 func GetFastlyKey() string {
-	file := "fastly_key"
-	if _, err := os.Stat(file); err == nil {
-		contents, _ := ioutil.ReadFile(file)
-		if contents[len(contents)-1] == []byte("\n")[0] {
-			contents = contents[:len(contents)-1]
+	return readDefaultFile("fastly_key")
+}
+
+// readDefaultFile returns the contents of a file in CWD which supplies a
+// flag default, or an empty string if there is no such file. Errors reading
+// it are reported rather than leaving the flag silently unset.
+func readDefaultFile(file string) string {
+	contents, err := readKeyFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+	return contents
+}
+
+// readKeyFile reads a key from file, returning an empty string if it doesn't
+// exist.
+func readKeyFile(file string) (string, error) {
+	contents, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("Error reading %s: %s", file, err)
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+var keyReference = regexp.MustCompile(`^\$\{(env|profile|helper):([^}]+)\}$`)
+
+// ResolveKey expands a Fastly API key reference from a config file. A
+// reference of the form ${env:NAME} is read from the environment variable
+// NAME, ${profile:NAME} from the file 'fastly_key.NAME' in CWD, and
+// ${helper:NAME} from the credential helper NAME. Any other value is returned
+// as-is.
+func ResolveKey(ref string) (string, error) {
+	match := keyReference.FindStringSubmatch(ref)
+	if match == nil {
+		return ref, nil
+	}
+	var key string
+	var err error
+	switch match[1] {
+	case "env":
+		key = os.Getenv(match[2])
+	case "profile":
+		if key, err = readKeyFile("fastly_key." + match[2]); err != nil {
+			return "", err
 		}
-		return string(contents)
+	case "helper":
+		return RunCredentialHelper(match[2])
+	}
+	if key == "" {
+		return "", fmt.Errorf("API key reference %s resolved to an empty key.", ref)
 	}
-	return ""
+	return key, nil
 }
 
 func GetDiffUrl(s *fastly.Service, from, to uint) *url.URL {